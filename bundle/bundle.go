@@ -0,0 +1,366 @@
+// Package bundle packages databases into a single tar file, so that they can
+// be carried to hosts that cannot reach the update server, and installs them
+// from it there.
+//
+// A bundle starts with manifest.json, which describes every database in it,
+// followed by the databases as <edition>.mmdb. An opened Bundle implements
+// the DatedClient interface of the updater package, so that installing its
+// databases with an updater.Updater takes the lock file, checks the hashes
+// and refuses downgrades exactly like an online update.
+package bundle
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+
+	"github.com/maxmind/geoipupdate/v8/client"
+	"github.com/maxmind/geoipupdate/v8/internal"
+)
+
+const (
+	manifestName = "manifest.json"
+	extension    = ".mmdb"
+)
+
+// ErrInvalidBundle is wrapped by the error returned when opening a file that
+// is not a well-formed bundle. Databases that do not match the hashes in the
+// manifest wrap [client.ErrHashMismatch] instead.
+var ErrInvalidBundle = errors.New("invalid bundle")
+
+// Manifest describes the databases in a bundle.
+type Manifest struct {
+	CreatedAt time.Time  `json:"created_at"`
+	Databases []Database `json:"databases"`
+}
+
+// Database describes a database in a bundle.
+type Database struct {
+	EditionID string `json:"edition_id"`
+	// Date is the date the database was built on, as YYYY-MM-DD.
+	Date       string    `json:"date"`
+	MD5        string    `json:"md5"`
+	SHA256     string    `json:"sha256"`
+	BuildEpoch uint      `json:"build_epoch"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// Write writes a bundle of the databases at paths, which are keyed by edition
+// ID, to w and returns its manifest.
+//
+// The databases are opened before anything is written, so databases that are
+// replaced while the bundle is written are still consistent with the
+// manifest.
+func Write(w io.Writer, paths map[string]string) (_ Manifest, err error) {
+	manifest := Manifest{CreatedAt: time.Now().UTC().Truncate(time.Second)}
+
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			if closeErr := f.Close(); closeErr != nil {
+				err = errors.Join(err, fmt.Errorf("closing %s: %w", f.Name(), closeErr))
+			}
+		}
+	}()
+
+	for _, editionID := range slices.Sorted(maps.Keys(paths)) {
+		//nolint:gosec // the paths are chosen by the caller.
+		f, err := os.Open(paths[editionID])
+		if err != nil {
+			return Manifest{}, fmt.Errorf("opening database of %s: %w", editionID, err)
+		}
+		files = append(files, f)
+
+		db, err := describe(editionID, f)
+		if err != nil {
+			return Manifest{}, err
+		}
+		manifest.Databases = append(manifest.Databases, db)
+	}
+
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return Manifest{}, fmt.Errorf("encoding manifest: %w", err)
+	}
+
+	tw := tar.NewWriter(w)
+	err = tw.WriteHeader(&tar.Header{
+		Name:    manifestName,
+		Mode:    0o644,
+		Size:    int64(len(b)),
+		ModTime: manifest.CreatedAt,
+	})
+	if err != nil {
+		return Manifest{}, fmt.Errorf("writing manifest: %w", err)
+	}
+	if _, err := tw.Write(b); err != nil {
+		return Manifest{}, fmt.Errorf("writing manifest: %w", err)
+	}
+
+	for i, db := range manifest.Databases {
+		err := tw.WriteHeader(&tar.Header{
+			Name:    db.EditionID + extension,
+			Mode:    0o644,
+			Size:    db.Size,
+			ModTime: db.ModifiedAt,
+		})
+		if err != nil {
+			return Manifest{}, fmt.Errorf("writing database of %s: %w", db.EditionID, err)
+		}
+		if _, err := files[i].Seek(0, io.SeekStart); err != nil {
+			return Manifest{}, fmt.Errorf("reading database of %s: %w", db.EditionID, err)
+		}
+		if _, err := io.CopyN(tw, files[i], db.Size); err != nil {
+			return Manifest{}, fmt.Errorf("writing database of %s: %w", db.EditionID, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return Manifest{}, fmt.Errorf("writing bundle: %w", err)
+	}
+	return manifest, nil
+}
+
+// describe hashes the database of an edition in f and reads its build epoch.
+func describe(editionID string, f *os.File) (Database, error) {
+	st, err := f.Stat()
+	if err != nil {
+		return Database{}, fmt.Errorf("reading database of %s: %w", editionID, err)
+	}
+
+	md5Hash := md5.New()
+	sha256Hash := sha256.New()
+	if _, err := io.CopyN(io.MultiWriter(md5Hash, sha256Hash), f, st.Size()); err != nil {
+		return Database{}, fmt.Errorf("hashing database of %s: %w", editionID, err)
+	}
+
+	reader, err := maxminddb.Open(f.Name())
+	if err != nil {
+		return Database{}, fmt.Errorf("reading metadata of %s: %w", editionID, err)
+	}
+	epoch := reader.Metadata.BuildEpoch
+	//nolint:errcheck // The database was only read.
+	_ = reader.Close()
+
+	return Database{
+		EditionID: editionID,
+		//nolint:gosec // build epochs are well within range.
+		Date:       time.Unix(int64(epoch), 0).UTC().Format(time.DateOnly),
+		MD5:        hex.EncodeToString(md5Hash.Sum(nil)),
+		SHA256:     hex.EncodeToString(sha256Hash.Sum(nil)),
+		BuildEpoch: epoch,
+		Size:       st.Size(),
+		ModifiedAt: st.ModTime().UTC(),
+	}, nil
+}
+
+// Bundle is an opened bundle. It serves the databases in it as an update
+// server would.
+//
+// It is valid for concurrent use.
+type Bundle struct {
+	path     string
+	manifest Manifest
+}
+
+// Open opens the bundle at path and verifies that it contains exactly the
+// databases in its manifest, with the hashes and sizes listed there.
+func Open(path string) (*Bundle, error) {
+	//nolint:gosec // the path is chosen by the caller.
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening bundle: %w", err)
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading manifest: %w", ErrInvalidBundle, err)
+	}
+	if hdr.Name != manifestName {
+		return nil, fmt.Errorf("%w: %s is not the first file", ErrInvalidBundle, manifestName)
+	}
+	var manifest Manifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("%w: decoding manifest: %w", ErrInvalidBundle, err)
+	}
+
+	databases := map[string]Database{}
+	for _, db := range manifest.Databases {
+		if _, ok := databases[db.EditionID]; ok {
+			return nil, fmt.Errorf("%w: %s is listed more than once", ErrInvalidBundle, db.EditionID)
+		}
+		databases[db.EditionID] = db
+	}
+
+	seen := map[string]bool{}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBundle, err)
+		}
+
+		db, ok := databases[editionOf(hdr.Name)]
+		if !ok || seen[db.EditionID] {
+			return nil, fmt.Errorf("%w: %s is not in the manifest", ErrInvalidBundle, hdr.Name)
+		}
+		seen[db.EditionID] = true
+
+		if err := verify(db, hdr, tr); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, db := range manifest.Databases {
+		if !seen[db.EditionID] {
+			return nil, fmt.Errorf("%w: the database of %s is missing", ErrInvalidBundle, db.EditionID)
+		}
+	}
+
+	return &Bundle{path: path, manifest: manifest}, nil
+}
+
+// verify checks that the database read from r matches its description.
+func verify(db Database, hdr *tar.Header, r io.Reader) error {
+	if hdr.Size != db.Size {
+		return fmt.Errorf(
+			"%w: the database of %s is %d bytes rather than %d",
+			ErrInvalidBundle, db.EditionID, hdr.Size, db.Size,
+		)
+	}
+
+	md5Hash := md5.New()
+	sha256Hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(md5Hash, sha256Hash), r); err != nil {
+		return fmt.Errorf("%w: reading database of %s: %w", ErrInvalidBundle, db.EditionID, err)
+	}
+	if err := checkHash("SHA-256", db, sha256Hash, db.SHA256); err != nil {
+		return err
+	}
+	return checkHash("MD5", db, md5Hash, db.MD5)
+}
+
+func checkHash(name string, db Database, h hash.Hash, expected string) error {
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != expected {
+		return fmt.Errorf(
+			"%w: %s of the database of %s is %s rather than %s",
+			internal.ErrHashMismatch, name, db.EditionID, actual, expected,
+		)
+	}
+	return nil
+}
+
+// Manifest returns the manifest of the bundle.
+func (b *Bundle) Manifest() Manifest {
+	return b.manifest
+}
+
+// Download returns the database of an edition, unless its MD5 is md5.
+func (b *Bundle) Download(
+	_ context.Context,
+	editionID,
+	md5 string,
+) (client.DownloadResponse, error) {
+	db, ok := b.database(editionID)
+	if !ok {
+		return client.DownloadResponse{}, fmt.Errorf("the bundle has no database of %s", editionID)
+	}
+	if db.MD5 == md5 {
+		return client.DownloadResponse{
+			Reader: io.NopCloser(strings.NewReader("")),
+		}, nil
+	}
+	return b.open(db)
+}
+
+// DownloadDate returns the database of an edition if it was built on date.
+func (b *Bundle) DownloadDate(
+	_ context.Context,
+	editionID,
+	date string,
+) (client.DownloadResponse, error) {
+	db, ok := b.database(editionID)
+	if !ok || db.Date != date {
+		return client.DownloadResponse{}, fmt.Errorf(
+			"the bundle has no database of %s built on %s", editionID, date)
+	}
+	return b.open(db)
+}
+
+func (b *Bundle) database(editionID string) (Database, bool) {
+	i := slices.IndexFunc(b.manifest.Databases, func(db Database) bool {
+		return db.EditionID == editionID
+	})
+	if i < 0 {
+		return Database{}, false
+	}
+	return b.manifest.Databases[i], true
+}
+
+// open returns a response whose Reader reads the database from the bundle.
+func (b *Bundle) open(db Database) (_ client.DownloadResponse, err error) {
+	f, err := os.Open(b.path)
+	if err != nil {
+		return client.DownloadResponse{}, fmt.Errorf("opening bundle: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			//nolint:errcheck // the error is already returned.
+			_ = f.Close()
+		}
+	}()
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			return client.DownloadResponse{}, fmt.Errorf(
+				"%w: finding database of %s: %w", ErrInvalidBundle, db.EditionID, err)
+		}
+		if editionOf(hdr.Name) == db.EditionID {
+			break
+		}
+	}
+
+	return client.DownloadResponse{
+		Date:            db.Date,
+		LastModified:    db.ModifiedAt,
+		MD5:             db.MD5,
+		Reader:          readCloser{Reader: tr, Closer: f},
+		UpdateAvailable: true,
+	}, nil
+}
+
+// editionOf returns the edition of a database file in a bundle, or an empty
+// string if name is not one.
+func editionOf(name string) string {
+	editionID, ok := strings.CutSuffix(name, extension)
+	if !ok {
+		return ""
+	}
+	return editionID
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
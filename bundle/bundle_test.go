@@ -0,0 +1,164 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/client"
+	"github.com/maxmind/geoipupdate/v8/internal/mmdbtest"
+)
+
+func TestWriteOpen(t *testing.T) {
+	dir := t.TempDir()
+	city := filepath.Join(dir, "GeoIP2-City.mmdb")
+	cityMD5 := mmdbtest.Write(t, city, "GeoIP2-City", 1789430400)
+	isp := filepath.Join(dir, "GeoIP2-ISP.mmdb")
+	mmdbtest.Write(t, isp, "GeoIP2-ISP", 1789430400-24*60*60)
+
+	path := filepath.Join(t.TempDir(), "bundle.tar")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	manifest, err := Write(f, map[string]string{"GeoIP2-ISP": isp, "GeoIP2-City": city})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	cityDB, err := os.ReadFile(city)
+	require.NoError(t, err)
+	require.Len(t, manifest.Databases, 2)
+	require.Equal(t, "GeoIP2-City", manifest.Databases[0].EditionID)
+	require.Equal(t, "2026-09-15", manifest.Databases[0].Date)
+	require.Equal(t, uint(1789430400), manifest.Databases[0].BuildEpoch)
+	require.Equal(t, cityMD5, manifest.Databases[0].MD5)
+	require.Equal(t, int64(len(cityDB)), manifest.Databases[0].Size)
+	require.Equal(t, "GeoIP2-ISP", manifest.Databases[1].EditionID)
+	require.Equal(t, "2026-09-14", manifest.Databases[1].Date)
+
+	b, err := Open(path)
+	require.NoError(t, err)
+	require.Equal(t, manifest, b.Manifest())
+
+	res, err := b.Download(t.Context(), "GeoIP2-City", "")
+	require.NoError(t, err)
+	require.True(t, res.UpdateAvailable)
+	require.Equal(t, cityMD5, res.MD5)
+	require.Equal(t, "2026-09-15", res.Date)
+	require.Equal(t, cityDB, readAll(t, res.Reader))
+
+	res, err = b.Download(t.Context(), "GeoIP2-City", cityMD5)
+	require.NoError(t, err)
+	require.False(t, res.UpdateAvailable)
+	require.Empty(t, readAll(t, res.Reader))
+
+	res, err = b.DownloadDate(t.Context(), "GeoIP2-City", "2026-09-15")
+	require.NoError(t, err)
+	require.Equal(t, cityDB, readAll(t, res.Reader))
+
+	_, err = b.DownloadDate(t.Context(), "GeoIP2-City", "2026-09-14")
+	require.EqualError(t, err, "the bundle has no database of GeoIP2-City built on 2026-09-14")
+
+	_, err = b.Download(t.Context(), "GeoIP2-Country", "")
+	require.EqualError(t, err, "the bundle has no database of GeoIP2-Country")
+}
+
+func TestOpenInvalid(t *testing.T) {
+	dir := t.TempDir()
+	city := filepath.Join(dir, "GeoIP2-City.mmdb")
+	mmdbtest.Write(t, city, "GeoIP2-City", 1789430400)
+
+	var valid bytes.Buffer
+	_, err := Write(&valid, map[string]string{"GeoIP2-City": city})
+	require.NoError(t, err)
+
+	tests := []struct {
+		description string
+		bundle      func() []byte
+		errIs       error
+		err         string
+	}{
+		{
+			description: "not a tar file",
+			bundle:      func() []byte { return []byte("not a bundle") },
+			errIs:       ErrInvalidBundle,
+		},
+		{
+			description: "no manifest",
+			bundle: func() []byte {
+				return tarOf(t, map[string][]byte{"GeoIP2-City.mmdb": []byte("db")})
+			},
+			errIs: ErrInvalidBundle,
+			err:   "invalid bundle: manifest.json is not the first file",
+		},
+		{
+			description: "database missing",
+			bundle: func() []byte {
+				return truncateAfterManifest(t, valid.Bytes())
+			},
+			errIs: ErrInvalidBundle,
+			err:   "invalid bundle: the database of GeoIP2-City is missing",
+		},
+		{
+			description: "database corrupted",
+			bundle: func() []byte {
+				b := bytes.Clone(valid.Bytes())
+				// The name starts the header block of the database, which
+				// is followed by its data.
+				i := bytes.LastIndex(b, []byte("GeoIP2-City.mmdb"))
+				b[i+512] ^= 0xff
+				return b
+			},
+			errIs: client.ErrHashMismatch,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bundle.tar")
+			require.NoError(t, os.WriteFile(path, test.bundle(), 0o600))
+
+			_, err := Open(path)
+			require.ErrorIs(t, err, test.errIs)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			}
+		})
+	}
+}
+
+// tarOf returns a tar file of the given files.
+func tarOf(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, b := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(b))}))
+		_, err := tw.Write(b)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+// truncateAfterManifest returns the bundle with only its manifest.
+func truncateAfterManifest(t *testing.T, bundle []byte) []byte {
+	t.Helper()
+	tr := tar.NewReader(bytes.NewReader(bundle))
+	_, err := tr.Next()
+	require.NoError(t, err)
+	manifest, err := io.ReadAll(tr)
+	require.NoError(t, err)
+	return tarOf(t, map[string][]byte{manifestName: manifest})
+}
+
+func readAll(t *testing.T, r io.ReadCloser) []byte {
+	t.Helper()
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return b
+}
@@ -4,7 +4,10 @@ package client
 
 import (
 	"fmt"
+	"log"
 	"net/http"
+
+	"github.com/cenkalti/backoff/v5"
 )
 
 // Client downloads GeoIP and GeoLite MMDB databases.
@@ -17,6 +20,14 @@ type Client struct {
 	endpoint   string
 	httpClient *http.Client
 	licenseKey string
+	logger     *log.Logger
+	resumeDir  string
+	// resumeBackOff returns how long to wait between the attempts to resume
+	// a download. It is only set in tests.
+	resumeBackOff func() backoff.BackOff
+	// signatureSuffix is the suffix DownloadSignature requests signatures
+	// with.
+	signatureSuffix string
 }
 
 // Option is an option for configuring Client.
@@ -38,6 +49,15 @@ func WithHTTPClient(httpClient *http.Client) Option {
 	}
 }
 
+// WithLogger sets the logger that problems which do not fail downloads are
+// logged to, such as failing to keep a partial download on disk. By default,
+// nothing is logged.
+func WithLogger(l *log.Logger) Option {
+	return func(c *Client) {
+		c.logger = l
+	}
+}
+
 // New creates a Client.
 func New(
 	accountID int,
@@ -65,3 +85,9 @@ func New(
 
 	return c, nil
 }
+
+func (c *Client) logf(format string, v ...any) {
+	if c.logger != nil {
+		c.logger.Printf(format, v...)
+	}
+}
@@ -9,27 +9,24 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/maxmind/geoipupdate/v8/internal"
-	"github.com/maxmind/geoipupdate/v8/internal/vars"
 )
 
-// HTTPError is an error from performing an HTTP request and receiving a non-200 status code.
-//
-// See https://dev.maxmind.com/geoip/docs/web-services/responses/#errors for more details.
-type HTTPError = internal.HTTPError
-
 // DownloadResponse describes the result of a Download call.
 type DownloadResponse struct {
+	// Date is the date that the database was built, e.g., "2024-02-23". It
+	// will only be set if UpdateAvailable is true.
+	Date string
+
 	// LastModified is the date that the database was last modified. It will
 	// only be set if UpdateAvailable is true.
 	LastModified time.Time
 
 	// MD5 is the string representation of the new database. It will only be set
-	// if UpdateAvailable is true.
+	// if UpdateAvailable is true, and is never set by DownloadDate.
 	MD5 string
 
 	// Reader can be read to access the database itself. It will only contain a
@@ -62,7 +59,9 @@ type DownloadResponse struct {
 // download is performed.
 //
 // Returns an [HTTPError] if the server returns a non-200 status code. This
-// can be used to identify problems with license.
+// can be used to identify problems with license. Errors the server reports
+// in its JSON error documents can be checked with errors.Is, e.g.,
+// errors.Is(err, [ErrInvalidLicenseKey]).
 func (c Client) Download(
 	ctx context.Context,
 	editionID,
@@ -80,12 +79,18 @@ func (c Client) Download(
 		}, nil
 	}
 
+	if c.resumeDir != "" {
+		latest := c.partialPath(editionID, strings.ReplaceAll(metadata.Date, "-", ""))
+		c.removeStalePartials(editionID, latest)
+	}
+
 	reader, modifiedTime, err := c.download(ctx, editionID, metadata.Date)
 	if err != nil {
 		return DownloadResponse{}, err
 	}
 
 	return DownloadResponse{
+		Date:            metadata.Date,
 		LastModified:    modifiedTime,
 		MD5:             metadata.MD5,
 		Reader:          reader,
@@ -93,13 +98,60 @@ func (c Client) Download(
 	}, nil
 }
 
+// DownloadDate downloads the database of the edition built on date, e.g.,
+// "2026-09-15", rather than the latest one. This makes it possible to pin an
+// edition to a known build.
+//
+// The MD5 of an earlier build is not known in advance, so the MD5 of the
+// response is empty. The database is still protected against corruption in
+// transit by the checksum of the gzip archive. UpdateAvailable is always
+// true, and the caller must read Reader to completion and close it.
+//
+// Returns an [HTTPError] if the server returns a non-200 status code, e.g.,
+// if there is no database of the edition built on date.
+func (c Client) DownloadDate(
+	ctx context.Context,
+	editionID,
+	date string,
+) (DownloadResponse, error) {
+	if _, err := time.Parse(time.DateOnly, date); err != nil {
+		return DownloadResponse{}, fmt.Errorf("invalid date %q: %w", date, err)
+	}
+
+	reader, modifiedTime, err := c.download(ctx, editionID, date)
+	if err != nil {
+		return DownloadResponse{}, err
+	}
+
+	return DownloadResponse{
+		Date:            date,
+		LastModified:    modifiedTime,
+		Reader:          reader,
+		UpdateAvailable: true,
+	}, nil
+}
+
 const downloadEndpoint = "%s/geoip/databases/%s/download?"
 
+// maxErrorBodySize is the most we read of an error response. It is enough
+// for the JSON error documents the server sends.
+const maxErrorBodySize = 4096
+
+// statusError closes a download response with an unexpected status code and
+// returns the HTTPError for it.
+func statusError(response *http.Response) error {
+	defer response.Body.Close()
+	// TODO(horgh): Should we fully consume the body?
+	//nolint:errcheck // we are already returning an error.
+	buf, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBodySize))
+	return internal.NewHTTPError(response.StatusCode, buf)
+}
+
 func (c *Client) download(
 	ctx context.Context,
 	editionID,
 	date string,
-) (io.ReadCloser, time.Time, error) {
+) (_ io.ReadCloser, _ time.Time, err error) {
 	date = strings.ReplaceAll(date, "-", "")
 
 	params := url.Values{}
@@ -109,38 +161,19 @@ func (c *Client) download(
 	escapedEdition := url.PathEscape(editionID)
 	requestURL := fmt.Sprintf(downloadEndpoint, c.endpoint, escapedEdition) + params.Encode()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, http.NoBody)
-	if err != nil {
-		return nil, time.Time{}, fmt.Errorf("creating download request: %w", err)
-	}
-	req.Header.Add("User-Agent", "geoipupdate/"+vars.Version)
-	req.SetBasicAuth(strconv.Itoa(c.accountID), c.licenseKey)
-
-	response, err := c.httpClient.Do(req)
+	body, response, err := c.openDownload(ctx, requestURL, editionID, date)
 	if err != nil {
-		return nil, time.Time{}, fmt.Errorf("performing download request: %w", err)
+		return nil, time.Time{}, err
 	}
-	// It is safe to close the response body reader as it wouldn't be
-	// consumed in case this function returns an error.
+	// It is safe to close the body as it wouldn't be consumed in case this
+	// function returns an error.
 	defer func() {
 		if err != nil {
-			// TODO(horgh): Should we fully consume the body?
-			response.Body.Close()
+			body.Close()
 		}
 	}()
 
-	if response.StatusCode != http.StatusOK {
-		// TODO(horgh): Should we fully consume the body?
-		//nolint:errcheck // we are already returning an error.
-		buf, _ := io.ReadAll(io.LimitReader(response.Body, 256))
-		httpErr := HTTPError{
-			Body:       string(buf),
-			StatusCode: response.StatusCode,
-		}
-		return nil, time.Time{}, fmt.Errorf("unexpected HTTP status code: %w", httpErr)
-	}
-
-	gzReader, err := gzip.NewReader(response.Body)
+	gzReader, err := gzip.NewReader(body)
 	if err != nil {
 		return nil, time.Time{}, fmt.Errorf("encountered an error creating GZIP reader: %w", err)
 	}
@@ -175,7 +208,7 @@ func (c *Client) download(
 	return editionReader{
 			Reader:         tarReader,
 			gzCloser:       gzReader,
-			responseCloser: response.Body,
+			responseCloser: body,
 		},
 		lastModified,
 		nil
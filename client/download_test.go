@@ -103,6 +103,7 @@ func TestDownload(t *testing.T) {
 				require.NoError(t, rerr)
 				require.Equal(t, dbContent, string(c))
 				require.Equal(t, "618dd27a10de24809ec160d6807f363f", res.MD5)
+				require.Equal(t, "2024-02-23", res.Date)
 				require.Equal(t, lastModified, res.LastModified)
 			},
 		},
@@ -127,6 +128,35 @@ func TestDownload(t *testing.T) {
 				require.Regexp(t, "^unexpected HTTP status code", err.Error())
 			},
 		},
+		{
+			description:      "edition not entitled",
+			preserveFileTime: false,
+			server: func(t *testing.T) *httptest.Server {
+				server := httptest.NewServer(
+					http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						if strings.HasPrefix(r.URL.Path, "/geoip/updates/metadata") {
+							metadataHandler.ServeHTTP(w, r)
+							return
+						}
+
+						w.Header().Set("Content-Type", "application/json")
+						w.WriteHeader(http.StatusForbidden)
+						_, err := w.Write([]byte(
+							`{"code":"PERMISSION_REQUIRED","error":"You do not have permission"}`,
+						))
+						assert.NoError(t, err)
+					}),
+				)
+				return server
+			},
+			checkResult: func(t *testing.T, _ DownloadResponse, err error) {
+				require.ErrorIs(t, err, ErrEditionNotEntitled)
+
+				var httpErr HTTPError
+				require.ErrorAs(t, err, &httpErr)
+				require.Equal(t, "PERMISSION_REQUIRED", httpErr.Code)
+			},
+		},
 		{
 			description:      "wrong file format",
 			preserveFileTime: false,
@@ -269,3 +299,49 @@ func TestDownload(t *testing.T) {
 		})
 	}
 }
+
+func TestDownloadDate(t *testing.T) {
+	dbContent := "edition-1 content"
+	lastModified := time.Date(2026, 9, 15, 12, 0, 0, 0, time.UTC)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/geoip/databases/edition-1/download", r.URL.Path)
+		if r.URL.Query().Get("date") != "20260915" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Last-Modified", lastModified.Format(time.RFC1123))
+		gw := gzip.NewWriter(w)
+		tw := tar.NewWriter(gw)
+		err := tw.WriteHeader(&tar.Header{Name: "edition-1.mmdb", Size: int64(len(dbContent))})
+		assert.NoError(t, err)
+		_, err = tw.Write([]byte(dbContent))
+		assert.NoError(t, err)
+		assert.NoError(t, tw.Close())
+		assert.NoError(t, gw.Close())
+	}))
+	defer server.Close()
+
+	c, err := New(10, "license", WithEndpoint(server.URL))
+	require.NoError(t, err)
+
+	res, err := c.DownloadDate(t.Context(), "edition-1", "2026-09-15")
+	require.NoError(t, err)
+	got, err := io.ReadAll(res.Reader)
+	require.NoError(t, err)
+	require.NoError(t, res.Reader.Close())
+	require.Equal(t, dbContent, string(got))
+	require.True(t, res.UpdateAvailable)
+	require.Equal(t, "2026-09-15", res.Date)
+	require.Empty(t, res.MD5)
+	require.Equal(t, lastModified, res.LastModified)
+
+	_, err = c.DownloadDate(t.Context(), "edition-1", "2026-09-16")
+	var httpErr HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	require.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+
+	_, err = c.DownloadDate(t.Context(), "edition-1", "20260915")
+	require.ErrorContains(t, err, `invalid date "20260915"`)
+}
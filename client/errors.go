@@ -0,0 +1,37 @@
+package client
+
+import (
+	"github.com/maxmind/geoipupdate/v8/internal"
+)
+
+// HTTPError is an error from performing an HTTP request and receiving a non-200 status code.
+//
+// When the server sends a JSON error document, its code and error fields are
+// available as Code and Message, and the HTTPError matches the errors below
+// with errors.Is.
+//
+// See https://dev.maxmind.com/geoip/docs/web-services/responses/#errors for more details.
+type HTTPError = internal.HTTPError
+
+var (
+	// ErrInvalidLicenseKey is matched by an [HTTPError] when the server
+	// rejected the account ID and license key.
+	ErrInvalidLicenseKey = internal.ErrInvalidLicenseKey
+
+	// ErrEditionNotEntitled is matched by an [HTTPError] when the account is
+	// not permitted to download the edition.
+	ErrEditionNotEntitled = internal.ErrEditionNotEntitled
+
+	// ErrAccountIDRequired is matched by an [HTTPError] when the request did
+	// not include an account ID.
+	ErrAccountIDRequired = internal.ErrAccountIDRequired
+
+	// ErrHashMismatch is wrapped by the error returned when a downloaded
+	// database does not match the MD5 in its [DownloadResponse], e.g., when
+	// geoipupdate writes it to disk.
+	ErrHashMismatch = internal.ErrHashMismatch
+
+	// ErrNotFound is matched by an [HTTPError] with status code 404, e.g.,
+	// when there is no database of an edition built on a date.
+	ErrNotFound = internal.ErrNotFound
+)
@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"strconv"
 
+	"github.com/maxmind/geoipupdate/v8/internal"
 	"github.com/maxmind/geoipupdate/v8/internal/vars"
 )
 
@@ -50,10 +51,7 @@ func (c *Client) getMetadata(
 	}
 
 	if response.StatusCode != http.StatusOK {
-		httpErr := HTTPError{
-			Body:       string(responseBody),
-			StatusCode: response.StatusCode,
-		}
+		httpErr := internal.NewHTTPError(response.StatusCode, responseBody)
 		return nil, fmt.Errorf("unexpected HTTP status code: %w", httpErr)
 	}
 
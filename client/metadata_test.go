@@ -65,6 +65,34 @@ func TestGetMetadata(t *testing.T) {
 				require.Regexp(t, "^unexpected HTTP status code", err.Error())
 			},
 		},
+		{
+			description:      "invalid license key",
+			preserveFileTime: false,
+			server: func(t *testing.T) *httptest.Server {
+				server := httptest.NewServer(
+					http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+						w.Header().Set("Content-Type", "application/json")
+						w.WriteHeader(http.StatusUnauthorized)
+						_, err := w.Write([]byte(
+							`{"code":"AUTHORIZATION_INVALID","error":"Invalid account ID or license key"}`,
+						))
+						assert.NoError(t, err)
+					}),
+				)
+				return server
+			},
+			checkResult: func(t *testing.T, receivedMetadata *metadata, err error) {
+				require.Nil(t, receivedMetadata)
+				require.ErrorIs(t, err, ErrInvalidLicenseKey)
+				require.NotErrorIs(t, err, ErrEditionNotEntitled)
+
+				var httpErr HTTPError
+				require.ErrorAs(t, err, &httpErr)
+				require.Equal(t, http.StatusUnauthorized, httpErr.StatusCode)
+				require.Equal(t, "AUTHORIZATION_INVALID", httpErr.Code)
+				require.Equal(t, "Invalid account ID or license key", httpErr.Message)
+			},
+		},
 	}
 
 	ctx := context.Background()
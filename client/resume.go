@@ -0,0 +1,415 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+
+	"github.com/maxmind/geoipupdate/v8/internal/vars"
+)
+
+const (
+	// maxResumes is the number of times a download is resumed within a
+	// Download call. The attempts back off exponentially.
+	maxResumes = 5
+
+	partialExtension   = ".tar.gz.partial"
+	validatorExtension = ".validator"
+)
+
+// WithResumeDirectory sets a directory to keep partially downloaded
+// databases in. If a download is interrupted, e.g., because the connection
+// dropped, the next Download of the same database resumes it rather than
+// starting over. By default, downloads are only resumed within a Download
+// call.
+func WithResumeDirectory(dir string) Option {
+	return func(c *Client) {
+		c.resumeDir = dir
+	}
+}
+
+// resumableBody is the compressed stream of a download. If reading it fails
+// part way through, it resumes the download with a Range request, using
+// If-Range so that it does not mix two different databases. When the client
+// has a resume directory, the stream is also kept on disk so that a later
+// download of the same database can resume from it.
+type resumableBody struct {
+	ctx    context.Context //nolint:containedctx // Read has no context.
+	client *Client
+	url    string
+
+	// validator is the ETag or Last-Modified of the download, if the server
+	// sent one that can be used with If-Range.
+	validator string
+	body      io.ReadCloser
+	// offset is the position in the stream that body is at.
+	offset  int64
+	resumes int
+	// backOff is how long to wait before each attempt to resume.
+	backOff backoff.BackOff
+
+	// replay reads what an earlier download kept on disk.
+	replay     io.Reader
+	replayFile *os.File
+
+	// spool is where the stream is kept, if it is kept on disk.
+	partialPath string
+	spool       *os.File
+
+	// interrupted is set when reading failed and the download could not be
+	// resumed. Only then is the partial download kept.
+	interrupted bool
+}
+
+// openDownload performs the download request. If a partial download of the
+// same database is on disk, it is resumed.
+func (c *Client) openDownload(
+	ctx context.Context,
+	requestURL,
+	editionID,
+	date string,
+) (*resumableBody, *http.Response, error) {
+	b := &resumableBody{
+		ctx:    ctx,
+		client: c,
+		url:    requestURL,
+	}
+
+	var partialSize int64
+	if c.resumeDir != "" {
+		b.partialPath = c.partialPath(editionID, date)
+		partialSize, b.validator = readPartial(b.partialPath)
+	}
+
+	header := http.Header{}
+	if partialSize > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", partialSize))
+		header.Set("If-Range", b.validator)
+	}
+
+	response, err := c.doDownloadRequest(ctx, requestURL, header)
+	if err != nil {
+		return nil, nil, fmt.Errorf("performing download request: %w", err)
+	}
+	if response.StatusCode == http.StatusRequestedRangeNotSatisfiable && partialSize > 0 {
+		// The partial download is of no use. Start over, once.
+		response.Body.Close()
+		c.removePartial(b.partialPath)
+		partialSize = 0
+		response, err = c.doDownloadRequest(ctx, requestURL, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("performing download request: %w", err)
+		}
+	}
+
+	switch response.StatusCode {
+	case http.StatusPartialContent:
+		if partialSize == 0 || contentRangeStart(response) != partialSize {
+			response.Body.Close()
+			c.removePartial(b.partialPath)
+			return nil, nil, errors.New("received an unexpected partial response")
+		}
+
+		f, err := os.Open(b.partialPath)
+		if err != nil {
+			response.Body.Close()
+			return nil, nil, fmt.Errorf("opening partial download: %w", err)
+		}
+		b.replayFile = f
+		b.replay = io.NewSectionReader(f, 0, partialSize)
+		b.offset = partialSize
+		b.body = response.Body
+		b.openSpool(os.O_WRONLY | os.O_APPEND)
+		return b, response, nil
+	case http.StatusOK:
+		b.validator = responseValidator(response)
+		b.body = response.Body
+		if b.partialPath != "" && b.validator != "" {
+			b.openSpool(os.O_WRONLY | os.O_CREATE | os.O_TRUNC)
+		} else {
+			c.removePartial(b.partialPath)
+		}
+		return b, response, nil
+	default:
+		return nil, nil, fmt.Errorf("unexpected HTTP status code: %w", statusError(response))
+	}
+}
+
+func (b *resumableBody) Read(p []byte) (int, error) {
+	if b.replay != nil {
+		n, err := b.replay.Read(p)
+		if errors.Is(err, io.EOF) {
+			b.replay = nil
+			err = nil
+		}
+		if err != nil {
+			return n, fmt.Errorf("reading partial download: %w", err)
+		}
+		if n > 0 || b.replay != nil {
+			return n, nil
+		}
+	}
+
+	for {
+		n, err := b.body.Read(p)
+		if n > 0 {
+			b.offset += int64(n)
+			b.writeSpool(p[:n])
+		}
+		if err == nil || errors.Is(err, io.EOF) {
+			return n, err
+		}
+
+		if resumeErr := b.resume(err); resumeErr != nil {
+			b.interrupted = true
+			return n, resumeErr
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// resume requests the rest of the download after reading failed with
+// cause. Requests that fail are retried up to maxResumes times in total,
+// backing off before each of them.
+func (b *resumableBody) resume(cause error) error {
+	b.body.Close()
+	b.body = http.NoBody
+
+	header := http.Header{}
+	header.Set("Range", fmt.Sprintf("bytes=%d-", b.offset))
+	header.Set("If-Range", b.validator)
+
+	var response *http.Response
+	for response == nil {
+		if b.validator == "" || b.resumes >= maxResumes || !b.wait() {
+			return cause
+		}
+		b.resumes++
+
+		var err error
+		response, err = b.client.doDownloadRequest(b.ctx, b.url, header)
+		if err != nil {
+			cause = err
+		}
+	}
+
+	switch response.StatusCode {
+	case http.StatusPartialContent:
+		if contentRangeStart(response) != b.offset {
+			response.Body.Close()
+			return fmt.Errorf("resuming download after %w: unexpected Content-Range", cause)
+		}
+	case http.StatusOK:
+		// The server does not support ranges, or the database changed.
+		if responseValidator(response) != b.validator {
+			response.Body.Close()
+			return fmt.Errorf("resuming download after %w: the database changed", cause)
+		}
+		if _, err := io.CopyN(io.Discard, response.Body, b.offset); err != nil {
+			response.Body.Close()
+			return fmt.Errorf("resuming download after %w: %w", cause, err)
+		}
+	default:
+		return fmt.Errorf("resuming download after %w: %w", cause, statusError(response))
+	}
+
+	b.body = response.Body
+	return nil
+}
+
+// wait waits before the next attempt to resume. It returns false if the
+// context is done first or there should be no more attempts.
+func (b *resumableBody) wait() bool {
+	if b.backOff == nil {
+		b.backOff = b.client.newResumeBackOff()
+	}
+	d := b.backOff.NextBackOff()
+	if d == backoff.Stop {
+		return false
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-b.ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// Close closes the download. The partial download is only kept on disk if
+// the download was interrupted.
+func (b *resumableBody) Close() error {
+	err := b.body.Close()
+	if b.replayFile != nil {
+		err = errors.Join(err, b.replayFile.Close())
+	}
+	if b.spool != nil {
+		err = errors.Join(err, b.spool.Close())
+	}
+	if !b.interrupted {
+		b.client.removePartial(b.partialPath)
+	}
+	return err
+}
+
+// openSpool opens the partial download for writing. It is not kept if that
+// fails.
+func (b *resumableBody) openSpool(flag int) {
+	if b.partialPath == "" {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(b.partialPath), 0o750); err != nil {
+		b.client.logf("Not keeping partial download: %s", err)
+		return
+	}
+
+	err := os.WriteFile(b.partialPath+validatorExtension, []byte(b.validator), 0o600)
+	if err != nil {
+		b.client.logf("Not keeping partial download: %s", err)
+		return
+	}
+
+	//nolint:gosec // the path is built from the resume directory.
+	f, err := os.OpenFile(b.partialPath, flag, 0o600)
+	if err != nil {
+		b.client.logf("Not keeping partial download: %s", err)
+		b.client.removePartial(b.partialPath)
+		return
+	}
+	b.spool = f
+}
+
+// writeSpool adds p to the partial download. If that fails, the partial
+// download is discarded, but the download itself carries on.
+func (b *resumableBody) writeSpool(p []byte) {
+	if b.spool == nil {
+		return
+	}
+	if _, err := b.spool.Write(p); err != nil {
+		b.client.logf("Not keeping partial download: %s", err)
+		//nolint:errcheck // we are already discarding it.
+		_ = b.spool.Close()
+		b.spool = nil
+		b.client.removePartial(b.partialPath)
+	}
+}
+
+// doDownloadRequest performs a GET request for the download with the given
+// extra headers.
+func (c *Client) doDownloadRequest(
+	ctx context.Context,
+	requestURL string,
+	header http.Header,
+) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("creating download request: %w", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Add("User-Agent", "geoipupdate/"+vars.Version)
+	req.SetBasicAuth(strconv.Itoa(c.accountID), c.licenseKey)
+
+	//nolint:gosec // the URL is built from the configured endpoint.
+	return c.httpClient.Do(req)
+}
+
+// newResumeBackOff returns how long to wait between the attempts to resume
+// a download.
+func (c *Client) newResumeBackOff() backoff.BackOff {
+	if c.resumeBackOff != nil {
+		return c.resumeBackOff()
+	}
+	return backoff.NewExponentialBackOff()
+}
+
+// partialPath returns where the partial download of the build of an edition
+// on date, as YYYYMMDD, is kept.
+func (c *Client) partialPath(editionID, date string) string {
+	return filepath.Join(c.resumeDir, editionID+"-"+date+partialExtension)
+}
+
+// readPartial returns the size and validator of a partial download. The size
+// is zero if there is none that can be resumed.
+func readPartial(path string) (int64, string) {
+	//nolint:gosec // the path is built from the resume directory.
+	validator, err := os.ReadFile(path + validatorExtension)
+	if err != nil || len(validator) == 0 {
+		return 0, ""
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, ""
+	}
+	return info.Size(), string(validator)
+}
+
+// removePartial removes a partial download, if there is one.
+func (c *Client) removePartial(path string) {
+	if path == "" {
+		return
+	}
+	for _, p := range []string{path, path + validatorExtension} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			c.logf("Removing partial download: %s", err)
+		}
+	}
+}
+
+// removeStalePartials removes the partial downloads of builds of the edition
+// other than the latest one, keep. Only downloads of the latest build prune
+// them, as downloads of several dates may run at the same time.
+func (c *Client) removeStalePartials(editionID, keep string) {
+	matches, err := filepath.Glob(filepath.Join(c.resumeDir, editionID+"-[0-9]*"+partialExtension))
+	if err != nil {
+		return
+	}
+	for _, m := range matches {
+		if m != keep {
+			c.removePartial(m)
+		}
+	}
+}
+
+// responseValidator returns the validator to use in If-Range headers for
+// the response. Weak ETags cannot be used with If-Range.
+func responseValidator(response *http.Response) string {
+	if etag := response.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return response.Header.Get("Last-Modified")
+}
+
+// contentRangeStart returns the first byte position of a partial response,
+// or -1 if it cannot be parsed.
+func contentRangeStart(response *http.Response) int64 {
+	rangeSpec, ok := strings.CutPrefix(response.Header.Get("Content-Range"), "bytes ")
+	if !ok {
+		return -1
+	}
+	start, _, ok := strings.Cut(rangeSpec, "-")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
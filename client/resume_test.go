@@ -0,0 +1,394 @@
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadResume(t *testing.T) {
+	tests := []struct {
+		description string
+		// supportRanges is whether the server honors Range headers.
+		supportRanges bool
+		// changeETag changes the ETag after the first request.
+		changeETag bool
+		wantRange  string
+		wantErr    string
+	}{
+		{
+			description:   "resumes with a range request",
+			supportRanges: true,
+			wantRange:     "bytes=",
+		},
+		{
+			description: "server without range support",
+		},
+		{
+			description:   "database changed",
+			supportRanges: true,
+			changeETag:    true,
+			wantErr:       "the database changed",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			content := strings.Repeat("database content ", 10000)
+			s := newRangeServer(t, content)
+			s.supportRanges = test.supportRanges
+			s.abortFull = 1
+			if test.changeETag {
+				s.nextETag = `"v2"`
+			}
+			server := httptest.NewServer(s)
+			defer server.Close()
+
+			c, err := New(10, "license", WithEndpoint(server.URL))
+			require.NoError(t, err)
+			c.resumeBackOff = func() backoff.BackOff { return &backoff.ZeroBackOff{} }
+
+			res, err := c.Download(t.Context(), "edition-1", "")
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(res.Reader)
+			// The gzip reader returns the error from reading again on Close.
+			closeErr := res.Reader.Close()
+			if test.wantErr != "" {
+				require.ErrorContains(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, closeErr)
+			require.Equal(t, content, string(got))
+
+			ranges := s.ranges()
+			require.Len(t, ranges, 2)
+			require.Empty(t, ranges[0])
+			if test.wantRange != "" {
+				require.Equal(t, "bytes="+strconv.Itoa(s.abortedAt)+"-", ranges[1])
+			}
+		})
+	}
+}
+
+func TestDownloadResumeFromDisk(t *testing.T) {
+	content := strings.Repeat("database content ", 10000)
+	s := newRangeServer(t, content)
+	s.supportRanges = true
+	// Every request of the first Download is interrupted.
+	s.abortFull = 1
+	s.abortRanged = maxResumes
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	dir := t.TempDir()
+	c, err := New(10, "license", WithEndpoint(server.URL), WithResumeDirectory(dir))
+	require.NoError(t, err)
+	waits := &countingBackOff{}
+	c.resumeBackOff = func() backoff.BackOff { return waits }
+
+	// A partial download of an older build is removed.
+	stale := filepath.Join(dir, "edition-1-20240101"+partialExtension)
+	require.NoError(t, os.WriteFile(stale, []byte("old"), 0o600))
+
+	res, err := c.Download(t.Context(), "edition-1", "")
+	require.NoError(t, err)
+	_, err = io.ReadAll(res.Reader)
+	require.Error(t, err)
+	require.Error(t, res.Reader.Close())
+	// Every attempt to resume backed off first.
+	require.Equal(t, maxResumes, waits.calls)
+
+	require.NoFileExists(t, stale)
+	partial := filepath.Join(dir, "edition-1-20240223"+partialExtension)
+	info, err := os.Stat(partial)
+	require.NoError(t, err)
+	require.Equal(t, int64(s.abortedAt), info.Size())
+
+	res, err = c.Download(t.Context(), "edition-1", "")
+	require.NoError(t, err)
+	got, err := io.ReadAll(res.Reader)
+	require.NoError(t, err)
+	require.NoError(t, res.Reader.Close())
+	require.Equal(t, content, string(got))
+
+	ranges := s.ranges()
+	require.Equal(t, "bytes="+strconv.Itoa(s.abortedAt)+"-", ranges[len(ranges)-1])
+
+	// The partial download is removed once it is complete.
+	require.NoFileExists(t, partial)
+	require.NoFileExists(t, partial+validatorExtension)
+}
+
+func TestDownloadResumeStopsWithContext(t *testing.T) {
+	content := strings.Repeat("database content ", 10000)
+	s := newRangeServer(t, content)
+	s.supportRanges = true
+	s.abortFull = 1
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	c, err := New(10, "license", WithEndpoint(server.URL))
+	require.NoError(t, err)
+	c.resumeBackOff = func() backoff.BackOff { return backoff.NewConstantBackOff(time.Hour) }
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	res, err := c.Download(ctx, "edition-1", "")
+	require.NoError(t, err)
+	_, err = io.ReadAll(res.Reader)
+	require.Error(t, err)
+	require.Error(t, res.Reader.Close())
+
+	// The download was not resumed while backing off.
+	require.Len(t, s.ranges(), 1)
+}
+
+func TestDownloadLogsUnkeptPartials(t *testing.T) {
+	content := strings.Repeat("database content ", 10000)
+	s := newRangeServer(t, content)
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	// The resume directory cannot be created under a file.
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+
+	var logs bytes.Buffer
+	c, err := New(
+		10,
+		"license",
+		WithEndpoint(server.URL),
+		WithResumeDirectory(filepath.Join(file, "resume")),
+		WithLogger(log.New(&logs, "", 0)),
+	)
+	require.NoError(t, err)
+
+	// The download itself succeeds.
+	res, err := c.Download(t.Context(), "edition-1", "")
+	require.NoError(t, err)
+	got, err := io.ReadAll(res.Reader)
+	require.NoError(t, err)
+	require.NoError(t, res.Reader.Close())
+	require.Equal(t, content, string(got))
+
+	require.Contains(t, logs.String(), "Not keeping partial download")
+}
+
+func TestDownloadDateKeepsPartials(t *testing.T) {
+	content := strings.Repeat("database content ", 10000)
+	s := newRangeServer(t, content)
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	dir := t.TempDir()
+	c, err := New(10, "license", WithEndpoint(server.URL), WithResumeDirectory(dir))
+	require.NoError(t, err)
+
+	// Downloads of other dates may be in progress.
+	other := filepath.Join(dir, "edition-1-20240101"+partialExtension)
+	require.NoError(t, os.WriteFile(other, []byte("other"), 0o600))
+
+	res, err := c.DownloadDate(t.Context(), "edition-1", "2024-01-02")
+	require.NoError(t, err)
+	got, err := io.ReadAll(res.Reader)
+	require.NoError(t, err)
+	require.NoError(t, res.Reader.Close())
+	require.Equal(t, content, string(got))
+
+	require.FileExists(t, other)
+}
+
+func TestDownloadRangeNotSatisfiable(t *testing.T) {
+	tests := []struct {
+		description string
+		// always416 makes the server refuse every request.
+		always416 bool
+		wantErr   bool
+	}{
+		{
+			description: "starts over",
+		},
+		{
+			description: "refused again",
+			always416:   true,
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			content := strings.Repeat("database content ", 10000)
+			s := newRangeServer(t, content)
+			s.supportRanges = true
+			var handler http.Handler = s
+			if test.always416 {
+				handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path == "/geoip/updates/metadata" {
+						s.ServeHTTP(w, r)
+						return
+					}
+					s.mu.Lock()
+					s.rangeHeaders = append(s.rangeHeaders, r.Header.Get("Range"))
+					s.mu.Unlock()
+					w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+				})
+			}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			// The partial download is longer than the archive.
+			dir := t.TempDir()
+			partial := filepath.Join(dir, "edition-1-20240223"+partialExtension)
+			require.NoError(t, os.WriteFile(partial, bytes.Repeat([]byte("x"), len(s.archive)+1), 0o600))
+			require.NoError(t, os.WriteFile(partial+validatorExtension, []byte(s.etag), 0o600))
+
+			c, err := New(10, "license", WithEndpoint(server.URL), WithResumeDirectory(dir))
+			require.NoError(t, err)
+
+			res, err := c.Download(t.Context(), "edition-1", "")
+			ranges := s.ranges()
+			require.Len(t, ranges, 2)
+			require.NotEmpty(t, ranges[0])
+			require.Empty(t, ranges[1])
+
+			if test.wantErr {
+				require.NoFileExists(t, partial)
+				var httpErr HTTPError
+				require.ErrorAs(t, err, &httpErr)
+				require.Equal(t, http.StatusRequestedRangeNotSatisfiable, httpErr.StatusCode)
+				return
+			}
+			require.NoError(t, err)
+			got, err := io.ReadAll(res.Reader)
+			require.NoError(t, err)
+			require.NoError(t, res.Reader.Close())
+			require.Equal(t, content, string(got))
+			require.NoFileExists(t, partial)
+		})
+	}
+}
+
+// countingBackOff never waits and counts how often it was asked to.
+type countingBackOff struct {
+	calls int
+}
+
+func (b *countingBackOff) NextBackOff() time.Duration {
+	b.calls++
+	return 0
+}
+
+func (b *countingBackOff) Reset() {}
+
+// rangeServer serves a database archive, interrupting some of the
+// responses part way through.
+type rangeServer struct {
+	t             *testing.T
+	archive       []byte
+	md5           string
+	supportRanges bool
+	// abortFull and abortRanged are the number of full and ranged responses
+	// to interrupt.
+	abortFull   int
+	abortRanged int
+	// abortedAt is where the first full response was interrupted.
+	abortedAt int
+	etag      string
+	nextETag  string
+
+	mu           sync.Mutex
+	rangeHeaders []string
+}
+
+func newRangeServer(t *testing.T, content string) *rangeServer {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	require.NoError(t, tw.WriteHeader(&tar.Header{
+		Name: "edition-1.mmdb",
+		Mode: 0o600,
+		Size: int64(len(content)),
+	}))
+	_, err := tw.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+
+	sum := md5.Sum([]byte(content))
+	return &rangeServer{
+		t:       t,
+		archive: buf.Bytes(),
+		md5:     hex.EncodeToString(sum[:]),
+		etag:    `"v1"`,
+	}
+}
+
+func (s *rangeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/geoip/updates/metadata" {
+		_, err := w.Write([]byte(`{"databases":[{"edition_id":"edition-1","md5":"` +
+			s.md5 + `","date":"2024-02-23"}]}`))
+		require.NoError(s.t, err)
+		return
+	}
+
+	s.mu.Lock()
+	rangeHeader := r.Header.Get("Range")
+	s.rangeHeaders = append(s.rangeHeaders, rangeHeader)
+	abort := false
+	if rangeHeader == "" && s.abortFull > 0 {
+		s.abortFull--
+		abort = true
+	} else if rangeHeader != "" && s.abortRanged > 0 {
+		s.abortRanged--
+		abort = true
+	}
+	etag := s.etag
+	if s.nextETag != "" && len(s.rangeHeaders) > 1 {
+		etag = s.nextETag
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", "Fri, 23 Feb 2024 00:00:00 GMT")
+
+	if abort {
+		if rangeHeader == "" {
+			s.abortedAt = len(s.archive) / 2
+			w.Header().Set("Content-Length", strconv.Itoa(len(s.archive)))
+			_, err := w.Write(s.archive[:s.abortedAt])
+			require.NoError(s.t, err)
+			w.(http.Flusher).Flush()
+		}
+		panic(http.ErrAbortHandler)
+	}
+
+	if !s.supportRanges {
+		r.Header.Del("Range")
+	}
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(s.archive))
+}
+
+func (s *rangeServer) ranges() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rangeHeaders
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
+)
+
+// maxSignatureSize is the most we read of a signature. Minisign signatures
+// are well under 1 KiB.
+const maxSignatureSize = 4096
+
+// WithSignatureSuffix sets the suffix that DownloadSignature requests the
+// detached signature of a database with from the download endpoint, e.g.,
+// "mmdb.minisig". The MaxMind update server does not serve signatures, so
+// this is only for servers that do, such as a mirror of signed databases.
+// By default, DownloadSignature fails.
+func WithSignatureSuffix(suffix string) Option {
+	return func(c *Client) {
+		c.signatureSuffix = suffix
+	}
+}
+
+// DownloadSignature downloads the detached minisign signature of the MMDB
+// file of the edition built on date, e.g., "2024-02-23". The date is the
+// DownloadResponse.Date of the download it is for. It requires a suffix set
+// with [WithSignatureSuffix].
+//
+// Returns an [HTTPError] if the server returns a non-200 status code.
+func (c Client) DownloadSignature(
+	ctx context.Context,
+	editionID,
+	date string,
+) ([]byte, error) {
+	if c.signatureSuffix == "" {
+		return nil, errors.New("no signature suffix is set, see WithSignatureSuffix")
+	}
+
+	params := url.Values{}
+	params.Add("date", strings.ReplaceAll(date, "-", ""))
+	params.Add("suffix", c.signatureSuffix)
+
+	escapedEdition := url.PathEscape(editionID)
+	requestURL := fmt.Sprintf(downloadEndpoint, c.endpoint, escapedEdition) + params.Encode()
+
+	response, err := c.doDownloadRequest(ctx, requestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("performing signature request: %w", err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxSignatureSize))
+	if err != nil {
+		return nil, fmt.Errorf("reading signature: %w", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		httpErr := internal.NewHTTPError(response.StatusCode, body)
+		return nil, fmt.Errorf("unexpected HTTP status code: %w", httpErr)
+	}
+
+	return body, nil
+}
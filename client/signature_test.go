@@ -0,0 +1,73 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadSignature(t *testing.T) {
+	const sig = "untrusted comment: signature\nRUQ...\ntrusted comment: x\n...\n"
+
+	tests := []struct {
+		description string
+		status      int
+		body        string
+		checkResult func(t *testing.T, sig []byte, err error)
+	}{
+		{
+			description: "successful request",
+			status:      http.StatusOK,
+			body:        sig,
+			checkResult: func(t *testing.T, got []byte, err error) {
+				require.NoError(t, err)
+				require.Equal(t, sig, string(got))
+			},
+		},
+		{
+			description: "no signature",
+			status:      http.StatusNotFound,
+			body:        `{"code":"NOT_FOUND","error":"not found"}`,
+			checkResult: func(t *testing.T, _ []byte, err error) {
+				var httpErr HTTPError
+				require.ErrorAs(t, err, &httpErr)
+				require.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+				require.Equal(t, "NOT_FOUND", httpErr.Code)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			server := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					assert.Equal(t, "/geoip/databases/edition-1/download", r.URL.Path)
+					assert.Equal(t, "20240223", r.URL.Query().Get("date"))
+					assert.Equal(t, "mmdb.minisig", r.URL.Query().Get("suffix"))
+
+					w.WriteHeader(test.status)
+					_, err := w.Write([]byte(test.body))
+					assert.NoError(t, err)
+				}),
+			)
+			defer server.Close()
+
+			c, err := New(10, "license", WithEndpoint(server.URL), WithSignatureSuffix("mmdb.minisig"))
+			require.NoError(t, err)
+
+			got, err := c.DownloadSignature(t.Context(), "edition-1", "2024-02-23")
+			test.checkResult(t, got, err)
+		})
+	}
+}
+
+func TestDownloadSignatureWithoutSuffix(t *testing.T) {
+	c, err := New(10, "license", WithEndpoint("http://127.0.0.1:0"))
+	require.NoError(t, err)
+
+	_, err = c.DownloadSignature(t.Context(), "edition-1", "2024-02-23")
+	require.EqualError(t, err, "no signature suffix is set, see WithSignatureSuffix")
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"log"
 	"os"
+	"time"
 
 	flag "github.com/spf13/pflag"
 
@@ -17,9 +18,14 @@ type Args struct {
 	Verbose           bool
 	Output            bool
 	Parallelism       int
+	Daemon            bool
+	Frequency         time.Duration
+	ContinueOnError   bool
+	AllowDowngrade    bool
 }
 
-func getArgs() *Args {
+// getConfigFileDefault returns the default of the --config-file flag.
+func getConfigFileDefault() string {
 	confFileDefault := vars.DefaultConfigFile
 	// Set the default config file only if it exists.
 	// Otherwise, geoipupdate requires the user to specify the config file
@@ -31,6 +37,11 @@ func getArgs() *Args {
 	if value, ok := os.LookupEnv("GEOIPUPDATE_CONF_FILE"); ok {
 		confFileDefault = value
 	}
+	return confFileDefault
+}
+
+func getArgs() *Args {
+	confFileDefault := getConfigFileDefault()
 
 	configFile := flag.StringP(
 		"config-file",
@@ -49,6 +60,22 @@ func getArgs() *Args {
 	output := flag.BoolP("output", "o", false, "Output download/update results in JSON format")
 	displayVersion := flag.BoolP("version", "V", false, "Display the version and exit")
 	parallelism := flag.Int("parallelism", 0, "Set the number of parallel database downloads")
+	continueOnError := flag.Bool(
+		"continue-on-error",
+		false,
+		"Keep updating the remaining databases after one fails",
+	)
+	allowDowngrade := flag.Bool(
+		"allow-downgrade",
+		false,
+		"Replace databases even with builds older than the current ones",
+	)
+	daemon := flag.Bool("daemon", false, "Keep running and update the databases periodically")
+	frequency := flag.Duration(
+		"frequency",
+		0,
+		"Time between updates in daemon mode (uses config if not specified)",
+	)
 
 	//nolint:revive // pre-existing deep exit
 	flag.Parse()
@@ -67,12 +94,21 @@ func getArgs() *Args {
 		printUsage()
 	}
 
+	if *frequency < 0 {
+		log.Print("Frequency must be a positive duration")
+		printUsage()
+	}
+
 	return &Args{
 		ConfigFile:        *configFile,
 		DatabaseDirectory: *databaseDirectory,
 		Verbose:           *verbose,
 		Output:            *output,
 		Parallelism:       *parallelism,
+		Daemon:            *daemon,
+		Frequency:         *frequency,
+		ContinueOnError:   *continueOnError,
+		AllowDowngrade:    *allowDowngrade,
 	}
 }
 
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/maxmind/geoipupdate/v8/internal/geoipupdate"
+)
+
+// backfillArgs are the command line arguments of the backfill command.
+type backfillArgs struct {
+	ConfigFile        string
+	DatabaseDirectory string
+	Verbose           bool
+	Parallelism       int
+	EditionID         string
+	From              time.Time
+	To                time.Time
+}
+
+func getBackfillArgs(arguments []string) *backfillArgs {
+	flags := flag.NewFlagSet("backfill", flag.ExitOnError)
+	flags.Usage = func() {
+		log.Printf("Usage: %s backfill [<arguments>] --edition <edition> --from <date> [--to <date>]\n", os.Args[0]) //nolint:gosec // logging program name
+		flags.PrintDefaults()
+	}
+
+	configFile := flags.StringP(
+		"config-file",
+		"f",
+		getConfigFileDefault(),
+		"Configuration file",
+	)
+	databaseDirectory := flags.StringP(
+		"database-directory",
+		"d",
+		"",
+		"Store databases in this directory (uses config if not specified)",
+	)
+	verbose := flags.BoolP("verbose", "v", false, "Use verbose output")
+	parallelism := flags.Int("parallelism", 0, "Set the number of parallel database downloads")
+	editionID := flags.String("edition", "", "The edition to backfill")
+	from := flags.String("from", "", "The first release date to backfill (YYYY-MM-DD)")
+	to := flags.String(
+		"to",
+		"",
+		"The last release date to backfill (YYYY-MM-DD, defaults to today)",
+	)
+
+	//nolint:errcheck // flags exits on errors.
+	_ = flags.Parse(arguments)
+
+	usageError := func(format string, v ...any) {
+		log.Printf(format, v...)
+		flags.Usage()
+		//nolint: revive // deep exit from main package
+		os.Exit(1)
+	}
+
+	if flags.NArg() != 0 || *editionID == "" || *from == "" {
+		usageError("An edition and a start date are required")
+	}
+	if *parallelism < 0 {
+		usageError("Parallelism must be a positive number")
+	}
+
+	fromDate, err := time.Parse(time.DateOnly, *from)
+	if err != nil {
+		usageError("Invalid start date %q", *from)
+	}
+	toDate := time.Now().UTC()
+	if *to != "" {
+		toDate, err = time.Parse(time.DateOnly, *to)
+		if err != nil {
+			usageError("Invalid end date %q", *to)
+		}
+	}
+	if toDate.Before(fromDate) {
+		usageError("The end date must not be before the start date")
+	}
+
+	return &backfillArgs{
+		ConfigFile:        *configFile,
+		DatabaseDirectory: *databaseDirectory,
+		Verbose:           *verbose,
+		Parallelism:       *parallelism,
+		EditionID:         *editionID,
+		From:              fromDate,
+		To:                toDate,
+	}
+}
+
+// backfill stores the past releases of an edition as dated files.
+func backfill(arguments []string) {
+	args := getBackfillArgs(arguments)
+
+	opts := []geoipupdate.Option{
+		geoipupdate.WithConfigFile(args.ConfigFile),
+		geoipupdate.WithDatabaseDirectory(args.DatabaseDirectory),
+		geoipupdate.WithParallelism(args.Parallelism),
+	}
+	if args.Verbose {
+		opts = append(opts, geoipupdate.WithVerbose)
+	}
+
+	config, err := geoipupdate.NewConfig(opts...)
+	if err != nil {
+		fatalf(err, "Error loading configuration: %s", err)
+	}
+
+	u, err := geoipupdate.NewUpdater(config)
+	if err != nil {
+		fatalf(err, "Error initializing updater: %s", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	results, err := u.Backfill(ctx, args.EditionID, args.From, args.To)
+
+	var downloaded int
+	for _, result := range results {
+		if result.Downloaded {
+			downloaded++
+		}
+	}
+	log.Printf(
+		"Backfilled %s: %d releases, %d downloaded, %d already stored",
+		args.EditionID,
+		len(results),
+		downloaded,
+		len(results)-downloaded,
+	)
+
+	if err != nil {
+		fatalf(err, "Error backfilling %s: %s", args.EditionID, err) //nolint:gocritic // stop needs no cleanup on exit.
+	}
+}
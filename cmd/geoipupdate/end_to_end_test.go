@@ -5,6 +5,8 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -19,16 +21,23 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/maxmind/geoipupdate/v8/internal/geoipupdate"
+	"github.com/maxmind/geoipupdate/v8/internal/mmdbtest"
 )
 
 func TestUpdater(t *testing.T) {
 	// mock existing databases.
 	tempDir := t.TempDir()
 
+	databases := map[string][]byte{
+		"edition-1": mmdbtest.Build("edition-1", 1),
+		"edition-2": mmdbtest.Build("edition-2", 2),
+	}
+	edition1MD5 := md5Hex(databases["edition-1"])
+	edition2MD5 := md5Hex(databases["edition-2"])
+
 	edition := "edition-1"
 	dbFile := filepath.Join(tempDir, edition+".mmdb")
-	// equivalent MD5: 618dd27a10de24809ec160d6807f363f
-	err := os.WriteFile(dbFile, []byte("edition-1 content"), os.ModePerm)
+	err := os.WriteFile(dbFile, databases[edition], os.ModePerm)
 	require.NoError(t, err)
 
 	edition = "edition-2"
@@ -53,7 +62,7 @@ func TestUpdater(t *testing.T) {
     		"databases": [
     		    {
     		        "edition_id": "edition-1",
-    		        "md5": "618dd27a10de24809ec160d6807f363f",
+    		        "md5": "` + edition1MD5 + `",
     		        "date": "2024-02-23"
     		    }
     		]
@@ -63,7 +72,7 @@ func TestUpdater(t *testing.T) {
 		    "databases": [
 		        {
 		            "edition_id": "edition-2",
-		            "md5": "c9bbf7cb507370339633b44001bae038",
+		            "md5": "` + edition2MD5 + `",
 		            "date": "2024-02-23"
 		        }
 		    ]
@@ -86,7 +95,7 @@ func TestUpdater(t *testing.T) {
 		gw := gzip.NewWriter(&buf)
 		tw := tar.NewWriter(gw)
 
-		content := name + " content"
+		content := databases[name]
 		header := &tar.Header{
 			Name: name + ".mmdb",
 			Size: int64(len(content)),
@@ -96,7 +105,7 @@ func TestUpdater(t *testing.T) {
 		if !assert.NoError(t, err) {
 			return
 		}
-		_, err = tw.Write([]byte(content))
+		_, err = tw.Write(content)
 		if !assert.NoError(t, err) {
 			return
 		}
@@ -155,7 +164,7 @@ func TestUpdater(t *testing.T) {
 	w.Close()
 	out, err := io.ReadAll(r)
 	require.NoError(t, err)
-	expectedOutput := `\[{"edition_id":"edition\-1","old_hash":"618dd27a10de24809ec160d6807f363f","new_hash":"618dd27a10de24809ec160d6807f363f","checked_at":\d+},{"edition_id":"edition\-2","old_hash":"2242f06b3b2d147987b67017cb7a5ab8","new_hash":"c9bbf7cb507370339633b44001bae038","modified_at":1708646400,"checked_at":\d+}]`
+	expectedOutput := `\[{"edition_id":"edition\-1","old_hash":"` + edition1MD5 + `","new_hash":"` + edition1MD5 + `","status":"up_to_date","attempts":1,"checked_at":\d+,"duration":[\d.e-]+},{"edition_id":"edition\-2","old_hash":"2242f06b3b2d147987b67017cb7a5ab8","new_hash":"` + edition2MD5 + `","status":"updated","attempts":1,"modified_at":1708646400,"checked_at":\d+,"duration":[\d.e-]+}]`
 	require.Regexp(t, expectedOutput, string(out))
 
 	for _, editionID := range config.EditionIDs {
@@ -164,9 +173,14 @@ func TestUpdater(t *testing.T) {
 		require.NoError(t, err, "read file")
 		require.Equal(
 			t,
-			editionID+" content",
-			string(buf),
+			databases[editionID],
+			buf,
 			"correct database",
 		)
 	}
 }
+
+func md5Hex(b []byte) string {
+	sum := md5.Sum(b)
+	return hex.EncodeToString(sum[:])
+}
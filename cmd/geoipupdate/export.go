@@ -0,0 +1,86 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/maxmind/geoipupdate/v8/internal/geoipupdate"
+)
+
+// exportArgs are the command line arguments of the export command.
+type exportArgs struct {
+	ConfigFile        string
+	DatabaseDirectory string
+	Verbose           bool
+	Out               string
+}
+
+func getExportArgs(arguments []string) *exportArgs {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	flags.Usage = func() {
+		log.Printf("Usage: %s export [<arguments>] --out <bundle>\n", os.Args[0]) //nolint:gosec // logging program name
+		flags.PrintDefaults()
+	}
+
+	configFile := flags.StringP(
+		"config-file",
+		"f",
+		getConfigFileDefault(),
+		"Configuration file",
+	)
+	databaseDirectory := flags.StringP(
+		"database-directory",
+		"d",
+		"",
+		"The directory of the databases (uses config if not specified)",
+	)
+	verbose := flags.BoolP("verbose", "v", false, "Use verbose output")
+	out := flags.String("out", "", "The file to write the bundle to")
+
+	//nolint:errcheck // flags exits on errors.
+	_ = flags.Parse(arguments)
+
+	if flags.NArg() != 0 || *out == "" {
+		log.Print("A bundle file is required")
+		flags.Usage()
+		//nolint: revive // deep exit from main package
+		os.Exit(1)
+	}
+
+	return &exportArgs{
+		ConfigFile:        *configFile,
+		DatabaseDirectory: *databaseDirectory,
+		Verbose:           *verbose,
+		Out:               *out,
+	}
+}
+
+// export writes the current databases to a bundle for hosts without access
+// to the update server.
+func export(arguments []string) {
+	args := getExportArgs(arguments)
+
+	opts := []geoipupdate.Option{
+		geoipupdate.WithConfigFile(args.ConfigFile),
+		geoipupdate.WithDatabaseDirectory(args.DatabaseDirectory),
+	}
+	if args.Verbose {
+		opts = append(opts, geoipupdate.WithVerbose)
+	}
+
+	config, err := geoipupdate.NewConfig(opts...)
+	if err != nil {
+		fatalf(err, "Error loading configuration: %s", err)
+	}
+
+	manifest, err := geoipupdate.Export(config, args.Out)
+	if err != nil {
+		fatalf(err, "Error exporting databases: %s", err)
+	}
+
+	for _, db := range manifest.Databases {
+		log.Printf("Exported %s built on %s (MD5 %s)", db.EditionID, db.Date, db.MD5)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/maxmind/geoipupdate/v8/internal/geoipupdate"
+)
+
+// importArgs are the command line arguments of the import command.
+type importArgs struct {
+	ConfigFile        string
+	DatabaseDirectory string
+	Verbose           bool
+	Output            bool
+	Bundle            string
+}
+
+func getImportArgs(arguments []string) *importArgs {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	flags.Usage = func() {
+		log.Printf("Usage: %s import [<arguments>] <bundle>\n", os.Args[0]) //nolint:gosec // logging program name
+		flags.PrintDefaults()
+	}
+
+	configFile := flags.StringP(
+		"config-file",
+		"f",
+		getConfigFileDefault(),
+		"Configuration file",
+	)
+	databaseDirectory := flags.StringP(
+		"database-directory",
+		"d",
+		"",
+		"Store databases in this directory (uses config if not specified)",
+	)
+	verbose := flags.BoolP("verbose", "v", false, "Use verbose output")
+	output := flags.BoolP("output", "o", false, "Output import results in JSON format")
+
+	//nolint:errcheck // flags exits on errors.
+	_ = flags.Parse(arguments)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		//nolint: revive // deep exit from main package
+		os.Exit(1)
+	}
+
+	return &importArgs{
+		ConfigFile:        *configFile,
+		DatabaseDirectory: *databaseDirectory,
+		Verbose:           *verbose,
+		Output:            *output,
+		Bundle:            flags.Arg(0),
+	}
+}
+
+// importBundle installs the databases of a bundle written by export.
+func importBundle(arguments []string) {
+	args := getImportArgs(arguments)
+
+	opts := []geoipupdate.Option{
+		geoipupdate.WithConfigFile(args.ConfigFile),
+		geoipupdate.WithDatabaseDirectory(args.DatabaseDirectory),
+	}
+	if args.Verbose {
+		opts = append(opts, geoipupdate.WithVerbose)
+	}
+	if args.Output {
+		opts = append(opts, geoipupdate.WithOutput)
+	}
+
+	config, err := geoipupdate.NewConfig(opts...)
+	if err != nil {
+		fatalf(err, "Error loading configuration: %s", err)
+	}
+
+	u, err := geoipupdate.NewUpdater(config)
+	if err != nil {
+		fatalf(err, "Error initializing updater: %s", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := u.Import(ctx, args.Bundle); err != nil {
+		fatalf(err, "Error importing %s: %s", args.Bundle, err) //nolint:gocritic // stop needs no cleanup on exit.
+	}
+}
@@ -3,14 +3,33 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/maxmind/geoipupdate/v8/internal"
 	"github.com/maxmind/geoipupdate/v8/internal/geoipupdate"
+	"github.com/maxmind/geoipupdate/v8/internal/minisign"
 	"github.com/maxmind/geoipupdate/v8/internal/vars"
 )
 
 const unknownVersion = "unknown"
 
+// Exit codes. These are documented in doc/geoipupdate.md and changing them
+// should be considered a breaking change.
+const (
+	exitError            = 1
+	exitConfigError      = 2
+	exitLockHeld         = 3
+	exitAuthError        = 4
+	exitHashMismatch     = 5
+	exitWriteError       = 6
+	exitRetriesExhausted = 7
+)
+
 // These values are set by build scripts. Changing the names of
 // the variables should be considered a breaking change.
 var (
@@ -30,12 +49,33 @@ func main() {
 		vars.DefaultDatabaseDirectory = defaultDatabaseDirectory
 	}
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backfill":
+			backfill(os.Args[2:])
+			return
+		case "export":
+			export(os.Args[2:])
+			return
+		case "import":
+			importBundle(os.Args[2:])
+			return
+		case "rollback":
+			rollback(os.Args[2:])
+			return
+		case "serve":
+			serve(os.Args[2:])
+			return
+		}
+	}
+
 	args := getArgs()
 
 	opts := []geoipupdate.Option{
 		geoipupdate.WithConfigFile(args.ConfigFile),
 		geoipupdate.WithDatabaseDirectory(args.DatabaseDirectory),
 		geoipupdate.WithParallelism(args.Parallelism),
+		geoipupdate.WithFrequency(args.Frequency),
 	}
 
 	if args.Output {
@@ -46,9 +86,17 @@ func main() {
 		opts = append(opts, geoipupdate.WithVerbose)
 	}
 
+	if args.ContinueOnError {
+		opts = append(opts, geoipupdate.WithContinueOnError)
+	}
+
+	if args.AllowDowngrade {
+		opts = append(opts, geoipupdate.WithAllowDowngrade)
+	}
+
 	config, err := geoipupdate.NewConfig(opts...)
 	if err != nil {
-		log.Fatalf("Error loading configuration: %s", err)
+		fatalf(err, "Error loading configuration: %s", err)
 	}
 
 	if config.Verbose {
@@ -59,10 +107,71 @@ func main() {
 
 	u, err := geoipupdate.NewUpdater(config)
 	if err != nil {
-		log.Fatalf("Error initializing updater: %s", err)
+		fatalf(err, "Error initializing updater: %s", err)
 	}
 
-	if err = u.Run(context.Background()); err != nil {
-		log.Fatalf("Error retrieving updates: %s", err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if args.Daemon {
+		if err = u.RunSchedule(ctx); err != nil {
+			fatalf(err, "Error running daemon: %s", err) //nolint:gocritic // stop needs no cleanup on exit.
+		}
+		return
+	}
+
+	if err = u.Run(ctx); err != nil {
+		fatalf(err, "Error retrieving updates: %s", err) //nolint:gocritic // stop needs no cleanup on exit.
 	}
 }
+
+// fatalf logs the message and exits with the exit code for err.
+func fatalf(err error, format string, v ...any) {
+	log.Printf(format, v...)
+	//nolint: revive // deep exit from main package
+	os.Exit(exitCode(err))
+}
+
+// exitCode returns the exit code for err. When err wraps errors of several
+// classes, e.g., with ContinueOnError, the lowest matching code is used.
+func exitCode(err error) int {
+	var configErr geoipupdate.ConfigError
+	if errors.As(err, &configErr) {
+		return exitConfigError
+	}
+
+	if errors.Is(err, internal.ErrLockHeld) {
+		return exitLockHeld
+	}
+
+	if errors.Is(err, internal.ErrInvalidLicenseKey) ||
+		errors.Is(err, internal.ErrEditionNotEntitled) ||
+		errors.Is(err, internal.ErrAccountIDRequired) {
+		return exitAuthError
+	}
+
+	var httpErr internal.HTTPError
+	if errors.As(err, &httpErr) &&
+		(httpErr.StatusCode == http.StatusUnauthorized ||
+			httpErr.StatusCode == http.StatusForbidden) {
+		return exitAuthError
+	}
+
+	if errors.Is(err, internal.ErrHashMismatch) ||
+		errors.Is(err, minisign.ErrInvalidSignature) ||
+		errors.Is(err, internal.ErrInvalidDatabase) {
+		return exitHashMismatch
+	}
+
+	var writeErr geoipupdate.WriteError
+	if errors.As(err, &writeErr) {
+		return exitWriteError
+	}
+
+	var retryErr geoipupdate.RetryError
+	if errors.As(err, &retryErr) {
+		return exitRetriesExhausted
+	}
+
+	return exitError
+}
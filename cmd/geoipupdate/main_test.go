@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
+	"github.com/maxmind/geoipupdate/v8/internal/geoipupdate"
+	"github.com/maxmind/geoipupdate/v8/internal/minisign"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		description string
+		err         error
+		want        int
+	}{
+		{
+			description: "unclassified error",
+			err:         errors.New("unknown"),
+			want:        exitError,
+		},
+		{
+			description: "config error",
+			err:         geoipupdate.ConfigError{Err: errors.New("bad config")},
+			want:        exitConfigError,
+		},
+		{
+			description: "lock held",
+			err:         fmt.Errorf("acquiring file lock: %w", internal.ErrLockHeld),
+			want:        exitLockHeld,
+		},
+		{
+			description: "unauthorized",
+			err: fmt.Errorf("downloading editions: %w", internal.HTTPError{
+				StatusCode: http.StatusUnauthorized,
+			}),
+			want: exitAuthError,
+		},
+		{
+			description: "forbidden",
+			err:         internal.HTTPError{StatusCode: http.StatusForbidden},
+			want:        exitAuthError,
+		},
+		{
+			description: "edition not entitled behind another error",
+			err: errors.Join(
+				internal.HTTPError{StatusCode: http.StatusNotFound},
+				internal.NewHTTPError(
+					http.StatusForbidden,
+					[]byte(`{"code":"PERMISSION_REQUIRED","error":"not entitled"}`),
+				),
+			),
+			want: exitAuthError,
+		},
+		{
+			description: "not found",
+			err:         internal.HTTPError{StatusCode: http.StatusNotFound},
+			want:        exitError,
+		},
+		{
+			description: "hash mismatch while writing",
+			err: geoipupdate.RetryError{
+				Attempts: 3,
+				Err:      geoipupdate.WriteError{Err: internal.ErrHashMismatch},
+			},
+			want: exitHashMismatch,
+		},
+		{
+			description: "invalid signature while writing",
+			err: geoipupdate.WriteError{
+				Err: fmt.Errorf("verifying signature: %w", minisign.ErrInvalidSignature),
+			},
+			want: exitHashMismatch,
+		},
+		{
+			description: "invalid database",
+			err: geoipupdate.WriteError{
+				Err: fmt.Errorf("validating database: %w", internal.ErrInvalidDatabase),
+			},
+			want: exitHashMismatch,
+		},
+		{
+			description: "write error after retries",
+			err: geoipupdate.RetryError{
+				Attempts: 3,
+				Err:      geoipupdate.WriteError{Err: errors.New("no space left on device")},
+			},
+			want: exitWriteError,
+		},
+		{
+			description: "retries exhausted",
+			err: geoipupdate.RetryError{
+				Attempts: 3,
+				Err:      errors.New("connection refused"),
+			},
+			want: exitRetriesExhausted,
+		},
+		{
+			description: "several editions failed",
+			err: errors.Join(
+				geoipupdate.RetryError{Attempts: 3, Err: errors.New("connection refused")},
+				internal.HTTPError{StatusCode: http.StatusUnauthorized},
+			),
+			want: exitAuthError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			require.Equal(t, test.want, exitCode(test.err))
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/maxmind/geoipupdate/v8/internal/geoipupdate"
+)
+
+// rollbackArgs are the command line arguments of the rollback command.
+type rollbackArgs struct {
+	ConfigFile        string
+	DatabaseDirectory string
+	Verbose           bool
+	EditionID         string
+	To                string
+	Resume            bool
+}
+
+func getRollbackArgs(arguments []string) *rollbackArgs {
+	flags := flag.NewFlagSet("rollback", flag.ExitOnError)
+	flags.Usage = func() {
+		log.Printf("Usage: %s rollback [<arguments>] <edition> [--to <date|hash> | --resume]\n", os.Args[0]) //nolint:gosec // logging program name
+		flags.PrintDefaults()
+	}
+
+	configFile := flags.StringP(
+		"config-file",
+		"f",
+		getConfigFileDefault(),
+		"Configuration file",
+	)
+	databaseDirectory := flags.StringP(
+		"database-directory",
+		"d",
+		"",
+		"The directory of the databases (uses config if not specified)",
+	)
+	verbose := flags.BoolP("verbose", "v", false, "Use verbose output")
+	to := flags.String(
+		"to",
+		"",
+		"Restore the version built on this date (YYYY-MM-DD) or with this MD5 prefix "+
+			"(defaults to the previous version)",
+	)
+	resume := flags.Bool(
+		"resume",
+		false,
+		"Resume updating the edition, which is held once it is rolled back",
+	)
+
+	//nolint:errcheck // flags exits on errors.
+	_ = flags.Parse(arguments)
+
+	if flags.NArg() != 1 || (*resume && *to != "") {
+		flags.Usage()
+		//nolint: revive // deep exit from main package
+		os.Exit(1)
+	}
+
+	return &rollbackArgs{
+		ConfigFile:        *configFile,
+		DatabaseDirectory: *databaseDirectory,
+		Verbose:           *verbose,
+		EditionID:         flags.Arg(0),
+		To:                *to,
+		Resume:            *resume,
+	}
+}
+
+// rollback restores a previous version of an edition kept with
+// KeepVersions, or resumes updating an edition that was rolled back.
+func rollback(arguments []string) {
+	args := getRollbackArgs(arguments)
+
+	opts := []geoipupdate.Option{
+		geoipupdate.WithConfigFile(args.ConfigFile),
+		geoipupdate.WithDatabaseDirectory(args.DatabaseDirectory),
+	}
+	if args.Verbose {
+		opts = append(opts, geoipupdate.WithVerbose)
+	}
+
+	config, err := geoipupdate.NewConfig(opts...)
+	if err != nil {
+		fatalf(err, "Error loading configuration: %s", err)
+	}
+
+	if args.Resume {
+		held, err := geoipupdate.Resume(config, args.EditionID)
+		if err != nil {
+			fatalf(err, "Error resuming updates of %s: %s", args.EditionID, err)
+		}
+		if !held {
+			log.Printf("%s was not held", args.EditionID)
+			return
+		}
+		log.Printf("Resumed updates of %s, the next update installs the latest database", args.EditionID)
+		return
+	}
+
+	version, err := geoipupdate.Rollback(config, args.EditionID, args.To)
+	if err != nil {
+		fatalf(err, "Error rolling back %s: %s", args.EditionID, err)
+	}
+
+	log.Printf(
+		"Rolled back %s to the database built on %s (MD5 %s)",
+		args.EditionID,
+		version.BuildDate,
+		version.MD5,
+	)
+	log.Printf("Updates of %s are held until it is resumed with --resume", args.EditionID)
+}
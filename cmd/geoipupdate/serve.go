@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/maxmind/geoipupdate/v8/internal/geoipupdate"
+)
+
+// serveArgs are the command line arguments of the serve command.
+type serveArgs struct {
+	ConfigFile        string
+	DatabaseDirectory string
+	Verbose           bool
+	Listen            string
+	Proxy             bool
+	TLSCert           string
+	TLSKey            string
+}
+
+func getServeArgs(arguments []string) *serveArgs {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags.Usage = func() {
+		log.Printf("Usage: %s serve [<arguments>]\n", os.Args[0]) //nolint:gosec // logging program name
+		flags.PrintDefaults()
+	}
+
+	configFile := flags.StringP(
+		"config-file",
+		"f",
+		getConfigFileDefault(),
+		"Configuration file",
+	)
+	databaseDirectory := flags.StringP(
+		"database-directory",
+		"d",
+		"",
+		"Serve the databases in this directory (uses config if not specified)",
+	)
+	verbose := flags.BoolP("verbose", "v", false, "Use verbose output")
+	listen := flags.String(
+		"listen",
+		"",
+		"The address to listen on (uses config if not specified)",
+	)
+
+	proxy := flags.Bool(
+		"proxy",
+		false,
+		"Forward requests to the update server and cache the databases (uses config if not specified)",
+	)
+	tlsCert := flags.String(
+		"tls-cert",
+		"",
+		"Serve HTTPS with the certificate in this file (uses config if not specified)",
+	)
+	tlsKey := flags.String(
+		"tls-key",
+		"",
+		"Serve HTTPS with the key in this file (uses config if not specified)",
+	)
+
+	//nolint:errcheck // flags exits on errors.
+	_ = flags.Parse(arguments)
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		//nolint: revive // deep exit from main package
+		os.Exit(1)
+	}
+
+	return &serveArgs{
+		ConfigFile:        *configFile,
+		DatabaseDirectory: *databaseDirectory,
+		Verbose:           *verbose,
+		Listen:            *listen,
+		Proxy:             *proxy,
+		TLSCert:           *tlsCert,
+		TLSKey:            *tlsKey,
+	}
+}
+
+// serve serves the databases to other geoipupdate instances, as a mirror
+// or a caching proxy, until it is interrupted.
+func serve(arguments []string) {
+	args := getServeArgs(arguments)
+
+	opts := []geoipupdate.Option{
+		geoipupdate.WithConfigFile(args.ConfigFile),
+		geoipupdate.WithDatabaseDirectory(args.DatabaseDirectory),
+		geoipupdate.WithServeAddress(args.Listen),
+		geoipupdate.WithServeTLS(args.TLSCert, args.TLSKey),
+	}
+	if args.Verbose {
+		opts = append(opts, geoipupdate.WithVerbose)
+	}
+	if args.Proxy {
+		opts = append(opts, geoipupdate.WithServeProxy)
+	}
+
+	config, err := geoipupdate.NewConfig(opts...)
+	if err != nil {
+		fatalf(err, "Error loading configuration: %s", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := geoipupdate.Serve(ctx, config); err != nil {
+		fatalf(err, "Error serving databases: %s", err) //nolint:gocritic // stop needs no cleanup on exit.
+	}
+}
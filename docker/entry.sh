@@ -2,23 +2,10 @@
 
 set -e
 
-# SIGTERM-handler
-term_handler() {
-  if [ $pid -ne 0 ]; then
-    kill -SIGTERM "$pid"
-    wait "$pid"
-  fi
-  exit 143; # 128 + 15 -- SIGTERM
-}
-
-trap 'kill ${!}; term_handler' SIGTERM
-
-pid=0
 database_dir=/usr/share/GeoIP
 log_dir="/tmp/geoipupdate"
 log_file="$log_dir/.healthcheck"
 flags="--output"
-frequency=$((GEOIPUPDATE_FREQUENCY * 60 * 60))
 
 if [ -z "$GEOIPUPDATE_DB_DIR" ]; then
   export GEOIPUPDATE_DB_DIR="$database_dir"
@@ -43,17 +30,17 @@ if [ -z "${GEOIPUPDATE_CONF_FILE}" ]; then
   fi
 fi
 
-mkdir -p $log_dir
+# geoipupdate reads GEOIPUPDATE_FREQUENCY and the schedule variables itself.
+# When any is set, it keeps running and updates the databases as they
+# describe. GEOIPUPDATE_FREQUENCY may be a duration such as 72h, so it is
+# compared as a string.
+if { [ -n "$GEOIPUPDATE_FREQUENCY" ] && [ "$GEOIPUPDATE_FREQUENCY" != 0 ]; } \
+  || [ -n "$GEOIPUPDATE_SCHEDULE" ] || [ -n "$GEOIPUPDATE_EDITION_SCHEDULES" ]; then
+  flags="$flags --daemon"
+fi
 
-while true; do
-    echo "# STATE: Running geoipupdate"
-    /usr/bin/geoipupdate $flags 1>$log_file
-    if [ "$frequency" -eq 0 ]; then
-        break
-    fi
+mkdir -p $log_dir
 
-    echo "# STATE: Sleeping for $GEOIPUPDATE_FREQUENCY hours"
-    sleep "$frequency" &
-    pid=$!
-    wait $!
-done
+echo "# STATE: Running geoipupdate"
+# The log is appended to, so that the health check can truncate it.
+exec /usr/bin/geoipupdate $flags 1>>$log_file
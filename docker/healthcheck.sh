@@ -2,17 +2,128 @@
 
 set -e
 
-# 2 minutes are added to the update frequency threshold to make room for slower starts.
-cutoff_duration=$(($GEOIPUPDATE_FREQUENCY * 60 * 60 + 120))
-current_time=$(date +%s)
-cutoff_date=$(($current_time - $cutoff_duration))
+log_dir="/tmp/geoipupdate"
+log_file="$log_dir/.healthcheck"
+# The state records when each edition was last checked, so that the log can
+# be truncated once it grows past max_log_size bytes.
+state_file="$log_dir/.healthcheck-state"
+max_log_size=65536
+# 2 minutes are added to the time allowed between checks to make room for
+# slower starts.
+slack=120
+
+# effective_schedule prints the cron expression that an edition is updated on,
+# which is its entry in GEOIPUPDATE_EDITION_SCHEDULES or else
+# GEOIPUPDATE_SCHEDULE. It prints nothing if the edition is updated every
+# GEOIPUPDATE_FREQUENCY instead.
+effective_schedule() {
+  printf '%s' "$GEOIPUPDATE_EDITION_SCHEDULES" | awk -v edition="$1" -v schedule="$GEOIPUPDATE_SCHEDULE" '
+    BEGIN { RS = ";" }
+    {
+      i = index($0, "=")
+      if (i == 0) next
+      id = substr($0, 1, i - 1)
+      gsub(/^[ \t\n]+|[ \t\n]+$/, "", id)
+      if (id != edition) next
+      schedule = substr($0, i + 1)
+      gsub(/^[ \t\n]+|[ \t\n]+$/, "", schedule)
+    }
+    END { print schedule }'
+}
+
+# schedule_period prints the most seconds there can be between two matches of
+# a cron expression, judging by the largest unit that it restricts.
+schedule_period() {
+  printf '%s\n' "$1" | awk '
+    {
+      i = 1
+      if ($1 ~ /^(CRON_)?TZ=/) i = 2
+      minute = $i; hour = $(i + 1); dom = $(i + 2); month = $(i + 3); dow = $(i + 4)
 
-log_file="/tmp/geoipupdate/.healthcheck"
-editions=$(cat "$log_file" | jq -r '.[] | select(.checked_at > '$cutoff_date') | .edition_id')
-checked_editions=$(echo "$editions" | wc -l)
-desired_editions=$(echo "$GEOIPUPDATE_EDITION_IDS" | awk -F' ' '{print NF}')
+      if (minute == "@hourly") period = 3600
+      else if (minute == "@daily" || minute == "@midnight") period = 86400
+      else if (minute == "@weekly") period = 7 * 86400
+      else if (minute == "@monthly") period = 62 * 86400
+      # Up to four years, for February 29.
+      else if (minute ~ /^@/ || month != "*") period = 1461 * 86400
+      else if (dom != "*" && dow != "*") {
+        # A day matches either field, unless one starts with "*".
+        if (dom ~ /^\*/ || dow ~ /^\*/) period = 1461 * 86400
+        else period = 7 * 86400
+      }
+      else if (dom != "*") period = 62 * 86400
+      else if (dow != "*") period = 7 * 86400
+      else if (hour != "*") period = 86400
+      else if (minute != "*") period = 3600
+      else period = 60
+      printf "%d\n", period
+    }'
+}
+
+# frequency_seconds prints GEOIPUPDATE_FREQUENCY in seconds. As for
+# geoipupdate, a bare integer is a number of hours and anything else is a
+# duration such as "72h" or "1h30m".
+frequency_seconds() {
+  printf '%s\n' "${GEOIPUPDATE_FREQUENCY:-0}" | awk '
+    /^[0-9]+$/ { printf "%d\n", $0 * 3600; exit }
+    {
+      s = $0
+      total = 0
+      while (s != "") {
+        if (!match(s, /^[0-9]*\.?[0-9]+/)) exit 1
+        n = substr(s, 1, RLENGTH)
+        s = substr(s, RLENGTH + 1)
+        if (!match(s, /^(ns|us|ms|s|m|h)/)) exit 1
+        unit = substr(s, 1, RLENGTH)
+        s = substr(s, RLENGTH + 1)
+        if (unit == "h") total += n * 3600
+        else if (unit == "m") total += n * 60
+        else if (unit == "s") total += n
+      }
+      printf "%d\n", total
+    }'
+}
+
+# Each run appends the results of the editions it checked to the log, which
+# are merged into the state.
+state='{}'
+if [ -f "$state_file" ]; then
+  state=$(cat "$state_file")
+fi
+if [ -f "$log_file" ]; then
+  state=$(jq -n -c --argjson state "$state" --slurpfile runs "$log_file" '
+    reduce ($runs[] | .[]?) as $edition ($state;
+      .[$edition.edition_id] = ([.[$edition.edition_id] // 0, $edition.checked_at] | max))')
 
-if [ "$checked_editions" != "$desired_editions" ]; then
-  echo "healtcheck editions number $checked_editions is less than the desired editions number $desired_editions"
-  exit 1
+  if [ "$(stat -c %s "$log_file")" -gt "$max_log_size" ]; then
+    printf '%s\n' "$state" > "$state_file.tmp"
+    mv "$state_file.tmp" "$state_file"
+    : > "$log_file"
+  fi
 fi
+
+frequency=$(frequency_seconds)
+current_time=$(date +%s)
+unhealthy=0
+for edition in $GEOIPUPDATE_EDITION_IDS; do
+  edition=${edition%%@*}
+
+  schedule=$(effective_schedule "$edition")
+  if [ -n "$schedule" ]; then
+    period=$(schedule_period "$schedule")
+  else
+    period=$frequency
+  fi
+  # Editions that are only updated once are not checked.
+  if [ "$period" -eq 0 ]; then
+    continue
+  fi
+
+  checked_at=$(printf '%s' "$state" | jq -r --arg edition "$edition" '.[$edition] // 0')
+  if [ $((current_time - checked_at)) -gt $((period + slack)) ]; then
+    echo "healthcheck: $edition was not checked within the last $((period + slack)) seconds"
+    unhealthy=1
+  fi
+done
+
+exit $unhealthy
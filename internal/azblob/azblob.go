@@ -0,0 +1,465 @@
+// Package azblob is a minimal client for Azure Blob Storage and emulators of
+// it such as Azurite. It only supports what geoipupdate needs, i.e., reading
+// the properties of, uploading, copying and deleting single block blobs, and
+// authorizes requests with a shared key or a shared access signature.
+package azblob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
+)
+
+const (
+	apiVersion     = "2021-08-06"
+	metadataPrefix = "X-Ms-Meta-"
+
+	// These are the well-known account and key of Azurite.
+	devStoreAccount = "devstoreaccount1"
+	devStoreKey     = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+	devStoreBlobURL = "http://127.0.0.1:10000/" + devStoreAccount
+
+	// copyPollInterval is how often the status of a copy that is still
+	// pending is checked.
+	copyPollInterval = time.Second
+)
+
+// Config holds the settings of a Client. Either AccountKey or SASToken is
+// required.
+type Config struct {
+	// AccountName is the name of the storage account.
+	AccountName string
+	// AccountKey is the base64-encoded shared key of the account.
+	AccountKey string
+	// SASToken is a shared access signature, which is added to the query
+	// of every request.
+	SASToken string
+	// Endpoint is the URL of the blob service, e.g.,
+	// http://127.0.0.1:10000/devstoreaccount1 for a local Azurite. The
+	// default is https://<AccountName>.blob.core.windows.net.
+	Endpoint   string
+	HTTPClient *http.Client
+}
+
+// Client makes requests to Azure Blob Storage.
+//
+// It is valid for concurrent use.
+type Client struct {
+	endpoint    *url.URL
+	accountName string
+	accountKey  []byte
+	sasToken    url.Values
+	httpClient  *http.Client
+	now         func() time.Time
+}
+
+// New creates a Client.
+func New(config Config) (*Client, error) {
+	if config.AccountName == "" {
+		return nil, errors.New("an account name is required")
+	}
+	if config.AccountKey == "" && config.SASToken == "" {
+		return nil, errors.New("an account key or SAS token is required")
+	}
+
+	c := &Client{
+		accountName: config.AccountName,
+		httpClient:  config.HTTPClient,
+		now:         time.Now,
+	}
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+
+	if config.AccountKey != "" {
+		key, err := base64.StdEncoding.DecodeString(config.AccountKey)
+		if err != nil {
+			return nil, fmt.Errorf("decoding account key: %w", err)
+		}
+		c.accountKey = key
+	} else {
+		sas, err := url.ParseQuery(strings.TrimPrefix(config.SASToken, "?"))
+		if err != nil {
+			return nil, fmt.Errorf("parsing SAS token: %w", err)
+		}
+		c.sasToken = sas
+	}
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = "https://" + config.AccountName + ".blob.core.windows.net"
+	}
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing endpoint: %w", err)
+	}
+	c.endpoint = u
+
+	return c, nil
+}
+
+// NewFromEnv creates a Client configured by the environment variables that
+// the Azure CLI uses: AZURE_STORAGE_CONNECTION_STRING, or
+// AZURE_STORAGE_ACCOUNT with AZURE_STORAGE_KEY or AZURE_STORAGE_SAS_TOKEN.
+func NewFromEnv(httpClient *http.Client) (*Client, error) {
+	if s := os.Getenv("AZURE_STORAGE_CONNECTION_STRING"); s != "" {
+		config, err := parseConnectionString(s)
+		if err != nil {
+			return nil, fmt.Errorf("parsing AZURE_STORAGE_CONNECTION_STRING: %w", err)
+		}
+		config.HTTPClient = httpClient
+		return New(config)
+	}
+
+	config := Config{
+		AccountName: os.Getenv("AZURE_STORAGE_ACCOUNT"),
+		AccountKey:  os.Getenv("AZURE_STORAGE_KEY"),
+		SASToken:    os.Getenv("AZURE_STORAGE_SAS_TOKEN"),
+		HTTPClient:  httpClient,
+	}
+	if config.AccountName == "" || (config.AccountKey == "" && config.SASToken == "") {
+		return nil, errors.New(
+			"the AZURE_STORAGE_CONNECTION_STRING environment variable, or AZURE_STORAGE_ACCOUNT " +
+				"and AZURE_STORAGE_KEY or AZURE_STORAGE_SAS_TOKEN, are required")
+	}
+	return New(config)
+}
+
+// parseConnectionString parses a storage account connection string, such as
+// those shown in the Azure portal.
+func parseConnectionString(s string) (Config, error) {
+	values := map[string]string{}
+	for field := range strings.SplitSeq(s, ";") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return Config{}, fmt.Errorf("invalid field %q", name)
+		}
+		values[strings.ToLower(name)] = value
+	}
+
+	if strings.EqualFold(values["usedevelopmentstorage"], "true") {
+		return Config{
+			AccountName: devStoreAccount,
+			AccountKey:  devStoreKey,
+			Endpoint:    devStoreBlobURL,
+		}, nil
+	}
+
+	config := Config{
+		AccountName: values["accountname"],
+		AccountKey:  values["accountkey"],
+		SASToken:    values["sharedaccesssignature"],
+		Endpoint:    values["blobendpoint"],
+	}
+	if config.Endpoint == "" && values["endpointsuffix"] != "" {
+		protocol := values["defaultendpointsprotocol"]
+		if protocol == "" {
+			protocol = "https"
+		}
+		config.Endpoint = protocol + "://" + config.AccountName + ".blob." + values["endpointsuffix"]
+	}
+	if config.AccountName == "" && config.Endpoint != "" {
+		// Shared access signatures do not need the account name, but it is
+		// the first label of the endpoint's host, or the first segment of
+		// its path for emulators.
+		u, err := url.Parse(config.Endpoint)
+		if err != nil {
+			return Config{}, fmt.Errorf("parsing BlobEndpoint: %w", err)
+		}
+		config.AccountName, _, _ = strings.Cut(u.Hostname(), ".")
+		if segment, _, _ := strings.Cut(strings.Trim(u.Path, "/"), "/"); segment != "" {
+			config.AccountName = segment
+		}
+	}
+	return config, nil
+}
+
+// ParseURL returns the container and blob name prefix of an
+// azblob://container/prefix URL.
+func ParseURL(u *url.URL) (container, prefix string, err error) {
+	if u.Scheme != "azblob" || u.Host == "" {
+		return "", "", fmt.Errorf("%s is not an azblob://container/prefix URL", u)
+	}
+	return u.Host, strings.Trim(u.Path, "/"), nil
+}
+
+// Blob holds the properties of a blob.
+type Blob struct {
+	// ETag changes whenever the blob is replaced.
+	ETag string
+	// Metadata is the metadata of the blob, with lower case keys.
+	Metadata map[string]string
+	Size     int64
+}
+
+// Properties returns the properties of a blob. A missing blob is an
+// internal.HTTPError matching internal.ErrNotFound.
+func (c *Client) Properties(ctx context.Context, container, name string) (Blob, error) {
+	res, err := c.do(ctx, http.MethodHead, container, name, nil, nil)
+	if err != nil {
+		return Blob{}, err
+	}
+	//nolint:errcheck // HEAD responses have no body.
+	_ = res.Body.Close()
+
+	blob := Blob{
+		ETag:     res.Header.Get("ETag"),
+		Metadata: map[string]string{},
+		Size:     res.ContentLength,
+	}
+	for name, values := range res.Header {
+		if key, ok := strings.CutPrefix(name, metadataPrefix); ok && len(values) > 0 {
+			blob.Metadata[strings.ToLower(key)] = values[0]
+		}
+	}
+	return blob, nil
+}
+
+// Put stores the size bytes read from body as a block blob, with the
+// metadata. contentMD5 is the MD5 of the content, which the service
+// verifies.
+func (c *Client) Put(
+	ctx context.Context,
+	container,
+	name string,
+	body io.Reader,
+	size int64,
+	contentMD5 []byte,
+	metadata map[string]string,
+) error {
+	header := http.Header{}
+	header.Set("X-Ms-Blob-Type", "BlockBlob")
+	header.Set("Content-Type", "application/octet-stream")
+	header.Set("Content-MD5", base64.StdEncoding.EncodeToString(contentMD5))
+	for key, value := range metadata {
+		header.Set(metadataPrefix+key, value)
+	}
+
+	res, err := c.do(ctx, http.MethodPut, container, name, header, &sizedBody{body, size})
+	if err != nil {
+		return err
+	}
+	return drain(res)
+}
+
+// Copy copies the blob src to dst in the same container, with its metadata,
+// if the ETag of dst is ifMatch. If ifMatch is empty, dst must not exist. A
+// copy refused because of this is an error matching
+// internal.ErrPreconditionFailed.
+func (c *Client) Copy(ctx context.Context, container, src, dst, ifMatch string) error {
+	source := c.blobURL(container, src)
+	if c.sasToken != nil {
+		source.RawQuery = c.sasToken.Encode()
+	}
+
+	header := http.Header{}
+	header.Set("X-Ms-Copy-Source", source.String())
+	if ifMatch == "" {
+		header.Set("If-None-Match", "*")
+	} else {
+		header.Set("If-Match", ifMatch)
+	}
+
+	res, err := c.do(ctx, http.MethodPut, container, dst, header, nil)
+	if err != nil {
+		// A blob that exists despite If-None-Match is a conflict rather than
+		// a failed precondition.
+		var httpErr internal.HTTPError
+		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusConflict &&
+			strings.Contains(httpErr.Body, "BlobAlreadyExists") {
+			return fmt.Errorf("%w: %w", internal.ErrPreconditionFailed, err)
+		}
+		return err
+	}
+	if err := drain(res); err != nil {
+		return err
+	}
+
+	// Copies within an account are usually done before the response, but
+	// may not be.
+	status := res.Header.Get("X-Ms-Copy-Status")
+	for status == "pending" {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(copyPollInterval):
+		}
+		res, err := c.do(ctx, http.MethodHead, container, dst, nil, nil)
+		if err != nil {
+			return err
+		}
+		//nolint:errcheck // HEAD responses have no body.
+		_ = res.Body.Close()
+		status = res.Header.Get("X-Ms-Copy-Status")
+	}
+	if status != "" && status != "success" {
+		return fmt.Errorf("copying %s to %s: the copy status is %s", src, dst, status)
+	}
+	return nil
+}
+
+// Delete deletes a blob.
+func (c *Client) Delete(ctx context.Context, container, name string) error {
+	res, err := c.do(ctx, http.MethodDelete, container, name, nil, nil)
+	if err != nil {
+		return err
+	}
+	return drain(res)
+}
+
+// sizedBody is a request body of a known size.
+type sizedBody struct {
+	io.Reader
+	size int64
+}
+
+// do authorizes and sends a request for a blob and returns the response if
+// its status is 2xx.
+func (c *Client) do(
+	ctx context.Context,
+	method,
+	container,
+	name string,
+	header http.Header,
+	body *sizedBody,
+) (*http.Response, error) {
+	u := c.blobURL(container, name)
+	if c.sasToken != nil {
+		u.RawQuery = c.sasToken.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = body.Reader
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	if body != nil {
+		req.ContentLength = body.size
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("X-Ms-Date", c.now().UTC().Format(http.TimeFormat))
+	req.Header.Set("X-Ms-Version", apiVersion)
+	if c.accountKey != nil {
+		req.Header.Set("Authorization", "SharedKey "+c.accountName+":"+c.signature(req))
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("performing %s request for %s: %w", req.Method, req.URL.Path, err)
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		defer res.Body.Close()
+		//nolint:errcheck // the status code is the error.
+		b, _ := io.ReadAll(io.LimitReader(res.Body, 1<<16))
+		if len(b) == 0 {
+			// Errors of HEAD requests only have a header.
+			b = []byte(res.Header.Get("X-Ms-Error-Code"))
+		}
+		return nil, internal.NewHTTPError(res.StatusCode, b)
+	}
+	return res, nil
+}
+
+// blobURL returns the URL of a blob.
+func (c *Client) blobURL(container, name string) *url.URL {
+	u := *c.endpoint
+	segments := append([]string{container}, strings.Split(name, "/")...)
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		escaped[i] = url.PathEscape(segment)
+	}
+	u.Path = strings.TrimSuffix(c.endpoint.Path, "/") + "/" + strings.Join(segments, "/")
+	u.RawPath = strings.TrimSuffix(c.endpoint.EscapedPath(), "/") + "/" + strings.Join(escaped, "/")
+	return &u
+}
+
+// signature returns the shared key signature of req.
+func (c *Client) signature(req *http.Request) string {
+	h := hmac.New(sha256.New, c.accountKey)
+	h.Write([]byte(c.stringToSign(req)))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// stringToSign returns the string that is signed for a shared key
+// authorization of req.
+func (c *Client) stringToSign(req *http.Request) string {
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = fmt.Sprint(req.ContentLength)
+	}
+
+	lines := []string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		// The date is in X-Ms-Date.
+		"",
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+	}
+
+	var names []string
+	for name := range req.Header {
+		if strings.HasPrefix(strings.ToLower(name), "x-ms-") {
+			names = append(names, name)
+		}
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	})
+	var canonical strings.Builder
+	for _, name := range names {
+		value := strings.Join(strings.Fields(strings.Join(req.Header.Values(name), ",")), " ")
+		canonical.WriteString(strings.ToLower(name) + ":" + value + "\n")
+	}
+
+	resource := "/" + c.accountName + req.URL.EscapedPath()
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		values := slices.Clone(query[key])
+		slices.Sort(values)
+		resource += "\n" + strings.ToLower(key) + ":" + strings.Join(values, ",")
+	}
+
+	return strings.Join(lines, "\n") + "\n" + canonical.String() + resource
+}
+
+// drain reads the rest of the body of res and closes it.
+func drain(res *http.Response) error {
+	defer res.Body.Close()
+	if _, err := io.Copy(io.Discard, res.Body); err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+	return nil
+}
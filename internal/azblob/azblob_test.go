@@ -0,0 +1,95 @@
+package azblob
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStringToSign(t *testing.T) {
+	c, err := New(Config{
+		AccountName: devStoreAccount,
+		AccountKey:  devStoreKey,
+		Endpoint:    devStoreBlobURL,
+	})
+	require.NoError(t, err)
+
+	req, err := http.NewRequestWithContext(
+		t.Context(),
+		http.MethodPut,
+		c.blobURL("geoip", "prod/GeoIP2 City.mmdb").String()+"?comp=metadata&timeout=30",
+		strings.NewReader("db"),
+	)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("If-Match", `"0x8DC"`)
+	req.Header.Set("X-Ms-Version", apiVersion)
+	req.Header.Set("X-Ms-Date", "Sat, 17 Oct 2026 00:00:00 GMT")
+	req.Header.Set("X-Ms-Meta-Md5", "  abc  ")
+
+	require.Equal(t, "http://127.0.0.1:10000/devstoreaccount1/geoip/prod/GeoIP2%20City.mmdb?comp=metadata&timeout=30",
+		req.URL.String())
+	require.Equal(t, strings.Join([]string{
+		"PUT",
+		"",
+		"",
+		"2",
+		"",
+		"application/octet-stream",
+		"",
+		"",
+		`"0x8DC"`,
+		"",
+		"",
+		"",
+		"x-ms-date:Sat, 17 Oct 2026 00:00:00 GMT",
+		"x-ms-meta-md5:abc",
+		"x-ms-version:" + apiVersion,
+		// Emulators have the account in the path too.
+		"/devstoreaccount1/devstoreaccount1/geoip/prod/GeoIP2%20City.mmdb",
+		"comp:metadata",
+		"timeout:30",
+	}, "\n"), c.stringToSign(req))
+}
+
+func TestParseConnectionString(t *testing.T) {
+	tests := []struct {
+		connectionString string
+		config           Config
+	}{
+		{
+			connectionString: "UseDevelopmentStorage=true",
+			config: Config{
+				AccountName: devStoreAccount,
+				AccountKey:  devStoreKey,
+				Endpoint:    devStoreBlobURL,
+			},
+		},
+		{
+			connectionString: "DefaultEndpointsProtocol=https;AccountName=geoip;AccountKey=a2V5;EndpointSuffix=core.windows.net",
+			config: Config{
+				AccountName: "geoip",
+				AccountKey:  "a2V5",
+				Endpoint:    "https://geoip.blob.core.windows.net",
+			},
+		},
+		{
+			connectionString: "BlobEndpoint=https://geoip.blob.core.windows.net/;SharedAccessSignature=sv=2021-08-06&sig=abc",
+			config: Config{
+				AccountName: "geoip",
+				SASToken:    "sv=2021-08-06&sig=abc",
+				Endpoint:    "https://geoip.blob.core.windows.net/",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.connectionString, func(t *testing.T) {
+			config, err := parseConnectionString(test.connectionString)
+			require.NoError(t, err)
+			require.Equal(t, test.config, config)
+		})
+	}
+}
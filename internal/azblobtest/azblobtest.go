@@ -0,0 +1,245 @@
+// Package azblobtest provides an in-memory Azure Blob Storage server for
+// tests, addressed like Azurite, i.e., with the account in the path.
+package azblobtest
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	// AccountName is the name of the account the server serves.
+	AccountName = "devstoreaccount1"
+	// AccountKey is the key of AccountName. Signatures are not checked.
+	AccountKey = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+
+	metadataPrefix = "X-Ms-Meta-"
+)
+
+// Blob is a blob stored by the Server.
+type Blob struct {
+	Body     []byte
+	Metadata map[string]string
+	ETag     string
+}
+
+// Server serves the blobs of the containers of AccountName.
+type Server struct {
+	*httptest.Server
+
+	mu    sync.Mutex
+	blobs map[string]Blob
+	etag  int
+}
+
+// NewServer starts a Server that is closed when the test finishes.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{blobs: map[string]Blob{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Endpoint returns the URL of the blob service of AccountName.
+func (s *Server) Endpoint() string {
+	return s.URL + "/" + AccountName
+}
+
+// ConnectionString returns a connection string for the server.
+func (s *Server) ConnectionString() string {
+	return fmt.Sprintf(
+		"DefaultEndpointsProtocol=http;AccountName=%s;AccountKey=%s;BlobEndpoint=%s;",
+		AccountName, AccountKey, s.Endpoint(),
+	)
+}
+
+// Put stores a blob at container/name with a new ETag.
+func (s *Server) Put(container, name string, body []byte, metadata map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(container+"/"+name, body, metadata)
+}
+
+// Blob returns the blob at container/name.
+func (s *Server) Blob(container, name string) (Blob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	blob, ok := s.blobs[container+"/"+name]
+	return blob, ok
+}
+
+func (s *Server) put(key string, body []byte, metadata map[string]string) {
+	s.etag++
+	s.blobs[key] = Blob{
+		Body:     body,
+		Metadata: metadata,
+		ETag:     fmt.Sprintf(`"0x8DC%013X"`, s.etag),
+	}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "SharedKey "+AccountName+":") &&
+		r.URL.Query().Get("sig") == "" {
+		writeError(w, http.StatusForbidden, "AuthenticationFailed")
+		return
+	}
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+AccountName+"/")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "InvalidUri")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodHead:
+		s.properties(w, key)
+	case http.MethodPut:
+		if source := r.Header.Get("X-Ms-Copy-Source"); source != "" {
+			s.copy(w, r, source, key)
+		} else {
+			s.upload(w, r, key)
+		}
+	case http.MethodDelete:
+		s.delete(w, key)
+	default:
+		writeError(w, http.StatusBadRequest, "UnsupportedHttpVerb")
+	}
+}
+
+func (s *Server) properties(w http.ResponseWriter, key string) {
+	s.mu.Lock()
+	blob, ok := s.blobs[key]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "BlobNotFound")
+		return
+	}
+
+	for name, value := range blob.Metadata {
+		w.Header().Set(metadataPrefix+name, value)
+	}
+	sum := md5.Sum(blob.Body)
+	w.Header().Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	w.Header().Set("ETag", blob.ETag)
+	w.Header().Set("Content-Length", strconv.Itoa(len(blob.Body)))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) upload(w http.ResponseWriter, r *http.Request, key string) {
+	if r.Header.Get("X-Ms-Blob-Type") != "BlockBlob" {
+		writeError(w, http.StatusBadRequest, "InvalidHeaderValue")
+		return
+	}
+	metadata, ok := metadataOf(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "InvalidMetadata")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidInput")
+		return
+	}
+	sum := md5.Sum(body)
+	if contentMD5 := r.Header.Get("Content-MD5"); contentMD5 != "" &&
+		contentMD5 != base64.StdEncoding.EncodeToString(sum[:]) {
+		writeError(w, http.StatusBadRequest, "Md5Mismatch")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if status, code := s.checkConditions(r, key); status != 0 {
+		writeError(w, status, code)
+		return
+	}
+	s.put(key, body, metadata)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) copy(w http.ResponseWriter, r *http.Request, source, key string) {
+	u, err := url.Parse(source)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidHeaderValue")
+		return
+	}
+	sourceKey, _ := strings.CutPrefix(u.Path, "/"+AccountName+"/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	blob, ok := s.blobs[sourceKey]
+	if !ok {
+		writeError(w, http.StatusNotFound, "CannotVerifyCopySource")
+		return
+	}
+	if status, code := s.checkConditions(r, key); status != 0 {
+		writeError(w, status, code)
+		return
+	}
+	s.put(key, blob.Body, blob.Metadata)
+	w.Header().Set("X-Ms-Copy-Status", "success")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) delete(w http.ResponseWriter, key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.blobs[key]; !ok {
+		writeError(w, http.StatusNotFound, "BlobNotFound")
+		return
+	}
+	delete(s.blobs, key)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// checkConditions returns the status and error code of the response if the
+// If-Match or If-None-Match header of r is not met by the blob at key. s.mu
+// must be held.
+func (s *Server) checkConditions(r *http.Request, key string) (int, string) {
+	blob, exists := s.blobs[key]
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && (!exists || ifMatch != blob.ETag) {
+		return http.StatusPreconditionFailed, "ConditionNotMet"
+	}
+	if r.Header.Get("If-None-Match") == "*" && exists {
+		return http.StatusConflict, "BlobAlreadyExists"
+	}
+	return 0, ""
+}
+
+// metadataOf returns the metadata of r, and false if a name is not a valid
+// C# identifier, as Azure requires.
+func metadataOf(r *http.Request) (map[string]string, bool) {
+	metadata := map[string]string{}
+	for name, values := range r.Header {
+		key, ok := strings.CutPrefix(name, metadataPrefix)
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(key)
+		if strings.ContainsFunc(key, func(c rune) bool {
+			return c != '_' && (c < 'a' || c > 'z') && (c < '0' || c > '9')
+		}) {
+			return nil, false
+		}
+		metadata[key] = values[0]
+	}
+	return metadata, true
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("X-Ms-Error-Code", code)
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"utf-8\"?><Error><Code>%s</Code></Error>", code)
+}
@@ -0,0 +1,272 @@
+// Package cron parses standard 5-field cron expressions and computes when
+// they next match.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	// domStar and dowStar record whether the day of month and day of week
+	// fields started with '*'. As in Vixie cron, when both fields are
+	// restricted a day matches if either field matches.
+	domStar bool
+	dowStar bool
+
+	// location is the time zone the expression is evaluated in. When nil,
+	// the location of the time passed to Next is used.
+	location *time.Location
+}
+
+type bounds struct {
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	minuteBounds = bounds{min: 0, max: 59}
+	hourBounds   = bounds{min: 0, max: 23}
+	domBounds    = bounds{min: 1, max: 31}
+	monthBounds  = bounds{
+		min: 1,
+		max: 12,
+		names: map[string]int{
+			"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+			"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+		},
+	}
+	// 7 is accepted as an alias for Sunday and folded into 0 after parsing.
+	dowBounds = bounds{
+		min: 0,
+		max: 7,
+		names: map[string]int{
+			"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+		},
+	}
+)
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a cron expression made up of the minute, hour, day of month,
+// month and day of week fields, e.g., "0 6 * * 2,5". Fields may contain
+// lists, ranges, steps and, for months and days of the week, three-letter
+// English names. The macros @yearly, @annually, @monthly, @weekly, @daily,
+// @midnight and @hourly are also accepted.
+//
+// The expression may be prefixed with "CRON_TZ=<zone>" or "TZ=<zone>" to
+// evaluate it in an IANA time zone rather than in the location of the times
+// passed to Next.
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	s := &Schedule{}
+
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		prefix, rest, _ := strings.Cut(spec, " ")
+		_, zone, _ := strings.Cut(prefix, "=")
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			return nil, fmt.Errorf("loading time zone %q: %w", zone, err)
+		}
+		s.location = loc
+		spec = strings.TrimSpace(rest)
+	}
+
+	if expanded, ok := macros[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields in cron expression, found %d", len(fields))
+	}
+
+	var err error
+	if s.minute, err = parseField(fields[0], minuteBounds); err != nil {
+		return nil, fmt.Errorf("parsing minute field: %w", err)
+	}
+	if s.hour, err = parseField(fields[1], hourBounds); err != nil {
+		return nil, fmt.Errorf("parsing hour field: %w", err)
+	}
+	if s.dom, err = parseField(fields[2], domBounds); err != nil {
+		return nil, fmt.Errorf("parsing day of month field: %w", err)
+	}
+	if s.month, err = parseField(fields[3], monthBounds); err != nil {
+		return nil, fmt.Errorf("parsing month field: %w", err)
+	}
+	if s.dow, err = parseField(fields[4], dowBounds); err != nil {
+		return nil, fmt.Errorf("parsing day of week field: %w", err)
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+
+	s.domStar = strings.HasPrefix(fields[2], "*")
+	s.dowStar = strings.HasPrefix(fields[4], "*")
+
+	return s, nil
+}
+
+// parseField parses a comma-separated list of values, ranges and steps into
+// a bit set.
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for item := range strings.SplitSeq(field, ",") {
+		itemBits, err := parseItem(item, b)
+		if err != nil {
+			return 0, err
+		}
+		bits |= itemBits
+	}
+	return bits, nil
+}
+
+func parseItem(item string, b bounds) (uint64, error) {
+	rangePart, stepPart, hasStep := strings.Cut(item, "/")
+
+	step := 1
+	if hasStep {
+		var err error
+		step, err = strconv.Atoi(stepPart)
+		if err != nil || step <= 0 {
+			return 0, fmt.Errorf("invalid step %q", stepPart)
+		}
+	}
+
+	var start, end int
+	switch {
+	case rangePart == "*":
+		start, end = b.min, b.max
+	case strings.Contains(rangePart, "-"):
+		lo, hi, _ := strings.Cut(rangePart, "-")
+		var err error
+		if start, err = parseValue(lo, b); err != nil {
+			return 0, err
+		}
+		if end, err = parseValue(hi, b); err != nil {
+			return 0, err
+		}
+		if start > end {
+			return 0, fmt.Errorf("range %q is backwards", rangePart)
+		}
+	default:
+		var err error
+		if start, err = parseValue(rangePart, b); err != nil {
+			return 0, err
+		}
+		end = start
+		// As in other cron implementations, "5/15" means "5-max/15".
+		if hasStep {
+			end = b.max
+		}
+	}
+
+	var bits uint64
+	for i := start; i <= end; i += step {
+		bits |= bit(i)
+	}
+	return bits, nil
+}
+
+func parseValue(s string, b bounds) (int, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if v < b.min || v > b.max {
+		return 0, fmt.Errorf("value %d is out of range [%d, %d]", v, b.min, b.max)
+	}
+	return v, nil
+}
+
+// Next returns the first time strictly after t that matches the schedule. It
+// returns the zero time if the schedule never matches, e.g., for
+// "0 0 30 2 *".
+//
+// Times are matched against the wall clock, so around daylight saving time
+// transitions a matching time may be skipped or matched twice.
+func (s *Schedule) Next(t time.Time) time.Time {
+	origLocation := t.Location()
+	loc := origLocation
+	if s.location != nil {
+		loc = s.location
+	}
+	t = t.In(loc)
+
+	// Start at the beginning of the next minute.
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).
+		Add(time.Minute)
+
+	// Every matching date recurs within a few years; the leap day is the
+	// rarest at one in four, or eight across a skipped century leap year.
+	yearLimit := t.Year() + 9
+
+	for t.Year() <= yearLimit {
+		if s.month&bit(int(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if s.hour&bit(t.Hour()) == 0 {
+			next := time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			// Around a daylight saving time transition, the next wall clock
+			// hour may not be an hour later. Adding an hour of absolute time
+			// keeps us moving forward.
+			if !next.After(t) {
+				next = t.Add(time.Hour)
+			}
+			t = next
+			continue
+		}
+
+		if s.minute&bit(t.Minute()) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t.In(origLocation)
+	}
+
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&bit(t.Day()) != 0
+	dowMatch := s.dow&bit(int(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// bit returns a bit set with only bit n set. n must be between 0 and 63.
+func bit(n int) uint64 {
+	return 1 << uint(n) //nolint:gosec // n is never negative.
+}
@@ -0,0 +1,151 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"too few fields":        "* * * *",
+		"too many fields":       "* * * * * *",
+		"minute out of range":   "60 * * * *",
+		"hour out of range":     "* 24 * * *",
+		"day of month zero":     "* * 0 * *",
+		"month out of range":    "* * * 13 *",
+		"day of week too large": "* * * * 8",
+		"backwards range":       "* * * * 5-1",
+		"zero step":             "*/0 * * * *",
+		"unknown name":          "* * * foo *",
+		"unknown time zone":     "CRON_TZ=Nowhere/Special 0 0 * * *",
+		"empty":                 "",
+	}
+
+	for name, spec := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse(spec)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	tests := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{
+			spec: "* * * * *",
+			from: time.Date(2026, 10, 14, 12, 0, 30, 0, time.UTC),
+			want: time.Date(2026, 10, 14, 12, 1, 0, 0, time.UTC),
+		},
+		{
+			spec: "0 6 * * *",
+			from: time.Date(2026, 10, 14, 6, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 10, 15, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			// Tuesdays and Fridays. 2026-10-14 is a Wednesday.
+			spec: "0 6 * * 2,5",
+			from: time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 10, 16, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			spec: "30 4 * * tue,FRI",
+			from: time.Date(2026, 10, 16, 5, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 10, 20, 4, 30, 0, 0, time.UTC),
+		},
+		{
+			spec: "*/15 * * * *",
+			from: time.Date(2026, 10, 14, 12, 46, 0, 0, time.UTC),
+			want: time.Date(2026, 10, 14, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			spec: "5/20 * * * *",
+			from: time.Date(2026, 10, 14, 12, 26, 0, 0, time.UTC),
+			want: time.Date(2026, 10, 14, 12, 45, 0, 0, time.UTC),
+		},
+		{
+			spec: "0 0 1 jan-mar *",
+			from: time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			spec: "@weekly",
+			from: time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// Sunday may be written as 7.
+			spec: "0 0 * * 7",
+			from: time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// When both day fields are restricted, either may match.
+			spec: "0 0 20 * mon",
+			from: time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// When one day field is a star, both must match. The 13th of a
+			// month is next a Friday in November 2026.
+			spec: "0 0 13 * */5",
+			from: time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 11, 13, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			spec: "0 0 29 2 *",
+			from: time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			spec: "0 0 30 2 *",
+			from: time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC),
+			want: time.Time{},
+		},
+		{
+			// The time zone of the expression is used, but the result is in
+			// the location of the time passed in.
+			spec: "CRON_TZ=America/New_York 0 6 * * *",
+			from: time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			// 2:30 does not exist on 2026-03-08 in New York.
+			spec: "30 2 * * *",
+			from: time.Date(2026, 3, 8, 0, 0, 0, 0, newYork),
+			want: time.Date(2026, 3, 9, 2, 30, 0, 0, newYork),
+		},
+		{
+			spec: "0 * * * *",
+			from: time.Date(2026, 3, 8, 1, 30, 0, 0, newYork),
+			want: time.Date(2026, 3, 8, 3, 0, 0, 0, newYork),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			s, err := Parse(test.spec)
+			require.NoError(t, err)
+
+			got := s.Next(test.from)
+			require.True(
+				t,
+				test.want.Equal(got),
+				"expected %s, got %s",
+				test.want,
+				got,
+			)
+			if !got.IsZero() {
+				require.Equal(t, test.from.Location(), got.Location())
+			}
+		})
+	}
+}
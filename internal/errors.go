@@ -2,27 +2,143 @@
 package internal
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+
+	"github.com/maxmind/geoipupdate/v8/internal/minisign"
 )
 
+var (
+	// ErrLockHeld is returned when the lock file is held by another process.
+	ErrLockHeld = errors.New("already acquired by another process")
+
+	// ErrHashMismatch is returned when the MD5 of a downloaded database does
+	// not match the MD5 the server sent for it.
+	ErrHashMismatch = errors.New("hash mismatch")
+
+	// ErrInvalidDatabase is returned when a downloaded database is not a
+	// well-formed MMDB of the edition it was downloaded as.
+	ErrInvalidDatabase = errors.New("invalid database")
+
+	// ErrDowngradeRefused is returned when a downloaded database was built
+	// before the current one and downgrades are not allowed.
+	ErrDowngradeRefused = errors.New("downgrade refused")
+
+	// ErrInvalidLicenseKey is matched by an HTTPError when the server rejected
+	// the account ID and license key.
+	ErrInvalidLicenseKey = errors.New("invalid license key")
+
+	// ErrEditionNotEntitled is matched by an HTTPError when the account is not
+	// permitted to download the edition.
+	ErrEditionNotEntitled = errors.New("edition not entitled")
+
+	// ErrAccountIDRequired is matched by an HTTPError when the request did not
+	// include an account ID.
+	ErrAccountIDRequired = errors.New("account ID required")
+
+	// ErrNotFound is wrapped by the error returned when a source has no such
+	// database, and matched by an HTTPError with status code 404.
+	ErrNotFound = errors.New("not found")
+
+	// ErrPreconditionFailed is wrapped by the error returned when a
+	// conditional write was refused because the object changed, and matched
+	// by an HTTPError with status code 412.
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// codeErrors maps the error codes in the server's JSON error responses to
+// the errors HTTPError matches.
+var codeErrors = map[string]error{
+	"ACCOUNT_ID_REQUIRED":   ErrAccountIDRequired,
+	"ACCOUNT_ID_UNKNOWN":    ErrInvalidLicenseKey,
+	"AUTHORIZATION_INVALID": ErrInvalidLicenseKey,
+	"LICENSE_KEY_INVALID":   ErrInvalidLicenseKey,
+	"LICENSE_KEY_REQUIRED":  ErrInvalidLicenseKey,
+	"PERMISSION_REQUIRED":   ErrEditionNotEntitled,
+}
+
 // HTTPError is an error from performing an HTTP request.
+//
+// When the server sent a JSON error document, Code and Message hold its
+// code and error fields, and the HTTPError matches the corresponding error
+// such as ErrInvalidLicenseKey with errors.Is.
 type HTTPError struct {
 	Body       string
 	StatusCode int
+	Code       string
+	Message    string
+}
+
+// NewHTTPError creates an HTTPError from a response's status code and body,
+// parsing the body if it is a JSON error document.
+func NewHTTPError(statusCode int, body []byte) HTTPError {
+	var doc struct {
+		Code  string `json:"code"`
+		Error string `json:"error"`
+	}
+	// Bodies that are not JSON error documents, e.g., from proxies, are
+	// only kept as is.
+	//nolint:errcheck // see above.
+	_ = json.Unmarshal(body, &doc)
+
+	return HTTPError{
+		Body:       string(body),
+		StatusCode: statusCode,
+		Code:       doc.Code,
+		Message:    doc.Error,
+	}
 }
 
 func (h HTTPError) Error() string {
 	return fmt.Sprintf("received HTTP status code: %d: %s", h.StatusCode, h.Body)
 }
 
+// Is reports whether target is the error corresponding to the error code
+// sent by the server, ErrNotFound for status code 404 or
+// ErrPreconditionFailed for status code 412.
+func (h HTTPError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return h.StatusCode == http.StatusNotFound
+	case ErrPreconditionFailed:
+		return h.StatusCode == http.StatusPreconditionFailed
+	}
+	codeErr, ok := codeErrors[h.Code]
+	return ok && codeErr == target
+}
+
 // IsRetryableError returns true if the error should be retried.
 func IsRetryableError(err error) bool {
 	if err == nil {
 		return false
 	}
 
+	// These depend on the account and won't change by retrying, whatever
+	// status code the server used.
+	if errors.Is(err, ErrInvalidLicenseKey) ||
+		errors.Is(err, ErrEditionNotEntitled) ||
+		errors.Is(err, ErrAccountIDRequired) {
+		return false
+	}
+
+	// A signature that does not match, a database that is not valid or is
+	// older than the current one, or one that another updater replaced in the
+	// meantime, will not be any different on the next attempt.
+	if errors.Is(err, minisign.ErrInvalidSignature) ||
+		errors.Is(err, ErrInvalidDatabase) ||
+		errors.Is(err, ErrDowngradeRefused) ||
+		errors.Is(err, ErrNotFound) ||
+		errors.Is(err, ErrPreconditionFailed) {
+		return false
+	}
+
+	// The database may have been corrupted in transit.
+	if errors.Is(err, ErrHashMismatch) {
+		return true
+	}
+
 	var httpErr HTTPError
 	if errors.As(err, &httpErr) {
 		return isRetryableHTTPStatusCode(httpErr.StatusCode)
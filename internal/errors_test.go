@@ -2,11 +2,15 @@ package internal
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"testing"
 
+	"github.com/stretchr/testify/require"
 	"golang.org/x/net/http2"
+
+	"github.com/maxmind/geoipupdate/v8/internal/minisign"
 )
 
 func TestIsRetryableError(t *testing.T) {
@@ -50,6 +54,12 @@ func TestIsRetryableError(t *testing.T) {
 			},
 			want: false,
 		},
+		"precondition failed": {
+			err: fmt.Errorf("publishing: %w", HTTPError{
+				StatusCode: http.StatusPreconditionFailed,
+			}),
+			want: false,
+		},
 		"url error wrapping proxy CONNECT HTTPError forbidden": {
 			err: &url.Error{
 				Op:  "Get",
@@ -66,6 +76,36 @@ func TestIsRetryableError(t *testing.T) {
 			},
 			want: true,
 		},
+		"invalid license key with a server error status": {
+			err: NewHTTPError(
+				http.StatusInternalServerError,
+				[]byte(`{"code":"LICENSE_KEY_INVALID","error":"invalid"}`),
+			),
+			want: false,
+		},
+		"edition not entitled": {
+			err: fmt.Errorf("downloading: %w", NewHTTPError(
+				http.StatusForbidden,
+				[]byte(`{"code":"PERMISSION_REQUIRED","error":"not permitted"}`),
+			)),
+			want: false,
+		},
+		"hash mismatch": {
+			err:  fmt.Errorf("validating hash: %w", ErrHashMismatch),
+			want: true,
+		},
+		"invalid signature": {
+			err:  fmt.Errorf("verifying signature: %w", minisign.ErrInvalidSignature),
+			want: false,
+		},
+		"invalid database": {
+			err:  fmt.Errorf("validating database: %w", ErrInvalidDatabase),
+			want: false,
+		},
+		"downgrade refused": {
+			err:  fmt.Errorf("checking build: %w", ErrDowngradeRefused),
+			want: false,
+		},
 		"plain forbidden error": {
 			err:  errors.New("Forbidden"),
 			want: true,
@@ -85,3 +125,68 @@ func TestIsRetryableError(t *testing.T) {
 		})
 	}
 }
+
+func TestNewHTTPError(t *testing.T) {
+	tests := []struct {
+		description string
+		statusCode  int
+		body        string
+		wantCode    string
+		wantMessage string
+		wantErr     error
+	}{
+		{
+			description: "invalid license key",
+			statusCode:  http.StatusUnauthorized,
+			body:        `{"code":"AUTHORIZATION_INVALID","error":"Invalid account ID or license key"}`,
+			wantCode:    "AUTHORIZATION_INVALID",
+			wantMessage: "Invalid account ID or license key",
+			wantErr:     ErrInvalidLicenseKey,
+		},
+		{
+			description: "edition not entitled",
+			statusCode:  http.StatusForbidden,
+			body:        `{"code":"PERMISSION_REQUIRED","error":"You do not have permission"}`,
+			wantCode:    "PERMISSION_REQUIRED",
+			wantMessage: "You do not have permission",
+			wantErr:     ErrEditionNotEntitled,
+		},
+		{
+			description: "account ID required",
+			statusCode:  http.StatusUnauthorized,
+			body:        `{"code":"ACCOUNT_ID_REQUIRED","error":"Account ID required"}`,
+			wantCode:    "ACCOUNT_ID_REQUIRED",
+			wantMessage: "Account ID required",
+			wantErr:     ErrAccountIDRequired,
+		},
+		{
+			description: "unknown code",
+			statusCode:  http.StatusBadRequest,
+			body:        `{"code":"SOMETHING_ELSE","error":"Something else"}`,
+			wantCode:    "SOMETHING_ELSE",
+			wantMessage: "Something else",
+		},
+		{
+			description: "not JSON",
+			statusCode:  http.StatusBadGateway,
+			body:        "<html>Bad Gateway</html>",
+		},
+	}
+
+	sentinels := []error{ErrInvalidLicenseKey, ErrEditionNotEntitled, ErrAccountIDRequired}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			httpErr := NewHTTPError(test.statusCode, []byte(test.body))
+			require.Equal(t, test.statusCode, httpErr.StatusCode)
+			require.Equal(t, test.body, httpErr.Body)
+			require.Equal(t, test.wantCode, httpErr.Code)
+			require.Equal(t, test.wantMessage, httpErr.Message)
+
+			err := fmt.Errorf("wrapped: %w", httpErr)
+			for _, sentinel := range sentinels {
+				require.Equal(t, sentinel == test.wantErr, errors.Is(err, sentinel), sentinel)
+			}
+		})
+	}
+}
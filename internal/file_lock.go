@@ -53,7 +53,7 @@ func (f *FileLock) Acquire() error {
 		return fmt.Errorf("acquiring file lock at %s: %w", f.lock.Path(), err)
 	}
 	if !ok {
-		return fmt.Errorf("lock %s already acquired by another process", f.lock.Path())
+		return fmt.Errorf("lock %s %w", f.lock.Path(), ErrLockHeld)
 	}
 	if f.verbose {
 		log.Printf("Acquired lock file at %s", f.lock.Path())
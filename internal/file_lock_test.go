@@ -40,3 +40,20 @@ func TestAcquireFileLock(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, fl.lock.Locked())
 }
+
+func TestAcquireFileLockHeld(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".geoipupdate.lock")
+
+	fl, err := NewFileLock(path, false)
+	require.NoError(t, err)
+	require.NoError(t, fl.Acquire())
+	defer func() {
+		require.NoError(t, fl.Release())
+	}()
+
+	// A second lock on the same file behaves like one held by another
+	// process.
+	other, err := NewFileLock(path, false)
+	require.NoError(t, err)
+	require.ErrorIs(t, other.Acquire(), ErrLockHeld)
+}
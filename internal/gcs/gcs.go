@@ -0,0 +1,281 @@
+// Package gcs is a minimal client for the JSON API of Google Cloud Storage
+// and emulators of it such as fake-gcs-server. It only supports what
+// geoipupdate needs, i.e., reading the attributes of, uploading, copying and
+// deleting single objects.
+package gcs
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
+)
+
+const defaultEndpoint = "https://storage.googleapis.com"
+
+// Config holds the settings of a Client.
+type Config struct {
+	// Endpoint is the URL of the API, e.g., http://localhost:4443 for a local
+	// fake-gcs-server. The default is that of Google Cloud Storage.
+	Endpoint string
+	// TokenSource provides the OAuth 2.0 access tokens the requests are
+	// authorized with. If it is nil, the requests are not authorized, as
+	// emulators expect.
+	TokenSource TokenSource
+	HTTPClient  *http.Client
+}
+
+// Client makes requests to the JSON API of Google Cloud Storage.
+//
+// It is valid for concurrent use.
+type Client struct {
+	endpoint    string
+	tokenSource TokenSource
+	httpClient  *http.Client
+}
+
+// New creates a Client.
+func New(config Config) (*Client, error) {
+	c := &Client{
+		endpoint:    strings.TrimSuffix(config.Endpoint, "/"),
+		tokenSource: config.TokenSource,
+		httpClient:  config.HTTPClient,
+	}
+	if c.endpoint == "" {
+		c.endpoint = defaultEndpoint
+	}
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+	if _, err := url.Parse(c.endpoint); err != nil {
+		return nil, fmt.Errorf("parsing endpoint: %w", err)
+	}
+	return c, nil
+}
+
+// NewFromEnv creates a Client configured as the Google Cloud tools are.
+// If STORAGE_EMULATOR_HOST is set, requests go to that emulator without
+// authorization. Otherwise, the access token is GOOGLE_OAUTH_ACCESS_TOKEN,
+// or obtained with the application default credentials, i.e., the
+// credentials file at GOOGLE_APPLICATION_CREDENTIALS or the one written by
+// `gcloud auth application-default login`, or the service account of the
+// instance from the metadata server.
+func NewFromEnv(httpClient *http.Client) (*Client, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+		if !strings.Contains(host, "://") {
+			host = "http://" + host
+		}
+		return New(Config{Endpoint: host, HTTPClient: httpClient})
+	}
+
+	tokenSource, err := defaultTokenSource(httpClient)
+	if err != nil {
+		return nil, err
+	}
+	return New(Config{TokenSource: tokenSource, HTTPClient: httpClient})
+}
+
+// ParseURL returns the bucket and object name prefix of a gs://bucket/prefix
+// URL.
+func ParseURL(u *url.URL) (bucket, prefix string, err error) {
+	if u.Scheme != "gs" || u.Host == "" {
+		return "", "", fmt.Errorf("%s is not a gs://bucket/prefix URL", u)
+	}
+	return u.Host, strings.Trim(u.Path, "/"), nil
+}
+
+// Object holds the attributes of an object.
+type Object struct {
+	Name string `json:"name"`
+	// Generation is the version of the object, which changes whenever it is
+	// replaced.
+	Generation int64 `json:"generation,string"`
+	Size       int64 `json:"size,string"`
+	// MD5Hash is the base64-encoded MD5 of the object. Composite objects
+	// have none.
+	MD5Hash string `json:"md5Hash,omitempty"`
+	// Metadata is the custom metadata of the object.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Attrs returns the attributes of an object. A missing object is an
+// internal.HTTPError matching internal.ErrNotFound.
+func (c *Client) Attrs(ctx context.Context, bucket, name string) (Object, error) {
+	var obj Object
+	err := c.doJSON(ctx, http.MethodGet, c.objectURL(bucket, name), nil, &obj)
+	return obj, err
+}
+
+// Upload stores the size bytes read from body as the object name, with the
+// custom metadata. contentMD5 is the MD5 of the content, which the service
+// verifies.
+func (c *Client) Upload(
+	ctx context.Context,
+	bucket,
+	name string,
+	body io.Reader,
+	size int64,
+	contentMD5 []byte,
+	metadata map[string]string,
+) error {
+	resource, err := json.Marshal(Object{
+		Name:     name,
+		MD5Hash:  base64.StdEncoding.EncodeToString(contentMD5),
+		Metadata: metadata,
+	})
+	if err != nil {
+		return fmt.Errorf("encoding object resource: %w", err)
+	}
+
+	// The resource and the content are sent as a multipart/related body,
+	// whose parts are written in full but for the content, which is
+	// streamed.
+	var head bytes.Buffer
+	mw := multipart.NewWriter(&head)
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"application/json; charset=UTF-8"},
+	})
+	if err != nil {
+		return fmt.Errorf("creating upload body: %w", err)
+	}
+	if _, err := part.Write(resource); err != nil {
+		return fmt.Errorf("creating upload body: %w", err)
+	}
+	if _, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"application/octet-stream"},
+	}); err != nil {
+		return fmt.Errorf("creating upload body: %w", err)
+	}
+	tail := "\r\n--" + mw.Boundary() + "--\r\n"
+
+	u := c.endpoint + "/upload/storage/v1/b/" + url.PathEscape(bucket) + "/o?uploadType=multipart"
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		u,
+		io.MultiReader(&head, io.LimitReader(body, size), strings.NewReader(tail)),
+	)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.ContentLength = int64(head.Len()) + size + int64(len(tail))
+	req.Header.Set("Content-Type", "multipart/related; boundary="+mw.Boundary())
+
+	return c.doRequest(req, nil)
+}
+
+// Rewrite copies the object src to dst in the same bucket, with its
+// metadata, if the generation of dst is ifGenerationMatch. If
+// ifGenerationMatch is 0, dst must not exist. A copy refused because of this
+// is an internal.HTTPError matching internal.ErrPreconditionFailed.
+func (c *Client) Rewrite(
+	ctx context.Context,
+	bucket,
+	src,
+	dst string,
+	ifGenerationMatch int64,
+) error {
+	query := url.Values{"ifGenerationMatch": {strconv.FormatInt(ifGenerationMatch, 10)}}
+	// Large copies between locations or storage classes take several calls.
+	for {
+		u := c.objectURL(bucket, src) + "/rewriteTo/b/" + url.PathEscape(bucket) +
+			"/o/" + url.PathEscape(dst) + "?" + query.Encode()
+
+		var res struct {
+			Done         bool   `json:"done"`
+			RewriteToken string `json:"rewriteToken"`
+		}
+		if err := c.doJSON(ctx, http.MethodPost, u, nil, &res); err != nil {
+			return err
+		}
+		if res.Done {
+			return nil
+		}
+		if res.RewriteToken == "" {
+			return errors.New("the rewrite is not done but has no token")
+		}
+		query.Set("rewriteToken", res.RewriteToken)
+	}
+}
+
+// Delete deletes an object.
+func (c *Client) Delete(ctx context.Context, bucket, name string) error {
+	return c.doJSON(ctx, http.MethodDelete, c.objectURL(bucket, name), nil, nil)
+}
+
+// objectURL returns the URL of an object in the JSON API. Slashes in the
+// name are escaped too.
+func (c *Client) objectURL(bucket, name string) string {
+	return c.endpoint + "/storage/v1/b/" + url.PathEscape(bucket) + "/o/" + url.PathEscape(name)
+}
+
+// doJSON sends a request with the JSON encoding of in, if it is not nil, and
+// decodes the response into out, if it is not nil.
+func (c *Client) doJSON(ctx context.Context, method, u string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.doRequest(req, out)
+}
+
+// doRequest authorizes and sends req and decodes the response into out, if
+// it is not nil. Responses whose status is not 2xx are internal.HTTPErrors.
+func (c *Client) doRequest(req *http.Request, out any) error {
+	if c.tokenSource != nil {
+		token, err := c.tokenSource.Token(req.Context())
+		if err != nil {
+			return fmt.Errorf("getting access token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("performing %s request for %s: %w", req.Method, req.URL.Path, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		//nolint:errcheck // the status code is the error.
+		b, _ := io.ReadAll(io.LimitReader(res.Body, 1<<16))
+		return internal.NewHTTPError(res.StatusCode, b)
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			return fmt.Errorf("decoding response: %w", err)
+		}
+	}
+	if _, err := io.Copy(io.Discard, res.Body); err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+	return nil
+}
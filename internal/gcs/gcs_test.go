@@ -0,0 +1,87 @@
+package gcs
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceAccountToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	requests := 0
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "urn:ietf:params:oauth:grant-type:jwt-bearer", r.Form.Get("grant_type"))
+
+		// The assertion is signed by the key of the service account.
+		parts := strings.Split(r.Form.Get("assertion"), ".")
+		if !assert.Len(t, parts, 3) {
+			return
+		}
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		assert.NoError(t, err)
+		sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], signature))
+
+		claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+		assert.NoError(t, err)
+		assert.Contains(t, string(claims), `"iss":"updater@geoip.iam.gserviceaccount.com"`)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"secret-token","expires_in":3600}`))
+	}))
+	t.Cleanup(tokenServer.Close)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	creds, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "updater@geoip.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    tokenServer.URL,
+	})
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	require.NoError(t, os.WriteFile(path, creds, 0o600))
+
+	var authorization []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		assert.Equal(t, "/storage/v1/b/geoip/o/prod%2FGeoIP2-City.mmdb", r.URL.EscapedPath())
+		_, _ = w.Write([]byte(`{"name":"prod/GeoIP2-City.mmdb","generation":"42","size":"3"}`))
+	}))
+	t.Cleanup(api.Close)
+
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+	tokenSource, err := defaultTokenSource(http.DefaultClient)
+	require.NoError(t, err)
+	c, err := New(Config{Endpoint: api.URL, TokenSource: tokenSource})
+	require.NoError(t, err)
+
+	// The token is reused until it is about to expire.
+	for range 2 {
+		obj, err := c.Attrs(t.Context(), "geoip", "prod/GeoIP2-City.mmdb")
+		require.NoError(t, err)
+		require.Equal(t, int64(42), obj.Generation)
+		require.Equal(t, int64(3), obj.Size)
+	}
+	require.Equal(t, 1, requests)
+	require.Equal(t, []string{"Bearer secret-token", "Bearer secret-token"}, authorization)
+}
@@ -0,0 +1,310 @@
+package gcs
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
+)
+
+const (
+	scope            = "https://www.googleapis.com/auth/devstorage.read_write"
+	defaultTokenURI  = "https://oauth2.googleapis.com/token"
+	metadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	// expiryMargin is how long before it expires a token is renewed.
+	expiryMargin = time.Minute
+)
+
+// TokenSource provides OAuth 2.0 access tokens.
+type TokenSource interface {
+	// Token returns a valid access token.
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a TokenSource that always returns the same token.
+type StaticToken string
+
+// Token returns the token.
+func (t StaticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+// defaultTokenSource returns the TokenSource of the application default
+// credentials. See NewFromEnv.
+func defaultTokenSource(httpClient *http.Client) (TokenSource, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return StaticToken(token), nil
+	}
+
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		// This is where `gcloud auth application-default login` writes the
+		// credentials. On Windows, it is under %APPDATA% rather than the
+		// user's configuration directory.
+		if dir, err := os.UserConfigDir(); err == nil {
+			wellKnown := filepath.Join(dir, "gcloud", "application_default_credentials.json")
+			if _, err := os.Stat(wellKnown); err == nil {
+				path = wellKnown
+			}
+		}
+	}
+	if path != "" {
+		return credentialsFile(path, httpClient)
+	}
+
+	return newCachingTokenSource(metadataToken{httpClient: httpClient}), nil
+}
+
+// credentialsFile returns the TokenSource of the credentials file at path,
+// which is either a service account key or the credentials of a user.
+func credentialsFile(path string, httpClient *http.Client) (TokenSource, error) {
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("reading credentials: %w", err)
+	}
+
+	var creds struct {
+		Type         string `json:"type"`
+		ClientEmail  string `json:"client_email"`
+		PrivateKey   string `json:"private_key"`
+		TokenURI     string `json:"token_uri"`
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(b, &creds); err != nil {
+		return nil, fmt.Errorf("decoding credentials in %s: %w", path, err)
+	}
+	if creds.TokenURI == "" {
+		creds.TokenURI = defaultTokenURI
+	}
+
+	switch creds.Type {
+	case "service_account":
+		key, err := parsePrivateKey(creds.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("parsing private key in %s: %w", path, err)
+		}
+		return newCachingTokenSource(serviceAccountToken{
+			email:      creds.ClientEmail,
+			key:        key,
+			tokenURI:   creds.TokenURI,
+			httpClient: httpClient,
+			now:        time.Now,
+		}), nil
+	case "authorized_user":
+		return newCachingTokenSource(refreshToken{
+			form: url.Values{
+				"grant_type":    {"refresh_token"},
+				"client_id":     {creds.ClientID},
+				"client_secret": {creds.ClientSecret},
+				"refresh_token": {creds.RefreshToken},
+			},
+			tokenURI:   creds.TokenURI,
+			httpClient: httpClient,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unsupported credentials type %q in %s", creds.Type, path)
+	}
+}
+
+func parsePrivateKey(s string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("the private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// token is an access token and when it expires.
+type token struct {
+	value   string
+	expires time.Time
+}
+
+// tokenFetcher fetches a new access token.
+type tokenFetcher interface {
+	fetch(ctx context.Context) (token, error)
+}
+
+// cachingTokenSource is a TokenSource that reuses the token of a
+// tokenFetcher until shortly before it expires.
+type cachingTokenSource struct {
+	fetcher tokenFetcher
+	now     func() time.Time
+
+	mu    sync.Mutex
+	token token
+}
+
+func newCachingTokenSource(fetcher tokenFetcher) *cachingTokenSource {
+	return &cachingTokenSource{fetcher: fetcher, now: time.Now}
+}
+
+func (s *cachingTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token.value != "" && s.now().Add(expiryMargin).Before(s.token.expires) {
+		return s.token.value, nil
+	}
+	t, err := s.fetcher.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	s.token = t
+	return t.value, nil
+}
+
+// serviceAccountToken fetches tokens for a service account with a JWT
+// signed by its key.
+type serviceAccountToken struct {
+	email      string
+	key        *rsa.PrivateKey
+	tokenURI   string
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+func (s serviceAccountToken) fetch(ctx context.Context) (token, error) {
+	assertion, err := s.assertion()
+	if err != nil {
+		return token{}, err
+	}
+	return requestToken(ctx, s.httpClient, s.tokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	})
+}
+
+// assertion returns the signed JWT that is exchanged for a token.
+func (s serviceAccountToken) assertion() (string, error) {
+	now := s.now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", fmt.Errorf("encoding JWT header: %w", err)
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iss":   s.email,
+		"scope": scope,
+		"aud":   s.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("encoding JWT claims: %w", err)
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(nil, s.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", fmt.Errorf("signing JWT: %w", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// refreshToken fetches tokens for a user with a refresh token.
+type refreshToken struct {
+	form       url.Values
+	tokenURI   string
+	httpClient *http.Client
+}
+
+func (r refreshToken) fetch(ctx context.Context) (token, error) {
+	return requestToken(ctx, r.httpClient, r.tokenURI, r.form)
+}
+
+// metadataToken fetches tokens for the service account of the instance from
+// the metadata server.
+type metadataToken struct {
+	httpClient *http.Client
+}
+
+func (m metadataToken) fetch(ctx context.Context) (token, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataTokenURL, http.NoBody)
+	if err != nil {
+		return token{}, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	return doTokenRequest(m.httpClient, req)
+}
+
+// requestToken posts form to the token endpoint at tokenURI.
+func requestToken(
+	ctx context.Context,
+	httpClient *http.Client,
+	tokenURI string,
+	form url.Values,
+) (token, error) {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		tokenURI,
+		strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return token{}, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return doTokenRequest(httpClient, req)
+}
+
+func doTokenRequest(httpClient *http.Client, req *http.Request) (token, error) {
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return token{}, fmt.Errorf("requesting token from %s: %w", req.URL.Host, err)
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(io.LimitReader(res.Body, 1<<16))
+	if err != nil {
+		return token{}, fmt.Errorf("reading token: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return token{}, internal.NewHTTPError(res.StatusCode, b)
+	}
+
+	var doc struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return token{}, fmt.Errorf("decoding token: %w", err)
+	}
+	if doc.AccessToken == "" {
+		return token{}, errors.New("the token response has no access token")
+	}
+	return token{
+		value:   doc.AccessToken,
+		expires: time.Now().Add(time.Duration(doc.ExpiresIn) * time.Second),
+	}, nil
+}
@@ -0,0 +1,223 @@
+// Package gcstest provides an in-memory Google Cloud Storage JSON API server
+// for tests.
+package gcstest
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Object is an object stored by the Server.
+type Object struct {
+	Body       []byte
+	Metadata   map[string]string
+	Generation int64
+}
+
+// Server serves the objects of its buckets. Like fake-gcs-server, it does
+// not check authorization.
+type Server struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	objects    map[string]Object
+	generation int64
+}
+
+// NewServer starts a Server that is closed when the test finishes.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{objects: map[string]Object{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Put stores an object at bucket/name with a new generation.
+func (s *Server) Put(bucket, name string, body []byte, metadata map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(bucket+"/"+name, body, metadata)
+}
+
+// Object returns the object at bucket/name.
+func (s *Server) Object(bucket, name string) (Object, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[bucket+"/"+name]
+	return obj, ok
+}
+
+func (s *Server) put(key string, body []byte, metadata map[string]string) Object {
+	s.generation++
+	obj := Object{Body: body, Metadata: metadata, Generation: s.generation}
+	s.objects[key] = obj
+	return obj
+}
+
+// serveHTTP serves the object requests, whose paths are, after the version,
+// b/<bucket>/o/<name> optionally followed by rewriteTo/b/<bucket>/o/<name>.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(r.URL.EscapedPath(), "/")
+	for i, segment := range segments {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		segments[i] = unescaped
+	}
+
+	switch {
+	case len(segments) == 7 && segments[1] == "upload" && r.Method == http.MethodPost:
+		s.upload(w, r, segments[5])
+	case len(segments) == 7 && r.Method == http.MethodGet:
+		s.attrs(w, segments[4]+"/"+segments[6])
+	case len(segments) == 7 && r.Method == http.MethodDelete:
+		s.delete(w, segments[4]+"/"+segments[6])
+	case len(segments) == 12 && segments[7] == "rewriteTo" && r.Method == http.MethodPost:
+		s.rewrite(w, r, segments[4]+"/"+segments[6], segments[9]+"/"+segments[11])
+	default:
+		http.Error(w, "unsupported request", http.StatusBadRequest)
+	}
+}
+
+func (s *Server) attrs(w http.ResponseWriter, key string) {
+	s.mu.Lock()
+	obj, ok := s.objects[key]
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, `{"error":{"code":404,"message":"Not Found"}}`, http.StatusNotFound)
+		return
+	}
+	writeResource(w, key, obj)
+}
+
+func (s *Server) upload(w http.ResponseWriter, r *http.Request, bucket string) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	mr := multipart.NewReader(r.Body, params["boundary"])
+
+	var resource struct {
+		Name     string            `json:"name"`
+		MD5Hash  string            `json:"md5Hash"`
+		Metadata map[string]string `json:"metadata"`
+	}
+	part, err := mr.NextPart()
+	if err == nil {
+		err = json.NewDecoder(part).Decode(&resource)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	part, err = mr.NextPart()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(part)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sum := md5.Sum(body)
+	if resource.MD5Hash != "" && resource.MD5Hash != base64.StdEncoding.EncodeToString(sum[:]) {
+		http.Error(w, `{"error":{"code":400,"message":"Provided MD5 hash does not match"}}`, http.StatusBadRequest)
+		return
+	}
+
+	key := bucket + "/" + resource.Name
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.preconditionMet(r, key) {
+		http.Error(w, `{"error":{"code":412,"message":"Precondition Failed"}}`, http.StatusPreconditionFailed)
+		return
+	}
+	writeResource(w, key, s.put(key, body, resource.Metadata))
+}
+
+func (s *Server) rewrite(w http.ResponseWriter, r *http.Request, src, dst string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj, ok := s.objects[src]
+	if !ok {
+		http.Error(w, `{"error":{"code":404,"message":"Not Found"}}`, http.StatusNotFound)
+		return
+	}
+	if !s.preconditionMet(r, dst) {
+		http.Error(w, `{"error":{"code":412,"message":"Precondition Failed"}}`, http.StatusPreconditionFailed)
+		return
+	}
+	copied := s.put(dst, obj.Body, obj.Metadata)
+
+	w.Header().Set("Content-Type", "application/json")
+	//nolint:errcheck // test server.
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"kind":     "storage#rewriteResponse",
+		"done":     true,
+		"resource": resource(dst, copied),
+	})
+}
+
+func (s *Server) delete(w http.ResponseWriter, key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.objects[key]; !ok {
+		http.Error(w, `{"error":{"code":404,"message":"Not Found"}}`, http.StatusNotFound)
+		return
+	}
+	delete(s.objects, key)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// preconditionMet returns whether the object at key has the generation in
+// the ifGenerationMatch parameter of r, if there is one. s.mu must be held.
+func (s *Server) preconditionMet(r *http.Request, key string) bool {
+	value := r.URL.Query().Get("ifGenerationMatch")
+	if value == "" {
+		return true
+	}
+	generation, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false
+	}
+	return s.objects[key].Generation == generation
+}
+
+func writeResource(w http.ResponseWriter, key string, obj Object) {
+	w.Header().Set("Content-Type", "application/json")
+	//nolint:errcheck // test server.
+	_ = json.NewEncoder(w).Encode(resource(key, obj))
+}
+
+func resource(key string, obj Object) map[string]any {
+	bucket, name, _ := strings.Cut(key, "/")
+	sum := md5.Sum(obj.Body)
+	return map[string]any{
+		"kind":       "storage#object",
+		"bucket":     bucket,
+		"name":       name,
+		"generation": strconv.FormatInt(obj.Generation, 10),
+		"size":       strconv.Itoa(len(obj.Body)),
+		"md5Hash":    base64.StdEncoding.EncodeToString(sum[:]),
+		"metadata":   obj.Metadata,
+	}
+}
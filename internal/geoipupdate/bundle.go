@@ -0,0 +1,126 @@
+package geoipupdate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+
+	"github.com/maxmind/geoipupdate/v8/bundle"
+	"github.com/maxmind/geoipupdate/v8/internal"
+	"github.com/maxmind/geoipupdate/v8/internal/geoipupdate/database"
+)
+
+// Export writes a bundle of the current databases of the configured
+// editions to out while holding the lock file, so that no update replaces
+// them in the meantime.
+func Export(config *Config, out string) (_ bundle.Manifest, err error) {
+	if err := requireLocalDirectory(config, "exporting"); err != nil {
+		return bundle.Manifest{}, err
+	}
+
+	fileLock, err := internal.NewFileLock(config.LockFile, config.Verbose)
+	if err != nil {
+		return bundle.Manifest{}, fmt.Errorf("initializing file lock: %w", err)
+	}
+	if err := fileLock.Acquire(); err != nil {
+		return bundle.Manifest{}, fmt.Errorf("acquiring file lock: %w", err)
+	}
+	defer func() {
+		if err := fileLock.Release(); err != nil {
+			log.Printf("releasing file lock: %s", err)
+		}
+	}()
+
+	writer, err := database.NewLocalFileWriter(
+		config.DatabaseDirectory,
+		config.PreserveFileTimes,
+		config.Verbose,
+	)
+	if err != nil {
+		return bundle.Manifest{}, err
+	}
+
+	paths := map[string]string{}
+	for _, editionID := range config.EditionIDs {
+		if slices.Contains(config.AtomicGroup, editionID) {
+			paths[editionID] = database.GroupPath(config.DatabaseDirectory, editionID)
+		} else {
+			paths[editionID] = writer.Path(editionID)
+		}
+	}
+
+	// The bundle is written next to out and only renamed into place once
+	// it is complete.
+	tmp := out + ".temporary"
+	//nolint:gosec // the path is chosen by the user.
+	f, err := os.Create(tmp)
+	if err != nil {
+		return bundle.Manifest{}, fmt.Errorf("creating bundle: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			//nolint:errcheck // the error is already returned.
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	manifest, err := bundle.Write(f, paths)
+	if err != nil {
+		//nolint:errcheck // the error is already returned.
+		_ = f.Close()
+		return bundle.Manifest{}, err
+	}
+	if err := f.Sync(); err != nil {
+		//nolint:errcheck // the error is already returned.
+		_ = f.Close()
+		return bundle.Manifest{}, fmt.Errorf("syncing bundle: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return bundle.Manifest{}, fmt.Errorf("closing bundle: %w", err)
+	}
+	if err := os.Rename(tmp, out); err != nil {
+		return bundle.Manifest{}, fmt.Errorf("renaming bundle: %w", err)
+	}
+	return manifest, nil
+}
+
+// Import installs the databases of the configured editions from the bundle
+// at path. They are written exactly as downloaded ones would be, so the lock
+// file, hash checks, file times, downgrade rules, KeepVersions and
+// AtomicGroup all apply.
+func (u *Updater) Import(ctx context.Context, path string) error {
+	b, err := bundle.Open(path)
+	if err != nil {
+		return err
+	}
+
+	var editionIDs []string
+	for _, editionID := range u.config.EditionIDs {
+		if slices.ContainsFunc(b.Manifest().Databases, func(db bundle.Database) bool {
+			return db.EditionID == editionID
+		}) {
+			editionIDs = append(editionIDs, editionID)
+		} else if u.config.Verbose {
+			log.Printf("The bundle has no database of %s", editionID)
+		}
+	}
+	if len(editionIDs) == 0 {
+		return errors.New("the bundle has none of the configured editions")
+	}
+
+	// Reading from the bundle fails the same way on every attempt.
+	config := *u.config
+	config.RetryFor = 0
+
+	importer := &Updater{
+		config:       &config,
+		output:       u.output,
+		updateClient: b,
+		httpClient:   u.httpClient,
+		writer:       u.writer,
+	}
+	return importer.run(ctx, editionIDs)
+}
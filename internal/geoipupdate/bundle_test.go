@@ -0,0 +1,67 @@
+package geoipupdate
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/internal/geoipupdate/database"
+	"github.com/maxmind/geoipupdate/v8/internal/mmdbtest"
+)
+
+func TestExportImport(t *testing.T) {
+	online := t.TempDir()
+	cityMD5 := mmdbtest.Write(t, filepath.Join(online, "GeoIP2-City.mmdb"), "GeoIP2-City", 1789430400)
+	mmdbtest.Write(t, filepath.Join(online, "GeoIP2-ISP.mmdb"), "GeoIP2-ISP", 1789430400)
+
+	out := filepath.Join(t.TempDir(), "bundle.tar")
+	manifest, err := Export(&Config{
+		DatabaseDirectory: online,
+		EditionIDs:        []string{"GeoIP2-City", "GeoIP2-ISP"},
+		LockFile:          filepath.Join(online, ".geoipupdate.lock"),
+	}, out)
+	require.NoError(t, err)
+	require.Len(t, manifest.Databases, 2)
+	require.Equal(t, cityMD5, manifest.Databases[0].MD5)
+
+	// The isolated host only has the City edition configured, and a newer
+	// database of it than the one in the bundle at first.
+	isolated := t.TempDir()
+	mmdbtest.Write(t, filepath.Join(isolated, "GeoIP2-City.mmdb"), "GeoIP2-City", 1789430400+24*60*60)
+
+	var output bytes.Buffer
+	config := &Config{
+		AccountID:         1,
+		LicenseKey:        "000000000001",
+		DatabaseDirectory: isolated,
+		EditionIDs:        []string{"GeoIP2-City", "GeoIP2-Country"},
+		LockFile:          filepath.Join(isolated, ".geoipupdate.lock"),
+		Output:            true,
+		Parallelism:       1,
+	}
+	u, err := NewUpdater(config)
+	require.NoError(t, err)
+	u.output = log.New(&output, "", 0)
+
+	require.NoError(t, u.Import(t.Context(), out))
+	var results []database.ReadResult
+	require.NoError(t, json.Unmarshal(output.Bytes(), &results))
+	require.Len(t, results, 1)
+	require.Equal(t, database.StatusDowngradeRefused, results[0].Status)
+
+	require.NoError(t, os.Remove(filepath.Join(isolated, "GeoIP2-City.mmdb")))
+	output.Reset()
+	require.NoError(t, u.Import(t.Context(), out))
+	require.NoError(t, json.Unmarshal(output.Bytes(), &results))
+	require.Equal(t, database.StatusUpdated, results[0].Status)
+	require.Equal(t, cityMD5, results[0].NewHash)
+	require.NoFileExists(t, filepath.Join(isolated, "GeoIP2-ISP.mmdb"))
+
+	config.EditionIDs = []string{"GeoIP2-Country"}
+	require.EqualError(t, u.Import(t.Context(), out), "the bundle has none of the configured editions")
+}
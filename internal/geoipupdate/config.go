@@ -8,10 +8,14 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/maxmind/geoipupdate/v8/internal/cron"
+	"github.com/maxmind/geoipupdate/v8/internal/geoipupdate/database"
+	"github.com/maxmind/geoipupdate/v8/internal/minisign"
 	"github.com/maxmind/geoipupdate/v8/internal/vars"
 )
 
@@ -25,14 +29,39 @@ const (
 type Config struct {
 	// AccountID is the account ID.
 	AccountID int
+	// AllowDowngrade sets whether a database may be replaced by one that was
+	// built before it.
+	AllowDowngrade bool
+	// AtomicGroup are editions that are published together in a new
+	// release directory that the current symlink in DatabaseDirectory points
+	// to.
+	AtomicGroup []string
 	// confFile is the path to any configuration file used when
 	// potentially populating Config fields.
 	configFile string
+	// ContinueOnError sets whether the remaining editions are still updated
+	// after one fails.
+	ContinueOnError bool
 	// DatabaseDirectory is where database files are going to be
 	// stored.
 	DatabaseDirectory string
+	// Destinations are further directories, or URLs, that every database is
+	// also written to.
+	Destinations []string
+	// EditionDates pins editions to the database built on a date, as
+	// YYYY-MM-DD. They are given in EditionIDs as EditionID@YYYY-MM-DD.
+	EditionDates map[string]string
 	// EditionIDs are the database editions to be updated.
 	EditionIDs []string
+	// EditionSchedules maps edition IDs to cron expressions that override
+	// Schedule for those editions.
+	EditionSchedules map[string]string
+	// Frequency is the interval between updates when running as a daemon.
+	// Zero means that no interval has been configured.
+	Frequency time.Duration
+	// KeepVersions is the number of replaced databases kept for each
+	// edition, so that they can be rolled back to.
+	KeepVersions int
 	// LicenseKey is the license attached to the account.
 	LicenseKey string
 	// LockFile is the path of a lock file that ensures that only one
@@ -55,6 +84,37 @@ type Config struct {
 	// RetryFor is the retry timeout for HTTP requests. It defaults
 	// to 5 minutes.
 	RetryFor time.Duration
+	// Schedule is a cron expression saying when to check for updates when
+	// running as a daemon. It takes precedence over Frequency.
+	Schedule string
+	// ServeAccountID is the account ID that clients of the serve command
+	// authenticate with.
+	ServeAccountID int
+	// ServeAddress is the address the serve command listens on. It defaults
+	// to :8080.
+	ServeAddress string
+	// ServeLicenseKey is the license key that clients of the serve command
+	// authenticate with. It is unrelated to LicenseKey.
+	ServeLicenseKey string
+	// ServeProxy sets whether the serve command forwards requests to the
+	// update server and caches the databases rather than serving those in
+	// DatabaseDirectory.
+	ServeProxy bool
+	// ServeTLSCertFile and ServeTLSKeyFile are the certificate and key that
+	// the serve command serves HTTPS with. It serves plain HTTP if they are
+	// empty.
+	ServeTLSCertFile string
+	ServeTLSKeyFile  string
+	// SignatureDirectory is where the detached signatures of the databases
+	// are read from. If it is empty, they are downloaded next to the
+	// databases.
+	SignatureDirectory string
+	// SignaturePublicKey is the minisign public key that databases must be
+	// signed with. Signatures are not checked if it is empty.
+	SignaturePublicKey string
+	// SignatureSuffix is the suffix that signatures are downloaded from the
+	// update server with, for servers that serve them.
+	SignatureSuffix string
 	// URL points to maxmind servers.
 	URL string
 	// Verbose turns on debug statements.
@@ -82,23 +142,82 @@ func WithParallelism(i int) Option {
 	}
 }
 
+// WithFrequency returns an Option that sets the Frequency value of a
+// config.
+func WithFrequency(d time.Duration) Option {
+	return func(c *Config) error {
+		if d < 0 {
+			return fmt.Errorf("frequency can't be negative, got '%s'", d)
+		}
+		if d > 0 {
+			c.Frequency = d
+		}
+		return nil
+	}
+}
+
 // WithDatabaseDirectory returns an Option that sets the DatabaseDirectory
 // value of a config.
 func WithDatabaseDirectory(dir string) Option {
 	return func(c *Config) error {
 		if dir != "" {
-			c.DatabaseDirectory = filepath.Clean(dir)
+			c.DatabaseDirectory = cleanDatabaseDirectory(dir)
 		}
 		return nil
 	}
 }
 
+// WithServeAddress returns an Option that sets the ServeAddress value of a
+// config.
+func WithServeAddress(address string) Option {
+	return func(c *Config) error {
+		if address != "" {
+			c.ServeAddress = address
+		}
+		return nil
+	}
+}
+
+// WithServeTLS returns an Option that sets the ServeTLSCertFile and
+// ServeTLSKeyFile values of a config.
+func WithServeTLS(certFile, keyFile string) Option {
+	return func(c *Config) error {
+		if certFile != "" {
+			c.ServeTLSCertFile = filepath.Clean(certFile)
+		}
+		if keyFile != "" {
+			c.ServeTLSKeyFile = filepath.Clean(keyFile)
+		}
+		return nil
+	}
+}
+
+// WithServeProxy makes the serve command a caching proxy of the update
+// server.
+func WithServeProxy(c *Config) error {
+	c.ServeProxy = true
+	return nil
+}
+
 // WithVerbose enable verbose output for the config.
 func WithVerbose(c *Config) error {
 	c.Verbose = true
 	return nil
 }
 
+// WithContinueOnError enables updating the remaining editions after one
+// fails.
+func WithContinueOnError(c *Config) error {
+	c.ContinueOnError = true
+	return nil
+}
+
+// WithAllowDowngrade allows replacing databases with older builds.
+func WithAllowDowngrade(c *Config) error {
+	c.AllowDowngrade = true
+	return nil
+}
+
 // WithOutput enables JSON output for the config.
 func WithOutput(c *Config) error {
 	c.Output = true
@@ -120,9 +239,17 @@ func WithConfigFile(file string) Option {
 // config file pointed to by an option set with WithConfigFile, then by various
 // environment variables, and then finally by flag overrides provided by
 // flagOptions. Values from the later override the former.
+//
+// The returned error is a [ConfigError].
 func NewConfig(
 	flagOptions ...Option,
-) (*Config, error) {
+) (_ *Config, err error) {
+	defer func() {
+		if err != nil {
+			err = ConfigError{Err: err}
+		}
+	}()
+
 	// config defaults
 	config := &Config{
 		URL:               defaultURL,
@@ -133,7 +260,7 @@ func NewConfig(
 
 	// Potentially populate config.configFilePath. We will rerun this function
 	// again later to ensure the flag values override env variables.
-	err := setConfigFromFlags(config, flagOptions...)
+	err = setConfigFromFlags(config, flagOptions...)
 	if err != nil {
 		return nil, err
 	}
@@ -169,7 +296,13 @@ func NewConfig(
 	}
 
 	if config.LockFile == "" {
-		config.LockFile = filepath.Join(config.DatabaseDirectory, ".geoipupdate.lock")
+		// There is no local directory to put the lock file in when the
+		// databases are in object storage.
+		dir := config.DatabaseDirectory
+		if database.IsRemoteURL(dir) {
+			dir = os.TempDir()
+		}
+		config.LockFile = filepath.Join(dir, ".geoipupdate.lock")
 	}
 
 	// Validate config values now that all config sources have been considered and
@@ -216,7 +349,9 @@ func setConfigFromFile(config *Config, path string) error {
 		key := fields[0]
 		value := strings.Join(fields[1:], " ")
 
-		if _, ok := keysSeen[key]; ok {
+		// EditionSchedule may be given once per edition, which is checked
+		// below.
+		if _, ok := keysSeen[key]; ok && key != "EditionSchedule" {
 			return fmt.Errorf("`%s' is in the config multiple times", key)
 		}
 		keysSeen[key] = struct{}{}
@@ -230,12 +365,47 @@ func setConfigFromFile(config *Config, path string) error {
 			config.AccountID = accountID
 			keysSeen["AccountID"] = struct{}{}
 			keysSeen["UserId"] = struct{}{}
+		case "AllowDowngrade":
+			if value != "0" && value != "1" {
+				return errors.New("`AllowDowngrade' must be 0 or 1")
+			}
+			config.AllowDowngrade = value == "1"
+		case "AtomicGroup":
+			config.AtomicGroup = strings.Fields(value)
+		case "ContinueOnError":
+			if value != "0" && value != "1" {
+				return errors.New("`ContinueOnError' must be 0 or 1")
+			}
+			config.ContinueOnError = value == "1"
 		case "DatabaseDirectory":
-			config.DatabaseDirectory = filepath.Clean(value)
+			config.DatabaseDirectory = cleanDatabaseDirectory(value)
+		case "Destinations":
+			config.Destinations = parseDestinations(value)
 		case "EditionIDs", "ProductIds":
-			config.EditionIDs = strings.Fields(value)
+			config.EditionIDs, config.EditionDates, err = parseEditionIDs(value)
+			if err != nil {
+				return fmt.Errorf("invalid `%s': %w", key, err)
+			}
 			keysSeen["EditionIDs"] = struct{}{}
 			keysSeen["ProductIds"] = struct{}{}
+		case "EditionSchedule":
+			if len(fields) < 3 {
+				return fmt.Errorf("invalid format on line %d", lineNumber)
+			}
+			editionID := fields[1]
+			if _, ok := config.EditionSchedules[editionID]; ok {
+				return fmt.Errorf("`EditionSchedule' is in the config multiple times for %s", editionID)
+			}
+			if config.EditionSchedules == nil {
+				config.EditionSchedules = map[string]string{}
+			}
+			config.EditionSchedules[editionID] = strings.Join(fields[2:], " ")
+		case "Frequency":
+			frequency, err := parseFrequency(value)
+			if err != nil {
+				return err
+			}
+			config.Frequency = frequency
 		case "Host":
 			u, err := url.Parse(value)
 			if err != nil {
@@ -245,6 +415,15 @@ func setConfigFromFile(config *Config, path string) error {
 				u.Scheme = schemeHTTPS
 			}
 			config.URL = u.String()
+		case "KeepVersions":
+			keepVersions, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("'%s' is not a valid KeepVersions value: %w", value, err)
+			}
+			if keepVersions < 0 {
+				return fmt.Errorf("KeepVersions can't be negative, got '%d'", keepVersions)
+			}
+			config.KeepVersions = keepVersions
 		case "LicenseKey":
 			config.LicenseKey = value
 		case "LockFile":
@@ -275,6 +454,33 @@ func setConfigFromFile(config *Config, path string) error {
 				return fmt.Errorf("parallelism should be greater than 0, got '%d'", parallelism)
 			}
 			config.Parallelism = parallelism
+		case "Schedule":
+			config.Schedule = value
+		case "ServeAccountID":
+			accountID, err := strconv.Atoi(value)
+			if err != nil {
+				return errors.New("invalid `ServeAccountID' format")
+			}
+			config.ServeAccountID = accountID
+		case "ServeAddress":
+			config.ServeAddress = value
+		case "ServeLicenseKey":
+			config.ServeLicenseKey = value
+		case "ServeProxy":
+			if value != "0" && value != "1" {
+				return errors.New("`ServeProxy' must be 0 or 1")
+			}
+			config.ServeProxy = value == "1"
+		case "ServeTLSCertFile":
+			config.ServeTLSCertFile = filepath.Clean(value)
+		case "ServeTLSKeyFile":
+			config.ServeTLSKeyFile = filepath.Clean(value)
+		case "SignatureDirectory":
+			config.SignatureDirectory = filepath.Clean(value)
+		case "SignaturePublicKey":
+			config.SignaturePublicKey = value
+		case "SignatureSuffix":
+			config.SignatureSuffix = value
 		default:
 			return fmt.Errorf("unknown option on line %d", lineNumber)
 		}
@@ -311,12 +517,54 @@ func setConfigFromEnv(config *Config) error {
 		}
 	}
 
+	if value, ok := os.LookupEnv("GEOIPUPDATE_ALLOW_DOWNGRADE"); ok {
+		if value != "0" && value != "1" {
+			return errors.New("`GEOIPUPDATE_ALLOW_DOWNGRADE' must be 0 or 1")
+		}
+		config.AllowDowngrade = value == "1"
+	}
+
+	if value, ok := os.LookupEnv("GEOIPUPDATE_ATOMIC_GROUP"); ok {
+		config.AtomicGroup = strings.Fields(value)
+	}
+
+	if value, ok := os.LookupEnv("GEOIPUPDATE_CONTINUE_ON_ERROR"); ok {
+		if value != "0" && value != "1" {
+			return errors.New("`GEOIPUPDATE_CONTINUE_ON_ERROR' must be 0 or 1")
+		}
+		config.ContinueOnError = value == "1"
+	}
+
 	if value, ok := os.LookupEnv("GEOIPUPDATE_DB_DIR"); ok {
 		config.DatabaseDirectory = value
 	}
 
+	if value, ok := os.LookupEnv("GEOIPUPDATE_DESTINATIONS"); ok {
+		config.Destinations = parseDestinations(value)
+	}
+
 	if value, ok := os.LookupEnv("GEOIPUPDATE_EDITION_IDS"); ok {
-		config.EditionIDs = strings.Fields(value)
+		var err error
+		config.EditionIDs, config.EditionDates, err = parseEditionIDs(value)
+		if err != nil {
+			return fmt.Errorf("invalid `GEOIPUPDATE_EDITION_IDS': %w", err)
+		}
+	}
+
+	if value, ok := os.LookupEnv("GEOIPUPDATE_EDITION_SCHEDULES"); ok {
+		schedules, err := parseEditionSchedules(value)
+		if err != nil {
+			return err
+		}
+		config.EditionSchedules = schedules
+	}
+
+	if value, ok := os.LookupEnv("GEOIPUPDATE_FREQUENCY"); ok {
+		frequency, err := parseFrequency(value)
+		if err != nil {
+			return err
+		}
+		config.Frequency = frequency
 	}
 
 	if value, ok := os.LookupEnv("GEOIPUPDATE_HOST"); ok {
@@ -345,6 +593,17 @@ func setConfigFromEnv(config *Config) error {
 		config.LicenseKey = strings.TrimSpace(string(licenseKey))
 	}
 
+	if value, ok := os.LookupEnv("GEOIPUPDATE_KEEP_VERSIONS"); ok {
+		keepVersions, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid KeepVersions value: %w", value, err)
+		}
+		if keepVersions < 0 {
+			return fmt.Errorf("KeepVersions can't be negative, got '%d'", keepVersions)
+		}
+		config.KeepVersions = keepVersions
+	}
+
 	if value, ok := os.LookupEnv("GEOIPUPDATE_LOCK_FILE"); ok {
 		config.LockFile = value
 	}
@@ -383,6 +642,62 @@ func setConfigFromEnv(config *Config) error {
 		config.RetryFor = dur
 	}
 
+	if value, ok := os.LookupEnv("GEOIPUPDATE_SCHEDULE"); ok {
+		config.Schedule = value
+	}
+
+	if value, ok := os.LookupEnv("GEOIPUPDATE_SERVE_ACCOUNT_ID"); ok {
+		var err error
+		config.ServeAccountID, err = strconv.Atoi(value)
+		if err != nil {
+			return errors.New("invalid `GEOIPUPDATE_SERVE_ACCOUNT_ID' format")
+		}
+	}
+
+	if value, ok := os.LookupEnv("GEOIPUPDATE_SERVE_ADDRESS"); ok {
+		config.ServeAddress = value
+	}
+
+	if value, ok := os.LookupEnv("GEOIPUPDATE_SERVE_LICENSE_KEY"); ok {
+		config.ServeLicenseKey = value
+	}
+
+	if value := os.Getenv("GEOIPUPDATE_SERVE_LICENSE_KEY_FILE"); value != "" {
+		licenseKey, err := os.ReadFile(filepath.Clean(value))
+		if err != nil {
+			return fmt.Errorf("failed to open GEOIPUPDATE_SERVE_LICENSE_KEY_FILE: %w", err)
+		}
+
+		config.ServeLicenseKey = strings.TrimSpace(string(licenseKey))
+	}
+
+	if value, ok := os.LookupEnv("GEOIPUPDATE_SERVE_PROXY"); ok {
+		if value != "0" && value != "1" {
+			return errors.New("`GEOIPUPDATE_SERVE_PROXY' must be 0 or 1")
+		}
+		config.ServeProxy = value == "1"
+	}
+
+	if value, ok := os.LookupEnv("GEOIPUPDATE_SERVE_TLS_CERT_FILE"); ok {
+		config.ServeTLSCertFile = value
+	}
+
+	if value, ok := os.LookupEnv("GEOIPUPDATE_SERVE_TLS_KEY_FILE"); ok {
+		config.ServeTLSKeyFile = value
+	}
+
+	if value, ok := os.LookupEnv("GEOIPUPDATE_SIGNATURE_DIRECTORY"); ok {
+		config.SignatureDirectory = value
+	}
+
+	if value, ok := os.LookupEnv("GEOIPUPDATE_SIGNATURE_PUBLIC_KEY"); ok {
+		config.SignaturePublicKey = value
+	}
+
+	if value, ok := os.LookupEnv("GEOIPUPDATE_SIGNATURE_SUFFIX"); ok {
+		config.SignatureSuffix = value
+	}
+
 	if value, ok := os.LookupEnv("GEOIPUPDATE_VERBOSE"); ok {
 		if value != "0" && value != "1" {
 			return errors.New("`GEOIPUPDATE_VERBOSE' must be 0 or 1")
@@ -403,6 +718,29 @@ func setConfigFromFlags(config *Config, flagOptions ...Option) error {
 	return nil
 }
 
+// parseEditionIDs parses space-separated edition IDs, each of which may be
+// pinned to a date as EditionID@YYYY-MM-DD. The dates are returned by
+// edition ID, and are nil if no edition is pinned.
+func parseEditionIDs(value string) ([]string, map[string]string, error) {
+	var editionIDs []string
+	var dates map[string]string
+	for _, field := range strings.Fields(value) {
+		editionID, date, pinned := strings.Cut(field, "@")
+		editionIDs = append(editionIDs, editionID)
+		if !pinned {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return nil, nil, fmt.Errorf("%s is not pinned to a date of the form YYYY-MM-DD", field)
+		}
+		if dates == nil {
+			dates = map[string]string{}
+		}
+		dates[editionID] = date
+	}
+	return editionIDs, dates, nil
+}
+
 func validateConfig(config *Config) error {
 	// We used to recommend using 999999 / 000000000000 for free downloads
 	// and many people still use this combination. With a real account id
@@ -417,17 +755,173 @@ func validateConfig(config *Config) error {
 		return errors.New("the `EditionIDs` option is required")
 	}
 
-	if config.AccountID == 0 {
-		return errors.New("the `AccountID` option is required")
+	// Only the update server, and mirrors of it, authenticate. Other sources
+	// are selected by the scheme of Host.
+	if isUpdateServer(config.URL) {
+		if config.AccountID == 0 {
+			return errors.New("the `AccountID` option is required")
+		}
+
+		if config.LicenseKey == "" {
+			return errors.New("the `LicenseKey` option is required")
+		}
+	}
+
+	if config.Schedule != "" {
+		if err := validateSchedule(config.Schedule); err != nil {
+			return fmt.Errorf("invalid `Schedule': %w", err)
+		}
+	}
+
+	if config.SignaturePublicKey != "" {
+		if _, err := minisign.ParsePublicKey(config.SignaturePublicKey); err != nil {
+			return fmt.Errorf("invalid `SignaturePublicKey': %w", err)
+		}
+		if config.SignatureDirectory == "" && config.SignatureSuffix == "" {
+			return errors.New("`SignaturePublicKey' requires `SignatureDirectory' or `SignatureSuffix'")
+		}
+	} else if config.SignatureDirectory != "" {
+		return errors.New("`SignatureDirectory' is set but `SignaturePublicKey' is not")
+	} else if config.SignatureSuffix != "" {
+		return errors.New("`SignatureSuffix' is set but `SignaturePublicKey' is not")
+	}
+
+	if err := database.CheckDirectory(config.DatabaseDirectory); err != nil {
+		return fmt.Errorf("invalid `DatabaseDirectory': %w", err)
+	}
+	if database.IsRemoteURL(config.DatabaseDirectory) {
+		if len(config.AtomicGroup) > 0 {
+			return errors.New("`AtomicGroup' requires a local `DatabaseDirectory'")
+		}
+		if config.KeepVersions > 0 {
+			return errors.New("`KeepVersions' requires a local `DatabaseDirectory'")
+		}
+	}
+
+	if len(config.Destinations) > 0 && len(config.AtomicGroup) > 0 {
+		return errors.New("`AtomicGroup' cannot be used with `Destinations'")
+	}
+	for i, dir := range config.Destinations {
+		if dir == config.DatabaseDirectory {
+			return fmt.Errorf("`Destinations' contains the `DatabaseDirectory', %s", dir)
+		}
+		if err := database.CheckDirectory(dir); err != nil {
+			return fmt.Errorf("invalid `Destinations': %w", err)
+		}
+		if slices.Contains(config.Destinations[:i], dir) {
+			return fmt.Errorf("`Destinations' contains %s more than once", dir)
+		}
+		if database.IsRemoteURL(dir) && config.KeepVersions > 0 {
+			return errors.New("`KeepVersions' requires local `Destinations'")
+		}
+	}
+
+	for _, editionID := range config.AtomicGroup {
+		if !slices.Contains(config.EditionIDs, editionID) {
+			return fmt.Errorf("`AtomicGroup' contains %s, which is not in `EditionIDs'", editionID)
+		}
+	}
+
+	for editionID, schedule := range config.EditionSchedules {
+		if !slices.Contains(config.EditionIDs, editionID) {
+			return fmt.Errorf("`EditionSchedule' is set for %s, which is not in `EditionIDs'", editionID)
+		}
+		if err := validateSchedule(schedule); err != nil {
+			return fmt.Errorf("invalid `EditionSchedule' for %s: %w", editionID, err)
+		}
+	}
+
+	return nil
+}
+
+// parseDestinations parses the space-separated directories and URLs of
+// the `Destinations' option.
+func parseDestinations(value string) []string {
+	destinations := strings.Fields(value)
+	for i, dir := range destinations {
+		destinations[i] = cleanDatabaseDirectory(dir)
+	}
+	return destinations
+}
+
+// cleanDatabaseDirectory cleans dir if it is a local directory. URLs,
+// including those of unsupported schemes that validateConfig rejects, are
+// kept as they are.
+func cleanDatabaseDirectory(dir string) string {
+	if strings.Contains(dir, "://") {
+		return dir
 	}
+	return filepath.Clean(dir)
+}
 
-	if config.LicenseKey == "" {
-		return errors.New("the `LicenseKey` option is required")
+// requireLocalDirectory returns a ConfigError if the database directory is
+// in object storage, which doing, e.g., "serving", does not support.
+func requireLocalDirectory(config *Config, doing string) error {
+	if database.IsRemoteURL(config.DatabaseDirectory) {
+		return ConfigError{
+			Err: fmt.Errorf("%s requires a local `DatabaseDirectory'", doing),
+		}
 	}
+	return nil
+}
 
+// isUpdateServer returns whether rawURL is that of an update server rather
+// than another source.
+func isUpdateServer(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err != nil || u.Scheme == "" || u.Scheme == "http" || u.Scheme == schemeHTTPS
+}
+
+// validateSchedule checks that a cron expression parses and will match at
+// some point.
+func validateSchedule(schedule string) error {
+	s, err := cron.Parse(schedule)
+	if err != nil {
+		return err
+	}
+	if s.Next(time.Now()).IsZero() {
+		return fmt.Errorf("'%s' never matches", schedule)
+	}
 	return nil
 }
 
+// parseEditionSchedules parses a semicolon-separated list of
+// EditionID=expression pairs.
+func parseEditionSchedules(value string) (map[string]string, error) {
+	schedules := map[string]string{}
+	for item := range strings.SplitSeq(value, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		editionID, schedule, ok := strings.Cut(item, "=")
+		editionID = strings.TrimSpace(editionID)
+		if !ok || editionID == "" {
+			return nil, fmt.Errorf("'%s' is not a valid edition schedule", item)
+		}
+		schedules[editionID] = strings.TrimSpace(schedule)
+	}
+	return schedules, nil
+}
+
+// parseFrequency parses an update frequency. A bare integer is a number of
+// hours, which is how the Docker image has always interpreted
+// GEOIPUPDATE_FREQUENCY. Anything else must be a duration such as "72h".
+func parseFrequency(value string) (time.Duration, error) {
+	if hours, err := strconv.Atoi(value); err == nil {
+		if hours < 0 {
+			return 0, fmt.Errorf("'%s' is not a valid frequency", value)
+		}
+		return time.Duration(hours) * time.Hour, nil
+	}
+
+	dur, err := time.ParseDuration(value)
+	if err != nil || dur < 0 {
+		return 0, fmt.Errorf("'%s' is not a valid frequency", value)
+	}
+	return dur, nil
+}
+
 var schemeRE = regexp.MustCompile(`(?i)\A([a-z][a-z0-9+\-.]*)://`)
 
 func parseProxy(
@@ -283,6 +283,78 @@ LicenseKey 000000000000
 EditionIDs GeoIP2-City`,
 			Err: "geoipupdate requires a valid AccountID and LicenseKey combination",
 		},
+		{
+			Description: "Invalid Schedule",
+			Input: `AccountID 42
+LicenseKey 000000000001
+EditionIDs GeoIP2-City
+Schedule 0 6 * *`,
+			Err: "invalid `Schedule': expected 5 fields in cron expression, found 4",
+		},
+		{
+			Description: "Schedule that never matches",
+			Input: `AccountID 42
+LicenseKey 000000000001
+EditionIDs GeoIP2-City
+Schedule 0 0 31 2 *`,
+			Err: "invalid `Schedule': '0 0 31 2 *' never matches",
+		},
+		{
+			Description: "EditionSchedule for an edition that is not updated",
+			Input: `AccountID 42
+LicenseKey 000000000001
+EditionIDs GeoIP2-City
+EditionSchedule GeoLite2-ASN @daily`,
+			Err: "`EditionSchedule' is set for GeoLite2-ASN, which is not in `EditionIDs'",
+		},
+		{
+			Description: "AtomicGroup with an edition that is not updated",
+			Input: `AccountID 42
+LicenseKey 000000000001
+EditionIDs GeoIP2-City
+AtomicGroup GeoIP2-City GeoIP2-ISP`,
+			Err: "`AtomicGroup' contains GeoIP2-ISP, which is not in `EditionIDs'",
+		},
+		{
+			Description: "Invalid EditionSchedule",
+			Input: `AccountID 42
+LicenseKey 000000000001
+EditionIDs GeoIP2-City
+EditionSchedule GeoIP2-City 0 25 * * *`,
+			Err: "invalid `EditionSchedule' for GeoIP2-City: parsing hour field: value 25 is out of range [0, 23]",
+		},
+		{
+			Description: "Invalid SignaturePublicKey",
+			Input: `AccountID 42
+LicenseKey 000000000001
+EditionIDs GeoIP2-City
+SignaturePublicKey RWQBAgMEBQYHCAAB`,
+			Err: "invalid `SignaturePublicKey': not a minisign public key",
+		},
+		{
+			Description: "SignatureDirectory without SignaturePublicKey",
+			Input: `AccountID 42
+LicenseKey 000000000001
+EditionIDs GeoIP2-City
+SignatureDirectory /etc/geoip-signatures`,
+			Err: "`SignatureDirectory' is set but `SignaturePublicKey' is not",
+		},
+		{
+			Description: "SignatureSuffix without SignaturePublicKey",
+			Input: `AccountID 42
+LicenseKey 000000000001
+EditionIDs GeoIP2-City
+SignatureSuffix mmdb.minisig`,
+			Err: "`SignatureSuffix' is set but `SignaturePublicKey' is not",
+		},
+		{
+			Description: "SignaturePublicKey without SignatureDirectory or SignatureSuffix",
+			Input: `AccountID 42
+LicenseKey 000000000001
+EditionIDs GeoIP2-City
+SignaturePublicKey RWQBAgMEBQYHCAABAgMEBQYHCAkKCwwNDg8QERITFBUWFxgZGhscHR4f`,
+			Err: "`SignaturePublicKey' requires `SignatureDirectory' or `SignatureSuffix'",
+		},
 		{
 			Description: "RetryFor needs a unit",
 			Input: `AccountID 42
@@ -331,6 +403,27 @@ Parallelism 2`,
 				Parallelism: 4,
 			},
 		},
+		{
+			Description: "Frequency overridden by flag",
+			Input: `AccountID 999999
+LicenseKey abcd
+EditionIDs GeoIP2-City
+Frequency 24h`,
+			Flags: []Option{WithFrequency(6 * time.Hour)},
+			Output: &Config{
+				AccountID:         999999,
+				DatabaseDirectory: filepath.Clean(vars.DefaultDatabaseDirectory),
+				EditionIDs:        []string{"GeoIP2-City"},
+				Frequency:         6 * time.Hour,
+				LicenseKey:        "abcd",
+				LockFile: filepath.Clean(
+					filepath.Join(vars.DefaultDatabaseDirectory, ".geoipupdate.lock"),
+				),
+				URL:         "https://updates.maxmind.com",
+				RetryFor:    5 * time.Minute,
+				Parallelism: 1,
+			},
+		},
 		{
 			Description: "DatabaseDirectory overridden by flag",
 			Input: `AccountID 999999
@@ -496,6 +589,100 @@ EditionIDs    GeoLite2-City      GeoLite2-Country
 				URL:         "http://test",
 			},
 		},
+		{
+			Description: "Sources other than the update server need no account",
+			Input:       "EditionIDs\t\tGeoIP2-City\nHost\t\tfile:///srv/geoip",
+			Output: &Config{
+				DatabaseDirectory: filepath.Clean(vars.DefaultDatabaseDirectory),
+				EditionIDs:        []string{"GeoIP2-City"},
+				LockFile: filepath.Clean(
+					filepath.Join(vars.DefaultDatabaseDirectory, ".geoipupdate.lock"),
+				),
+				RetryFor:    5 * time.Minute,
+				Parallelism: 1,
+				URL:         "file:///srv/geoip",
+			},
+		},
+		{
+			Description: "Object storage database directory",
+			Input:       "AccountID\t\t42\nLicenseKey\t\tabc\nEditionIDs\t\tGeoIP2-City\nDatabaseDirectory\t\ts3://geoip/prod/",
+			Output: &Config{
+				AccountID:         42,
+				DatabaseDirectory: "s3://geoip/prod/",
+				EditionIDs:        []string{"GeoIP2-City"},
+				LicenseKey:        "abc",
+				LockFile:          filepath.Join(os.TempDir(), ".geoipupdate.lock"),
+				RetryFor:          5 * time.Minute,
+				Parallelism:       1,
+				URL:               "https://updates.maxmind.com",
+			},
+		},
+		{
+			Description: "Object storage database directory with KeepVersions",
+			Input:       "AccountID\t\t42\nLicenseKey\t\tabc\nEditionIDs\t\tGeoIP2-City\nDatabaseDirectory\t\ts3://geoip/prod\nKeepVersions\t\t2",
+			Err:         "`KeepVersions' requires a local `DatabaseDirectory'",
+		},
+		{
+			Description: "Database directory with an unsupported scheme",
+			Input:       "AccountID\t\t42\nLicenseKey\t\tabc\nEditionIDs\t\tGeoIP2-City\nDatabaseDirectory\t\tfile:///usr/share/GeoIP",
+			Err:         "invalid `DatabaseDirectory': the scheme \"file\" of file:///usr/share/GeoIP is not supported",
+		},
+		{
+			Description: "Destinations with an unsupported scheme",
+			Input:       "AccountID\t\t42\nLicenseKey\t\tabc\nEditionIDs\t\tGeoIP2-City\nDatabaseDirectory\t\t/usr/share/GeoIP\nDestinations\t\tftp://example.com/geoip",
+			Err:         "invalid `Destinations': the scheme \"ftp\" of ftp://example.com/geoip is not supported",
+		},
+		{
+			Description: "Destinations",
+			Input:       "AccountID\t\t42\nLicenseKey\t\tabc\nEditionIDs\t\tGeoIP2-City\nDatabaseDirectory\t\t/usr/share/GeoIP\nDestinations\t\t/srv/app/geoip/ s3://geoip/prod",
+			Output: &Config{
+				AccountID:         42,
+				DatabaseDirectory: "/usr/share/GeoIP",
+				Destinations:      []string{"/srv/app/geoip", "s3://geoip/prod"},
+				EditionIDs:        []string{"GeoIP2-City"},
+				LicenseKey:        "abc",
+				LockFile:          filepath.Join("/usr/share/GeoIP", ".geoipupdate.lock"),
+				RetryFor:          5 * time.Minute,
+				Parallelism:       1,
+				URL:               "https://updates.maxmind.com",
+			},
+		},
+		{
+			Description: "Destinations from the environment",
+			Input:       "AccountID\t\t42\nLicenseKey\t\tabc\nEditionIDs\t\tGeoIP2-City\nDatabaseDirectory\t\t/usr/share/GeoIP\nDestinations\t\t/srv/app/geoip",
+			Env:         map[string]string{"GEOIPUPDATE_DESTINATIONS": "/srv/other/geoip"},
+			Output: &Config{
+				AccountID:         42,
+				DatabaseDirectory: "/usr/share/GeoIP",
+				Destinations:      []string{"/srv/other/geoip"},
+				EditionIDs:        []string{"GeoIP2-City"},
+				LicenseKey:        "abc",
+				LockFile:          filepath.Join("/usr/share/GeoIP", ".geoipupdate.lock"),
+				RetryFor:          5 * time.Minute,
+				Parallelism:       1,
+				URL:               "https://updates.maxmind.com",
+			},
+		},
+		{
+			Description: "Destinations with the database directory",
+			Input:       "AccountID\t\t42\nLicenseKey\t\tabc\nEditionIDs\t\tGeoIP2-City\nDatabaseDirectory\t\t/usr/share/GeoIP\nDestinations\t\t/srv/app/geoip /usr/share/GeoIP/",
+			Err:         "`Destinations' contains the `DatabaseDirectory', /usr/share/GeoIP",
+		},
+		{
+			Description: "Destinations with a duplicate",
+			Input:       "AccountID\t\t42\nLicenseKey\t\tabc\nEditionIDs\t\tGeoIP2-City\nDestinations\t\t/srv/app/geoip /srv/app/geoip",
+			Err:         "`Destinations' contains /srv/app/geoip more than once",
+		},
+		{
+			Description: "Destinations with AtomicGroup",
+			Input:       "AccountID\t\t42\nLicenseKey\t\tabc\nEditionIDs\t\tGeoIP2-City\nDestinations\t\t/srv/app/geoip\nAtomicGroup\t\tGeoIP2-City",
+			Err:         "`AtomicGroup' cannot be used with `Destinations'",
+		},
+		{
+			Description: "Object storage destination with KeepVersions",
+			Input:       "AccountID\t\t42\nLicenseKey\t\tabc\nEditionIDs\t\tGeoIP2-City\nDestinations\t\ts3://geoip/prod\nKeepVersions\t\t2",
+			Err:         "`KeepVersions' requires local `Destinations'",
+		},
 	}
 
 	for _, test := range tests {
@@ -528,6 +715,7 @@ func TestSetConfigFromFile(t *testing.T) {
 			Input: `AccountID 1
 			DatabaseDirectory /tmp/db
 			EditionIDs GeoLite2-Country GeoLite2-City
+			Frequency 72h
 			Host updates.maxmind.com
 			LicenseKey 000000000001
 			LockFile /tmp/lock
@@ -541,6 +729,7 @@ func TestSetConfigFromFile(t *testing.T) {
 				AccountID:         1,
 				DatabaseDirectory: filepath.Clean("/tmp/db"),
 				EditionIDs:        []string{"GeoLite2-Country", "GeoLite2-City"},
+				Frequency:         72 * time.Hour,
 				LicenseKey:        "000000000001",
 				LockFile:          filepath.Clean("/tmp/lock"),
 				Parallelism:       2,
@@ -581,6 +770,141 @@ func TestSetConfigFromFile(t *testing.T) {
 			Input:       "Parallelism a",
 			Err:         "'a' is not a valid parallelism value: strconv.Atoi: parsing \"a\": invalid syntax",
 		},
+		{
+			Description: "ContinueOnError",
+			Input:       "ContinueOnError 1",
+			Expected:    Config{ContinueOnError: true},
+		},
+		{
+			Description: "Invalid ContinueOnError",
+			Input:       "ContinueOnError yes",
+			Err:         "`ContinueOnError' must be 0 or 1",
+		},
+		{
+			Description: "KeepVersions",
+			Input:       "KeepVersions 3",
+			Expected:    Config{KeepVersions: 3},
+		},
+		{
+			Description: "Invalid KeepVersions",
+			Input:       "KeepVersions -1",
+			Err:         "KeepVersions can't be negative, got '-1'",
+		},
+		{
+			Description: "Serve credentials",
+			Input: `ServeAccountID 42
+ServeLicenseKey downstream
+ServeAddress 127.0.0.1:8081
+ServeProxy 1
+ServeTLSCertFile /etc/geoipupdate/cert.pem
+ServeTLSKeyFile /etc/geoipupdate/key.pem`,
+			Expected: Config{
+				ServeAccountID:   42,
+				ServeAddress:     "127.0.0.1:8081",
+				ServeLicenseKey:  "downstream",
+				ServeProxy:       true,
+				ServeTLSCertFile: "/etc/geoipupdate/cert.pem",
+				ServeTLSKeyFile:  "/etc/geoipupdate/key.pem",
+			},
+		},
+		{
+			Description: "Invalid ServeProxy",
+			Input:       "ServeProxy yes",
+			Err:         "`ServeProxy' must be 0 or 1",
+		},
+		{
+			Description: "Invalid ServeAccountID",
+			Input:       "ServeAccountID abc",
+			Err:         "invalid `ServeAccountID' format",
+		},
+		{
+			Description: "Pinned editions",
+			Input:       "EditionIDs GeoIP2-City@2026-09-15 GeoIP2-Country",
+			Expected: Config{
+				EditionIDs:   []string{"GeoIP2-City", "GeoIP2-Country"},
+				EditionDates: map[string]string{"GeoIP2-City": "2026-09-15"},
+			},
+		},
+		{
+			Description: "Invalid pinned date",
+			Input:       "EditionIDs GeoIP2-City@20260915",
+			Err:         "invalid `EditionIDs': GeoIP2-City@20260915 is not pinned to a date of the form YYYY-MM-DD",
+		},
+		{
+			Description: "AtomicGroup",
+			Input: `EditionIDs GeoIP2-City GeoIP2-ISP GeoIP2-Country
+AtomicGroup GeoIP2-City GeoIP2-ISP`,
+			Expected: Config{
+				EditionIDs:  []string{"GeoIP2-City", "GeoIP2-ISP", "GeoIP2-Country"},
+				AtomicGroup: []string{"GeoIP2-City", "GeoIP2-ISP"},
+			},
+		},
+		{
+			Description: "AllowDowngrade",
+			Input:       "AllowDowngrade 1",
+			Expected:    Config{AllowDowngrade: true},
+		},
+		{
+			Description: "Invalid AllowDowngrade",
+			Input:       "AllowDowngrade yes",
+			Err:         "`AllowDowngrade' must be 0 or 1",
+		},
+		{
+			Description: "Schedules",
+			Input: `Schedule CRON_TZ=America/New_York 0 6 * * tue,fri
+			EditionSchedule GeoLite2-ASN 30 3 * * *
+			EditionSchedule GeoIP2-City @weekly
+	`,
+			Expected: Config{
+				Schedule: "CRON_TZ=America/New_York 0 6 * * tue,fri",
+				EditionSchedules: map[string]string{
+					"GeoLite2-ASN": "30 3 * * *",
+					"GeoIP2-City":  "@weekly",
+				},
+			},
+		},
+		{
+			Description: "Signatures",
+			Input: `SignaturePublicKey RWQBAgMEBQYHCAABAgMEBQYHCAkKCwwNDg8QERITFBUWFxgZGhscHR4f
+SignatureDirectory /etc/geoip-signatures/`,
+			Expected: Config{
+				SignaturePublicKey: "RWQBAgMEBQYHCAABAgMEBQYHCAkKCwwNDg8QERITFBUWFxgZGhscHR4f",
+				SignatureDirectory: "/etc/geoip-signatures",
+			},
+		},
+		{
+			Description: "SignatureSuffix",
+			Input: `SignaturePublicKey RWQBAgMEBQYHCAABAgMEBQYHCAkKCwwNDg8QERITFBUWFxgZGhscHR4f
+SignatureSuffix mmdb.minisig`,
+			Expected: Config{
+				SignaturePublicKey: "RWQBAgMEBQYHCAABAgMEBQYHCAkKCwwNDg8QERITFBUWFxgZGhscHR4f",
+				SignatureSuffix:    "mmdb.minisig",
+			},
+		},
+		{
+			Description: "EditionSchedule needs an edition and an expression",
+			Input:       "EditionSchedule GeoLite2-ASN",
+			Err:         "invalid format on line 1",
+		},
+		{
+			Description: "EditionSchedule for the same edition multiple times",
+			Input: `EditionSchedule GeoLite2-ASN @daily
+EditionSchedule GeoLite2-ASN @weekly`,
+			Expected: Config{
+				EditionSchedules: map[string]string{"GeoLite2-ASN": "@daily"},
+			},
+			Err: "`EditionSchedule' is in the config multiple times for GeoLite2-ASN",
+		},
+		{
+			Description: "Frequency may be a number of hours",
+			Input:       "Frequency 12",
+			Expected:    Config{Frequency: 12 * time.Hour},
+		},
+		{
+			Description: "Frequency needs to be non-negative",
+			Input:       "Frequency -1h",
+			Err:         "'-1h' is not a valid frequency",
+		},
 		{
 			Description: "Parallelism should be a positive number",
 			Input:       "Parallelism 0",
@@ -622,6 +946,7 @@ func TestSetConfigFromEnv(t *testing.T) {
 				"GEOIPUPDATE_ACCOUNT_ID_FILE":     "",
 				"GEOIPUPDATE_DB_DIR":              "/tmp/db",
 				"GEOIPUPDATE_EDITION_IDS":         "GeoLite2-Country GeoLite2-City",
+				"GEOIPUPDATE_FREQUENCY":           "72",
 				"GEOIPUPDATE_HOST":                "updates.maxmind.com",
 				"GEOIPUPDATE_LICENSE_KEY":         "000000000001",
 				"GEOIPUPDATE_LICENSE_KEY_FILE":    "",
@@ -637,6 +962,7 @@ func TestSetConfigFromEnv(t *testing.T) {
 				AccountID:         1,
 				DatabaseDirectory: "/tmp/db",
 				EditionIDs:        []string{"GeoLite2-Country", "GeoLite2-City"},
+				Frequency:         72 * time.Hour,
 				LicenseKey:        "000000000001",
 				LockFile:          "/tmp/lock",
 				Parallelism:       2,
@@ -765,6 +1091,130 @@ func TestSetConfigFromEnv(t *testing.T) {
 			},
 			Err: "parallelism should be greater than 0, got '0'",
 		},
+		{
+			Description: "ContinueOnError",
+			Env: map[string]string{
+				"GEOIPUPDATE_CONTINUE_ON_ERROR": "1",
+			},
+			Expected: Config{ContinueOnError: true},
+		},
+		{
+			Description: "Invalid ContinueOnError",
+			Env: map[string]string{
+				"GEOIPUPDATE_CONTINUE_ON_ERROR": "true",
+			},
+			Err: "`GEOIPUPDATE_CONTINUE_ON_ERROR' must be 0 or 1",
+		},
+		{
+			Description: "Pinned editions",
+			Env: map[string]string{
+				"GEOIPUPDATE_EDITION_IDS": "GeoIP2-City@2026-09-15 GeoIP2-Country",
+			},
+			Expected: Config{
+				EditionIDs:   []string{"GeoIP2-City", "GeoIP2-Country"},
+				EditionDates: map[string]string{"GeoIP2-City": "2026-09-15"},
+			},
+		},
+		{
+			Description: "KeepVersions",
+			Env: map[string]string{
+				"GEOIPUPDATE_KEEP_VERSIONS": "3",
+			},
+			Expected: Config{KeepVersions: 3},
+		},
+		{
+			Description: "Invalid KeepVersions",
+			Env: map[string]string{
+				"GEOIPUPDATE_KEEP_VERSIONS": "three",
+			},
+			Err: "'three' is not a valid KeepVersions value: strconv.Atoi: parsing \"three\": invalid syntax",
+		},
+		{
+			Description: "Serve credentials",
+			Env: map[string]string{
+				"GEOIPUPDATE_SERVE_ACCOUNT_ID":    "42",
+				"GEOIPUPDATE_SERVE_ADDRESS":       "127.0.0.1:8081",
+				"GEOIPUPDATE_SERVE_LICENSE_KEY":   "downstream",
+				"GEOIPUPDATE_SERVE_PROXY":         "1",
+				"GEOIPUPDATE_SERVE_TLS_CERT_FILE": "/etc/geoipupdate/cert.pem",
+				"GEOIPUPDATE_SERVE_TLS_KEY_FILE":  "/etc/geoipupdate/key.pem",
+			},
+			Expected: Config{
+				ServeAccountID:   42,
+				ServeAddress:     "127.0.0.1:8081",
+				ServeLicenseKey:  "downstream",
+				ServeProxy:       true,
+				ServeTLSCertFile: "/etc/geoipupdate/cert.pem",
+				ServeTLSKeyFile:  "/etc/geoipupdate/key.pem",
+			},
+		},
+		{
+			Description: "Invalid ServeAccountID",
+			Env: map[string]string{
+				"GEOIPUPDATE_SERVE_ACCOUNT_ID": "abc",
+			},
+			Err: "invalid `GEOIPUPDATE_SERVE_ACCOUNT_ID' format",
+		},
+		{
+			Description: "AllowDowngrade",
+			Env: map[string]string{
+				"GEOIPUPDATE_ALLOW_DOWNGRADE": "1",
+			},
+			Expected: Config{AllowDowngrade: true},
+		},
+		{
+			Description: "Invalid AllowDowngrade",
+			Env: map[string]string{
+				"GEOIPUPDATE_ALLOW_DOWNGRADE": "true",
+			},
+			Err: "`GEOIPUPDATE_ALLOW_DOWNGRADE' must be 0 or 1",
+		},
+		{
+			Description: "Schedules",
+			Env: map[string]string{
+				"GEOIPUPDATE_EDITION_SCHEDULES": "GeoLite2-ASN=30 3 * * *; GeoIP2-City=@weekly;",
+				"GEOIPUPDATE_SCHEDULE":          "0 6 * * 2,5",
+			},
+			Expected: Config{
+				Schedule: "0 6 * * 2,5",
+				EditionSchedules: map[string]string{
+					"GeoLite2-ASN": "30 3 * * *",
+					"GeoIP2-City":  "@weekly",
+				},
+			},
+		},
+		{
+			Description: "Signatures",
+			Env: map[string]string{
+				"GEOIPUPDATE_SIGNATURE_DIRECTORY":  "/etc/geoip-signatures",
+				"GEOIPUPDATE_SIGNATURE_PUBLIC_KEY": "RWQBAgMEBQYHCAABAgMEBQYHCAkKCwwNDg8QERITFBUWFxgZGhscHR4f",
+			},
+			Expected: Config{
+				SignaturePublicKey: "RWQBAgMEBQYHCAABAgMEBQYHCAkKCwwNDg8QERITFBUWFxgZGhscHR4f",
+				SignatureDirectory: "/etc/geoip-signatures",
+			},
+		},
+		{
+			Description: "Invalid edition schedules",
+			Env: map[string]string{
+				"GEOIPUPDATE_EDITION_SCHEDULES": "@weekly",
+			},
+			Err: "'@weekly' is not a valid edition schedule",
+		},
+		{
+			Description: "Frequency may be a duration",
+			Env: map[string]string{
+				"GEOIPUPDATE_FREQUENCY": "90m",
+			},
+			Expected: Config{Frequency: 90 * time.Minute},
+		},
+		{
+			Description: "Invalid Frequency",
+			Env: map[string]string{
+				"GEOIPUPDATE_FREQUENCY": "weekly",
+			},
+			Err: "'weekly' is not a valid frequency",
+		},
 		{
 			Description: "Invalid Verbose",
 			Env: map[string]string{
@@ -1030,3 +1480,8 @@ func withEnvVars(t *testing.T, newEnvVars map[string]string, f func()) {
 		require.NoError(t, err)
 	}
 }
+
+func TestNewConfigReturnsConfigError(t *testing.T) {
+	_, err := NewConfig(WithConfigFile(filepath.Join(t.TempDir(), "missing.conf")))
+	require.ErrorAs(t, err, &ConfigError{})
+}
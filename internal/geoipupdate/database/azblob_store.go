@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/maxmind/geoipupdate/v8/internal/azblob"
+)
+
+// azblobStore is an objectStore for a prefix in an Azure Blob Storage
+// container. Object versions are ETags.
+//
+// Azure metadata names must be C# identifiers, so the dashes of the
+// metadata keys are stored as underscores.
+type azblobStore struct {
+	client    *azblob.Client
+	container string
+	prefix    string
+}
+
+// newAzblobStore creates an azblobStore for azblob://container/prefix. The
+// credentials are read from the environment, see azblob.NewFromEnv.
+func newAzblobStore(rawURL string, httpClient *http.Client) (*azblobStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", rawURL, err)
+	}
+	container, prefix, err := azblob.ParseURL(u)
+	if err != nil {
+		return nil, err
+	}
+	c, err := azblob.NewFromEnv(httpClient)
+	if err != nil {
+		return nil, err
+	}
+	return &azblobStore{client: c, container: container, prefix: prefix}, nil
+}
+
+func (s *azblobStore) head(ctx context.Context, name string) (objectAttrs, error) {
+	blob, err := s.client.Properties(ctx, s.container, s.key(name))
+	if err != nil {
+		return objectAttrs{}, err
+	}
+	metadata := map[string]string{}
+	for key, value := range blob.Metadata {
+		metadata[strings.ReplaceAll(key, "_", "-")] = value
+	}
+	return objectAttrs{size: blob.Size, metadata: metadata, version: blob.ETag}, nil
+}
+
+func (s *azblobStore) put(
+	ctx context.Context,
+	name string,
+	f *os.File,
+	size int64,
+	md5 []byte,
+	metadata map[string]string,
+) error {
+	azMetadata := map[string]string{}
+	for key, value := range metadata {
+		azMetadata[strings.ReplaceAll(key, "-", "_")] = value
+	}
+	return s.client.Put(ctx, s.container, s.key(name), f, size, md5, azMetadata)
+}
+
+func (s *azblobStore) copy(ctx context.Context, from, to, ifVersion string) error {
+	return s.client.Copy(ctx, s.container, s.key(from), s.key(to), ifVersion)
+}
+
+func (s *azblobStore) remove(ctx context.Context, name string) error {
+	return s.client.Delete(ctx, s.container, s.key(name))
+}
+
+func (s *azblobStore) url(name string) string {
+	return "azblob://" + s.container + "/" + s.key(name)
+}
+
+func (s *azblobStore) key(name string) string {
+	return path.Join(s.prefix, name)
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DatedPath returns the path that WriteDated stores the release of an
+// edition on date, as YYYY-MM-DD, at.
+func (w *LocalFileWriter) DatedPath(editionID, date string) string {
+	return filepath.Join(w.dir, editionID+"_"+date) + extension
+}
+
+// DatedBuildPath returns the path that WriteDated records the build of the
+// release of an edition on date at. A database may be released on a
+// different date than it was built on, so dated databases are named after
+// their release date and their build is recorded next to them.
+func (w *LocalFileWriter) DatedBuildPath(editionID, date string) string {
+	return w.DatedPath(editionID, date) + ".json"
+}
+
+// datedBuild is the record at DatedBuildPath.
+type datedBuild struct {
+	BuildEpoch uint   `json:"build_epoch"`
+	BuildDate  string `json:"build_date"`
+}
+
+// WriteDated stores the release of an edition on date, as YYYY-MM-DD, at
+// DatedPath and records its build at DatedBuildPath. The database content
+// will be read from reader. Unlike Write, it leaves the current database of
+// the edition alone, so the database is neither checked against it nor kept
+// in the history.
+func (w *LocalFileWriter) WriteDated(
+	editionID string,
+	date string,
+	reader io.ReadCloser,
+	lastModified time.Time,
+) (err error) {
+	defer func() {
+		_, _ = io.Copy(io.Discard, reader) //nolint:errcheck // Best effort.
+		if closeErr := reader.Close(); closeErr != nil {
+			err = errors.Join(
+				err,
+				fmt.Errorf("closing reader for %s: %w", editionID, closeErr),
+			)
+		}
+	}()
+
+	databaseFilePath := w.DatedPath(editionID, date)
+
+	fw, err := newFileWriter(databaseFilePath + tempExtension)
+	if err != nil {
+		return fmt.Errorf("setting up database writer for %s: %w", editionID, err)
+	}
+	defer func() {
+		if closeErr := fw.close(); closeErr != nil {
+			err = errors.Join(
+				err,
+				fmt.Errorf("closing file writer: %w", closeErr),
+			)
+		}
+	}()
+
+	if err = fw.write(reader); err != nil {
+		return fmt.Errorf("writing to the temp file for %s: %w", editionID, err)
+	}
+
+	metadata, err := validateDatabase(fw.file.Name(), editionID)
+	if err != nil {
+		return fmt.Errorf("validating database for %s: %w", editionID, err)
+	}
+	built := formatBuildDate(metadata.BuildEpoch)
+	if built != date && w.verbose {
+		log.Printf("Database %s released on %s was built on %s", editionID, date, built)
+	}
+
+	if err = fw.syncAndRename(databaseFilePath); err != nil {
+		return fmt.Errorf("renaming temp file: %w", err)
+	}
+	if err = syncDir(w.dir); err != nil {
+		return fmt.Errorf("syncing database directory: %w", err)
+	}
+
+	if w.preserveFileTime {
+		if err = setModifiedAtTime(databaseFilePath, lastModified); err != nil {
+			return err
+		}
+	}
+
+	build := datedBuild{BuildEpoch: metadata.BuildEpoch, BuildDate: built}
+	if err = writeDatedBuild(w.DatedBuildPath(editionID, date), build); err != nil {
+		return err
+	}
+
+	if w.verbose {
+		log.Printf("Database %s released on %s successfully stored", editionID, date)
+	}
+
+	return nil
+}
+
+// writeDatedBuild writes build to path, replacing it atomically.
+func writeDatedBuild(path string, build datedBuild) error {
+	b, err := json.Marshal(build)
+	if err != nil {
+		return fmt.Errorf("encoding the build of %s: %w", path, err)
+	}
+	tmp := path + tempExtension
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("renaming %s: %w", tmp, err)
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
+)
+
+// These are the operations of the protocol spoken with the programs of
+// ExecWriter, and its version.
+const (
+	execProtocolVersion = 1
+	execGetHash         = "get_hash"
+	execWrite           = "write"
+	// execDatabaseFD is the file descriptor the database is passed on, i.e.,
+	// the first one after stdin, stdout and stderr.
+	execDatabaseFD = 3
+	// execCodeDowngradeRefused is the error code with which a program refuses
+	// to replace a database with an older one.
+	execCodeDowngradeRefused = "downgrade_refused"
+	// execTimeout is how long the program may take for an operation before
+	// it is killed.
+	execTimeout = 10 * time.Minute
+)
+
+// execRequest is the JSON document written to the stdin of the program.
+type execRequest struct {
+	ProtocolVersion int    `json:"protocol_version"`
+	Operation       string `json:"operation"`
+	EditionID       string `json:"edition_id"`
+	// The rest is only set for writes.
+	MD5            string `json:"md5,omitempty"`
+	BuildEpoch     uint   `json:"build_epoch,omitempty"`
+	BuildDate      string `json:"build_date,omitempty"`
+	Size           int64  `json:"size,omitempty"`
+	LastModified   string `json:"last_modified,omitempty"`
+	AllowDowngrade bool   `json:"allow_downgrade,omitempty"`
+	FD             int    `json:"fd,omitempty"`
+}
+
+// execResponse is the JSON document the program writes to its stdout.
+type execResponse struct {
+	// MD5 and BuildEpoch are the MD5 and build epoch of the current database
+	// in response to get_hash. MD5 is empty if there is none.
+	MD5        string `json:"md5"`
+	BuildEpoch uint   `json:"build_epoch"`
+	// Error is set if the operation failed, and Code may classify it.
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// ExecWriter is a Writer that delegates storing the databases to an
+// external program, so that geoipupdate can write to storage it does not
+// support itself.
+//
+// The program is run once for each operation. It reads a JSON request from
+// its stdin and writes a JSON response to its stdout. Its stderr is that of
+// geoipupdate. get_hash asks for the MD5 and build epoch of the current
+// database of an edition. write asks it to store a database, which is
+// validated before and passed on file descriptor 3. The current database is
+// read back with get_hash after writing it, to verify it.
+//
+// A response with an error, or an exit status other than 0, is a failure,
+// as is taking longer than ten minutes.
+type ExecWriter struct {
+	program string
+	verbose bool
+	timeout time.Duration
+	// allowDowngrade and allowDowngradeFor are as for LocalFileWriter.
+	allowDowngrade    bool
+	allowDowngradeFor []string
+}
+
+// ExecWriterOption is an option for configuring ExecWriter.
+type ExecWriterOption func(*ExecWriter)
+
+// WithExecAllowDowngrade sets whether a database may be replaced by one that
+// was built before it, and editions for which it may be regardless, e.g.,
+// because they are pinned to a date.
+func WithExecAllowDowngrade(allow bool, editionIDs ...string) ExecWriterOption {
+	return func(w *ExecWriter) {
+		w.allowDowngrade = allow
+		w.allowDowngradeFor = editionIDs
+	}
+}
+
+// IsExecURL returns whether the database directory dir is the URL of a
+// program to delegate writing to, exec:///path/to/program.
+func IsExecURL(dir string) bool {
+	return strings.HasPrefix(dir, "exec://")
+}
+
+// NewExecWriter creates an ExecWriter for the program at
+// exec:///path/to/program. Passing the database on a file descriptor is not
+// supported on Windows.
+func NewExecWriter(
+	rawURL string,
+	verbose bool,
+	options ...ExecWriterOption,
+) (*ExecWriter, error) {
+	if runtime.GOOS == "windows" {
+		return nil, errors.New("exec:// database directories are not supported on Windows")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", rawURL, err)
+	}
+	if u.Scheme != "exec" || u.Host != "" || !filepath.IsAbs(u.Path) {
+		return nil, fmt.Errorf("%s is not an exec:///path/to/program URL", rawURL)
+	}
+
+	w := &ExecWriter{
+		program: u.Path,
+		verbose: verbose,
+		timeout: execTimeout,
+	}
+	for _, opt := range options {
+		opt(w)
+	}
+	return w, nil
+}
+
+// Write is WriteContext with the background context.
+func (w *ExecWriter) Write(
+	editionID string,
+	reader io.ReadCloser,
+	newMD5 string,
+	lastModified time.Time,
+) error {
+	return w.WriteContext(context.Background(), editionID, reader, newMD5, lastModified)
+}
+
+// WriteContext validates the database read from reader and passes it to the
+// program. If newMD5 is empty, the hash is not checked. The program is
+// killed if ctx is done before it finished.
+func (w *ExecWriter) WriteContext(
+	ctx context.Context,
+	editionID string,
+	reader io.ReadCloser,
+	newMD5 string,
+	lastModified time.Time,
+) error {
+	return withSpooledDatabase(editionID, reader, newMD5, func(db *spooledDatabase) error {
+		return w.write(ctx, editionID, db, lastModified)
+	})
+}
+
+// write passes the database to the program, after checking that it is not
+// older than the current one, and verifies it was written.
+func (w *ExecWriter) write(
+	ctx context.Context,
+	editionID string,
+	db *spooledDatabase,
+	lastModified time.Time,
+) error {
+	allowDowngrade := w.allowDowngrade || slices.Contains(w.allowDowngradeFor, editionID)
+	if !allowDowngrade {
+		current, err := w.run(ctx, execRequest{Operation: execGetHash, EditionID: editionID}, nil)
+		if err != nil {
+			return fmt.Errorf("getting current build of %s: %w", editionID, err)
+		}
+		if err := checkEpochNotOlder(db.metadata.BuildEpoch, current.BuildEpoch); err != nil {
+			return fmt.Errorf("checking build of %s: %w", editionID, err)
+		}
+	}
+
+	// The program gets a descriptor of its own, which it can only read.
+	f, err := os.Open(db.fw.file.Name())
+	if err != nil {
+		return fmt.Errorf("opening the temp file for %s: %w", editionID, err)
+	}
+	defer f.Close()
+
+	md5 := byteToString(db.md5)
+	request := execRequest{
+		Operation:      execWrite,
+		EditionID:      editionID,
+		MD5:            md5,
+		BuildEpoch:     db.metadata.BuildEpoch,
+		BuildDate:      formatBuildDate(db.metadata.BuildEpoch),
+		Size:           db.size,
+		AllowDowngrade: allowDowngrade,
+		FD:             execDatabaseFD,
+	}
+	if !lastModified.IsZero() {
+		request.LastModified = lastModified.UTC().Format(time.RFC3339)
+	}
+	if _, err = w.run(ctx, request, f); err != nil {
+		return fmt.Errorf("writing %s: %w", editionID, err)
+	}
+
+	written, err := w.run(ctx, execRequest{Operation: execGetHash, EditionID: editionID}, nil)
+	if err != nil {
+		return fmt.Errorf("verifying %s: %w", editionID, err)
+	}
+	if !strings.EqualFold(written.MD5, md5) {
+		return fmt.Errorf(
+			"%w: md5 of written database (%s) does not match expected md5 (%s)",
+			internal.ErrHashMismatch,
+			written.MD5,
+			md5,
+		)
+	}
+
+	if w.verbose {
+		log.Printf("Database %s successfully written by %s: %s", editionID, w.program, md5)
+	}
+	return nil
+}
+
+// GetHash is GetHashContext with the background context.
+func (w *ExecWriter) GetHash(editionID string) (string, error) {
+	return w.GetHashContext(context.Background(), editionID)
+}
+
+// GetHashContext returns the MD5 of the current database of an edition, as
+// reported by the program, or ZeroMD5 if it has none.
+func (w *ExecWriter) GetHashContext(ctx context.Context, editionID string) (string, error) {
+	res, err := w.run(ctx, execRequest{Operation: execGetHash, EditionID: editionID}, nil)
+	if err != nil {
+		return "", fmt.Errorf("getting hash of %s: %w", editionID, err)
+	}
+	if res.MD5 == "" {
+		if w.verbose {
+			log.Printf("%s has no database of %s, returning zeroed hash", w.program, editionID)
+		}
+		return ZeroMD5, nil
+	}
+	if w.verbose {
+		log.Printf("MD5 sum of %s: %s", editionID, res.MD5)
+	}
+	return strings.ToLower(res.MD5), nil
+}
+
+// run runs the program with request, passing database on execDatabaseFD if
+// it is not nil, and returns its response. The program is killed once ctx
+// is done or it ran for longer than the timeout.
+func (w *ExecWriter) run(
+	ctx context.Context,
+	request execRequest,
+	database *os.File,
+) (execResponse, error) {
+	request.ProtocolVersion = execProtocolVersion
+	b, err := json.Marshal(request)
+	if err != nil {
+		return execResponse{}, fmt.Errorf("encoding request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	//nolint:gosec // the program is configured by the user.
+	cmd := exec.CommandContext(ctx, w.program)
+	// Processes the program started may keep stdout open after it was
+	// killed.
+	cmd.WaitDelay = time.Second
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if database != nil {
+		cmd.ExtraFiles = []*os.File{database}
+	}
+	runErr := cmd.Run()
+
+	var res execResponse
+	if stdout.Len() > 0 {
+		if err := json.Unmarshal(stdout.Bytes(), &res); err != nil && runErr == nil {
+			return execResponse{}, fmt.Errorf("decoding response of %s: %w", w.program, err)
+		}
+	}
+	if res.Error != "" {
+		err := fmt.Errorf("%s %s: %s", w.program, request.Operation, res.Error)
+		if res.Code == execCodeDowngradeRefused {
+			err = fmt.Errorf("%w: %w", internal.ErrDowngradeRefused, err)
+		}
+		return execResponse{}, err
+	}
+	if runErr != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			// The program was killed.
+			runErr = ctxErr
+		}
+		return execResponse{}, fmt.Errorf("running %s %s: %w", w.program, request.Operation, runErr)
+	}
+	return res, nil
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
+	"github.com/maxmind/geoipupdate/v8/internal/mmdbtest"
+)
+
+// execTestDirEnv makes the test binary act as the program of an ExecWriter
+// that stores the databases in the directory it names.
+const execTestDirEnv = "GEOIPUPDATE_TEST_EXEC_DIR"
+
+func TestMain(m *testing.M) {
+	if dir := os.Getenv(execTestDirEnv); dir != "" {
+		os.Exit(runTestProgram(dir))
+	}
+	os.Exit(m.Run())
+}
+
+// runTestProgram stores databases as <edition>.mmdb files in dir, with the
+// write request next to them as <edition>.json. GeoIP2-ISP databases are
+// refused as downgrades, the program fails for GeoIP2-Domain, and it hangs
+// for GeoIP2-Connection-Type.
+func runTestProgram(dir string) int {
+	var request execRequest
+	if err := json.NewDecoder(os.Stdin).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	respond := func(res execResponse) int {
+		if err := json.NewEncoder(os.Stdout).Encode(res); err != nil {
+			return 1
+		}
+		return 0
+	}
+
+	name := filepath.Join(dir, request.EditionID)
+	switch {
+	case request.ProtocolVersion != execProtocolVersion:
+		return respond(execResponse{Error: "unsupported protocol version"})
+	case request.EditionID == "GeoIP2-Domain":
+		return 1
+	case request.EditionID == "GeoIP2-Connection-Type":
+		time.Sleep(time.Minute)
+		return 1
+	case request.Operation == execGetHash:
+		b, err := os.ReadFile(name + ".json")
+		if os.IsNotExist(err) {
+			return respond(execResponse{})
+		}
+		if err != nil || json.Unmarshal(b, &request) != nil {
+			return respond(execResponse{Error: "reading request"})
+		}
+		return respond(execResponse{MD5: request.MD5, BuildEpoch: request.BuildEpoch})
+	case request.Operation == execWrite && request.EditionID == "GeoIP2-ISP":
+		return respond(execResponse{Error: "older", Code: execCodeDowngradeRefused})
+	case request.Operation == execWrite:
+		b, err := io.ReadAll(os.NewFile(uintptr(request.FD), "database"))
+		if err != nil {
+			return respond(execResponse{Error: err.Error()})
+		}
+		requestJSON, err := json.Marshal(request)
+		if err != nil {
+			return respond(execResponse{Error: err.Error()})
+		}
+		if err := os.WriteFile(name+".mmdb", b, 0o600); err != nil {
+			return respond(execResponse{Error: err.Error()})
+		}
+		if err := os.WriteFile(name+".json", requestJSON, 0o600); err != nil {
+			return respond(execResponse{Error: err.Error()})
+		}
+		return respond(execResponse{})
+	default:
+		return respond(execResponse{Error: "unknown operation " + request.Operation})
+	}
+}
+
+func TestExecWriter(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file descriptors cannot be passed on Windows")
+	}
+
+	dir := t.TempDir()
+	t.Setenv(execTestDirEnv, dir)
+	program, err := os.Executable()
+	require.NoError(t, err)
+
+	w, err := NewExecWriter("exec://"+program, false)
+	require.NoError(t, err)
+
+	hash, err := w.GetHash("GeoIP2-City")
+	require.NoError(t, err)
+	require.Equal(t, ZeroMD5, hash)
+
+	db := mmdbtest.Build("GeoIP2-City", 1789430400)
+	lastModified := time.Date(2026, 9, 15, 12, 0, 0, 0, time.UTC)
+	err = w.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(db)), md5Hex(db), lastModified)
+	require.NoError(t, err)
+
+	written, err := os.ReadFile(filepath.Join(dir, "GeoIP2-City.mmdb"))
+	require.NoError(t, err)
+	require.Equal(t, db, written)
+	b, err := os.ReadFile(filepath.Join(dir, "GeoIP2-City.json"))
+	require.NoError(t, err)
+	var request execRequest
+	require.NoError(t, json.Unmarshal(b, &request))
+	require.Equal(t, execRequest{
+		ProtocolVersion: execProtocolVersion,
+		Operation:       execWrite,
+		EditionID:       "GeoIP2-City",
+		MD5:             md5Hex(db),
+		BuildEpoch:      1789430400,
+		BuildDate:       "2026-09-15",
+		Size:            int64(len(db)),
+		LastModified:    "2026-09-15T12:00:00Z",
+		FD:              execDatabaseFD,
+	}, request)
+
+	hash, err = w.GetHash("GeoIP2-City")
+	require.NoError(t, err)
+	require.Equal(t, md5Hex(db), hash)
+
+	// Invalid and older databases are never passed to the program.
+	err = w.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(db)), "badhash", time.Time{})
+	require.ErrorIs(t, err, internal.ErrHashMismatch)
+
+	older := mmdbtest.Build("GeoIP2-City", 1789430400-24*60*60)
+	err = w.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(older)), md5Hex(older), time.Time{})
+	require.ErrorIs(t, err, internal.ErrDowngradeRefused)
+
+	w, err = NewExecWriter("exec://"+program, false, WithExecAllowDowngrade(true))
+	require.NoError(t, err)
+	err = w.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(older)), md5Hex(older), time.Time{})
+	require.NoError(t, err)
+	written, err = os.ReadFile(filepath.Join(dir, "GeoIP2-City.mmdb"))
+	require.NoError(t, err)
+	require.Equal(t, older, written)
+
+	// The program may refuse databases itself, or fail.
+	isp := mmdbtest.Build("GeoIP2-ISP", 1789430400)
+	err = w.Write("GeoIP2-ISP", io.NopCloser(bytes.NewReader(isp)), md5Hex(isp), time.Time{})
+	require.ErrorIs(t, err, internal.ErrDowngradeRefused)
+
+	_, err = w.GetHash("GeoIP2-Domain")
+	require.ErrorContains(t, err, "exit status 1")
+
+	// Programs that hang are killed once the context is done or they ran
+	// for too long.
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err = w.GetHashContext(ctx, "GeoIP2-City")
+	require.ErrorIs(t, err, context.Canceled)
+
+	w.timeout = 100 * time.Millisecond
+	_, err = w.GetHash("GeoIP2-Connection-Type")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestNewExecWriterInvalidURL(t *testing.T) {
+	for _, rawURL := range []string{
+		"exec://host/program",
+		"exec:program",
+		"exec://",
+	} {
+		_, err := NewExecWriter(rawURL, false)
+		require.Error(t, err, rawURL)
+	}
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/maxmind/geoipupdate/v8/internal/gcs"
+)
+
+// gcsStore is an objectStore for a prefix in a Google Cloud Storage bucket.
+// Object versions are generations.
+type gcsStore struct {
+	client *gcs.Client
+	bucket string
+	prefix string
+}
+
+// newGCSStore creates a gcsStore for gs://bucket/prefix. The credentials
+// are read from the environment, see gcs.NewFromEnv.
+func newGCSStore(rawURL string, httpClient *http.Client) (*gcsStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", rawURL, err)
+	}
+	bucket, prefix, err := gcs.ParseURL(u)
+	if err != nil {
+		return nil, err
+	}
+	c, err := gcs.NewFromEnv(httpClient)
+	if err != nil {
+		return nil, err
+	}
+	return &gcsStore{client: c, bucket: bucket, prefix: prefix}, nil
+}
+
+func (s *gcsStore) head(ctx context.Context, name string) (objectAttrs, error) {
+	obj, err := s.client.Attrs(ctx, s.bucket, s.key(name))
+	if err != nil {
+		return objectAttrs{}, err
+	}
+	return objectAttrs{
+		size:     obj.Size,
+		metadata: obj.Metadata,
+		version:  strconv.FormatInt(obj.Generation, 10),
+	}, nil
+}
+
+func (s *gcsStore) put(
+	ctx context.Context,
+	name string,
+	f *os.File,
+	size int64,
+	md5 []byte,
+	metadata map[string]string,
+) error {
+	return s.client.Upload(ctx, s.bucket, s.key(name), f, size, md5, metadata)
+}
+
+func (s *gcsStore) copy(ctx context.Context, from, to, ifVersion string) error {
+	// Generation 0 is the precondition that there is no object.
+	var generation int64
+	if ifVersion != "" {
+		var err error
+		generation, err = strconv.ParseInt(ifVersion, 10, 64)
+		if err != nil {
+			return fmt.Errorf("parsing generation %q: %w", ifVersion, err)
+		}
+	}
+	return s.client.Rewrite(ctx, s.bucket, s.key(from), s.key(to), generation)
+}
+
+func (s *gcsStore) remove(ctx context.Context, name string) error {
+	return s.client.Delete(ctx, s.bucket, s.key(name))
+}
+
+func (s *gcsStore) url(name string) string {
+	return "gs://" + s.bucket + "/" + s.key(name)
+}
+
+func (s *gcsStore) key(name string) string {
+	return path.Join(s.prefix, name)
+}
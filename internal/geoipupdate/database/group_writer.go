@@ -0,0 +1,226 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// releasesDir is the directory under the database directory that the
+	// releases of an atomic group are kept in.
+	releasesDir = ".releases"
+	// currentLink is the symlink in the database directory to the current
+	// release of an atomic group.
+	currentLink = "current"
+	// stagingPrefix is the prefix of the release directories that are still
+	// being written.
+	stagingPrefix = ".staging-"
+)
+
+// GroupWriter is a Writer for editions that are published together. Writes
+// go to a staging directory, which starts out with the current databases of
+// the group. Commit then turns the staging directory into a new release and
+// atomically points the current symlink in the database directory to it, so
+// that the databases of the group are always from the same update.
+//
+// A GroupWriter is used for a single update.
+type GroupWriter struct {
+	dir        string
+	stagingDir string
+	staging    *LocalFileWriter
+	verbose    bool
+
+	// changed is set by Write, which is called for the editions of the
+	// group concurrently.
+	changed atomic.Bool
+}
+
+// NewGroupWriter creates a GroupWriter for the editions and stages their
+// current databases. The options are those of the LocalFileWriter that
+// writes to the staging directory.
+func NewGroupWriter(
+	databaseDir string,
+	editionIDs []string,
+	preserveFileTime bool,
+	verbose bool,
+	options ...LocalFileWriterOption,
+) (_ *GroupWriter, err error) {
+	releases := filepath.Join(databaseDir, releasesDir)
+	if err := os.MkdirAll(releases, 0o750); err != nil {
+		return nil, fmt.Errorf("creating releases directory: %w", err)
+	}
+
+	stagingDir, err := os.MkdirTemp(releases, stagingPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("creating staging directory: %w", err)
+	}
+	// MkdirTemp creates directories that only the owner can read.
+	//nolint:gosec // the databases are not secret.
+	if err := os.Chmod(stagingDir, 0o755); err != nil {
+		return nil, fmt.Errorf("setting permissions of staging directory: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			//nolint:errcheck // Best effort.
+			_ = os.RemoveAll(stagingDir)
+		}
+	}()
+
+	// The editions that are not updated are carried over into the new
+	// release. The databases are only ever renamed over, so hard links are
+	// safe.
+	for _, editionID := range editionIDs {
+		name := editionID + extension
+		current := filepath.Join(databaseDir, currentLink, name)
+		err := os.Link(current, filepath.Join(stagingDir, name))
+		if err == nil || errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err := copyFile(current, filepath.Join(stagingDir, name)); err != nil {
+			return nil, fmt.Errorf("staging %s: %w", editionID, err)
+		}
+	}
+
+	staging, err := NewLocalFileWriter(stagingDir, preserveFileTime, verbose, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GroupWriter{
+		dir:        databaseDir,
+		stagingDir: stagingDir,
+		staging:    staging,
+		verbose:    verbose,
+	}, nil
+}
+
+// Write writes the database to the staging directory. It is not published
+// until Commit is called.
+func (w *GroupWriter) Write(
+	editionID string,
+	reader io.ReadCloser,
+	newMD5 string,
+	lastModified time.Time,
+) error {
+	if err := w.staging.Write(editionID, reader, newMD5, lastModified); err != nil {
+		return err
+	}
+	w.changed.Store(true)
+	return nil
+}
+
+// GetHash returns the hash of the staged database, which is the current one
+// until it is written.
+func (w *GroupWriter) GetHash(editionID string) (string, error) {
+	return w.staging.GetHash(editionID)
+}
+
+// Path returns the path the database of an edition is published at.
+func (w *GroupWriter) Path(editionID string) string {
+	return GroupPath(w.dir, editionID)
+}
+
+// GroupPath returns the path the database of an edition in an atomic group
+// is published at in databaseDir.
+func GroupPath(databaseDir, editionID string) string {
+	return filepath.Join(databaseDir, currentLink, editionID) + extension
+}
+
+// Commit publishes the staged databases as a new release, if any was
+// written. The previous release is kept, so that programs that still have
+// its databases open can keep using them, and older ones are removed.
+func (w *GroupWriter) Commit() error {
+	if !w.changed.Load() {
+		return w.Abort()
+	}
+
+	if err := syncDir(w.stagingDir); err != nil {
+		return err
+	}
+
+	releases := filepath.Join(w.dir, releasesDir)
+	release := time.Now().UTC().Format("20060102T150405.000000000Z")
+	if err := os.Rename(w.stagingDir, filepath.Join(releases, release)); err != nil {
+		return fmt.Errorf("moving staging directory into place: %w", err)
+	}
+
+	// A symlink cannot be replaced in place, so a new one is renamed over
+	// it.
+	link := filepath.Join(w.dir, currentLink)
+	tempLink := link + tempExtension
+	if err := os.Remove(tempLink); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing temporary symlink: %w", err)
+	}
+	if err := os.Symlink(filepath.Join(releasesDir, release), tempLink); err != nil {
+		return fmt.Errorf("creating symlink to release: %w", err)
+	}
+	if err := os.Rename(tempLink, link); err != nil {
+		return fmt.Errorf("switching to release: %w", err)
+	}
+	if err := syncDir(w.dir); err != nil {
+		return fmt.Errorf("syncing database directory: %w", err)
+	}
+
+	if w.verbose {
+		log.Printf("Published release %s", release)
+	}
+
+	// The release is published, so failing to remove old ones is not an
+	// error.
+	if err := pruneReleases(releases, 2); err != nil {
+		log.Printf("Pruning releases: %v", err)
+	}
+	return nil
+}
+
+// Abort removes the staging directory without publishing it.
+func (w *GroupWriter) Abort() error {
+	if err := os.RemoveAll(w.stagingDir); err != nil {
+		return fmt.Errorf("removing staging directory: %w", err)
+	}
+	return nil
+}
+
+// pruneReleases removes all but the newest keep releases in dir. Staging
+// directories of interrupted updates are removed too.
+func pruneReleases(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("reading releases: %w", err)
+	}
+
+	var releases []string
+	var errs []error
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if strings.HasPrefix(name, stagingPrefix) {
+			// These are left over from interrupted updates, as updates are
+			// serialized by the lock file.
+			if err := os.RemoveAll(filepath.Join(dir, name)); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		releases = append(releases, name)
+	}
+
+	// Release names sort by time.
+	slices.Sort(releases)
+	for _, release := range releases[:max(len(releases)-keep, 0)] {
+		if err := os.RemoveAll(filepath.Join(dir, release)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
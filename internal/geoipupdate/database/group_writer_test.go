@@ -0,0 +1,101 @@
+package database
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/internal/mmdbtest"
+)
+
+func TestGroupWriter(t *testing.T) {
+	dir := t.TempDir()
+	editionIDs := []string{"GeoIP2-City", "GeoIP2-ISP"}
+
+	city1 := mmdbtest.Build("GeoIP2-City", epoch1)
+	isp1 := mmdbtest.Build("GeoIP2-ISP", epoch1)
+	city2 := mmdbtest.Build("GeoIP2-City", epoch2)
+	city3 := mmdbtest.Build("GeoIP2-City", epoch3)
+
+	// The first release has both editions.
+	gw, err := NewGroupWriter(dir, editionIDs, false, false)
+	require.NoError(t, err)
+	writeGroupDatabase(t, gw, "GeoIP2-City", city1)
+	writeGroupDatabase(t, gw, "GeoIP2-ISP", isp1)
+	require.NoError(t, gw.Commit())
+
+	requireCurrent(t, dir, "GeoIP2-City", city1)
+	requireCurrent(t, dir, "GeoIP2-ISP", isp1)
+	first, err := os.Readlink(filepath.Join(dir, "current"))
+	require.NoError(t, err)
+
+	// The edition that is not updated is carried over.
+	gw, err = NewGroupWriter(dir, editionIDs, false, false)
+	require.NoError(t, err)
+	hash, err := gw.GetHash("GeoIP2-ISP")
+	require.NoError(t, err)
+	require.Equal(t, md5Hex(isp1), hash)
+	writeGroupDatabase(t, gw, "GeoIP2-City", city2)
+
+	// Nothing changes until the release is committed.
+	requireCurrent(t, dir, "GeoIP2-City", city1)
+	require.NoError(t, gw.Commit())
+	requireCurrent(t, dir, "GeoIP2-City", city2)
+	requireCurrent(t, dir, "GeoIP2-ISP", isp1)
+	require.Equal(t, filepath.Join(dir, "current", "GeoIP2-City.mmdb"), gw.Path("GeoIP2-City"))
+
+	// An aborted release is discarded.
+	gw, err = NewGroupWriter(dir, editionIDs, false, false)
+	require.NoError(t, err)
+	writeGroupDatabase(t, gw, "GeoIP2-City", city3)
+	require.NoError(t, gw.Abort())
+	requireCurrent(t, dir, "GeoIP2-City", city2)
+
+	// Only the previous release is kept.
+	gw, err = NewGroupWriter(dir, editionIDs, false, false)
+	require.NoError(t, err)
+	writeGroupDatabase(t, gw, "GeoIP2-City", city3)
+	require.NoError(t, gw.Commit())
+	requireCurrent(t, dir, "GeoIP2-City", city3)
+
+	entries, err := os.ReadDir(filepath.Join(dir, ".releases"))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	_, err = os.Stat(filepath.Join(dir, first))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestGroupWriterUnchanged(t *testing.T) {
+	dir := t.TempDir()
+
+	gw, err := NewGroupWriter(dir, []string{"GeoIP2-City"}, false, false)
+	require.NoError(t, err)
+	require.NoError(t, gw.Commit())
+
+	// Nothing is published when no database was written.
+	_, err = os.Lstat(filepath.Join(dir, "current"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+	entries, err := os.ReadDir(filepath.Join(dir, ".releases"))
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func writeGroupDatabase(t *testing.T, gw *GroupWriter, editionID string, db []byte) {
+	t.Helper()
+
+	err := gw.Write(editionID, io.NopCloser(bytes.NewReader(db)), md5Hex(db), time.Time{})
+	require.NoError(t, err)
+}
+
+func requireCurrent(t *testing.T, dir, editionID string, want []byte) {
+	t.Helper()
+
+	got, err := os.ReadFile(filepath.Join(dir, "current", editionID+".mmdb"))
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
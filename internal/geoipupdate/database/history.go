@@ -0,0 +1,278 @@
+package database
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+// historyDir is the directory under the database directory that replaced
+// databases are kept in, as <edition>/<build-date>-<md5>.mmdb.
+const historyDir = ".history"
+
+// ErrNoVersion is returned by Rollback when no kept version of the edition
+// matches.
+var ErrNoVersion = errors.New("no matching version")
+
+// Version is a previous version of a database kept in the history.
+type Version struct {
+	// BuildDate is the date the database was built on, as YYYY-MM-DD.
+	BuildDate string
+	// MD5 is the hash of the database.
+	MD5 string
+	// Path is where the database is kept.
+	Path string
+
+	modTime time.Time
+}
+
+// WithKeepVersions sets the number of replaced databases that are kept for
+// each edition, so that they can be restored with Rollback. None are kept
+// by default.
+func WithKeepVersions(n int) LocalFileWriterOption {
+	return func(w *LocalFileWriter) {
+		w.keepVersions = n
+	}
+}
+
+// Versions returns the kept versions of an edition, newest first.
+func (w *LocalFileWriter) Versions(editionID string) ([]Version, error) {
+	dir := w.historyPath(editionID)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading history of %s: %w", editionID, err)
+	}
+
+	var versions []Version
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), extension)
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		// The build date itself contains dashes.
+		i := strings.LastIndexByte(name, '-')
+		if i == -1 {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("reading history of %s: %w", editionID, err)
+		}
+		versions = append(versions, Version{
+			BuildDate: name[:i],
+			MD5:       name[i+1:],
+			Path:      filepath.Join(dir, entry.Name()),
+			modTime:   info.ModTime(),
+		})
+	}
+
+	slices.SortFunc(versions, func(a, b Version) int {
+		return cmp.Or(
+			strings.Compare(b.BuildDate, a.BuildDate),
+			b.modTime.Compare(a.modTime),
+		)
+	})
+	return versions, nil
+}
+
+// Rollback replaces the database of an edition with a kept version. If to
+// is empty, the newest version that differs from the current database is
+// restored. Otherwise, the newest version built on the date to, as
+// YYYY-MM-DD, or whose MD5 starts with to is restored. The current database
+// is kept in the history in turn.
+func (w *LocalFileWriter) Rollback(editionID, to string) (Version, error) {
+	versions, err := w.Versions(editionID)
+	if err != nil {
+		return Version{}, err
+	}
+	currentMD5, err := w.GetHash(editionID)
+	if err != nil {
+		return Version{}, err
+	}
+
+	i := slices.IndexFunc(versions, func(v Version) bool {
+		if to == "" {
+			return v.MD5 != currentMD5
+		}
+		return v.BuildDate == to || strings.HasPrefix(v.MD5, strings.ToLower(to))
+	})
+	if i == -1 {
+		if to == "" {
+			return Version{}, fmt.Errorf("%w: no previous version of %s is kept", ErrNoVersion, editionID)
+		}
+		return Version{}, fmt.Errorf("%w: no kept version of %s matches %q", ErrNoVersion, editionID, to)
+	}
+	version := versions[i]
+
+	if err := w.restore(editionID, version); err != nil {
+		return Version{}, fmt.Errorf("restoring %s from %s: %w", editionID, version.Path, err)
+	}
+	return version, nil
+}
+
+// restore copies a kept version into place.
+func (w *LocalFileWriter) restore(editionID string, version Version) (err error) {
+	//nolint:gosec // we really need to read this file.
+	src, err := os.Open(version.Path)
+	if err != nil {
+		return fmt.Errorf("opening kept version: %w", err)
+	}
+	defer src.Close()
+
+	databaseFilePath := w.Path(editionID)
+
+	fw, err := newFileWriter(databaseFilePath + tempExtension)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := fw.close(); closeErr != nil {
+			err = errors.Join(err, fmt.Errorf("closing file writer: %w", closeErr))
+		}
+	}()
+
+	if err = fw.write(src); err != nil {
+		return err
+	}
+
+	// a kept version may have been damaged since it was archived.
+	if err = fw.validateHash(version.MD5); err != nil {
+		return err
+	}
+	if _, err = validateDatabase(fw.file.Name(), editionID); err != nil {
+		return err
+	}
+
+	if err = w.replace(editionID, fw.syncAndRename); err != nil {
+		return err
+	}
+
+	if w.preserveFileTime {
+		return setModifiedAtTime(databaseFilePath, version.modTime)
+	}
+	return nil
+}
+
+// archive keeps the current database of an edition in the history. It does
+// nothing if there is no current database.
+func (w *LocalFileWriter) archive(editionID string) error {
+	databaseFilePath := w.Path(editionID)
+
+	md5, err := w.GetHash(editionID)
+	if err != nil {
+		return err
+	}
+	if md5 == ZeroMD5 {
+		return nil
+	}
+
+	dir := w.historyPath(editionID)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("creating history directory: %w", err)
+	}
+
+	path := filepath.Join(dir, buildDate(databaseFilePath)+"-"+md5+extension)
+
+	// The current database is about to be renamed over, so a hard link
+	// keeps it without copying.
+	err = os.Link(databaseFilePath, path)
+	switch {
+	case err == nil, errors.Is(err, os.ErrExist):
+		return nil
+	case errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("linking database into history: %w", err)
+	}
+
+	// Hard links are not supported by every file system.
+	if err := copyFile(databaseFilePath, path); err != nil {
+		return fmt.Errorf("copying database into history: %w", err)
+	}
+	return nil
+}
+
+// prune removes the kept versions of an edition beyond the number to keep.
+func (w *LocalFileWriter) prune(editionID string) error {
+	versions, err := w.Versions(editionID)
+	if err != nil {
+		return err
+	}
+	if len(versions) <= w.keepVersions {
+		return nil
+	}
+
+	for _, version := range versions[w.keepVersions:] {
+		if err := os.Remove(version.Path); err != nil {
+			return fmt.Errorf("removing old version of %s: %w", editionID, err)
+		}
+		if w.verbose {
+			log.Printf("Removed old version %s", version.Path)
+		}
+	}
+	return nil
+}
+
+// historyPath returns the directory the versions of an edition are kept in.
+func (w *LocalFileWriter) historyPath(editionID string) string {
+	return filepath.Join(w.dir, historyDir, editionID)
+}
+
+// buildDate returns the date the database at path was built on. The
+// modification time of the file is used if its metadata cannot be read.
+func buildDate(path string) string {
+	reader, err := maxminddb.Open(path)
+	if err == nil {
+		epoch := reader.Metadata.BuildEpoch
+		//nolint:errcheck // The database was only read.
+		_ = reader.Close()
+		//nolint:gosec // build epochs are well within range.
+		return time.Unix(int64(epoch), 0).UTC().Format(time.DateOnly)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "unknown"
+	}
+	return info.ModTime().UTC().Format(time.DateOnly)
+}
+
+// copyFile copies the file at src to a new file at dst.
+func copyFile(src, dst string) (err error) {
+	//nolint:gosec // we really need to read this file.
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	//nolint:gosec // we really need to write this file.
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := out.Close(); closeErr != nil {
+			err = errors.Join(err, closeErr)
+		}
+		if err != nil {
+			//nolint:errcheck // Best effort.
+			_ = os.Remove(dst)
+		}
+	}()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	return out.Sync()
+}
@@ -0,0 +1,190 @@
+package database
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/internal/mmdbtest"
+)
+
+const (
+	// These are build epochs on consecutive days.
+	epoch1 = 1789430400 // 2026-09-15
+	epoch2 = epoch1 + 24*60*60
+	epoch3 = epoch2 + 24*60*60
+	epoch4 = epoch3 + 24*60*60
+)
+
+func TestKeepVersions(t *testing.T) {
+	dir := t.TempDir()
+	fw, err := NewLocalFileWriter(dir, false, false, WithKeepVersions(2))
+	require.NoError(t, err)
+
+	var dbs [][]byte
+	for _, epoch := range []uint64{epoch1, epoch2, epoch3, epoch4} {
+		db := mmdbtest.Build("GeoIP2-City", epoch)
+		dbs = append(dbs, db)
+		writeDatabase(t, fw, db)
+	}
+
+	versions, err := fw.Versions("GeoIP2-City")
+	require.NoError(t, err)
+	require.Equal(t, []Version{
+		{
+			BuildDate: "2026-09-17",
+			MD5:       md5Hex(dbs[2]),
+			Path:      filepath.Join(dir, ".history", "GeoIP2-City", "2026-09-17-"+md5Hex(dbs[2])+".mmdb"),
+		},
+		{
+			BuildDate: "2026-09-16",
+			MD5:       md5Hex(dbs[1]),
+			Path:      filepath.Join(dir, ".history", "GeoIP2-City", "2026-09-16-"+md5Hex(dbs[1])+".mmdb"),
+		},
+	}, withoutModTimes(versions))
+
+	for i, version := range versions {
+		got, err := os.ReadFile(version.Path)
+		require.NoError(t, err)
+		require.Equal(t, dbs[2-i], got)
+	}
+
+	got, err := os.ReadFile(fw.Path("GeoIP2-City"))
+	require.NoError(t, err)
+	require.Equal(t, dbs[3], got)
+}
+
+func TestNoVersionsKept(t *testing.T) {
+	dir := t.TempDir()
+	fw, err := NewLocalFileWriter(dir, false, false)
+	require.NoError(t, err)
+
+	writeDatabase(t, fw, mmdbtest.Build("GeoIP2-City", epoch1))
+	writeDatabase(t, fw, mmdbtest.Build("GeoIP2-City", epoch2))
+
+	_, err = os.Stat(filepath.Join(dir, ".history"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRollback(t *testing.T) {
+	dbs := [][]byte{
+		mmdbtest.Build("GeoIP2-City", epoch1),
+		mmdbtest.Build("GeoIP2-City", epoch2),
+		mmdbtest.Build("GeoIP2-City", epoch3),
+	}
+
+	tests := []struct {
+		description string
+		to          string
+		// want is the index of the restored database.
+		want int
+		err  string
+	}{
+		{
+			description: "previous version",
+			want:        1,
+		},
+		{
+			description: "build date",
+			to:          "2026-09-15",
+			want:        0,
+		},
+		{
+			description: "MD5 prefix",
+			to:          md5Hex(dbs[0])[:8],
+			want:        0,
+		},
+		{
+			description: "upper case MD5",
+			to:          strings.ToUpper(md5Hex(dbs[1])),
+			want:        1,
+		},
+		{
+			description: "no match",
+			to:          "2026-01-01",
+			err:         `no matching version: no kept version of GeoIP2-City matches "2026-01-01"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			fw, err := NewLocalFileWriter(t.TempDir(), false, false, WithKeepVersions(5))
+			require.NoError(t, err)
+			for _, db := range dbs {
+				writeDatabase(t, fw, db)
+			}
+
+			version, err := fw.Rollback("GeoIP2-City", test.to)
+
+			got, readErr := os.ReadFile(fw.Path("GeoIP2-City"))
+			require.NoError(t, readErr)
+
+			if test.err != "" {
+				require.ErrorIs(t, err, ErrNoVersion)
+				require.EqualError(t, err, test.err)
+				require.Equal(t, dbs[2], got)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, md5Hex(dbs[test.want]), version.MD5)
+			require.Equal(t, dbs[test.want], got)
+
+			// The database that was rolled back from is kept in turn.
+			versions, err := fw.Versions("GeoIP2-City")
+			require.NoError(t, err)
+			require.Equal(t, md5Hex(dbs[2]), versions[0].MD5)
+		})
+	}
+}
+
+func TestRollbackDamagedVersion(t *testing.T) {
+	fw, err := NewLocalFileWriter(t.TempDir(), false, false, WithKeepVersions(5))
+	require.NoError(t, err)
+
+	writeDatabase(t, fw, mmdbtest.Build("GeoIP2-City", epoch1))
+	current := mmdbtest.Build("GeoIP2-City", epoch2)
+	writeDatabase(t, fw, current)
+
+	versions, err := fw.Versions("GeoIP2-City")
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	require.NoError(t, os.WriteFile(versions[0].Path, []byte("damaged"), 0o600))
+
+	_, err = fw.Rollback("GeoIP2-City", "")
+	require.ErrorContains(t, err, "does not match expected md5")
+
+	got, err := os.ReadFile(fw.Path("GeoIP2-City"))
+	require.NoError(t, err)
+	require.Equal(t, current, got)
+}
+
+func TestRollbackWithoutHistory(t *testing.T) {
+	fw, err := NewLocalFileWriter(t.TempDir(), false, false, WithKeepVersions(5))
+	require.NoError(t, err)
+
+	writeDatabase(t, fw, mmdbtest.Build("GeoIP2-City", epoch1))
+
+	_, err = fw.Rollback("GeoIP2-City", "")
+	require.EqualError(t, err, "no matching version: no previous version of GeoIP2-City is kept")
+}
+
+func writeDatabase(t *testing.T, fw *LocalFileWriter, db []byte) {
+	t.Helper()
+
+	err := fw.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(db)), md5Hex(db), time.Time{})
+	require.NoError(t, err)
+}
+
+func withoutModTimes(versions []Version) []Version {
+	for i := range versions {
+		versions[i].modTime = time.Time{}
+	}
+	return versions
+}
@@ -11,8 +11,11 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
 )
 
 const (
@@ -26,6 +29,30 @@ type LocalFileWriter struct {
 	dir              string
 	preserveFileTime bool
 	verbose          bool
+	allowDowngrade   bool
+	// allowDowngradeFor are editions that may be downgraded even if
+	// allowDowngrade is false, e.g., because they are pinned to a date.
+	allowDowngradeFor []string
+	keepVersions      int
+}
+
+// LocalFileWriterOption is an option for configuring LocalFileWriter.
+type LocalFileWriterOption func(*LocalFileWriter)
+
+// WithAllowDowngrade sets whether a database may be replaced by one that was
+// built before it. By default, such databases are refused.
+func WithAllowDowngrade(allow bool) LocalFileWriterOption {
+	return func(w *LocalFileWriter) {
+		w.allowDowngrade = allow
+	}
+}
+
+// WithAllowDowngradeFor sets editions whose databases may be replaced by
+// ones that were built before them, e.g., because they are pinned to a date.
+func WithAllowDowngradeFor(editionIDs ...string) LocalFileWriterOption {
+	return func(w *LocalFileWriter) {
+		w.allowDowngradeFor = editionIDs
+	}
 }
 
 // NewLocalFileWriter create a LocalFileWriter.
@@ -33,21 +60,27 @@ func NewLocalFileWriter(
 	databaseDir string,
 	preserveFileTime bool,
 	verbose bool,
+	options ...LocalFileWriterOption,
 ) (*LocalFileWriter, error) {
 	err := os.MkdirAll(filepath.Dir(databaseDir), 0o750)
 	if err != nil {
 		return nil, fmt.Errorf("creating database directory: %w", err)
 	}
 
-	return &LocalFileWriter{
+	w := &LocalFileWriter{
 		dir:              databaseDir,
 		preserveFileTime: preserveFileTime,
 		verbose:          verbose,
-	}, nil
+	}
+	for _, opt := range options {
+		opt(w)
+	}
+	return w, nil
 }
 
 // Write writes the database to a file. The database content will be read from
-// reader.
+// reader. If newMD5 is empty because it is not known in advance, e.g., for a
+// database pinned to a date, the hash is not checked.
 func (w *LocalFileWriter) Write(
 	editionID string,
 	reader io.ReadCloser,
@@ -64,7 +97,7 @@ func (w *LocalFileWriter) Write(
 		}
 	}()
 
-	databaseFilePath := w.getFilePath(editionID)
+	databaseFilePath := w.Path(editionID)
 
 	// Write into a temporary file.
 	fw, err := newFileWriter(databaseFilePath + tempExtension)
@@ -85,19 +118,28 @@ func (w *LocalFileWriter) Write(
 	}
 
 	// make sure the hash of the temp file matches the expected hash.
-	if err = fw.validateHash(newMD5); err != nil {
-		return fmt.Errorf("validating hash for %s: %w", editionID, err)
+	if newMD5 != "" {
+		if err = fw.validateHash(newMD5); err != nil {
+			return fmt.Errorf("validating hash for %s: %w", editionID, err)
+		}
 	}
 
-	// move the temoporary database file into its final location and
-	// sync the directory.
-	if err = fw.syncAndRename(databaseFilePath); err != nil {
-		return fmt.Errorf("renaming temp file: %w", err)
+	// make sure the temp file is a database of the edition, so that a
+	// truncated or corrupt file never replaces a working one.
+	metadata, err := validateDatabase(fw.file.Name(), editionID)
+	if err != nil {
+		return fmt.Errorf("validating database for %s: %w", editionID, err)
 	}
 
-	// sync database directory.
-	if err = syncDir(filepath.Dir(databaseFilePath)); err != nil {
-		return fmt.Errorf("syncing database directory: %w", err)
+	// make sure that the new database is not older than the current one.
+	if !w.allowDowngrade && !slices.Contains(w.allowDowngradeFor, editionID) {
+		if err = checkNotOlder(databaseFilePath, metadata.BuildEpoch); err != nil {
+			return fmt.Errorf("checking build of %s: %w", editionID, err)
+		}
+	}
+
+	if err = w.replace(editionID, fw.syncAndRename); err != nil {
+		return err
 	}
 
 	// check if we need to set the file's modified at time
@@ -114,9 +156,103 @@ func (w *LocalFileWriter) Write(
 	return nil
 }
 
+// replace moves the new database of the edition into place with install,
+// which renames its temporary file to the path it is given, keeping the
+// current one in the history if versions are kept.
+func (w *LocalFileWriter) replace(editionID string, install func(string) error) error {
+	databaseFilePath := w.Path(editionID)
+
+	if w.keepVersions > 0 {
+		if err := w.archive(editionID); err != nil {
+			return fmt.Errorf("keeping the current version of %s: %w", editionID, err)
+		}
+	}
+
+	// move the temoporary database file into its final location and
+	// sync the directory.
+	if err := install(databaseFilePath); err != nil {
+		return fmt.Errorf("renaming temp file: %w", err)
+	}
+
+	// sync database directory.
+	if err := syncDir(filepath.Dir(databaseFilePath)); err != nil {
+		return fmt.Errorf("syncing database directory: %w", err)
+	}
+
+	// The new database is in place, so failing to remove old versions is
+	// not an error.
+	if w.keepVersions > 0 {
+		if err := w.prune(editionID); err != nil {
+			log.Printf("Pruning the history of %s: %v", editionID, err)
+		}
+	}
+
+	return nil
+}
+
+// link installs the database at source, which was already written and
+// validated elsewhere on the same file system, as a hard link rather than a
+// copy. The link is validated and checked like a written database. linked
+// is false if the hard link could not be created, e.g., because source is
+// on another file system, in which case nothing was changed.
+func (w *LocalFileWriter) link(
+	editionID string,
+	source string,
+	lastModified time.Time,
+) (linked bool, err error) {
+	databaseFilePath := w.Path(editionID)
+	tempPath := databaseFilePath + tempExtension
+
+	// A temporary file left behind by an interrupted update is in the way.
+	if err := os.Remove(tempPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, fmt.Errorf("removing temporary file: %w", err)
+	}
+	if err := os.Link(source, tempPath); err != nil {
+		return false, nil //nolint:nilerr // the database is copied instead.
+	}
+	defer func() {
+		if err := os.Remove(tempPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("removing temporary file: %v", err)
+		}
+	}()
+
+	metadata, err := validateDatabase(tempPath, editionID)
+	if err != nil {
+		return true, fmt.Errorf("validating database for %s: %w", editionID, err)
+	}
+
+	if !w.allowDowngrade && !slices.Contains(w.allowDowngradeFor, editionID) {
+		if err := checkNotOlder(databaseFilePath, metadata.BuildEpoch); err != nil {
+			return true, fmt.Errorf("checking build of %s: %w", editionID, err)
+		}
+	}
+
+	err = w.replace(editionID, func(path string) error {
+		if err := os.Rename(tempPath, path); err != nil {
+			return fmt.Errorf("moving database into place: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return true, err
+	}
+
+	// The times are those of source too, as the link shares them.
+	if w.preserveFileTime {
+		if err := setModifiedAtTime(databaseFilePath, lastModified); err != nil {
+			return true, err
+		}
+	}
+
+	if w.verbose {
+		log.Printf("Database %s successfully linked to %s", editionID, source)
+	}
+	return true, nil
+}
+
 // GetHash returns the hash of the current database file.
 func (w *LocalFileWriter) GetHash(editionID string) (string, error) {
-	databaseFilePath := w.getFilePath(editionID)
+	databaseFilePath := w.Path(editionID)
 	//nolint:gosec // we really need to read this file.
 	database, err := os.Open(databaseFilePath)
 	if err != nil {
@@ -147,8 +283,8 @@ func (w *LocalFileWriter) GetHash(editionID string) (string, error) {
 	return result, nil
 }
 
-// getFilePath construct the file path for a database edition.
-func (w *LocalFileWriter) getFilePath(editionID string) string {
+// Path returns the path of the database file for an edition.
+func (w *LocalFileWriter) Path(editionID string) string {
 	return filepath.Join(w.dir, editionID) + extension
 }
 
@@ -206,7 +342,8 @@ func (w *fileWriter) validateHash(h string) error {
 	tempFileHash := byteToString(w.md5Writer.Sum(nil))
 	if !strings.EqualFold(h, tempFileHash) {
 		return fmt.Errorf(
-			"md5 of new database (%s) does not match expected md5 (%s)",
+			"%w: md5 of new database (%s) does not match expected md5 (%s)",
+			internal.ErrHashMismatch,
 			tempFileHash,
 			h,
 		)
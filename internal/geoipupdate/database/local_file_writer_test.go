@@ -1,6 +1,9 @@
 package database
 
 import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
 	"io"
 	"os"
 	"strings"
@@ -8,12 +11,19 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
+	"github.com/maxmind/geoipupdate/v8/internal/mmdbtest"
 )
 
 // TestLocalFileWriterWrite tests functionality of the LocalFileWriter.Write method.
 func TestLocalFileWriterWrite(t *testing.T) {
 	testTime := time.Date(2023, 4, 10, 12, 47, 31, 0, time.UTC)
 
+	db := mmdbtest.Build("GeoIP2-City", 1)
+	dbMD5 := md5Hex(db)
+	notADatabase := "database content"
+
 	tests := []struct {
 		description      string
 		checkErr         func(require.TestingT, error, ...any)
@@ -30,8 +40,8 @@ func TestLocalFileWriterWrite(t *testing.T) {
 			preserveFileTime: true,
 			checkTime:        require.Equal,
 			editionID:        "GeoIP2-City",
-			reader:           io.NopCloser(strings.NewReader("database content")),
-			newMD5:           "cfa36ddc8279b5483a5aa25e9a6151f4",
+			reader:           io.NopCloser(bytes.NewReader(db)),
+			newMD5:           dbMD5,
 			lastModified:     testTime,
 		}, {
 			description:      "hash does not match",
@@ -39,7 +49,7 @@ func TestLocalFileWriterWrite(t *testing.T) {
 			preserveFileTime: true,
 			checkTime:        require.Equal,
 			editionID:        "GeoIP2-City",
-			reader:           io.NopCloser(strings.NewReader("database content")),
+			reader:           io.NopCloser(bytes.NewReader(db)),
 			newMD5:           "badhash",
 			lastModified:     testTime,
 		}, {
@@ -48,8 +58,8 @@ func TestLocalFileWriterWrite(t *testing.T) {
 			preserveFileTime: true,
 			checkTime:        require.Equal,
 			editionID:        "GeoIP2-City",
-			reader:           io.NopCloser(strings.NewReader("database content")),
-			newMD5:           "cfa36ddc8279b5483a5aa25e9a6151f4",
+			reader:           io.NopCloser(bytes.NewReader(db)),
+			newMD5:           dbMD5,
 			lastModified:     testTime,
 		}, {
 			description:      "do not preserve file modification time",
@@ -57,8 +67,35 @@ func TestLocalFileWriterWrite(t *testing.T) {
 			preserveFileTime: false,
 			checkTime:        require.NotEqual,
 			editionID:        "GeoIP2-City",
-			reader:           io.NopCloser(strings.NewReader("database content")),
-			newMD5:           "CFA36DDC8279B5483A5AA25E9A6151F4",
+			reader:           io.NopCloser(bytes.NewReader(db)),
+			newMD5:           strings.ToUpper(dbMD5),
+			lastModified:     testTime,
+		}, {
+			description:      "hash is not known",
+			checkErr:         require.NoError,
+			preserveFileTime: true,
+			checkTime:        require.Equal,
+			editionID:        "GeoIP2-City",
+			reader:           io.NopCloser(bytes.NewReader(db)),
+			newMD5:           "",
+			lastModified:     testTime,
+		}, {
+			description:      "not a database",
+			checkErr:         requireInvalidDatabase,
+			preserveFileTime: true,
+			checkTime:        require.Equal,
+			editionID:        "GeoIP2-City",
+			reader:           io.NopCloser(strings.NewReader(notADatabase)),
+			newMD5:           md5Hex([]byte(notADatabase)),
+			lastModified:     testTime,
+		}, {
+			description:      "database of another edition",
+			checkErr:         requireInvalidDatabase,
+			preserveFileTime: true,
+			checkTime:        require.Equal,
+			editionID:        "GeoLite2-City",
+			reader:           io.NopCloser(bytes.NewReader(db)),
+			newMD5:           dbMD5,
 			lastModified:     testTime,
 		},
 	}
@@ -77,8 +114,13 @@ func TestLocalFileWriterWrite(t *testing.T) {
 				test.lastModified,
 			)
 			test.checkErr(t, err)
-			if err == nil {
-				database, err := os.Stat(fw.getFilePath(test.editionID))
+			if err != nil {
+				// The database is not replaced and the temp file is removed.
+				entries, err := os.ReadDir(tempDir)
+				require.NoError(t, err)
+				require.Empty(t, entries)
+			} else {
+				database, err := os.Stat(fw.Path(test.editionID))
 				require.NoError(t, err)
 
 				test.checkTime(t, database.ModTime().UTC(), testTime)
@@ -90,8 +132,9 @@ func TestLocalFileWriterWrite(t *testing.T) {
 // TestLocalFileWriterGetHash tests functionality of the LocalFileWriter.GetHash method.
 func TestLocalFileWriterGetHash(t *testing.T) {
 	editionID := "GeoIP2-City"
-	reader := io.NopCloser(strings.NewReader("database content"))
-	newMD5 := "cfa36ddc8279b5483a5aa25e9a6151f4"
+	db := mmdbtest.Build(editionID, 1)
+	reader := io.NopCloser(bytes.NewReader(db))
+	newMD5 := md5Hex(db)
 	lastModified := time.Time{}
 
 	tempDir := t.TempDir()
@@ -112,3 +155,89 @@ func TestLocalFileWriterGetHash(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, ZeroMD5, hash)
 }
+
+// TestLocalFileWriterDowngrade tests that databases are not replaced with
+// older builds unless downgrades are allowed.
+func TestLocalFileWriterDowngrade(t *testing.T) {
+	current := mmdbtest.Build("GeoIP2-City", 2000)
+
+	tests := []struct {
+		description    string
+		current        []byte
+		buildEpoch     uint64
+		allowDowngrade bool
+		pinned         bool
+		err            string
+	}{
+		{
+			description: "newer build",
+			current:     current,
+			buildEpoch:  3000,
+		},
+		{
+			description: "same build",
+			current:     current,
+			buildEpoch:  2000,
+		},
+		{
+			description: "older build",
+			current:     current,
+			buildEpoch:  1000,
+			err: "checking build of GeoIP2-City: downgrade refused: the new database was " +
+				"built at 1970-01-01T00:16:40Z, before the current one, built at 1970-01-01T00:33:20Z",
+		},
+		{
+			description:    "older build with downgrades allowed",
+			current:        current,
+			buildEpoch:     1000,
+			allowDowngrade: true,
+		},
+		{
+			description: "older build of a pinned edition",
+			current:     current,
+			buildEpoch:  1000,
+			pinned:      true,
+		},
+		{
+			description: "current database is not valid",
+			current:     []byte("database content"),
+			buildEpoch:  1000,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			opts := []LocalFileWriterOption{WithAllowDowngrade(test.allowDowngrade)}
+			if test.pinned {
+				opts = append(opts, WithAllowDowngradeFor("GeoIP2-Country", "GeoIP2-City"))
+			}
+			fw, err := NewLocalFileWriter(t.TempDir(), false, false, opts...)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(fw.Path("GeoIP2-City"), test.current, 0o600))
+
+			db := mmdbtest.Build("GeoIP2-City", test.buildEpoch)
+			err = fw.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(db)), md5Hex(db), time.Time{})
+
+			got, readErr := os.ReadFile(fw.Path("GeoIP2-City"))
+			require.NoError(t, readErr)
+
+			if test.err == "" {
+				require.NoError(t, err)
+				require.Equal(t, db, got)
+				return
+			}
+			require.ErrorIs(t, err, internal.ErrDowngradeRefused)
+			require.EqualError(t, err, test.err)
+			require.Equal(t, test.current, got, "the current database is untouched")
+		})
+	}
+}
+
+func requireInvalidDatabase(t require.TestingT, err error, _ ...any) {
+	require.ErrorIs(t, err, internal.ErrInvalidDatabase)
+}
+
+func md5Hex(b []byte) string {
+	sum := md5.Sum(b)
+	return hex.EncodeToString(sum[:])
+}
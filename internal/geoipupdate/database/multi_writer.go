@@ -0,0 +1,220 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
+)
+
+// Destination is a Writer that MultiWriter writes the databases to, and the
+// name its results are reported under.
+type Destination struct {
+	Name   string
+	Writer Writer
+}
+
+// MultiWriter is a Writer that writes every database to several
+// destinations, e.g., the directories of applications that run as different
+// users. A database is downloaded once, validated, and then written to each
+// destination, which validates and checks it again. Where a LocalFileWriter
+// is on the same file system as one that was already written, the database
+// is installed as a hard link rather than copied.
+//
+// A destination failing does not keep the database from being written to
+// the others. The outcome for each destination of the last write of an
+// edition is returned by DestinationResults.
+type MultiWriter struct {
+	destinations []Destination
+	verbose      bool
+
+	mu      sync.Mutex
+	results map[string][]DestinationResult
+}
+
+// NewMultiWriter creates a MultiWriter. The first destination is the
+// primary one, whose path is reported as the path of the databases.
+func NewMultiWriter(destinations []Destination, verbose bool) *MultiWriter {
+	return &MultiWriter{
+		destinations: destinations,
+		verbose:      verbose,
+		results:      map[string][]DestinationResult{},
+	}
+}
+
+// Write is WriteContext with the background context.
+func (w *MultiWriter) Write(
+	editionID string,
+	reader io.ReadCloser,
+	newMD5 string,
+	lastModified time.Time,
+) error {
+	return w.WriteContext(context.Background(), editionID, reader, newMD5, lastModified)
+}
+
+// WriteContext validates the database read from reader and writes it to
+// every destination that does not have it yet. If newMD5 is empty, the hash
+// is not checked. ctx is passed on to the destinations that are
+// ContextWriters.
+//
+// The error joins the errors of the destinations that failed. If none
+// failed and none was updated, but some refused the database as a
+// downgrade, it matches internal.ErrDowngradeRefused.
+func (w *MultiWriter) WriteContext(
+	ctx context.Context,
+	editionID string,
+	reader io.ReadCloser,
+	newMD5 string,
+	lastModified time.Time,
+) error {
+	// The database is read once, into a temporary file that every
+	// destination reads it from.
+	return withSpooledDatabase(editionID, reader, newMD5, func(db *spooledDatabase) error {
+		return w.writeAll(ctx, editionID, db, lastModified)
+	})
+}
+
+// writeAll writes the database to every destination and records the
+// results.
+func (w *MultiWriter) writeAll(
+	ctx context.Context,
+	editionID string,
+	db *spooledDatabase,
+	lastModified time.Time,
+) error {
+	md5 := byteToString(db.md5)
+
+	results := make([]DestinationResult, 0, len(w.destinations))
+	// linkSources are the local copies of the database so far.
+	var linkSources []string
+	var errs, refusals []error
+	updated := false
+	for _, d := range w.destinations {
+		status, linked, err := w.writeTo(ctx, d, editionID, db, md5, lastModified, linkSources)
+		result := DestinationResult{Destination: d.Name, Status: status, Linked: linked}
+		switch {
+		case errors.Is(err, internal.ErrDowngradeRefused):
+			result.Status = StatusDowngradeRefused
+			result.Error = err.Error()
+			refusals = append(refusals, fmt.Errorf("writing to %s: %w", d.Name, err))
+		case err != nil:
+			result.Status = StatusFailed
+			result.Error = err.Error()
+			errs = append(errs, fmt.Errorf("writing to %s: %w", d.Name, err))
+		default:
+			if result.Status == StatusUpdated {
+				updated = true
+			}
+			if local, ok := d.Writer.(*LocalFileWriter); ok {
+				linkSources = append(linkSources, local.Path(editionID))
+			}
+		}
+		results = append(results, result)
+
+		if w.verbose {
+			log.Printf("Database %s %s in %s", editionID, result.Status, d.Name)
+		}
+	}
+
+	w.mu.Lock()
+	w.results[editionID] = results
+	w.mu.Unlock()
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	if !updated {
+		return errors.Join(refusals...)
+	}
+	return nil
+}
+
+// writeTo writes the database to a destination, unless the destination
+// already has it, and returns StatusUpdated or StatusUpToDate. Local
+// destinations link to one of linkSources if they can.
+func (w *MultiWriter) writeTo(
+	ctx context.Context,
+	d Destination,
+	editionID string,
+	db *spooledDatabase,
+	md5 string,
+	lastModified time.Time,
+	linkSources []string,
+) (status string, linked bool, err error) {
+	current, err := GetHashContext(ctx, d.Writer, editionID)
+	if err != nil {
+		return "", false, fmt.Errorf("getting hash: %w", err)
+	}
+	if strings.EqualFold(current, md5) {
+		return StatusUpToDate, false, nil
+	}
+
+	if local, ok := d.Writer.(*LocalFileWriter); ok {
+		for _, source := range linkSources {
+			linked, err := local.link(editionID, source, lastModified)
+			if linked || err != nil {
+				return StatusUpdated, linked, err
+			}
+		}
+	}
+
+	// Every destination gets a descriptor of its own, which it closes.
+	f, err := os.Open(db.fw.file.Name())
+	if err != nil {
+		return "", false, fmt.Errorf("opening the temp file: %w", err)
+	}
+	return StatusUpdated, false, WriteContext(ctx, d.Writer, editionID, f, md5, lastModified)
+}
+
+// GetHash is GetHashContext with the background context.
+func (w *MultiWriter) GetHash(editionID string) (string, error) {
+	return w.GetHashContext(context.Background(), editionID)
+}
+
+// GetHashContext returns the MD5 of the current database of an edition if
+// every destination has the same one. Otherwise, it returns ZeroMD5, so that
+// the database is downloaded and written to the destinations that lack it.
+func (w *MultiWriter) GetHashContext(ctx context.Context, editionID string) (string, error) {
+	var hash string
+	for i, d := range w.destinations {
+		h, err := GetHashContext(ctx, d.Writer, editionID)
+		if err != nil {
+			return "", fmt.Errorf("getting hash from %s: %w", d.Name, err)
+		}
+		if i > 0 && !strings.EqualFold(h, hash) {
+			if w.verbose {
+				log.Printf("The destinations of %s have different databases, returning zeroed hash", editionID)
+			}
+			return ZeroMD5, nil
+		}
+		hash = h
+	}
+	return hash, nil
+}
+
+// Path returns the path of the database of an edition in the primary
+// destination, if it has one.
+func (w *MultiWriter) Path(editionID string) string {
+	if len(w.destinations) == 0 {
+		return ""
+	}
+	if p, ok := w.destinations[0].Writer.(interface{ Path(string) string }); ok {
+		return p.Path(editionID)
+	}
+	return ""
+}
+
+// DestinationResults returns the outcome for each destination of the last
+// write of an edition, in the order of the destinations.
+func (w *MultiWriter) DestinationResults(editionID string) []DestinationResult {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.results[editionID]
+}
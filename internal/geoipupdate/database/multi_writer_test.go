@@ -0,0 +1,141 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
+	"github.com/maxmind/geoipupdate/v8/internal/mmdbtest"
+)
+
+func TestMultiWriter(t *testing.T) {
+	nginxDir := t.TempDir()
+	appDir := t.TempDir()
+
+	nginx, err := NewLocalFileWriter(nginxDir, false, false)
+	require.NoError(t, err)
+	app, err := NewLocalFileWriter(appDir, false, false)
+	require.NoError(t, err)
+	broken := &failingWriter{}
+
+	w := NewMultiWriter([]Destination{
+		{Name: nginxDir, Writer: nginx},
+		{Name: appDir, Writer: app},
+		{Name: "broken", Writer: broken},
+	}, false)
+	require.Equal(t, nginx.Path("GeoIP2-City"), w.Path("GeoIP2-City"))
+
+	// A destination failing does not keep the others from being written.
+	broken.err = errors.New("disk full")
+	city1 := mmdbtest.Build("GeoIP2-City", epoch1)
+	err = w.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(city1)), md5Hex(city1), time.Time{})
+	require.ErrorContains(t, err, "writing to broken: disk full")
+	require.Equal(t, []DestinationResult{
+		{Destination: nginxDir, Status: StatusUpdated},
+		{Destination: appDir, Status: StatusUpdated, Linked: true},
+		{Destination: "broken", Status: StatusFailed, Error: "disk full"},
+	}, w.DestinationResults("GeoIP2-City"))
+
+	requireDatabase(t, nginx.Path("GeoIP2-City"), city1)
+	requireDatabase(t, app.Path("GeoIP2-City"), city1)
+	nginxInfo, err := os.Stat(nginx.Path("GeoIP2-City"))
+	require.NoError(t, err)
+	appInfo, err := os.Stat(app.Path("GeoIP2-City"))
+	require.NoError(t, err)
+	require.True(t, os.SameFile(nginxInfo, appInfo), "the databases are hard linked")
+
+	// Until every destination has the database, it is downloaded again.
+	hash, err := w.GetHash("GeoIP2-City")
+	require.NoError(t, err)
+	require.Equal(t, ZeroMD5, hash)
+
+	// Only the destinations that lack it are written.
+	broken.err = nil
+	err = w.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(city1)), md5Hex(city1), time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, []DestinationResult{
+		{Destination: nginxDir, Status: StatusUpToDate},
+		{Destination: appDir, Status: StatusUpToDate},
+		{Destination: "broken", Status: StatusUpdated},
+	}, w.DestinationResults("GeoIP2-City"))
+	require.Equal(t, city1, broken.databases["GeoIP2-City"])
+
+	hash, err = w.GetHash("GeoIP2-City")
+	require.NoError(t, err)
+	require.Equal(t, md5Hex(city1), hash)
+
+	// Linked databases are validated and checked for downgrades like
+	// written ones.
+	city2 := mmdbtest.Build("GeoIP2-City", epoch2)
+	other, err := NewLocalFileWriter(t.TempDir(), false, false)
+	require.NoError(t, err)
+	err = other.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(city2)), md5Hex(city2), time.Time{})
+	require.NoError(t, err)
+	fresh, err := NewLocalFileWriter(t.TempDir(), false, false)
+	require.NoError(t, err)
+	w = NewMultiWriter([]Destination{
+		{Name: "fresh", Writer: fresh},
+		{Name: "other", Writer: other},
+	}, false)
+
+	err = w.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(city1)), md5Hex(city1), time.Time{})
+	require.NoError(t, err, "refusals are not errors if another destination was updated")
+	require.Equal(t, StatusUpdated, w.DestinationResults("GeoIP2-City")[0].Status)
+	require.Equal(t, StatusDowngradeRefused, w.DestinationResults("GeoIP2-City")[1].Status)
+	require.True(t, w.DestinationResults("GeoIP2-City")[1].Linked)
+	requireDatabase(t, other.Path("GeoIP2-City"), city2)
+
+	// A database that every destination refuses is refused.
+	city0 := mmdbtest.Build("GeoIP2-City", epoch1-24*60*60)
+	err = w.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(city0)), md5Hex(city0), time.Time{})
+	require.ErrorIs(t, err, internal.ErrDowngradeRefused)
+
+	// Invalid databases are not written anywhere.
+	err = w.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(city2)), "badhash", time.Time{})
+	require.ErrorIs(t, err, internal.ErrHashMismatch)
+	requireDatabase(t, fresh.Path("GeoIP2-City"), city1)
+}
+
+func requireDatabase(t *testing.T, path string, expected []byte) {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, expected, b)
+}
+
+// failingWriter is a Writer that keeps the databases in memory, or fails
+// with err if it is set.
+type failingWriter struct {
+	err       error
+	databases map[string][]byte
+}
+
+func (w *failingWriter) Write(editionID string, reader io.ReadCloser, _ string, _ time.Time) error {
+	defer reader.Close()
+	if w.err != nil {
+		return w.err
+	}
+	b, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if w.databases == nil {
+		w.databases = map[string][]byte{}
+	}
+	w.databases[editionID] = b
+	return nil
+}
+
+func (w *failingWriter) GetHash(editionID string) (string, error) {
+	b, ok := w.databases[editionID]
+	if !ok {
+		return ZeroMD5, nil
+	}
+	return md5Hex(b), nil
+}
@@ -0,0 +1,310 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
+)
+
+// These are the metadata keys of the objects written by ObjectWriter.
+const (
+	metadataMD5        = "md5"
+	metadataBuildDate  = "build-date"
+	metadataBuildEpoch = "build-epoch"
+)
+
+// objectStore stores objects under a prefix in an object storage service.
+// Names are relative to the prefix. Missing objects are errors matching
+// internal.ErrNotFound, and refused conditional copies are errors matching
+// internal.ErrPreconditionFailed.
+type objectStore interface {
+	// head returns the attributes of an object.
+	head(ctx context.Context, name string) (objectAttrs, error)
+	// put stores the size bytes of f, whose MD5 is md5, with the metadata.
+	put(ctx context.Context, name string, f *os.File, size int64, md5 []byte, metadata map[string]string) error
+	// copy copies an object with its metadata if the version of the
+	// destination is ifVersion or, if ifVersion is empty, if there is no
+	// destination. Stores that cannot make copies conditional copy
+	// regardless.
+	copy(ctx context.Context, from, to, ifVersion string) error
+	// remove deletes an object.
+	remove(ctx context.Context, name string) error
+	// url returns the URL of an object.
+	url(name string) string
+}
+
+// objectAttrs are the attributes of an object.
+type objectAttrs struct {
+	size     int64
+	metadata map[string]string
+	// version changes whenever the object is replaced, e.g., it is the
+	// generation of GCS objects or the ETag of Azure blobs.
+	version string
+}
+
+// ObjectWriter is a Writer that stores the databases as <edition>.mmdb
+// objects in object storage. The MD5, build date and build epoch of each
+// database are stored in the metadata of its object, as the ETag is not
+// the MD5 of multipart uploads.
+//
+// A database is validated locally, uploaded next to its object with its
+// MD5, which the store verifies, and only then copied into place, so that
+// readers never see a partial or invalid database. Where the store supports
+// it, the copy is conditional on the object not having changed since Write
+// read its attributes, so that concurrent updaters cannot replace each
+// other's databases with older ones.
+type ObjectWriter struct {
+	store   objectStore
+	verbose bool
+	// id tells the temporary objects of concurrent updaters apart.
+	id string
+	// allowDowngrade and allowDowngradeFor are as for LocalFileWriter.
+	allowDowngrade    bool
+	allowDowngradeFor []string
+}
+
+// ObjectWriterOption is an option for configuring ObjectWriter.
+type ObjectWriterOption func(*ObjectWriter)
+
+// WithObjectAllowDowngrade sets whether a database may be replaced by one
+// that was built before it, and editions for which it may be regardless,
+// e.g., because they are pinned to a date.
+func WithObjectAllowDowngrade(allow bool, editionIDs ...string) ObjectWriterOption {
+	return func(w *ObjectWriter) {
+		w.allowDowngrade = allow
+		w.allowDowngradeFor = editionIDs
+	}
+}
+
+// IsObjectURL returns whether the database directory dir is the URL of
+// object storage, s3://bucket/prefix, gs://bucket/prefix or
+// azblob://container/prefix.
+func IsObjectURL(dir string) bool {
+	return strings.HasPrefix(dir, "s3://") ||
+		strings.HasPrefix(dir, "gs://") ||
+		strings.HasPrefix(dir, "azblob://")
+}
+
+// NewObjectWriter creates an ObjectWriter for the object storage at rawURL,
+// which must be s3://bucket/prefix, gs://bucket/prefix or
+// azblob://container/prefix. httpClient is used for the requests.
+func NewObjectWriter(
+	rawURL string,
+	httpClient *http.Client,
+	verbose bool,
+	options ...ObjectWriterOption,
+) (*ObjectWriter, error) {
+	store, err := newObjectStore(rawURL, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("generating writer ID: %w", err)
+	}
+
+	w := &ObjectWriter{
+		store:   store,
+		verbose: verbose,
+		id:      hex.EncodeToString(id),
+	}
+	for _, opt := range options {
+		opt(w)
+	}
+	return w, nil
+}
+
+// Write is WriteContext with the background context.
+func (w *ObjectWriter) Write(
+	editionID string,
+	reader io.ReadCloser,
+	newMD5 string,
+	lastModified time.Time,
+) error {
+	return w.WriteContext(context.Background(), editionID, reader, newMD5, lastModified)
+}
+
+// WriteContext validates the database read from reader and publishes it as
+// the object of the edition. If newMD5 is empty, the hash is not checked.
+// The requests to the store are canceled once ctx is done.
+func (w *ObjectWriter) WriteContext(
+	ctx context.Context,
+	editionID string,
+	reader io.ReadCloser,
+	newMD5 string,
+	_ time.Time,
+) error {
+	// The database is validated in a local temporary file first.
+	return withSpooledDatabase(editionID, reader, newMD5, func(db *spooledDatabase) error {
+		return w.publish(ctx, editionID, db)
+	})
+}
+
+// publish uploads the database and publishes it as the object of the
+// edition.
+func (w *ObjectWriter) publish(ctx context.Context, editionID string, db *spooledDatabase) error {
+	name := editionID + extension
+
+	// The version of the current object is the precondition of publishing.
+	current, err := w.store.head(ctx, name)
+	exists := err == nil
+	if err != nil && !errors.Is(err, internal.ErrNotFound) {
+		return fmt.Errorf("getting attributes of %s: %w", w.Path(editionID), err)
+	}
+
+	if exists && !w.allowDowngrade && !slices.Contains(w.allowDowngradeFor, editionID) {
+		if err = checkObjectNotOlder(current, db.metadata.BuildEpoch); err != nil {
+			return fmt.Errorf("checking build of %s: %w", editionID, err)
+		}
+	}
+
+	f, err := db.file()
+	if err != nil {
+		return fmt.Errorf("uploading %s: %w", editionID, err)
+	}
+
+	objectMetadata := map[string]string{
+		metadataMD5:        byteToString(db.md5),
+		metadataBuildDate:  formatBuildDate(db.metadata.BuildEpoch),
+		metadataBuildEpoch: strconv.FormatUint(uint64(db.metadata.BuildEpoch), 10),
+	}
+
+	// The store verifies the upload against db.md5, so a corrupted upload
+	// is refused.
+	tempName := w.tempName(name)
+	if err = w.store.put(ctx, tempName, f, db.size, db.md5, objectMetadata); err != nil {
+		return fmt.Errorf("uploading %s: %w", editionID, err)
+	}
+	defer func() {
+		// The temporary object is removed even if ctx is done.
+		if removeErr := w.store.remove(context.WithoutCancel(ctx), tempName); removeErr != nil {
+			log.Printf("Removing %s: %s", w.store.url(tempName), removeErr)
+		}
+	}()
+
+	err = w.store.copy(ctx, tempName, name, current.version)
+	if errors.Is(err, internal.ErrPreconditionFailed) {
+		return w.checkConcurrentWrite(ctx, editionID, objectMetadata[metadataMD5], err)
+	}
+	if err != nil {
+		return fmt.Errorf("publishing %s: %w", editionID, err)
+	}
+
+	if w.verbose {
+		log.Printf("Database %s successfully uploaded to %s: %s", editionID, w.store.url(name), objectMetadata[metadataMD5])
+	}
+	return nil
+}
+
+// tempName returns the name of the temporary object that the database of
+// the object name is uploaded to.
+func (w *ObjectWriter) tempName(name string) string {
+	return name + "." + w.id + tempExtension
+}
+
+// checkObjectNotOlder returns an error wrapping internal.ErrDowngradeRefused
+// if the current object holds a database built after buildEpoch.
+func checkObjectNotOlder(current objectAttrs, buildEpoch uint) error {
+	// Objects written by other programs can always be replaced.
+	epoch, err := strconv.ParseUint(current.metadata[metadataBuildEpoch], 10, 0)
+	if err != nil {
+		return nil //nolint:nilerr // see above.
+	}
+	return checkEpochNotOlder(buildEpoch, uint(epoch))
+}
+
+// checkConcurrentWrite handles a copy that was refused because another
+// updater replaced the object of an edition after Write read its attributes.
+// If that updater published the same database, there is nothing left to do.
+func (w *ObjectWriter) checkConcurrentWrite(
+	ctx context.Context,
+	editionID,
+	md5 string,
+	copyErr error,
+) error {
+	attrs, err := w.store.head(ctx, editionID+extension)
+	if err == nil && attrs.metadata[metadataMD5] == md5 {
+		if w.verbose {
+			log.Printf("Database %s was already published by another updater", editionID)
+		}
+		return nil
+	}
+	return fmt.Errorf(
+		"publishing %s: %s was replaced by another updater: %w",
+		editionID,
+		w.Path(editionID),
+		copyErr,
+	)
+}
+
+// GetHash is GetHashContext with the background context.
+func (w *ObjectWriter) GetHash(editionID string) (string, error) {
+	return w.GetHashContext(context.Background(), editionID)
+}
+
+// GetHashContext returns the MD5 of the current database of an edition, as
+// stored in the metadata of its object. Objects without it, e.g., because
+// they were written by other programs, are treated as missing so that they
+// are replaced.
+func (w *ObjectWriter) GetHashContext(ctx context.Context, editionID string) (string, error) {
+	attrs, err := w.store.head(ctx, editionID+extension)
+	if err != nil {
+		if errors.Is(err, internal.ErrNotFound) {
+			if w.verbose {
+				log.Printf("%s does not exist, returning zeroed hash", w.Path(editionID))
+			}
+			return ZeroMD5, nil
+		}
+		return "", fmt.Errorf("getting attributes of %s: %w", w.Path(editionID), err)
+	}
+
+	md5 := attrs.metadata[metadataMD5]
+	if md5 == "" {
+		if w.verbose {
+			log.Printf("%s has no MD5 metadata, returning zeroed hash", w.Path(editionID))
+		}
+		return ZeroMD5, nil
+	}
+	if w.verbose {
+		log.Printf("MD5 sum of %s: %s", w.Path(editionID), md5)
+	}
+	return md5, nil
+}
+
+// Path returns the URL of the object of an edition.
+func (w *ObjectWriter) Path(editionID string) string {
+	return w.store.url(editionID + extension)
+}
+
+// newObjectStore returns the objectStore for rawURL.
+func newObjectStore(rawURL string, httpClient *http.Client) (objectStore, error) {
+	scheme, _, _ := strings.Cut(rawURL, "://")
+	switch scheme {
+	case "azblob":
+		return newAzblobStore(rawURL, httpClient)
+	case "gs":
+		return newGCSStore(rawURL, httpClient)
+	case "s3":
+		return newS3Store(rawURL, httpClient)
+	default:
+		return nil, fmt.Errorf("unsupported database directory scheme %q", scheme)
+	}
+}
+
+func formatBuildDate(epoch uint) string {
+	//nolint:gosec // build epochs are well within range.
+	return time.Unix(int64(epoch), 0).UTC().Format(time.DateOnly)
+}
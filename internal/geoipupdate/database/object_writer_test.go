@@ -0,0 +1,226 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
+	"github.com/maxmind/geoipupdate/v8/internal/azblobtest"
+	"github.com/maxmind/geoipupdate/v8/internal/gcstest"
+	"github.com/maxmind/geoipupdate/v8/internal/mmdbtest"
+	"github.com/maxmind/geoipupdate/v8/internal/s3test"
+)
+
+// testStore is a fake object storage service.
+type testStore struct {
+	// url is the URL of the prefix the databases are written to.
+	url string
+	// object returns the content and metadata of an object under the prefix.
+	object func(name string) ([]byte, map[string]string, bool)
+	// put stores an object under the prefix without metadata.
+	put func(name string, body []byte)
+}
+
+var testStores = map[string]func(t *testing.T) testStore{
+	"s3": func(t *testing.T) testStore {
+		server := s3test.NewServer(t)
+		t.Setenv("AWS_ACCESS_KEY_ID", s3test.AccessKeyID)
+		t.Setenv("AWS_SECRET_ACCESS_KEY", s3test.SecretAccessKey)
+		t.Setenv("AWS_ENDPOINT_URL_S3", server.URL)
+		return testStore{
+			url: "s3://geoip/prod",
+			object: func(name string) ([]byte, map[string]string, bool) {
+				obj, ok := server.Object("geoip", "prod/"+name)
+				return obj.Body, obj.Metadata, ok
+			},
+			put: func(name string, body []byte) { server.Put("geoip", "prod/"+name, body, nil) },
+		}
+	},
+	"gs": func(t *testing.T) testStore {
+		server := gcstest.NewServer(t)
+		t.Setenv("STORAGE_EMULATOR_HOST", server.URL)
+		return testStore{
+			url: "gs://geoip/prod",
+			object: func(name string) ([]byte, map[string]string, bool) {
+				obj, ok := server.Object("geoip", "prod/"+name)
+				return obj.Body, obj.Metadata, ok
+			},
+			put: func(name string, body []byte) { server.Put("geoip", "prod/"+name, body, nil) },
+		}
+	},
+	"azblob": func(t *testing.T) testStore {
+		server := azblobtest.NewServer(t)
+		t.Setenv("AZURE_STORAGE_CONNECTION_STRING", server.ConnectionString())
+		return testStore{
+			url: "azblob://geoip/prod",
+			object: func(name string) ([]byte, map[string]string, bool) {
+				blob, ok := server.Blob("geoip", "prod/"+name)
+				return blob.Body, blob.Metadata, ok
+			},
+			put: func(name string, body []byte) { server.Put("geoip", "prod/"+name, body, nil) },
+		}
+	},
+}
+
+func TestObjectWriter(t *testing.T) {
+	for scheme, newStore := range testStores {
+		t.Run(scheme, func(t *testing.T) {
+			store := newStore(t)
+
+			w, err := NewObjectWriter(store.url, http.DefaultClient, false)
+			require.NoError(t, err)
+			require.Equal(t, store.url+"/GeoIP2-City.mmdb", w.Path("GeoIP2-City"))
+
+			hash, err := w.GetHash("GeoIP2-City")
+			require.NoError(t, err)
+			require.Equal(t, ZeroMD5, hash)
+
+			db := mmdbtest.Build("GeoIP2-City", 1789430400)
+			err = w.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(db)), md5Hex(db), time.Time{})
+			require.NoError(t, err)
+
+			body, _, ok := store.object("GeoIP2-City.mmdb")
+			require.True(t, ok)
+			require.Equal(t, db, body)
+			attrs, err := w.store.head(t.Context(), "GeoIP2-City.mmdb")
+			require.NoError(t, err)
+			require.Equal(t, "2026-09-15", attrs.metadata[metadataBuildDate])
+			_, _, ok = store.object(w.tempName("GeoIP2-City.mmdb"))
+			require.False(t, ok, "the temporary object is removed")
+
+			hash, err = w.GetHash("GeoIP2-City")
+			require.NoError(t, err)
+			require.Equal(t, md5Hex(db), hash)
+
+			// Invalid and older databases are never uploaded.
+			err = w.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(db)), "badhash", time.Time{})
+			require.ErrorIs(t, err, internal.ErrHashMismatch)
+
+			older := mmdbtest.Build("GeoIP2-City", 1789430400-24*60*60)
+			err = w.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(older)), md5Hex(older), time.Time{})
+			require.ErrorIs(t, err, internal.ErrDowngradeRefused)
+
+			// Nor are databases once the context is done.
+			ctx, cancel := context.WithCancel(t.Context())
+			cancel()
+			newer := mmdbtest.Build("GeoIP2-City", 1789430400+24*60*60)
+			err = w.WriteContext(ctx, "GeoIP2-City", io.NopCloser(bytes.NewReader(newer)), md5Hex(newer), time.Time{})
+			require.ErrorIs(t, err, context.Canceled)
+			_, err = w.GetHashContext(ctx, "GeoIP2-City")
+			require.ErrorIs(t, err, context.Canceled)
+
+			body, _, _ = store.object("GeoIP2-City.mmdb")
+			require.Equal(t, db, body)
+
+			w, err = NewObjectWriter(store.url, http.DefaultClient, false, WithObjectAllowDowngrade(true))
+			require.NoError(t, err)
+			err = w.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(older)), md5Hex(older), time.Time{})
+			require.NoError(t, err)
+			body, _, _ = store.object("GeoIP2-City.mmdb")
+			require.Equal(t, older, body)
+
+			// Objects written by other programs have no MD5 and are replaced.
+			store.put("GeoIP2-ISP.mmdb", []byte("isp"))
+			hash, err = w.GetHash("GeoIP2-ISP")
+			require.NoError(t, err)
+			require.Equal(t, ZeroMD5, hash)
+		})
+	}
+}
+
+func TestObjectWriterS3Metadata(t *testing.T) {
+	store := testStores["s3"](t)
+
+	w, err := NewObjectWriter(store.url, http.DefaultClient, false)
+	require.NoError(t, err)
+	db := mmdbtest.Build("GeoIP2-City", 1789430400)
+	err = w.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(db)), md5Hex(db), time.Time{})
+	require.NoError(t, err)
+
+	// The metadata is where the s3 source reads it from.
+	_, metadata, _ := store.object("GeoIP2-City.mmdb")
+	require.Equal(t, map[string]string{
+		"md5":         md5Hex(db),
+		"build-date":  "2026-09-15",
+		"build-epoch": "1789430400",
+	}, metadata)
+}
+
+func TestObjectWriterConcurrentWrite(t *testing.T) {
+	first := mmdbtest.Build("GeoIP2-City", 1789430400)
+	second := mmdbtest.Build("GeoIP2-City", 1789430400+24*60*60)
+
+	tests := []struct {
+		description string
+		// other is published by another updater while the first one is
+		// written.
+		other []byte
+		err   error
+	}{
+		{
+			description: "another database",
+			other:       second,
+			err:         internal.ErrPreconditionFailed,
+		},
+		{
+			description: "the same database",
+			other:       first,
+		},
+	}
+
+	// S3 cannot make copies conditional.
+	for _, scheme := range []string{"gs", "azblob"} {
+		for _, test := range tests {
+			t.Run(scheme+"/"+test.description, func(t *testing.T) {
+				store := testStores[scheme](t)
+
+				other, err := NewObjectWriter(store.url, http.DefaultClient, false)
+				require.NoError(t, err)
+				w, err := NewObjectWriter(store.url, http.DefaultClient, false)
+				require.NoError(t, err)
+				w.store = &racingStore{
+					objectStore: w.store,
+					race: func() {
+						err := other.Write(
+							"GeoIP2-City",
+							io.NopCloser(bytes.NewReader(test.other)),
+							md5Hex(test.other),
+							time.Time{},
+						)
+						require.NoError(t, err)
+					},
+				}
+
+				err = w.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(first)), md5Hex(first), time.Time{})
+				if test.err != nil {
+					require.ErrorIs(t, err, test.err)
+				} else {
+					require.NoError(t, err)
+				}
+
+				body, _, _ := store.object("GeoIP2-City.mmdb")
+				require.Equal(t, test.other, body)
+			})
+		}
+	}
+}
+
+// racingStore is an objectStore that calls race before the first copy.
+type racingStore struct {
+	objectStore
+	race func()
+}
+
+func (s *racingStore) copy(ctx context.Context, from, to, ifVersion string) error {
+	if s.race != nil {
+		s.race()
+		s.race = nil
+	}
+	return s.objectStore.copy(ctx, from, to, ifVersion)
+}
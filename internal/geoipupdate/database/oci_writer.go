@@ -0,0 +1,299 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
+	"github.com/maxmind/geoipupdate/v8/internal/oci"
+)
+
+// These describe the OCI artifacts pushed by OCIWriter.
+const (
+	ociArtifactType  = "application/vnd.maxmind.geoipupdate.database.v1"
+	ociLayerType     = "application/vnd.maxmind.mmdb"
+	ociLatestTag     = "latest"
+	ociAnnotationMD5 = "com.maxmind.geoipupdate.md5"
+	// ociAnnotationBuildDate is the date the database was built on, which
+	// is also the tag of the artifact.
+	ociAnnotationBuildDate  = "com.maxmind.geoipupdate.build-date"
+	ociAnnotationBuildEpoch = "com.maxmind.geoipupdate.build-epoch"
+	ociAnnotationEditionID  = "com.maxmind.geoipupdate.edition-id"
+	ociAnnotationCreated    = "org.opencontainers.image.created"
+	ociAnnotationTitle      = "org.opencontainers.image.title"
+)
+
+// ociEmptyConfig is the config blob of artifacts that have none.
+var ociEmptyConfig = []byte("{}")
+
+// OCIWriter is a Writer that pushes the databases as OCI artifacts to a
+// container registry, with a repository for each edition, named after it in
+// lower case. Each database is tagged with the date it was built on, as
+// YYYY-MM-DD, and latest once it is verified. Its MD5, build date and build
+// epoch are in the annotations of the manifest.
+type OCIWriter struct {
+	client   *oci.Client
+	registry string
+	prefix   string
+	verbose  bool
+	// allowDowngrade and allowDowngradeFor are as for LocalFileWriter.
+	allowDowngrade    bool
+	allowDowngradeFor []string
+}
+
+// OCIWriterOption is an option for configuring OCIWriter.
+type OCIWriterOption func(*OCIWriter)
+
+// WithOCIAllowDowngrade sets whether a database may be replaced by one that
+// was built before it, and editions for which it may be regardless, e.g.,
+// because they are pinned to a date.
+func WithOCIAllowDowngrade(allow bool, editionIDs ...string) OCIWriterOption {
+	return func(w *OCIWriter) {
+		w.allowDowngrade = allow
+		w.allowDowngradeFor = editionIDs
+	}
+}
+
+// IsOCIURL returns whether the database directory dir is the URL of a
+// container registry, oci://registry/prefix.
+func IsOCIURL(dir string) bool {
+	return strings.HasPrefix(dir, "oci://")
+}
+
+// NewOCIWriter creates an OCIWriter for the repositories under
+// oci://registry/prefix. httpClient is used for the requests.
+func NewOCIWriter(
+	rawURL string,
+	httpClient *http.Client,
+	verbose bool,
+	options ...OCIWriterOption,
+) (*OCIWriter, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", rawURL, err)
+	}
+	registry, prefix, err := oci.ParseURL(u)
+	if err != nil {
+		return nil, err
+	}
+	c, err := oci.NewFromEnv(registry, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &OCIWriter{
+		client:   c,
+		registry: registry,
+		prefix:   prefix,
+		verbose:  verbose,
+	}
+	for _, opt := range options {
+		opt(w)
+	}
+	return w, nil
+}
+
+// Write is WriteContext with the background context.
+func (w *OCIWriter) Write(
+	editionID string,
+	reader io.ReadCloser,
+	newMD5 string,
+	lastModified time.Time,
+) error {
+	return w.WriteContext(context.Background(), editionID, reader, newMD5, lastModified)
+}
+
+// WriteContext validates the database read from reader and pushes it as the
+// artifact of the edition. If newMD5 is empty, the hash is not checked. The
+// requests to the registry are canceled once ctx is done.
+func (w *OCIWriter) WriteContext(
+	ctx context.Context,
+	editionID string,
+	reader io.ReadCloser,
+	newMD5 string,
+	_ time.Time,
+) error {
+	return withSpooledDatabase(editionID, reader, newMD5, func(db *spooledDatabase) error {
+		return w.push(ctx, editionID, db)
+	})
+}
+
+// push pushes the database as the artifact of the edition, tagged with its
+// build date and as the latest one.
+func (w *OCIWriter) push(ctx context.Context, editionID string, db *spooledDatabase) error {
+	repository := w.repository(editionID)
+
+	if !w.allowDowngrade && !slices.Contains(w.allowDowngradeFor, editionID) {
+		if err := w.checkNotOlder(ctx, repository, db.metadata.BuildEpoch); err != nil {
+			return fmt.Errorf("checking build of %s: %w", editionID, err)
+		}
+	}
+
+	layerDigest, err := db.sha256()
+	if err != nil {
+		return fmt.Errorf("hashing %s: %w", editionID, err)
+	}
+	err = w.client.PushBlob(ctx, repository, layerDigest, db.size, func() (io.Reader, error) {
+		return db.file()
+	})
+	if err != nil {
+		return fmt.Errorf("pushing %s: %w", editionID, err)
+	}
+	configDigest := ociDigest(ociEmptyConfig)
+	err = w.client.PushBlob(ctx, repository, configDigest, int64(len(ociEmptyConfig)), func() (io.Reader, error) {
+		return bytes.NewReader(ociEmptyConfig), nil
+	})
+	if err != nil {
+		return fmt.Errorf("pushing config of %s: %w", editionID, err)
+	}
+
+	md5 := byteToString(db.md5)
+	date := formatBuildDate(db.metadata.BuildEpoch)
+	manifest, err := json.Marshal(oci.Manifest{
+		SchemaVersion: 2,
+		MediaType:     oci.MediaTypeManifest,
+		ArtifactType:  ociArtifactType,
+		Config: oci.Descriptor{
+			MediaType: oci.MediaTypeEmpty,
+			Digest:    configDigest,
+			Size:      int64(len(ociEmptyConfig)),
+			Data:      ociEmptyConfig,
+		},
+		Layers: []oci.Descriptor{{
+			MediaType:   ociLayerType,
+			Digest:      layerDigest,
+			Size:        db.size,
+			Annotations: map[string]string{ociAnnotationTitle: editionID + extension},
+		}},
+		Annotations: map[string]string{
+			ociAnnotationMD5:        md5,
+			ociAnnotationBuildDate:  date,
+			ociAnnotationBuildEpoch: strconv.FormatUint(uint64(db.metadata.BuildEpoch), 10),
+			ociAnnotationEditionID:  editionID,
+			//nolint:gosec // build epochs are well within range.
+			ociAnnotationCreated: time.Unix(int64(db.metadata.BuildEpoch), 0).UTC().Format(time.RFC3339),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("encoding manifest of %s: %w", editionID, err)
+	}
+
+	// The date tag is pushed and read back before latest is moved to it.
+	if _, err = w.client.PutManifest(ctx, repository, date, manifest); err != nil {
+		return fmt.Errorf("pushing manifest of %s: %w", editionID, err)
+	}
+	if err = w.verify(ctx, repository, date, ociDigest(manifest)); err != nil {
+		return fmt.Errorf("verifying push of %s: %w", editionID, err)
+	}
+	if _, err = w.client.PutManifest(ctx, repository, ociLatestTag, manifest); err != nil {
+		return fmt.Errorf("tagging %s as %s: %w", editionID, ociLatestTag, err)
+	}
+
+	if w.verbose {
+		log.Printf("Database %s successfully pushed to %s:%s: %+v", editionID, w.registry+"/"+repository, date, md5)
+	}
+	return nil
+}
+
+// checkNotOlder returns an error wrapping internal.ErrDowngradeRefused if
+// the latest artifact of repository was built after buildEpoch.
+func (w *OCIWriter) checkNotOlder(ctx context.Context, repository string, buildEpoch uint) error {
+	manifest, _, err := w.client.Manifest(ctx, repository, ociLatestTag)
+	if errors.Is(err, internal.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Artifacts pushed by other programs can always be replaced.
+	current, err := strconv.ParseUint(manifest.Annotations[ociAnnotationBuildEpoch], 10, 0)
+	if err != nil {
+		return nil //nolint:nilerr // see above.
+	}
+	return checkEpochNotOlder(buildEpoch, uint(current))
+}
+
+// verify checks that tag refers to the manifest with the expected digest.
+// As the manifest has the digest of the layer and the MD5 of the database,
+// this also verifies those.
+func (w *OCIWriter) verify(ctx context.Context, repository, tag, digest string) error {
+	_, actual, err := w.client.Manifest(ctx, repository, tag)
+	if err != nil {
+		return err
+	}
+	if actual != digest {
+		return fmt.Errorf(
+			"%w: %s:%s is %s rather than %s",
+			internal.ErrHashMismatch,
+			repository,
+			tag,
+			actual,
+			digest,
+		)
+	}
+	return nil
+}
+
+// GetHash is GetHashContext with the background context.
+func (w *OCIWriter) GetHash(editionID string) (string, error) {
+	return w.GetHashContext(context.Background(), editionID)
+}
+
+// GetHashContext returns the MD5 of the latest database of an edition, as
+// stored in the annotations of its manifest. Artifacts without it are
+// treated as missing so that they are replaced.
+func (w *OCIWriter) GetHashContext(ctx context.Context, editionID string) (string, error) {
+	manifest, _, err := w.client.Manifest(ctx, w.repository(editionID), ociLatestTag)
+	if err != nil {
+		if errors.Is(err, internal.ErrNotFound) {
+			if w.verbose {
+				log.Printf("%s does not exist, returning zeroed hash", w.Path(editionID))
+			}
+			return ZeroMD5, nil
+		}
+		return "", fmt.Errorf("getting manifest of %s: %w", w.Path(editionID), err)
+	}
+
+	md5 := manifest.Annotations[ociAnnotationMD5]
+	if md5 == "" {
+		if w.verbose {
+			log.Printf("%s has no MD5 annotation, returning zeroed hash", w.Path(editionID))
+		}
+		return ZeroMD5, nil
+	}
+	if w.verbose {
+		log.Printf("MD5 sum of %s: %s", w.Path(editionID), md5)
+	}
+	return md5, nil
+}
+
+// Path returns the reference of the latest artifact of an edition.
+func (w *OCIWriter) Path(editionID string) string {
+	return w.registry + "/" + w.repository(editionID) + ":" + ociLatestTag
+}
+
+// repository returns the repository of an edition. Repository names are
+// lower case.
+func (w *OCIWriter) repository(editionID string) string {
+	return path.Join(w.prefix, strings.ToLower(editionID))
+}
+
+func ociDigest(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
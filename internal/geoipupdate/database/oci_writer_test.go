@@ -0,0 +1,124 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
+	"github.com/maxmind/geoipupdate/v8/internal/mmdbtest"
+	"github.com/maxmind/geoipupdate/v8/internal/oci"
+	"github.com/maxmind/geoipupdate/v8/internal/ocitest"
+)
+
+func TestOCIWriter(t *testing.T) {
+	registry := ocitest.NewRegistry(t, true)
+
+	dockerConfig := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dockerConfig)
+	auth := base64.StdEncoding.EncodeToString([]byte(ocitest.Username + ":" + ocitest.Password))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(dockerConfig, "config.json"),
+		[]byte(`{"auths":{"`+registry.Host()+`":{"auth":"`+auth+`"}}}`),
+		0o600,
+	))
+
+	w, err := NewOCIWriter("oci://"+registry.Host()+"/geoip", http.DefaultClient, false)
+	require.NoError(t, err)
+	require.Equal(t, registry.Host()+"/geoip/geoip2-city:latest", w.Path("GeoIP2-City"))
+
+	hash, err := w.GetHash("GeoIP2-City")
+	require.NoError(t, err)
+	require.Equal(t, ZeroMD5, hash)
+
+	db := mmdbtest.Build("GeoIP2-City", 1789430400)
+	err = w.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(db)), md5Hex(db), time.Time{})
+	require.NoError(t, err)
+
+	b, ok := registry.Manifest("geoip/geoip2-city", "2026-09-15")
+	require.True(t, ok, "the database is tagged with its build date")
+	latest, ok := registry.Manifest("geoip/geoip2-city", "latest")
+	require.True(t, ok)
+	require.Equal(t, b, latest)
+
+	var manifest oci.Manifest
+	require.NoError(t, json.Unmarshal(b, &manifest))
+	require.Equal(t, ociArtifactType, manifest.ArtifactType)
+	require.Equal(t, map[string]string{
+		"com.maxmind.geoipupdate.md5":         md5Hex(db),
+		"com.maxmind.geoipupdate.build-date":  "2026-09-15",
+		"com.maxmind.geoipupdate.build-epoch": "1789430400",
+		"com.maxmind.geoipupdate.edition-id":  "GeoIP2-City",
+		"org.opencontainers.image.created":    "2026-09-15T00:00:00Z",
+	}, manifest.Annotations)
+	require.Len(t, manifest.Layers, 1)
+	layer, ok := registry.Blob("geoip/geoip2-city", manifest.Layers[0].Digest)
+	require.True(t, ok)
+	require.Equal(t, db, layer)
+
+	hash, err = w.GetHash("GeoIP2-City")
+	require.NoError(t, err)
+	require.Equal(t, md5Hex(db), hash)
+
+	// Blobs the registry already has are not uploaded again.
+	uploads := registry.Uploads()
+	err = w.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(db)), md5Hex(db), time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, uploads, registry.Uploads())
+
+	// Invalid and older databases are never pushed.
+	err = w.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(db)), "badhash", time.Time{})
+	require.ErrorIs(t, err, internal.ErrHashMismatch)
+
+	older := mmdbtest.Build("GeoIP2-City", 1789430400-24*60*60)
+	err = w.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(older)), md5Hex(older), time.Time{})
+	require.ErrorIs(t, err, internal.ErrDowngradeRefused)
+	_, ok = registry.Manifest("geoip/geoip2-city", "2026-09-14")
+	require.False(t, ok)
+
+	// Nor are databases once the context is done.
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	newer := mmdbtest.Build("GeoIP2-City", 1789430400+24*60*60)
+	err = w.WriteContext(ctx, "GeoIP2-City", io.NopCloser(bytes.NewReader(newer)), md5Hex(newer), time.Time{})
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = w.GetHashContext(ctx, "GeoIP2-City")
+	require.ErrorIs(t, err, context.Canceled)
+	_, ok = registry.Manifest("geoip/geoip2-city", "2026-09-16")
+	require.False(t, ok)
+
+	w, err = NewOCIWriter(
+		"oci://"+registry.Host()+"/geoip",
+		http.DefaultClient,
+		false,
+		WithOCIAllowDowngrade(false, "GeoIP2-City"),
+	)
+	require.NoError(t, err)
+	err = w.Write("GeoIP2-City", io.NopCloser(bytes.NewReader(older)), md5Hex(older), time.Time{})
+	require.NoError(t, err)
+	hash, err = w.GetHash("GeoIP2-City")
+	require.NoError(t, err)
+	require.Equal(t, md5Hex(older), hash)
+}
+
+func TestOCIWriterUnauthorized(t *testing.T) {
+	registry := ocitest.NewRegistry(t, true)
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	w, err := NewOCIWriter("oci://"+registry.Host()+"/geoip", http.DefaultClient, false)
+	require.NoError(t, err)
+
+	_, err = w.GetHash("GeoIP2-City")
+	var httpErr internal.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	require.Equal(t, http.StatusUnauthorized, httpErr.StatusCode)
+}
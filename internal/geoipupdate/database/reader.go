@@ -1,28 +1,63 @@
 package database
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"time"
 )
 
-// Reader provides an interface for retrieving a database update and copying it
-// into place.
-type Reader interface {
-	Read(context.Context, string, string) (*ReadResult, error)
-}
+// These are the values of ReadResult.Status.
+const (
+	// StatusUpdated means that a new database was installed.
+	StatusUpdated = "updated"
+	// StatusUpToDate means that the database was already current.
+	StatusUpToDate = "up_to_date"
+	// StatusFailed means that the edition could not be updated. The error is
+	// in ReadResult.Error.
+	StatusFailed = "failed"
+	// StatusDowngradeRefused means that the downloaded database was built
+	// before the current one, so it was not installed. The reason is in
+	// ReadResult.Error.
+	StatusDowngradeRefused = "downgrade_refused"
+)
 
-// ReadResult is the struct returned by a Reader's Get method.
+// ReadResult describes the outcome of updating an edition.
 type ReadResult struct {
 	EditionID  string    `json:"edition_id"`
 	OldHash    string    `json:"old_hash"`
 	NewHash    string    `json:"new_hash"`
 	ModifiedAt time.Time `json:"modified_at"`
 	CheckedAt  time.Time `json:"checked_at"`
+	// Status is one of the Status constants.
+	Status string `json:"status"`
+	// Error describes why the update failed or the database was refused.
+	Error string `json:"error,omitempty"`
+	// Attempts is the number of download attempts made.
+	Attempts int `json:"attempts"`
+	// Duration is how long the update took, including retries.
+	Duration time.Duration `json:"duration"`
+	// Destinations are the outcomes of writing the database to each
+	// destination, if there are several.
+	Destinations []DestinationResult `json:"destinations,omitempty"`
+}
+
+// DestinationResult describes the outcome of writing a database to one of
+// the destinations of a MultiWriter.
+type DestinationResult struct {
+	// Destination is the name of the destination, e.g., its directory.
+	Destination string `json:"destination"`
+	// Status is StatusUpdated, StatusUpToDate, StatusFailed or
+	// StatusDowngradeRefused.
+	Status string `json:"status"`
+	// Linked is whether the database was hard linked to a copy in another
+	// destination rather than written.
+	Linked bool `json:"linked,omitempty"`
+	// Error describes why writing failed or the database was refused.
+	Error string `json:"error,omitempty"`
 }
 
-// MarshalJSON is a custom json marshaler that strips out zero time fields.
+// MarshalJSON is a custom json marshaler that strips out zero time fields
+// and gives the duration in seconds.
 func (r *ReadResult) MarshalJSON() ([]byte, error) {
 	if r == nil {
 		return []byte("null"), nil
@@ -32,12 +67,14 @@ func (r *ReadResult) MarshalJSON() ([]byte, error) {
 	s := &struct {
 		partialResult
 
-		ModifiedAt int64 `json:"modified_at,omitempty"`
-		CheckedAt  int64 `json:"checked_at,omitempty"`
+		ModifiedAt int64   `json:"modified_at,omitempty"`
+		CheckedAt  int64   `json:"checked_at,omitempty"`
+		Duration   float64 `json:"duration"`
 	}{
 		partialResult: partialResult(*r),
 		ModifiedAt:    0,
 		CheckedAt:     0,
+		Duration:      r.Duration.Seconds(),
 	}
 
 	if !r.ModifiedAt.IsZero() {
@@ -55,15 +92,16 @@ func (r *ReadResult) MarshalJSON() ([]byte, error) {
 	return res, nil
 }
 
-// UnmarshalJSON is a custom json unmarshaler that converts timestamps to go
-// time fields.
+// UnmarshalJSON is a custom json unmarshaler that converts timestamps and
+// durations to go time fields.
 func (r *ReadResult) UnmarshalJSON(data []byte) error {
 	type partialResult ReadResult
 	s := &struct {
 		partialResult
 
-		ModifiedAt int64 `json:"modified_at,omitempty"`
-		CheckedAt  int64 `json:"checked_at,omitempty"`
+		ModifiedAt int64   `json:"modified_at,omitempty"`
+		CheckedAt  int64   `json:"checked_at,omitempty"`
+		Duration   float64 `json:"duration"`
 	}{}
 
 	err := json.Unmarshal(data, &s)
@@ -74,6 +112,7 @@ func (r *ReadResult) UnmarshalJSON(data []byte) error {
 	result := ReadResult(s.partialResult)
 	result.ModifiedAt = time.Unix(s.ModifiedAt, 0).In(time.UTC)
 	result.CheckedAt = time.Unix(s.CheckedAt, 0).In(time.UTC)
+	result.Duration = time.Duration(s.Duration * float64(time.Second))
 	*r = result
 
 	return nil
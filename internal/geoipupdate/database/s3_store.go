@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+
+	"github.com/maxmind/geoipupdate/v8/internal/s3"
+)
+
+// s3Store is an objectStore for a prefix in an S3 bucket.
+type s3Store struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// newS3Store creates an s3Store for s3://bucket/prefix. The credentials,
+// region and endpoint are read from the environment, see s3.NewFromEnv.
+func newS3Store(rawURL string, httpClient *http.Client) (*s3Store, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", rawURL, err)
+	}
+	bucket, prefix, err := s3.ParseURL(u)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s3.NewFromEnv(httpClient)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Store{client: c, bucket: bucket, prefix: prefix}, nil
+}
+
+func (s *s3Store) head(ctx context.Context, name string) (objectAttrs, error) {
+	obj, err := s.client.Head(ctx, s.bucket, s.key(name))
+	if err != nil {
+		return objectAttrs{}, err
+	}
+	return objectAttrs{size: obj.Size, metadata: obj.Metadata, version: obj.ETag}, nil
+}
+
+func (s *s3Store) put(
+	ctx context.Context,
+	name string,
+	f *os.File,
+	size int64,
+	md5 []byte,
+	metadata map[string]string,
+) error {
+	return s.client.Put(ctx, s.bucket, s.key(name), f, size, md5, metadata)
+}
+
+// copy copies regardless of ifVersion, as S3 cannot make copies conditional
+// on the destination.
+func (s *s3Store) copy(ctx context.Context, from, to, _ string) error {
+	return s.client.Copy(ctx, s.bucket, s.key(from), s.key(to))
+}
+
+func (s *s3Store) remove(ctx context.Context, name string) error {
+	return s.client.Delete(ctx, s.bucket, s.key(name))
+}
+
+func (s *s3Store) url(name string) string {
+	return "s3://" + s.bucket + "/" + s.key(name)
+}
+
+func (s *s3Store) key(name string) string {
+	return path.Join(s.prefix, name)
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+// spooledDatabase is a database that was written to a local temporary file
+// and validated there, before it is uploaded to remote storage.
+type spooledDatabase struct {
+	fw       *fileWriter
+	md5      []byte
+	size     int64
+	metadata maxminddb.Metadata
+}
+
+// withSpooledDatabase writes the database of an edition read from reader to
+// a temporary file, validates it there and passes it to store. If newMD5 is
+// empty, the hash is not checked. reader is drained and closed, and the
+// temporary file is removed once store returns.
+func withSpooledDatabase(
+	editionID string,
+	reader io.ReadCloser,
+	newMD5 string,
+	store func(*spooledDatabase) error,
+) (err error) {
+	defer func() {
+		_, _ = io.Copy(io.Discard, reader) //nolint:errcheck // Best effort.
+		if closeErr := reader.Close(); closeErr != nil {
+			err = errors.Join(
+				err,
+				fmt.Errorf("closing reader for %s: %w", editionID, closeErr),
+			)
+		}
+	}()
+
+	db, err := spoolDatabase(editionID, reader, newMD5)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := db.close(); closeErr != nil {
+			err = errors.Join(err, fmt.Errorf("removing the temp file for %s: %w", editionID, closeErr))
+		}
+	}()
+
+	return store(db)
+}
+
+// spoolDatabase writes the database of an edition read from reader to a
+// temporary file and validates it. If newMD5 is empty, the hash is not
+// checked. The caller must close the returned spooledDatabase.
+func spoolDatabase(editionID string, reader io.Reader, newMD5 string) (_ *spooledDatabase, err error) {
+	tmp, err := os.CreateTemp("", "geoipupdate-*"+extension)
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary file for %s: %w", editionID, err)
+	}
+	d := &spooledDatabase{fw: &fileWriter{file: tmp, md5Writer: md5.New()}}
+	defer func() {
+		if err != nil {
+			//nolint:errcheck // the error is already returned.
+			_ = d.close()
+		}
+	}()
+
+	if err := d.fw.write(reader); err != nil {
+		return nil, fmt.Errorf("writing to the temp file for %s: %w", editionID, err)
+	}
+	if newMD5 != "" {
+		if err := d.fw.validateHash(newMD5); err != nil {
+			return nil, fmt.Errorf("validating hash for %s: %w", editionID, err)
+		}
+	}
+	d.metadata, err = validateDatabase(tmp.Name(), editionID)
+	if err != nil {
+		return nil, fmt.Errorf("validating database for %s: %w", editionID, err)
+	}
+
+	d.md5 = d.fw.md5Writer.Sum(nil)
+	d.size, err = tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("reading the temp file for %s: %w", editionID, err)
+	}
+	return d, nil
+}
+
+// file returns the temporary file, positioned at its start.
+func (d *spooledDatabase) file() (*os.File, error) {
+	if _, err := d.fw.file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("reading the temp file: %w", err)
+	}
+	return d.fw.file, nil
+}
+
+// sha256 returns the SHA-256 digest of the database, as OCI digests are
+// written.
+func (d *spooledDatabase) sha256() (string, error) {
+	f, err := d.file()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("reading the temp file: %w", err)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// close closes and removes the temporary file.
+func (d *spooledDatabase) close() error {
+	return d.fw.close()
+}
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
+)
+
+const (
+	// metadataStartMarker precedes the metadata section of an MMDB.
+	metadataStartMarker = "\xab\xcd\xefMaxMind.com"
+
+	// maxMetadataSize is the largest metadata section that is looked for.
+	// The MaxMind DB format limits it to 128 KiB.
+	maxMetadataSize = 128 * 1024
+
+	// dataSectionSeparatorSize is the number of zero bytes between the search
+	// tree and the data section.
+	dataSectionSeparatorSize = 16
+)
+
+// validateDatabase checks that the file at path is a well-formed MMDB of
+// the edition and returns its metadata. The returned error wraps
+// internal.ErrInvalidDatabase if it is not.
+//
+// The metadata is decoded and its database_type must be the edition ID. The
+// search tree is then walked to check that it has node_count nodes with
+// records of record_size bits, and that every record points to a node, to
+// no data or into the data section.
+func validateDatabase(path, editionID string) (maxminddb.Metadata, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return maxminddb.Metadata{}, fmt.Errorf("%w: %w", internal.ErrInvalidDatabase, err)
+	}
+	metadata := reader.Metadata
+	if err := reader.Close(); err != nil {
+		return maxminddb.Metadata{}, fmt.Errorf("closing database: %w", err)
+	}
+
+	if metadata.DatabaseType != editionID {
+		return maxminddb.Metadata{}, fmt.Errorf(
+			"%w: database type is %q, expected %q",
+			internal.ErrInvalidDatabase,
+			metadata.DatabaseType,
+			editionID,
+		)
+	}
+
+	if err := walkSearchTree(path, metadata.NodeCount, metadata.RecordSize); err != nil {
+		return maxminddb.Metadata{}, err
+	}
+	return metadata, nil
+}
+
+// checkNotOlder returns an error wrapping internal.ErrDowngradeRefused if
+// the database at path was built after buildEpoch. A database that is
+// missing or cannot be read can always be replaced.
+func checkNotOlder(path string, buildEpoch uint) error {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil //nolint:nilerr // see above.
+	}
+	current := reader.Metadata.BuildEpoch
+	if err := reader.Close(); err != nil {
+		return fmt.Errorf("closing database: %w", err)
+	}
+
+	return checkEpochNotOlder(buildEpoch, current)
+}
+
+// checkEpochNotOlder returns an error wrapping internal.ErrDowngradeRefused
+// if buildEpoch is before current.
+func checkEpochNotOlder(buildEpoch, current uint) error {
+	if buildEpoch < current {
+		return fmt.Errorf(
+			"%w: the new database was built at %s, before the current one, built at %s",
+			internal.ErrDowngradeRefused,
+			formatBuildEpoch(buildEpoch),
+			formatBuildEpoch(current),
+		)
+	}
+	return nil
+}
+
+func formatBuildEpoch(epoch uint) string {
+	//nolint:gosec // build epochs are well within range.
+	return time.Unix(int64(epoch), 0).UTC().Format(time.RFC3339)
+}
+
+// walkSearchTree reads every node of the search tree of the database at
+// path and checks its records.
+func walkSearchTree(path string, nodeCount, recordSize uint) error {
+	if recordSize != 24 && recordSize != 28 && recordSize != 32 {
+		return fmt.Errorf("%w: unsupported record size %d", internal.ErrInvalidDatabase, recordSize)
+	}
+	nodeSize := int(recordSize) / 4
+
+	//nolint:gosec // we really need to read this file.
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer f.Close()
+
+	metadataStart, err := findMetadataStart(f)
+	if err != nil {
+		return err
+	}
+
+	treeSize := uint64(nodeCount) * uint64(nodeSize)
+	dataSectionStart := treeSize + dataSectionSeparatorSize
+	if dataSectionStart > uint64(metadataStart) {
+		return fmt.Errorf(
+			"%w: a search tree of %d nodes does not fit in the database",
+			internal.ErrInvalidDatabase,
+			nodeCount,
+		)
+	}
+	dataSectionSize := uint64(metadataStart) - dataSectionStart
+
+	r := bufio.NewReaderSize(io.NewSectionReader(f, 0, metadataStart), 64*1024)
+	node := make([]byte, nodeSize)
+	for i := range uint64(nodeCount) {
+		if _, err := io.ReadFull(r, node); err != nil {
+			return fmt.Errorf("reading node %d: %w", i, err)
+		}
+		left, right := decodeNode(node, recordSize)
+		for _, record := range []uint64{left, right} {
+			if err := checkRecord(record, uint64(nodeCount), dataSectionSize); err != nil {
+				return fmt.Errorf("%w: node %d: %w", internal.ErrInvalidDatabase, i, err)
+			}
+		}
+	}
+
+	separator := make([]byte, dataSectionSeparatorSize)
+	if _, err := io.ReadFull(r, separator); err != nil {
+		return fmt.Errorf("reading data section separator: %w", err)
+	}
+	if !bytes.Equal(separator, make([]byte, dataSectionSeparatorSize)) {
+		return fmt.Errorf(
+			"%w: the search tree is not followed by the data section separator",
+			internal.ErrInvalidDatabase,
+		)
+	}
+
+	return nil
+}
+
+// findMetadataStart returns the offset of the metadata start marker in f.
+func findMetadataStart(f *os.File) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("reading database size: %w", err)
+	}
+
+	tailStart := max(info.Size()-maxMetadataSize, 0)
+	tail := make([]byte, info.Size()-tailStart)
+	if _, err := f.ReadAt(tail, tailStart); err != nil && !errors.Is(err, io.EOF) {
+		return 0, fmt.Errorf("reading database metadata: %w", err)
+	}
+
+	i := bytes.LastIndex(tail, []byte(metadataStartMarker))
+	if i == -1 {
+		return 0, fmt.Errorf("%w: metadata not found", internal.ErrInvalidDatabase)
+	}
+	return tailStart + int64(i), nil
+}
+
+// decodeNode returns the left and right records of a node.
+func decodeNode(b []byte, recordSize uint) (uint64, uint64) {
+	switch recordSize {
+	case 24:
+		return uint64(b[0])<<16 | uint64(b[1])<<8 | uint64(b[2]),
+			uint64(b[3])<<16 | uint64(b[4])<<8 | uint64(b[5])
+	case 28:
+		// The middle byte holds the most significant bits of both records.
+		return uint64(b[3]&0xf0)<<20 | uint64(b[0])<<16 | uint64(b[1])<<8 | uint64(b[2]),
+			uint64(b[3]&0x0f)<<24 | uint64(b[4])<<16 | uint64(b[5])<<8 | uint64(b[6])
+	default:
+		return uint64(b[0])<<24 | uint64(b[1])<<16 | uint64(b[2])<<8 | uint64(b[3]),
+			uint64(b[4])<<24 | uint64(b[5])<<16 | uint64(b[6])<<8 | uint64(b[7])
+	}
+}
+
+// checkRecord checks that a record points to a node, to no data, which is
+// the value nodeCount, or into the data section.
+func checkRecord(record, nodeCount, dataSectionSize uint64) error {
+	if record <= nodeCount {
+		return nil
+	}
+	if record < nodeCount+dataSectionSeparatorSize {
+		return fmt.Errorf("record %d points into the data section separator", record)
+	}
+	if offset := record - nodeCount - dataSectionSeparatorSize; offset >= dataSectionSize {
+		return fmt.Errorf("record %d points past the data section", record)
+	}
+	return nil
+}
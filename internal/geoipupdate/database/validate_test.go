@@ -0,0 +1,138 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
+	"github.com/maxmind/geoipupdate/v8/internal/mmdbtest"
+)
+
+func TestValidateDatabase(t *testing.T) {
+	// A tree of three nodes whose records point to the other nodes, to no
+	// data (3) and into the data section (3+16+0).
+	nodes := [][2]uint32{{1, 2}, {3, 19}, {3, 20}}
+	data := []byte{0x44, 't', 'e', 's', 't'}
+
+	valid := mmdbtest.Build("GeoIP2-City", 1)
+
+	tests := []struct {
+		description string
+		db          []byte
+		err         string
+	}{
+		{
+			description: "valid database",
+			db:          valid,
+		},
+		{
+			description: "24-bit records",
+			db: mmdbtest.Database{
+				DatabaseType: "GeoIP2-City",
+				RecordSize:   24,
+				Nodes:        nodes,
+				Data:         data,
+			}.Bytes(),
+		},
+		{
+			description: "28-bit records",
+			db: mmdbtest.Database{
+				DatabaseType: "GeoIP2-City",
+				RecordSize:   28,
+				Nodes:        nodes,
+				Data:         data,
+			}.Bytes(),
+		},
+		{
+			description: "32-bit records",
+			db: mmdbtest.Database{
+				DatabaseType: "GeoIP2-City",
+				RecordSize:   32,
+				Nodes:        nodes,
+				Data:         data,
+			}.Bytes(),
+		},
+		{
+			description: "not a database",
+			db:          []byte("database content"),
+			err:         "invalid database: error opening database: invalid MaxMind DB file",
+		},
+		{
+			description: "truncated database",
+			db:          valid[:10],
+			err:         "invalid database: error opening database: invalid MaxMind DB file",
+		},
+		{
+			description: "other edition",
+			db:          mmdbtest.Build("GeoLite2-City", 1),
+			err:         `invalid database: database type is "GeoLite2-City", expected "GeoIP2-City"`,
+		},
+		{
+			description: "truncated search tree",
+			db: mmdbtest.Database{
+				DatabaseType: "GeoIP2-City",
+				RecordSize:   24,
+				Nodes:        nodes,
+				NodeCount:    100,
+			}.Bytes(),
+			err: "invalid database: the MaxMind DB contains invalid metadata",
+		},
+		{
+			description: "node count is too low",
+			db: mmdbtest.Database{
+				DatabaseType: "GeoIP2-City",
+				RecordSize:   24,
+				Nodes:        nodes,
+				Data:         data,
+				NodeCount:    2,
+			}.Bytes(),
+			err: "invalid database: node 1: record 3 points into the data section separator",
+		},
+		{
+			description: "record points past the data section",
+			db: mmdbtest.Database{
+				DatabaseType: "GeoIP2-City",
+				RecordSize:   24,
+				Nodes:        [][2]uint32{{1, 1 + 16 + 5}},
+				Data:         data,
+			}.Bytes(),
+			err: "invalid database: node 0: record 22 points past the data section",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "GeoIP2-City.mmdb")
+			require.NoError(t, os.WriteFile(path, test.db, 0o600))
+
+			_, err := validateDatabase(path, "GeoIP2-City")
+			if test.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, internal.ErrInvalidDatabase)
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestDecodeNode(t *testing.T) {
+	for _, recordSize := range []int{24, 28, 32} {
+		left, right := uint32(0x00abcdef), uint32(0x00fedcba)
+		if recordSize > 24 {
+			left, right = 0x0abcdef1, 0x0fedcba9
+		}
+
+		db := mmdbtest.Database{
+			RecordSize: recordSize,
+			Nodes:      [][2]uint32{{left, right}},
+		}.Bytes()
+
+		gotLeft, gotRight := decodeNode(db[:recordSize/4], uint(recordSize))
+		require.Equal(t, uint64(left), gotLeft, "left record with %d bits", recordSize)
+		require.Equal(t, uint64(right), gotRight, "right record with %d bits", recordSize)
+	}
+}
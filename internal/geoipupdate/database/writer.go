@@ -1,7 +1,10 @@
 package database
 
 import (
+	"context"
+	"fmt"
 	"io"
+	"strings"
 	"time"
 )
 
@@ -9,8 +12,56 @@ import (
 // database.
 const ZeroMD5 = "00000000000000000000000000000000"
 
+// IsRemoteURL returns whether the database directory dir is the URL of object
+// storage, a container registry or a program rather than a local directory.
+func IsRemoteURL(dir string) bool {
+	return IsObjectURL(dir) || IsOCIURL(dir) || IsExecURL(dir)
+}
+
+// CheckDirectory returns an error if the database directory dir is a URL of
+// a scheme that no writer supports, e.g., file://, rather than let it be
+// taken for a local directory.
+func CheckDirectory(dir string) error {
+	scheme, _, isURL := strings.Cut(dir, "://")
+	if isURL && !IsRemoteURL(dir) {
+		return fmt.Errorf("the scheme %q of %s is not supported", scheme, dir)
+	}
+	return nil
+}
+
 // Writer provides an interface for writing a database to a target location.
 type Writer interface {
 	Write(string, io.ReadCloser, string, time.Time) error
 	GetHash(editionID string) (string, error)
 }
+
+// ContextWriter is a Writer whose operations stop once a context is done.
+type ContextWriter interface {
+	Writer
+	WriteContext(context.Context, string, io.ReadCloser, string, time.Time) error
+	GetHashContext(ctx context.Context, editionID string) (string, error)
+}
+
+// WriteContext writes with w, passing ctx on if w is a ContextWriter.
+func WriteContext(
+	ctx context.Context,
+	w Writer,
+	editionID string,
+	reader io.ReadCloser,
+	newMD5 string,
+	lastModified time.Time,
+) error {
+	if cw, ok := w.(ContextWriter); ok {
+		return cw.WriteContext(ctx, editionID, reader, newMD5, lastModified)
+	}
+	return w.Write(editionID, reader, newMD5, lastModified)
+}
+
+// GetHashContext gets the hash from w, passing ctx on if w is a
+// ContextWriter.
+func GetHashContext(ctx context.Context, w Writer, editionID string) (string, error) {
+	if cw, ok := w.(ContextWriter); ok {
+		return cw.GetHashContext(ctx, editionID)
+	}
+	return w.GetHash(editionID)
+}
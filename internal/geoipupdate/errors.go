@@ -0,0 +1,25 @@
+package geoipupdate
+
+import (
+	"github.com/maxmind/geoipupdate/v8/updater"
+)
+
+// ConfigError is returned when the configuration is invalid.
+type ConfigError struct {
+	Err error
+}
+
+func (e ConfigError) Error() string {
+	return e.Err.Error()
+}
+
+func (e ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// WriteError is returned when a downloaded database could not be written.
+type WriteError = updater.WriteError
+
+// RetryError is returned when an edition could still not be downloaded after
+// retrying for the configured RetryFor duration.
+type RetryError = updater.RetryError
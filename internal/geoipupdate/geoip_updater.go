@@ -1,5 +1,5 @@
-// Package geoipupdate provides a library for using MaxMind's GeoIP Update
-// service.
+// Package geoipupdate implements the configuration, output and daemon mode
+// of the geoipupdate program on top of the updater package.
 package geoipupdate
 
 import (
@@ -10,20 +10,15 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"sync"
 	"time"
 
-	"github.com/cenkalti/backoff/v5"
-	"golang.org/x/sync/errgroup"
-
-	"github.com/maxmind/geoipupdate/v8/client"
 	"github.com/maxmind/geoipupdate/v8/internal"
 	"github.com/maxmind/geoipupdate/v8/internal/geoipupdate/database"
+	"github.com/maxmind/geoipupdate/v8/source"
+	"github.com/maxmind/geoipupdate/v8/updater"
 )
 
-type updateClient interface {
-	Download(context.Context, string, string) (client.DownloadResponse, error)
-}
+type updateClient = updater.Client
 
 // Updater uses config data to initiate a download or update
 // process for GeoIP databases.
@@ -31,43 +26,75 @@ type Updater struct {
 	config       *Config
 	output       *log.Logger
 	updateClient updateClient
-	writer       database.Writer
+	// httpClient is the HTTP client of the writers that updater.New creates
+	// for DatabaseDirectory and Destinations.
+	httpClient *http.Client
+	// writer, if set, is used instead.
+	writer database.Writer
 }
 
 // NewUpdater initialized a new Updater struct.
 func NewUpdater(config *Config) (*Updater, error) {
-	transport := http.DefaultTransport.(*http.Transport).Clone()
-	transport.OnProxyConnectResponse = proxyConnectResponse
-	if config.Proxy != nil {
-		transport.Proxy = http.ProxyURL(config.Proxy)
+	// Downloads are resumed from the database directory, unless it is in
+	// object storage.
+	var sourceOpts []source.Option
+	if !database.IsRemoteURL(config.DatabaseDirectory) {
+		sourceOpts = append(sourceOpts, source.WithResumeDirectory(config.DatabaseDirectory))
 	}
-	httpClient := &http.Client{Transport: transport}
-
-	updateClient, err := client.New(
-		config.AccountID,
-		config.LicenseKey,
-		client.WithEndpoint(config.URL),
-		client.WithHTTPClient(httpClient),
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	writer, err := database.NewLocalFileWriter(
-		config.DatabaseDirectory,
-		config.PreserveFileTimes,
-		config.Verbose,
-	)
+	updateClient, err := newSource(config, sourceOpts...)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Updater{
+	u := &Updater{
 		config:       config,
 		output:       log.New(os.Stdout, "", 0),
 		updateClient: updateClient,
-		writer:       writer,
-	}, nil
+		httpClient:   newHTTPClient(config),
+	}
+
+	// The updater, and with it the writer, is created for every run. This
+	// fails early if it cannot be.
+	if _, err := u.newUpdater(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// newSource creates the source for Host, which is the update server by
+// default.
+func newSource(config *Config, options ...source.Option) (source.Source, error) {
+	opts := []source.Option{
+		source.WithHTTPClient(newHTTPClient(config)),
+		source.WithSignatureSuffix(config.SignatureSuffix),
+	}
+	if config.Verbose {
+		opts = append(opts, source.WithLogger(log.Default()))
+	}
+	return source.New(
+		config.URL,
+		config.AccountID,
+		config.LicenseKey,
+		append(opts, options...)...,
+	)
+}
+
+// httpTimeout bounds each request of the source and object storage, so that
+// a stalled one fails rather than hanging. It leaves ample time for the
+// largest databases on slow links, and a download that times out is retried
+// and resumed.
+const httpTimeout = 15 * time.Minute
+
+// newHTTPClient creates the HTTP client for the source and object storage,
+// which goes through Proxy if it is set.
+func newHTTPClient(config *Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.OnProxyConnectResponse = proxyConnectResponse
+	transport.ResponseHeaderTimeout = time.Minute
+	if config.Proxy != nil {
+		transport.Proxy = http.ProxyURL(config.Proxy)
+	}
+	return &http.Client{Transport: transport, Timeout: httpTimeout}
 }
 
 func proxyConnectResponse(
@@ -88,48 +115,39 @@ func proxyConnectResponse(
 
 // Run starts the download or update process.
 func (u *Updater) Run(ctx context.Context) error {
-	fileLock, err := internal.NewFileLock(u.config.LockFile, u.config.Verbose)
-	if err != nil {
-		return fmt.Errorf("initializing file lock: %w", err)
-	}
-	if err := fileLock.Acquire(); err != nil {
-		return fmt.Errorf("acquiring file lock: %w", err)
-	}
-	defer func() {
-		if err := fileLock.Release(); err != nil {
-			log.Printf("releasing file lock: %s", err)
-		}
-	}()
-
-	g, ctx := errgroup.WithContext(ctx)
-	g.SetLimit(u.config.Parallelism)
-
-	var editions []database.ReadResult
-	var mu sync.Mutex
-	for _, editionID := range u.config.EditionIDs {
-		g.Go(func() error {
-			if err := ctx.Err(); err != nil {
-				return fmt.Errorf("stop updating on the first error: %w", err)
-			}
+	return u.run(ctx, u.config.EditionIDs)
+}
 
-			edition, err := u.downloadEdition(ctx, editionID, u.updateClient, u.writer)
-			if err != nil {
-				return err
-			}
+// Backfill stores the releases of an edition built on the dates from
+// through to in the database directory. See updater.Updater.Backfill.
+func (u *Updater) Backfill(
+	ctx context.Context,
+	editionID string,
+	from,
+	to time.Time,
+) ([]updater.BackfillResult, error) {
+	if err := requireLocalDirectory(u.config, "backfilling"); err != nil {
+		return nil, err
+	}
 
-			edition.CheckedAt = time.Now().In(time.UTC)
+	up, err := u.newUpdater()
+	if err != nil {
+		return nil, err
+	}
+	return up.Backfill(ctx, editionID, from, to)
+}
 
-			mu.Lock()
-			editions = append(editions, *edition)
-			mu.Unlock()
-			return nil
-		})
+// run downloads or updates the given editions.
+func (u *Updater) run(ctx context.Context, editionIDs []string) error {
+	up, err := u.newUpdater()
+	if err != nil {
+		return err
 	}
 
-	// Wait blocks until all the editions are downloaded or exits early after
-	// the first encountered error.
-	if err := g.Wait(); err != nil {
-		return fmt.Errorf("downloading editions: %w", err)
+	editions, err := up.RunEditions(ctx, editionIDs)
+	// Without ContinueOnError, nothing is output if an edition failed.
+	if err != nil && !u.config.ContinueOnError {
+		return err
 	}
 
 	if u.config.Output {
@@ -140,97 +158,44 @@ func (u *Updater) Run(ctx context.Context) error {
 		u.output.Print(string(result))
 	}
 
-	return nil
+	return err
 }
 
-// downloadEdition downloads the file with retries.
-func (u *Updater) downloadEdition(
-	ctx context.Context,
-	editionID string,
-	uc updateClient,
-	w database.Writer,
-) (*database.ReadResult, error) {
-	editionHash, err := w.GetHash(editionID)
-	if err != nil {
-		return nil, err
+// newUpdater creates the updater.Updater that does the work.
+func (u *Updater) newUpdater() (*updater.Updater, error) {
+	opts := []updater.Option{
+		updater.WithClient(u.updateClient),
+		updater.WithHTTPClient(u.httpClient),
+	}
+	if u.writer != nil {
+		opts = append(opts, updater.WithWriter(u.writer))
+	}
+	if u.config.Verbose {
+		opts = append(opts, updater.WithLogger(log.Default()))
 	}
 
-	b := backoff.NewExponentialBackOff()
-
-	opts := []backoff.RetryOption{
-		backoff.WithBackOff(b),
-		backoff.WithNotify(func(err error, d time.Duration) {
-			if u.config.Verbose {
-				log.Printf("Couldn't download %s, retrying in %v: %v", editionID, d, err)
-			}
-		}),
-	}
-
-	if u.config.RetryFor == 0 {
-		opts = append(opts, backoff.WithMaxTries(1))
-	} else {
-		opts = append(opts, backoff.WithMaxElapsedTime(u.config.RetryFor))
-	}
-
-	var edition *database.ReadResult
-	_, err = backoff.Retry(
-		ctx,
-		func() (bool, error) {
-			res, err := uc.Download(ctx, editionID, editionHash)
-			if err != nil {
-				if !internal.IsRetryableError(err) {
-					return false, backoff.Permanent(err)
-				}
-
-				return false, err
-			}
-			defer res.Reader.Close()
-
-			if !res.UpdateAvailable {
-				if u.config.Verbose {
-					log.Printf("No new updates available for %s", editionID)
-					log.Printf("Database %s up to date", editionID)
-				}
-
-				edition = &database.ReadResult{
-					EditionID: editionID,
-					OldHash:   editionHash,
-					NewHash:   editionHash,
-				}
-				return false, nil
-			}
-
-			if u.config.Verbose {
-				log.Printf("Updates available for %s", editionID)
-			}
-
-			err = u.writer.Write(
-				editionID,
-				res.Reader,
-				res.MD5,
-				res.LastModified,
-			)
-			if err != nil {
-				if !internal.IsRetryableError(err) {
-					return false, backoff.Permanent(err)
-				}
-
-				return false, err
-			}
-
-			edition = &database.ReadResult{
-				EditionID:  editionID,
-				OldHash:    editionHash,
-				NewHash:    res.MD5,
-				ModifiedAt: res.LastModified,
-			}
-			return false, nil
+	up, err := updater.New(
+		updater.Config{
+			EditionIDs:         u.config.EditionIDs,
+			EditionDates:       u.config.EditionDates,
+			DatabaseDirectory:  u.config.DatabaseDirectory,
+			Destinations:       u.config.Destinations,
+			PreserveFileTimes:  u.config.PreserveFileTimes,
+			LockFile:           u.config.LockFile,
+			Parallelism:        u.config.Parallelism,
+			RetryFor:           u.config.RetryFor,
+			ContinueOnError:    u.config.ContinueOnError,
+			SignaturePublicKey: u.config.SignaturePublicKey,
+			SignatureDirectory: u.config.SignatureDirectory,
+			SignatureSuffix:    u.config.SignatureSuffix,
+			AllowDowngrade:     u.config.AllowDowngrade,
+			AtomicGroup:        u.config.AtomicGroup,
+			KeepVersions:       u.config.KeepVersions,
 		},
 		opts...,
 	)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("initializing updater: %w", err)
 	}
-
-	return edition, nil
+	return up, nil
 }
@@ -5,8 +5,10 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/md5"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
@@ -26,6 +28,7 @@ import (
 	"github.com/maxmind/geoipupdate/v8/client"
 	"github.com/maxmind/geoipupdate/v8/internal"
 	"github.com/maxmind/geoipupdate/v8/internal/geoipupdate/database"
+	"github.com/maxmind/geoipupdate/v8/internal/mmdbtest"
 )
 
 // TestUpdaterOutput makes sure that the Updater outputs the result of its
@@ -128,6 +131,165 @@ func TestUpdaterOutput(t *testing.T) {
 	require.ErrorIs(t, err, streamErr)
 }
 
+// TestUpdaterContinueOnError checks that with ContinueOnError every edition
+// is attempted and the failures are reported per edition.
+func TestUpdaterContinueOnError(t *testing.T) {
+	tempDir := t.TempDir()
+
+	config := &Config{
+		ContinueOnError: true,
+		EditionIDs:      []string{"GeoIP2-City", "GeoLite2-City", "GeoLite2-Country"},
+		LockFile:        filepath.Join(tempDir, ".geoipupdate.lock"),
+		Output:          true,
+		Parallelism:     1,
+	}
+
+	unauthorized := internal.HTTPError{StatusCode: http.StatusUnauthorized}
+
+	logOutput := &bytes.Buffer{}
+	u := &Updater{
+		config: config,
+		output: log.New(logOutput, "", 0),
+		updateClient: mockEditionClient{
+			"GeoIP2-City": {err: unauthorized},
+			"GeoLite2-City": {res: client.DownloadResponse{
+				MD5:             "B",
+				Reader:          io.NopCloser(strings.NewReader("")),
+				UpdateAvailable: true,
+			}},
+			"GeoLite2-Country": {res: client.DownloadResponse{
+				Reader: io.NopCloser(strings.NewReader("")),
+			}},
+		},
+		writer: &mockWriter{
+			md5s: map[string]string{
+				"GeoIP2-City":      "A",
+				"GeoLite2-City":    "A",
+				"GeoLite2-Country": "C",
+			},
+		},
+	}
+
+	err := u.Run(t.Context())
+	require.ErrorIs(t, err, unauthorized)
+	require.ErrorContains(t, err, "GeoIP2-City: ")
+
+	var outputDatabases []database.ReadResult
+	require.NoError(t, json.Unmarshal(logOutput.Bytes(), &outputDatabases))
+	require.Len(t, outputDatabases, 3)
+
+	want := []struct {
+		editionID string
+		status    string
+		err       string
+		newHash   string
+	}{
+		{
+			editionID: "GeoIP2-City",
+			status:    database.StatusFailed,
+			err:       unauthorized.Error(),
+		},
+		{
+			editionID: "GeoLite2-City",
+			status:    database.StatusUpdated,
+			newHash:   "B",
+		},
+		{
+			editionID: "GeoLite2-Country",
+			status:    database.StatusUpToDate,
+			newHash:   "C",
+		},
+	}
+	for i, w := range want {
+		got := outputDatabases[i]
+		require.Equal(t, w.editionID, got.EditionID)
+		require.Equal(t, w.status, got.Status)
+		require.Equal(t, w.err, got.Error)
+		require.Equal(t, w.newHash, got.NewHash)
+		require.Equal(t, 1, got.Attempts)
+		require.False(t, got.CheckedAt.IsZero())
+	}
+
+	// Without ContinueOnError, the first failure stops the run and nothing
+	// is printed.
+	logOutput.Reset()
+	config.ContinueOnError = false
+	err = u.Run(t.Context())
+	require.ErrorIs(t, err, unauthorized)
+	require.Empty(t, logOutput.String())
+}
+
+func TestUpdaterErrorTypes(t *testing.T) {
+	connRefused := errors.New("connection refused")
+	unauthorized := internal.HTTPError{StatusCode: http.StatusUnauthorized}
+	diskFull := errors.New("no space left on device")
+
+	update := client.DownloadResponse{
+		MD5:             "B",
+		Reader:          io.NopCloser(strings.NewReader("")),
+		UpdateAvailable: true,
+	}
+
+	tests := []struct {
+		description string
+		response    mockEditionResponse
+		writeErr    error
+		check       func(*testing.T, error)
+	}{
+		{
+			description: "retryable error",
+			response:    mockEditionResponse{err: connRefused},
+			check: func(t *testing.T, err error) {
+				var retryErr RetryError
+				require.ErrorAs(t, err, &retryErr)
+				require.Equal(t, 1, retryErr.Attempts)
+				require.ErrorIs(t, err, connRefused)
+			},
+		},
+		{
+			description: "permanent error",
+			response:    mockEditionResponse{err: unauthorized},
+			check: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, unauthorized)
+				require.NotErrorAs(t, err, &RetryError{})
+			},
+		},
+		{
+			description: "write error",
+			response:    mockEditionResponse{res: update},
+			writeErr:    diskFull,
+			check: func(t *testing.T, err error) {
+				require.ErrorAs(t, err, &WriteError{})
+				require.ErrorIs(t, err, diskFull)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			u := &Updater{
+				config: &Config{
+					EditionIDs:  []string{"GeoIP2-City"},
+					LockFile:    filepath.Join(t.TempDir(), ".geoipupdate.lock"),
+					Parallelism: 1,
+				},
+				output:       log.New(io.Discard, "", 0),
+				updateClient: mockEditionClient{"GeoIP2-City": test.response},
+				writer: &mockWriter{
+					md5s: map[string]string{"GeoIP2-City": "A"},
+					writeFunc: func(string, io.ReadCloser, string, time.Time) error {
+						return test.writeErr
+					},
+				},
+			}
+
+			err := u.Run(t.Context())
+			require.Error(t, err)
+			test.check(t, err)
+		})
+	}
+}
+
 func TestRetryWhenWriting(t *testing.T) {
 	tempDir := t.TempDir()
 
@@ -137,16 +299,19 @@ func TestRetryWhenWriting(t *testing.T) {
 	err := os.MkdirAll(databaseDir, 0o750)
 	require.NoError(t, err)
 
+	db := mmdbtest.Build("foo-db-name", 1)
+	dbMD5 := fmt.Sprintf("%x", md5.Sum(db))
+
 	try := 0
 	sv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Mocking the metadata endpoint.
 		if r.URL.Path == "/geoip/updates/metadata" {
 			w.Header().Set("Content-Type", "application/json")
 
-			// The md5 here belongs to the tar.gz sent below.
+			// The md5 here belongs to the database in the tar.gz sent below.
 			metadata := []byte(
 				`{"databases":[{"edition_id":"foo-db-name",` +
-					`"md5":"83e01ba43c2a66e30cb3007c1a300c78","date":"2023-04-27"}]}`)
+					`"md5":"` + dbMD5 + `","date":"2023-04-27"}]}`)
 			_, err := w.Write(metadata)
 			assert.NoError(t, err)
 
@@ -162,7 +327,7 @@ func TestRetryWhenWriting(t *testing.T) {
 
 		info := mockFileInfo{
 			name: "foo-db-name.mmdb",
-			size: 1000,
+			size: int64(len(db)),
 		}
 		header, err := tar.FileInfoHeader(info, info.Name())
 		if !assert.NoError(t, err) {
@@ -176,18 +341,16 @@ func TestRetryWhenWriting(t *testing.T) {
 			return
 		}
 
-		bytesToWrite := 1000
+		content := db
 		if try == 0 {
 			// In the first try, we create a bad tar.gz file.
 			// That has less than the size defined in the header.
-			bytesToWrite = 100
+			content = db[:len(db)/2]
 		}
 
-		for range bytesToWrite {
-			_, err = tarWriter.Write([]byte("t"))
-			if !assert.NoError(t, err) {
-				return
-			}
+		_, err = tarWriter.Write(content)
+		if !assert.NoError(t, err) {
+			return
 		}
 		try++
 	}))
@@ -381,6 +544,26 @@ func (m *mockUpdateClient) Download(
 	return res, nil
 }
 
+type mockEditionResponse struct {
+	res client.DownloadResponse
+	err error
+}
+
+// mockEditionClient returns a fixed response for each edition.
+type mockEditionClient map[string]mockEditionResponse
+
+func (m mockEditionClient) Download(
+	_ context.Context,
+	editionID,
+	_ string,
+) (client.DownloadResponse, error) {
+	r, ok := m[editionID]
+	if !ok {
+		return client.DownloadResponse{}, errors.New("unknown edition")
+	}
+	return r.res, r.err
+}
+
 type mockWriter struct {
 	md5s      map[string]string
 	writeFunc func(string, io.ReadCloser, string, time.Time) error
@@ -0,0 +1,80 @@
+package geoipupdate
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
+	"github.com/maxmind/geoipupdate/v8/internal/geoipupdate/database"
+	"github.com/maxmind/geoipupdate/v8/updater"
+)
+
+// Rollback replaces the database of an edition with a version kept in the
+// history of the database directory while holding the lock file. See
+// database.LocalFileWriter.Rollback for how the version is selected. The
+// edition is then held, so that updates leave it alone until Resume is
+// called.
+func Rollback(config *Config, editionID, to string) (database.Version, error) {
+	if err := requireLocalDirectory(config, "rolling back"); err != nil {
+		return database.Version{}, err
+	}
+
+	release, err := acquireLock(config)
+	if err != nil {
+		return database.Version{}, err
+	}
+	defer release()
+
+	writer, err := database.NewLocalFileWriter(
+		config.DatabaseDirectory,
+		config.PreserveFileTimes,
+		config.Verbose,
+		database.WithKeepVersions(config.KeepVersions),
+	)
+	if err != nil {
+		return database.Version{}, err
+	}
+
+	version, err := writer.Rollback(editionID, to)
+	if err != nil {
+		return database.Version{}, err
+	}
+	if err := updater.Hold(config.DatabaseDirectory, editionID, version.MD5); err != nil {
+		return database.Version{}, fmt.Errorf("holding %s: %w", editionID, err)
+	}
+	return version, nil
+}
+
+// Resume clears the hold of an edition that was rolled back, so that the
+// next update installs the latest database again. It returns whether the
+// edition was held.
+func Resume(config *Config, editionID string) (bool, error) {
+	if err := requireLocalDirectory(config, "resuming updates"); err != nil {
+		return false, err
+	}
+
+	release, err := acquireLock(config)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	return updater.Unhold(config.DatabaseDirectory, editionID)
+}
+
+// acquireLock acquires the lock file and returns the function that releases
+// it.
+func acquireLock(config *Config) (func(), error) {
+	fileLock, err := internal.NewFileLock(config.LockFile, config.Verbose)
+	if err != nil {
+		return nil, fmt.Errorf("initializing file lock: %w", err)
+	}
+	if err := fileLock.Acquire(); err != nil {
+		return nil, fmt.Errorf("acquiring file lock: %w", err)
+	}
+	return func() {
+		if err := fileLock.Release(); err != nil {
+			log.Printf("releasing file lock: %s", err)
+		}
+	}, nil
+}
@@ -0,0 +1,173 @@
+package geoipupdate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/maxmind/geoipupdate/v8/internal/cron"
+)
+
+// maxSleep bounds how long the scheduler waits before looking at the wall
+// clock again. Timers follow the monotonic clock, which on some systems stops
+// while the machine is suspended, so a long timer could otherwise fire well
+// after the update it was waiting for was due.
+const maxSleep = time.Minute
+
+// editionSchedule decides when an edition is next due.
+type editionSchedule interface {
+	// Next returns the first time after t that the edition is due, or the
+	// zero time if it never will be again.
+	Next(t time.Time) time.Time
+}
+
+// interval is an editionSchedule that is due at a fixed interval.
+type interval time.Duration
+
+func (i interval) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(i))
+}
+
+// RunSchedule updates all editions and then keeps updating each edition
+// whenever its schedule says that it is due, until ctx is canceled. Editions
+// are scheduled by their EditionSchedules entry, then by Schedule and finally
+// by Frequency.
+//
+// A failed update is logged and the edition is tried again when it is next
+// due. If the process was not running when an update was due, e.g., because
+// the machine was suspended, the update is run once as soon as possible.
+func (u *Updater) RunSchedule(ctx context.Context) error {
+	schedules, err := u.editionSchedules()
+	if err != nil {
+		return ConfigError{Err: err}
+	}
+
+	s := &scheduler{
+		editionIDs: u.config.EditionIDs,
+		schedules:  schedules,
+		now:        wallClock,
+		after:      time.After,
+		run:        u.run,
+		verbose:    u.config.Verbose,
+	}
+	s.loop(ctx)
+	return nil
+}
+
+// editionSchedules returns the schedule for every edition.
+func (u *Updater) editionSchedules() (map[string]editionSchedule, error) {
+	schedules := map[string]editionSchedule{}
+	for _, editionID := range u.config.EditionIDs {
+		spec := u.config.Schedule
+		if editionSpec, ok := u.config.EditionSchedules[editionID]; ok {
+			spec = editionSpec
+		}
+
+		switch {
+		case spec != "":
+			s, err := cron.Parse(spec)
+			if err != nil {
+				return nil, fmt.Errorf("parsing schedule for %s: %w", editionID, err)
+			}
+			schedules[editionID] = s
+		case u.config.Frequency > 0:
+			schedules[editionID] = interval(u.config.Frequency)
+		default:
+			return nil, fmt.Errorf("%s has no schedule or frequency", editionID)
+		}
+	}
+	return schedules, nil
+}
+
+// wallClock returns the current time without a monotonic clock reading, so
+// that comparisons use the wall clock.
+func wallClock() time.Time {
+	return time.Now().Round(0)
+}
+
+type scheduler struct {
+	editionIDs []string
+	schedules  map[string]editionSchedule
+	now        func() time.Time
+	after      func(time.Duration) <-chan time.Time
+	run        func(context.Context, []string) error
+	verbose    bool
+}
+
+func (s *scheduler) loop(ctx context.Context) {
+	next := map[string]time.Time{}
+
+	// Update everything on start up so that the databases are current
+	// before we wait for the first scheduled update.
+	s.runEditions(ctx, s.editionIDs, next)
+
+	for ctx.Err() == nil {
+		now := s.now()
+
+		var due []string
+		var earliest time.Time
+		for _, editionID := range s.editionIDs {
+			n, ok := next[editionID]
+			if !ok {
+				continue
+			}
+			if !n.After(now) {
+				if s.verbose && now.Sub(n) > maxSleep {
+					log.Printf("Running update for %s that was due at %s", editionID, n)
+				}
+				due = append(due, editionID)
+				continue
+			}
+			if earliest.IsZero() || n.Before(earliest) {
+				earliest = n
+			}
+		}
+
+		if len(due) > 0 {
+			s.runEditions(ctx, due, next)
+			continue
+		}
+
+		if len(next) == 0 {
+			log.Print("No editions are scheduled to be updated again")
+			<-ctx.Done()
+			return
+		}
+
+		wait := min(earliest.Sub(now), maxSleep)
+		select {
+		case <-ctx.Done():
+		case <-s.after(wait):
+		}
+	}
+
+	if s.verbose {
+		log.Print("Shutting down")
+	}
+}
+
+// runEditions updates editionIDs and records when each is next due.
+func (s *scheduler) runEditions(
+	ctx context.Context,
+	editionIDs []string,
+	next map[string]time.Time,
+) {
+	if err := s.run(ctx, editionIDs); err != nil && ctx.Err() == nil {
+		log.Printf("Error retrieving updates: %s", err)
+	}
+
+	now := s.now()
+	for _, editionID := range editionIDs {
+		n := s.schedules[editionID].Next(now)
+		if n.IsZero() {
+			log.Printf("%s will not be updated again as its schedule never matches", editionID)
+			delete(next, editionID)
+			continue
+		}
+		if s.verbose {
+			log.Printf("Next update for %s at %s", editionID, n)
+		}
+		next[editionID] = n
+	}
+}
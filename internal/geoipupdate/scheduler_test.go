@@ -0,0 +1,164 @@
+package geoipupdate
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/internal/cron"
+)
+
+// fakeClock is a clock where waiting moves time forward instantly.
+type fakeClock struct {
+	now time.Time
+	// jumps are added to the clock, in order, in place of the duration
+	// being waited for. This simulates the machine being suspended.
+	jumps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	if len(c.jumps) > 0 {
+		d = c.jumps[0]
+		c.jumps = c.jumps[1:]
+	}
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+type scheduledRun struct {
+	at         time.Time
+	editionIDs []string
+}
+
+func TestSchedulerLoop(t *testing.T) {
+	daily, err := cron.Parse("0 6 * * *")
+	require.NoError(t, err)
+	twiceWeekly, err := cron.Parse("0 6 * * 2,5")
+	require.NoError(t, err)
+
+	// 2026-10-14 is a Wednesday.
+	start := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		description string
+		jumps       []time.Duration
+		runs        int
+		want        []scheduledRun
+	}{
+		{
+			description: "editions run when due",
+			runs:        4,
+			want: []scheduledRun{
+				{at: start, editionIDs: []string{"GeoIP2-City", "GeoLite2-ASN", "GeoLite2-Country"}},
+				{
+					at:         time.Date(2026, 10, 14, 18, 0, 0, 0, time.UTC),
+					editionIDs: []string{"GeoLite2-Country"},
+				},
+				{
+					at:         time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC),
+					editionIDs: []string{"GeoLite2-Country"},
+				},
+				{
+					at:         time.Date(2026, 10, 15, 6, 0, 0, 0, time.UTC),
+					editionIDs: []string{"GeoLite2-ASN", "GeoLite2-Country"},
+				},
+			},
+		},
+		{
+			description: "missed runs are caught up once",
+			jumps:       []time.Duration{72 * time.Hour},
+			runs:        2,
+			want: []scheduledRun{
+				{at: start, editionIDs: []string{"GeoIP2-City", "GeoLite2-ASN", "GeoLite2-Country"}},
+				{
+					at:         start.Add(72 * time.Hour),
+					editionIDs: []string{"GeoIP2-City", "GeoLite2-ASN", "GeoLite2-Country"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+
+			clock := &fakeClock{now: start, jumps: test.jumps}
+
+			var runs []scheduledRun
+			s := &scheduler{
+				editionIDs: []string{"GeoIP2-City", "GeoLite2-ASN", "GeoLite2-Country"},
+				schedules: map[string]editionSchedule{
+					"GeoIP2-City":      twiceWeekly,
+					"GeoLite2-ASN":     daily,
+					"GeoLite2-Country": interval(6 * time.Hour),
+				},
+				now:   clock.Now,
+				after: clock.After,
+				run: func(_ context.Context, editionIDs []string) error {
+					runs = append(runs, scheduledRun{
+						at:         clock.now,
+						editionIDs: slices.Clone(editionIDs),
+					})
+					if len(runs) == test.runs {
+						cancel()
+					}
+					// Failures must not stop the scheduler.
+					return errors.New("failed")
+				},
+			}
+
+			s.loop(ctx)
+
+			require.Equal(t, test.want, runs)
+		})
+	}
+}
+
+func TestEditionSchedules(t *testing.T) {
+	u := &Updater{
+		config: &Config{
+			EditionIDs: []string{"GeoIP2-City", "GeoLite2-ASN"},
+			EditionSchedules: map[string]string{
+				"GeoLite2-ASN": "0 3 * * *",
+			},
+			Schedule: "0 6 * * 2,5",
+		},
+	}
+	schedules, err := u.editionSchedules()
+	require.NoError(t, err)
+
+	from := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	require.Equal(
+		t,
+		time.Date(2026, 10, 16, 6, 0, 0, 0, time.UTC),
+		schedules["GeoIP2-City"].Next(from),
+	)
+	require.Equal(
+		t,
+		time.Date(2026, 10, 15, 3, 0, 0, 0, time.UTC),
+		schedules["GeoLite2-ASN"].Next(from),
+	)
+
+	// Without a schedule, the frequency is used.
+	u.config.Schedule = ""
+	u.config.Frequency = time.Hour
+	schedules, err = u.editionSchedules()
+	require.NoError(t, err)
+	require.Equal(t, from.Add(time.Hour), schedules["GeoIP2-City"].Next(from))
+
+	// Without either, the edition cannot be scheduled.
+	u.config.Frequency = 0
+	_, err = u.editionSchedules()
+	require.EqualError(t, err, "GeoIP2-City has no schedule or frequency")
+}
@@ -0,0 +1,149 @@
+package geoipupdate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/maxmind/geoipupdate/v8/mirror"
+	"github.com/maxmind/geoipupdate/v8/source"
+)
+
+// defaultServeAddress is the address the serve command listens on if
+// ServeAddress is not set.
+const defaultServeAddress = ":8080"
+
+// Serve serves the databases of the configured editions in the database
+// directory over the update API until ctx is done. With ServeProxy, the
+// databases are instead downloaded from the update server on demand and
+// cached in the database directory. Clients authenticate with
+// ServeAccountID and ServeLicenseKey, which are only protected in transit if
+// ServeTLSCertFile and ServeTLSKeyFile are set or HTTPS is terminated in
+// front of it.
+func Serve(ctx context.Context, config *Config) error {
+	if config.ServeAccountID == 0 || config.ServeLicenseKey == "" {
+		return ConfigError{
+			Err: errors.New("the `ServeAccountID' and `ServeLicenseKey' options are required to serve"),
+		}
+	}
+	if (config.ServeTLSCertFile == "") != (config.ServeTLSKeyFile == "") {
+		return ConfigError{
+			Err: errors.New("the `ServeTLSCertFile' and `ServeTLSKeyFile' options must be set together"),
+		}
+	}
+
+	if err := requireLocalDirectory(config, "serving"); err != nil {
+		return err
+	}
+
+	handler, err := newServeHandler(config)
+	if err != nil {
+		return err
+	}
+
+	address := config.ServeAddress
+	if address == "" {
+		address = defaultServeAddress
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", address, err)
+	}
+
+	return serve(ctx, listener, handler, config.ServeTLSCertFile, config.ServeTLSKeyFile)
+}
+
+// newServeHandler creates the mirror or, with ServeProxy, the caching proxy.
+func newServeHandler(config *Config) (http.Handler, error) {
+	if config.ServeProxy {
+		// Signature requests are forwarded with the suffix they were made
+		// with.
+		upstream, err := newSource(config, source.WithSignatureSuffix(mirror.SignatureSuffix))
+		if err != nil {
+			return nil, err
+		}
+
+		var opts []mirror.ProxyOption
+		if config.Verbose {
+			opts = append(opts, mirror.WithProxyLogger(log.Default()))
+		}
+		proxy, err := mirror.NewProxy(
+			config.DatabaseDirectory,
+			config.EditionIDs,
+			upstream,
+			config.ServeAccountID,
+			config.ServeLicenseKey,
+			opts...,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("initializing proxy: %w", err)
+		}
+		return proxy, nil
+	}
+
+	var opts []mirror.Option
+	if config.Verbose {
+		opts = append(opts, mirror.WithLogger(log.Default()))
+	}
+	if len(config.AtomicGroup) > 0 {
+		opts = append(opts, mirror.WithAtomicGroup(config.AtomicGroup...))
+	}
+	server, err := mirror.New(
+		config.DatabaseDirectory,
+		config.EditionIDs,
+		config.ServeAccountID,
+		config.ServeLicenseKey,
+		opts...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("initializing mirror: %w", err)
+	}
+	return server, nil
+}
+
+// serve serves handler on listener until ctx is done, then waits for the
+// requests in progress to finish. It serves HTTPS with the certificate and
+// key in certFile and keyFile if they are set, and plain HTTP otherwise.
+func serve(
+	ctx context.Context,
+	listener net.Listener,
+	handler http.Handler,
+	certFile,
+	keyFile string,
+) error {
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	if certFile != "" {
+		log.Printf("Serving databases on https://%s", listener.Addr())
+		go func() {
+			errs <- server.ServeTLS(listener, certFile, keyFile)
+		}()
+	} else {
+		log.Printf("Serving databases on http://%s", listener.Addr())
+		go func() {
+			errs <- server.Serve(listener)
+		}()
+	}
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("serving: %w", err)
+	case <-ctx.Done():
+	}
+
+	// Downloads in progress get some time to finish.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutting down: %w", err)
+	}
+	return nil
+}
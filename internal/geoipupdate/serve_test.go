@@ -0,0 +1,109 @@
+package geoipupdate
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServe(t *testing.T) {
+	err := Serve(t.Context(), &Config{ServeAccountID: 42})
+	var configErr ConfigError
+	require.ErrorAs(t, err, &configErr)
+	require.EqualError(t, err, "the `ServeAccountID' and `ServeLicenseKey' options are required to serve")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() {
+		done <- serve(ctx, listener, http.NotFoundHandler(), "", "")
+	}()
+
+	res, err := http.Get("http://" + listener.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	// The server shuts down cleanly once ctx is done.
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestServeTLS(t *testing.T) {
+	err := Serve(t.Context(), &Config{
+		ServeAccountID:   42,
+		ServeLicenseKey:  "downstream",
+		ServeTLSCertFile: "/etc/geoipupdate/cert.pem",
+	})
+	var configErr ConfigError
+	require.ErrorAs(t, err, &configErr)
+	require.EqualError(t, err, "the `ServeTLSCertFile' and `ServeTLSKeyFile' options must be set together")
+
+	certFile, keyFile, pool := writeTestCertificate(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() {
+		done <- serve(ctx, listener, http.NotFoundHandler(), certFile, keyFile)
+	}()
+
+	c := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	res, err := c.Get("https://" + listener.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+// writeTestCertificate writes a self-signed certificate for 127.0.0.1 and
+// its key, and returns their paths and a pool that trusts the certificate.
+func writeTestCertificate(t *testing.T) (certFile, keyFile string, pool *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "geoipupdate"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	pool = x509.NewCertPool()
+	pool.AddCert(cert)
+	return certFile, keyFile, pool
+}
@@ -0,0 +1,177 @@
+// Package minisign verifies minisign signatures.
+//
+// Only prehashed signatures, which minisign has created by default since
+// version 0.8, are supported, as they can be verified while a database is
+// streamed rather than after it has been read into memory.
+package minisign
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	keyIDSize = 8
+
+	trustedCommentPrefix = "trusted comment: "
+)
+
+var (
+	algEd25519 = []byte("Ed")
+	algHashed  = []byte("ED")
+)
+
+// ErrInvalidSignature is returned when a signature does not match the data
+// or the public key.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// PublicKey is a minisign public key.
+type PublicKey struct {
+	keyID [keyIDSize]byte
+	key   ed25519.PublicKey
+}
+
+// ParsePublicKey parses a public key in the format minisign prints, e.g.,
+// "RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3". The contents of
+// a minisign .pub file are accepted as well.
+func ParsePublicKey(s string) (*PublicKey, error) {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	encoded := strings.TrimSpace(lines[len(lines)-1])
+
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decoding public key: %w", err)
+	}
+	if len(b) != len(algEd25519)+keyIDSize+ed25519.PublicKeySize ||
+		!bytes.Equal(b[:2], algEd25519) {
+		return nil, errors.New("not a minisign public key")
+	}
+
+	k := &PublicKey{key: ed25519.PublicKey(b[2+keyIDSize:])}
+	copy(k.keyID[:], b[2:])
+	return k, nil
+}
+
+// Signature is a parsed minisign signature.
+type Signature struct {
+	keyID          [keyIDSize]byte
+	signature      []byte
+	trustedComment string
+	globalSig      []byte
+}
+
+// ParseSignature parses the contents of a minisign signature file.
+func ParseSignature(b []byte) (*Signature, error) {
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 4 {
+		return nil, errors.New("signature must have 4 lines")
+	}
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], "\r")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		return nil, fmt.Errorf("decoding signature: %w", err)
+	}
+	if len(sig) != 2+keyIDSize+ed25519.SignatureSize {
+		return nil, errors.New("signature has the wrong size")
+	}
+	switch alg := sig[:2]; {
+	case bytes.Equal(alg, algHashed):
+	case bytes.Equal(alg, algEd25519):
+		return nil, errors.New("legacy signatures are not supported, sign with `minisign -S -H'")
+	default:
+		return nil, fmt.Errorf("unknown signature algorithm %q", alg)
+	}
+
+	trustedComment, ok := strings.CutPrefix(lines[2], trustedCommentPrefix)
+	if !ok {
+		return nil, errors.New("signature has no trusted comment")
+	}
+
+	globalSig, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil {
+		return nil, fmt.Errorf("decoding global signature: %w", err)
+	}
+	if len(globalSig) != ed25519.SignatureSize {
+		return nil, errors.New("global signature has the wrong size")
+	}
+
+	s := &Signature{
+		signature:      sig[2+keyIDSize:],
+		trustedComment: trustedComment,
+		globalSig:      globalSig,
+	}
+	copy(s.keyID[:], sig[2:])
+	return s, nil
+}
+
+// TrustedComment returns the trusted comment of the signature. It is only
+// trustworthy once the signature has been verified.
+func (s *Signature) TrustedComment() string {
+	return s.trustedComment
+}
+
+// Verifier verifies a signature of the data written to it.
+type Verifier struct {
+	key  *PublicKey
+	sig  *Signature
+	hash hash.Hash
+}
+
+// NewVerifier returns a Verifier for sig. The signed data must be written to
+// it before calling Verify.
+func (k *PublicKey) NewVerifier(sig *Signature) *Verifier {
+	h, err := blake2b.New512(nil)
+	if err != nil {
+		// This only fails for keys that are too long.
+		panic(err)
+	}
+	return &Verifier{key: k, sig: sig, hash: h}
+}
+
+// Write adds p to the signed data. It never returns an error.
+func (v *Verifier) Write(p []byte) (int, error) {
+	return v.hash.Write(p)
+}
+
+// Verify checks the signature of the data written so far. The error wraps
+// ErrInvalidSignature if it does not match.
+func (v *Verifier) Verify() error {
+	if v.sig.keyID != v.key.keyID {
+		return fmt.Errorf(
+			"%w: signed with key %s, expected key %s",
+			ErrInvalidSignature,
+			keyIDString(v.sig.keyID),
+			keyIDString(v.key.keyID),
+		)
+	}
+
+	if !ed25519.Verify(v.key.key, v.hash.Sum(nil), v.sig.signature) {
+		return fmt.Errorf("%w: the signature does not match the data", ErrInvalidSignature)
+	}
+
+	globalMessage := append(
+		append([]byte{}, v.sig.signature...),
+		v.sig.trustedComment...,
+	)
+	if !ed25519.Verify(v.key.key, globalMessage, v.sig.globalSig) {
+		return fmt.Errorf("%w: the trusted comment does not match", ErrInvalidSignature)
+	}
+
+	return nil
+}
+
+// keyIDString formats a key ID the way minisign displays it.
+func keyIDString(keyID [keyIDSize]byte) string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(keyID[:]))
+}
@@ -0,0 +1,132 @@
+package minisign
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/internal/minisigntest"
+)
+
+// These were created with another minisign implementation.
+const (
+	testPublicKey = `untrusted comment: minisign public key: FCF21116F5A9DD3E
+RWQ+3an1FhHy/HBt7antSiHb6fAdeW1rp6DBSWPA82s2M5ldlmOwN/8q
+`
+	testSignature = `untrusted comment: signature from private key: FCF21116F5A9DD3E
+RUQ+3an1FhHy/FwdNpVYlBHqq8bPXAmKczs1vmzI7sfEEILWTJ7EDB7JDVPIutZ0lO4rOOkhmyuegpxTykHrDYmVeUuY/MK9ogs=
+trusted comment: timestamp:1792202314
+gqcTynlMAYTL6PXSaTgWY/V5TjPXaN3F8jl4WwbAULNlX4HhsenW0+DL9Z95fC6DA5qS+tQg+yRNekuBJ1hbBQ==
+`
+	testLegacySignature = `untrusted comment: signature from private key: D2EAE02D2DB67129
+RWQpcbYtLeDq0uRJwY/WQrIW9/qLjUn9NmqzvWBNlQjgVPDdb7pQhvJTcMADxRlZTImLWTNy4S5Qliwd1AMv+/Z7F0nx5Yp9bw8=
+trusted comment: timestamp:1792202308
+qAy2I4YB8+bFl/Ow6vrhO+BfyGzL4HwraxBpvFKRbVOwLC6cZwQfb4eAsXgxL3I593bBQwXdR0UNcG9JChK2AA==
+`
+	testMessage = "hello database"
+)
+
+func TestVerify(t *testing.T) {
+	otherPublic, otherPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherKeyID := [keyIDSize]byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+	tamperedComment := strings.Replace(testSignature, "1792202314", "1792202315", 1)
+
+	tests := []struct {
+		description string
+		publicKey   string
+		signature   string
+		message     string
+		err         string
+	}{
+		{
+			description: "valid signature",
+			publicKey:   testPublicKey,
+			signature:   testSignature,
+			message:     testMessage,
+		},
+		{
+			description: "bare public key",
+			publicKey:   "RWQ+3an1FhHy/HBt7antSiHb6fAdeW1rp6DBSWPA82s2M5ldlmOwN/8q",
+			signature:   testSignature,
+			message:     testMessage,
+		},
+		{
+			description: "valid signature created by minisigntest",
+			publicKey:   minisigntest.EncodePublicKey(otherPublic, otherKeyID),
+			signature:   string(minisigntest.Sign(otherPrivate, otherKeyID, []byte(testMessage), "comment")),
+			message:     testMessage,
+		},
+		{
+			description: "different data",
+			publicKey:   testPublicKey,
+			signature:   testSignature,
+			message:     "hello database!",
+			err:         "invalid signature: the signature does not match the data",
+		},
+		{
+			description: "tampered trusted comment",
+			publicKey:   testPublicKey,
+			signature:   tamperedComment,
+			message:     testMessage,
+			err:         "invalid signature: the trusted comment does not match",
+		},
+		{
+			description: "different key",
+			publicKey:   minisigntest.EncodePublicKey(otherPublic, otherKeyID),
+			signature:   testSignature,
+			message:     testMessage,
+			err:         "invalid signature: signed with key FCF21116F5A9DD3E, expected key 0807060504030201",
+		},
+		{
+			description: "different key with the same key ID",
+			publicKey:   minisigntest.EncodePublicKey(otherPublic, [keyIDSize]byte{0x3e, 0xdd, 0xa9, 0xf5, 0x16, 0x11, 0xf2, 0xfc}),
+			signature:   testSignature,
+			message:     testMessage,
+			err:         "invalid signature: the signature does not match the data",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			key, err := ParsePublicKey(test.publicKey)
+			require.NoError(t, err)
+			sig, err := ParseSignature([]byte(test.signature))
+			require.NoError(t, err)
+
+			v := key.NewVerifier(sig)
+			_, err = v.Write([]byte(test.message))
+			require.NoError(t, err)
+
+			err = v.Verify()
+			if test.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrInvalidSignature)
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := ParsePublicKey("RWQBAgMEBQYHCAAB")
+	require.EqualError(t, err, "not a minisign public key")
+
+	_, err = ParsePublicKey("not base64!")
+	require.ErrorContains(t, err, "decoding public key")
+
+	_, err = ParseSignature([]byte(testLegacySignature))
+	require.EqualError(t, err, "legacy signatures are not supported, sign with `minisign -S -H'")
+
+	_, err = ParseSignature([]byte("untrusted comment: x\n"))
+	require.EqualError(t, err, "signature must have 4 lines")
+
+	noComment := strings.Replace(testSignature, "\ntrusted comment: ", "\ncomment: ", 1)
+	_, err = ParseSignature([]byte(noComment))
+	require.EqualError(t, err, "signature has no trusted comment")
+}
@@ -0,0 +1,39 @@
+// Package minisigntest creates minisign keys and signatures for tests.
+package minisigntest
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Sign creates a prehashed signature of message in the format of a minisign
+// signature file.
+func Sign(
+	key ed25519.PrivateKey,
+	keyID [8]byte,
+	message []byte,
+	trustedComment string,
+) []byte {
+	digest := blake2b.Sum512(message)
+	signature := ed25519.Sign(key, digest[:])
+
+	sig := append(append([]byte("ED"), keyID[:]...), signature...)
+	globalSig := ed25519.Sign(key, append(append([]byte{}, signature...), trustedComment...))
+
+	var b bytes.Buffer
+	b.WriteString("untrusted comment: signature from geoipupdate\n")
+	b.WriteString(base64.StdEncoding.EncodeToString(sig) + "\n")
+	b.WriteString("trusted comment: " + trustedComment + "\n")
+	b.WriteString(base64.StdEncoding.EncodeToString(globalSig) + "\n")
+	return b.Bytes()
+}
+
+// EncodePublicKey returns the minisign representation of a public key, as
+// accepted by minisign.ParsePublicKey.
+func EncodePublicKey(key ed25519.PublicKey, keyID [8]byte) string {
+	b := append(append([]byte("Ed"), keyID[:]...), key...)
+	return base64.StdEncoding.EncodeToString(b)
+}
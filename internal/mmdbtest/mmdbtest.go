@@ -0,0 +1,170 @@
+// Package mmdbtest builds small MMDB files for tests.
+package mmdbtest
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+)
+
+// MetadataStartMarker is the marker that precedes the metadata section.
+const MetadataStartMarker = "\xab\xcd\xefMaxMind.com"
+
+// Build returns an IPv4 MMDB with the given database type and build epoch. Its
+// search tree has a single node, so no address has any data.
+func Build(databaseType string, buildEpoch uint64) []byte {
+	return Database{
+		DatabaseType: databaseType,
+		BuildEpoch:   buildEpoch,
+		RecordSize:   24,
+		// Both records point to the node count, which means that there is
+		// no data.
+		Nodes: [][2]uint32{{1, 1}},
+	}.Bytes()
+}
+
+// Database describes an IPv4 MMDB whose search tree is given explicitly.
+type Database struct {
+	DatabaseType string
+	BuildEpoch   uint64
+	// RecordSize is 24, 28 or 32.
+	RecordSize int
+	// Nodes are the left and right records of the nodes of the search tree.
+	Nodes [][2]uint32
+	// Data is the data section. It is not checked.
+	Data []byte
+	// NodeCount is the node count in the metadata. If it is zero,
+	// len(Nodes) is used.
+	NodeCount uint32
+}
+
+// Bytes returns the database as an MMDB file.
+func (d Database) Bytes() []byte {
+	var b []byte
+
+	for _, node := range d.Nodes {
+		b = appendNode(b, d.RecordSize, node[0], node[1])
+	}
+
+	// Data section separator.
+	b = append(b, make([]byte, 16)...)
+	b = append(b, d.Data...)
+
+	nodeCount := d.NodeCount
+	if nodeCount == 0 {
+		nodeCount = uint32(len(d.Nodes)) //nolint:gosec // test databases are small.
+	}
+
+	b = append(b, MetadataStartMarker...)
+	b = appendValue(b, map[string]any{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 d.BuildEpoch,
+		"database_type":               d.DatabaseType,
+		"description":                 map[string]any{"en": "Test database"},
+		"ip_version":                  uint16(4),
+		"languages":                   []any{"en"},
+		"node_count":                  nodeCount,
+		"record_size":                 uint16(d.RecordSize), //nolint:gosec // see above.
+	})
+	return b
+}
+
+func appendNode(b []byte, recordSize int, left, right uint32) []byte {
+	switch recordSize {
+	case 24:
+		return append(b,
+			byte(left>>16), byte(left>>8), byte(left),
+			byte(right>>16), byte(right>>8), byte(right),
+		)
+	case 28:
+		// The middle byte holds the most significant bits of both records.
+		return append(b,
+			byte(left>>16), byte(left>>8), byte(left),
+			byte(left>>20&0xf0|right>>24&0x0f),
+			byte(right>>16), byte(right>>8), byte(right),
+		)
+	case 32:
+		b = binary.BigEndian.AppendUint32(b, left)
+		return binary.BigEndian.AppendUint32(b, right)
+	default:
+		panic(fmt.Sprintf("unsupported record size %d", recordSize))
+	}
+}
+
+// Write writes a database built by Build to path and returns its MD5.
+func Write(t testing.TB, path, databaseType string, buildEpoch uint64) string {
+	t.Helper()
+
+	b := Build(databaseType, buildEpoch)
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatalf("writing %s: %s", path, err)
+	}
+
+	sum := md5.Sum(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// appendValue appends v in the MMDB data format. Only the types needed by
+// Build are supported.
+func appendValue(b []byte, v any) []byte {
+	switch v := v.(type) {
+	case string:
+		b = appendControl(b, 2, len(v))
+		return append(b, v...)
+	case uint16:
+		return appendUint(b, 5, uint64(v))
+	case uint32:
+		return appendUint(b, 6, uint64(v))
+	case uint64:
+		return appendUint(b, 9, v)
+	case map[string]any:
+		b = appendControl(b, 7, len(v))
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			b = appendValue(b, k)
+			b = appendValue(b, v[k])
+		}
+		return b
+	case []any:
+		b = appendControl(b, 11, len(v))
+		for _, e := range v {
+			b = appendValue(b, e)
+		}
+		return b
+	default:
+		panic(fmt.Sprintf("unsupported type %T", v))
+	}
+}
+
+func appendUint(b []byte, typeNum int, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	i := 0
+	for i < len(buf) && buf[i] == 0 {
+		i++
+	}
+	b = appendControl(b, typeNum, len(buf)-i)
+	return append(b, buf[i:]...)
+}
+
+// appendControl appends the control byte for a value of the given type and
+// size. size must be less than 29.
+func appendControl(b []byte, typeNum, size int) []byte {
+	if size >= 29 {
+		panic(fmt.Sprintf("unsupported size %d", size))
+	}
+	if typeNum <= 7 {
+		return append(b, byte(typeNum<<5|size))
+	}
+	// Extended types store the type in the following byte.
+	return append(b, byte(size), byte(typeNum-7))
+}
@@ -0,0 +1,459 @@
+// Package oci is a minimal client for the OCI distribution API of container
+// registries such as registry:2. It only supports what geoipupdate needs,
+// i.e., pushing blobs, and pushing and reading image manifests by tag.
+//
+// Registries are authorized with the credentials in the Docker
+// configuration, either directly or through the bearer token service the
+// registry points to.
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
+)
+
+// These are the media types of the manifests and blobs that are pushed.
+const (
+	MediaTypeManifest = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeEmpty    = "application/vnd.oci.empty.v1+json"
+)
+
+// Config holds the settings of a Client.
+type Config struct {
+	// Registry is the host, and optionally port, of the registry.
+	Registry string
+	// PlainHTTP makes requests over HTTP rather than HTTPS.
+	PlainHTTP bool
+	// Username and Password are the credentials of the registry. If they
+	// are empty, requests are anonymous.
+	Username   string
+	Password   string
+	HTTPClient *http.Client
+}
+
+// Client makes requests to a registry.
+//
+// It is valid for concurrent use.
+type Client struct {
+	baseURL    string
+	username   string
+	password   string
+	httpClient *http.Client
+
+	mu sync.Mutex
+	// authorization is the Authorization header for each repository, once
+	// the registry asked for one.
+	authorization map[string]string
+}
+
+// New creates a Client.
+func New(config Config) *Client {
+	scheme := "https"
+	if config.PlainHTTP {
+		scheme = "http"
+	}
+	c := &Client{
+		baseURL:       scheme + "://" + config.Registry,
+		username:      config.Username,
+		password:      config.Password,
+		httpClient:    config.HTTPClient,
+		authorization: map[string]string{},
+	}
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+	return c
+}
+
+// NewFromEnv creates a Client for registry with the credentials stored for
+// it in the Docker configuration, i.e., config.json in DOCKER_CONFIG or
+// ~/.docker, as written by `docker login`. Credential helpers are not
+// supported. Like Docker, registries on the loopback interface are reached
+// over plain HTTP.
+func NewFromEnv(registry string, httpClient *http.Client) (*Client, error) {
+	username, password, err := dockerCredentials(registry)
+	if err != nil {
+		return nil, err
+	}
+	return New(Config{
+		Registry:   registry,
+		PlainHTTP:  isLoopback(registry),
+		Username:   username,
+		Password:   password,
+		HTTPClient: httpClient,
+	}), nil
+}
+
+// dockerCredentials returns the credentials of registry in the Docker
+// configuration, if there are any.
+func dockerCredentials(registry string) (username, password string, err error) {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", nil //nolint:nilerr // no configuration then.
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("reading Docker configuration: %w", err)
+	}
+
+	var config struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(b, &config); err != nil {
+		return "", "", fmt.Errorf("decoding Docker configuration: %w", err)
+	}
+
+	for key, auth := range config.Auths {
+		// Keys may be URLs, as older versions of Docker wrote them.
+		host := key
+		if u, err := url.Parse(key); err == nil && u.Host != "" {
+			host = u.Host
+		}
+		if host != registry || auth.Auth == "" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return "", "", fmt.Errorf("decoding Docker credentials of %s: %w", registry, err)
+		}
+		username, password, _ := strings.Cut(string(decoded), ":")
+		return username, password, nil
+	}
+	return "", "", nil
+}
+
+func isLoopback(registry string) bool {
+	host := registry
+	if h, _, err := net.SplitHostPort(registry); err == nil {
+		host = h
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ParseURL returns the registry and repository prefix of an
+// oci://registry/prefix URL.
+func ParseURL(u *url.URL) (registry, prefix string, err error) {
+	if u.Scheme != "oci" || u.Host == "" {
+		return "", "", fmt.Errorf("%s is not an oci://registry/repository URL", u)
+	}
+	return u.Host, strings.Trim(u.Path, "/"), nil
+}
+
+// Descriptor describes a blob.
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Data        []byte            `json:"data,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Manifest is an OCI image manifest.
+type Manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        Descriptor        `json:"config"`
+	Layers        []Descriptor      `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// Manifest returns the manifest of repository tagged or with the digest
+// reference, and its digest. The digest is computed from the manifest as
+// returned, as registries need not send it. A missing manifest is an
+// internal.HTTPError matching internal.ErrNotFound.
+func (c *Client) Manifest(ctx context.Context, repository, reference string) (Manifest, string, error) {
+	res, err := c.do(ctx, repository, func() (*http.Request, error) {
+		req, err := c.newRequest(ctx, http.MethodGet, "/v2/"+repository+"/manifests/"+reference, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", MediaTypeManifest)
+		return req, nil
+	})
+	if err != nil {
+		return Manifest{}, "", err
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(io.LimitReader(res.Body, 4<<20))
+	if err != nil {
+		return Manifest{}, "", fmt.Errorf("reading manifest: %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return Manifest{}, "", fmt.Errorf("decoding manifest: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return manifest, "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// PutManifest tags manifest, whose encoding is b, as reference in
+// repository and returns its digest.
+func (c *Client) PutManifest(ctx context.Context, repository, reference string, b []byte) (string, error) {
+	res, err := c.do(ctx, repository, func() (*http.Request, error) {
+		req, err := c.newRequest(
+			ctx,
+			http.MethodPut,
+			"/v2/"+repository+"/manifests/"+reference,
+			bytes.NewReader(b),
+		)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", MediaTypeManifest)
+		return req, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := drain(res); err != nil {
+		return "", err
+	}
+	return res.Header.Get("Docker-Content-Digest"), nil
+}
+
+// PushBlob uploads the size bytes returned by open, whose digest is digest,
+// to repository, unless the registry already has them. The registry
+// verifies the digest. open may be called more than once.
+func (c *Client) PushBlob(
+	ctx context.Context,
+	repository,
+	digest string,
+	size int64,
+	open func() (io.Reader, error),
+) error {
+	res, err := c.do(ctx, repository, func() (*http.Request, error) {
+		return c.newRequest(ctx, http.MethodHead, "/v2/"+repository+"/blobs/"+digest, nil)
+	})
+	if err == nil {
+		return drain(res)
+	}
+	if !errors.Is(err, internal.ErrNotFound) {
+		return err
+	}
+
+	res, err = c.do(ctx, repository, func() (*http.Request, error) {
+		return c.newRequest(ctx, http.MethodPost, "/v2/"+repository+"/blobs/uploads/", nil)
+	})
+	if err != nil {
+		return fmt.Errorf("starting upload: %w", err)
+	}
+	if err := drain(res); err != nil {
+		return err
+	}
+	location, err := res.Request.URL.Parse(res.Header.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("parsing upload location: %w", err)
+	}
+	query := location.Query()
+	query.Set("digest", digest)
+	location.RawQuery = query.Encode()
+
+	res, err = c.do(ctx, repository, func() (*http.Request, error) {
+		body, err := open()
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, location.String(), body)
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		req.ContentLength = size
+		req.Header.Set("Content-Type", "application/octet-stream")
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("uploading blob: %w", err)
+	}
+	return drain(res)
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	return req, nil
+}
+
+// do sends the request returned by newRequest and returns the response if
+// its status is 2xx. If the registry asks for authorization, the request is
+// created and sent again with it.
+func (c *Client) do(
+	ctx context.Context,
+	repository string,
+	newRequest func() (*http.Request, error),
+) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		authorization := c.authorization[repository]
+		c.mu.Unlock()
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+
+		res, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("performing %s request for %s: %w", req.Method, req.URL.Path, err)
+		}
+
+		if res.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			challenge := res.Header.Get("WWW-Authenticate")
+			if err := drain(res); err != nil {
+				return nil, err
+			}
+			authorization, err := c.authorize(ctx, repository, challenge)
+			if err != nil {
+				return nil, err
+			}
+			c.mu.Lock()
+			c.authorization[repository] = authorization
+			c.mu.Unlock()
+			continue
+		}
+
+		if res.StatusCode < 200 || res.StatusCode >= 300 {
+			defer res.Body.Close()
+			//nolint:errcheck // the status code is the error.
+			b, _ := io.ReadAll(io.LimitReader(res.Body, 1<<16))
+			return nil, internal.NewHTTPError(res.StatusCode, b)
+		}
+		return res, nil
+	}
+}
+
+// authorize returns the Authorization header that answers challenge, the
+// WWW-Authenticate header of a response.
+func (c *Client) authorize(ctx context.Context, repository, challenge string) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if c.username == "" {
+			return "", errors.New("the registry requires credentials")
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.username+":"+c.password)), nil
+	case "bearer":
+		token, err := c.fetchToken(ctx, repository, params)
+		if err != nil {
+			return "", fmt.Errorf("getting registry token: %w", err)
+		}
+		return "Bearer " + token, nil
+	default:
+		return "", fmt.Errorf("unsupported authorization challenge %q", challenge)
+	}
+}
+
+// fetchToken gets a token to pull from and push to repository from the
+// token service of a bearer challenge.
+func (c *Client) fetchToken(ctx context.Context, repository string, params map[string]string) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("invalid realm %q", params["realm"])
+	}
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", "repository:"+repository+":pull,push")
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), http.NoBody)
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("requesting token from %s: %w", realm.Host, err)
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(res.Body, 1<<16))
+	if err != nil {
+		return "", fmt.Errorf("reading token: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", internal.NewHTTPError(res.StatusCode, b)
+	}
+
+	var doc struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return "", fmt.Errorf("decoding token: %w", err)
+	}
+	if doc.Token != "" {
+		return doc.Token, nil
+	}
+	if doc.AccessToken != "" {
+		return doc.AccessToken, nil
+	}
+	return "", errors.New("the token response has no token")
+}
+
+// parseChallenge parses a WWW-Authenticate header such as
+// `Bearer realm="https://auth.example.com/token",service="registry"`.
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := map[string]string{}
+	for rest != "" {
+		var name, value string
+		name, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if name = strings.TrimSpace(name); name != "" {
+			params[strings.ToLower(name)] = value
+		}
+	}
+	return scheme, params
+}
+
+// drain reads the rest of the body of res and closes it.
+func drain(res *http.Response) error {
+	defer res.Body.Close()
+	if _, err := io.Copy(io.Discard, res.Body); err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+	return nil
+}
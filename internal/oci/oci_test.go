@@ -0,0 +1,46 @@
+package oci
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChallenge(t *testing.T) {
+	tests := []struct {
+		challenge string
+		scheme    string
+		params    map[string]string
+	}{
+		{
+			challenge: `Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:geoip/geoip2-city:pull"`,
+			scheme:    "Bearer",
+			params: map[string]string{
+				"realm":   "https://auth.docker.io/token",
+				"service": "registry.docker.io",
+				"scope":   "repository:geoip/geoip2-city:pull",
+			},
+		},
+		{
+			challenge: `Basic realm="Registry Realm"`,
+			scheme:    "Basic",
+			params:    map[string]string{"realm": "Registry Realm"},
+		},
+		{
+			challenge: `Bearer realm=https://ghcr.io/token, service=ghcr.io`,
+			scheme:    "Bearer",
+			params: map[string]string{
+				"realm":   "https://ghcr.io/token",
+				"service": "ghcr.io",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.challenge, func(t *testing.T) {
+			scheme, params := parseChallenge(test.challenge)
+			require.Equal(t, test.scheme, scheme)
+			require.Equal(t, test.params, params)
+		})
+	}
+}
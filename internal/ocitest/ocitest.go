@@ -0,0 +1,225 @@
+// Package ocitest provides an in-memory OCI distribution registry for tests.
+package ocitest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	// Username and Password are the credentials the registry accepts, if it
+	// requires authorization.
+	Username = "test-user"
+	Password = "test-password"
+
+	token = "test-token"
+)
+
+// Registry serves blobs and manifests. With authorization, it sends bearer
+// challenges whose token service accepts Username and Password, like
+// registries with token authentication do.
+type Registry struct {
+	*httptest.Server
+
+	authorize bool
+
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+	tags      map[string]string
+	uploads   int
+}
+
+// NewRegistry starts a Registry that is closed when the test finishes.
+func NewRegistry(t testing.TB, authorize bool) *Registry {
+	t.Helper()
+
+	r := &Registry{
+		authorize: authorize,
+		blobs:     map[string][]byte{},
+		manifests: map[string][]byte{},
+		tags:      map[string]string{},
+	}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.serveHTTP))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// Host returns the host and port of the registry.
+func (r *Registry) Host() string {
+	return strings.TrimPrefix(r.URL, "http://")
+}
+
+// Manifest returns the manifest of repository tagged tag.
+func (r *Registry) Manifest(repository, tag string) ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	digest, ok := r.tags[repository+":"+tag]
+	if !ok {
+		return nil, false
+	}
+	return r.manifests[repository+"@"+digest], true
+}
+
+// Blob returns the blob of repository with digest.
+func (r *Registry) Blob(repository, digest string) ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.blobs[repository+"@"+digest]
+	return b, ok
+}
+
+// Uploads returns the number of blobs that were uploaded.
+func (r *Registry) Uploads() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.uploads
+}
+
+func (r *Registry) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/token" {
+		r.serveToken(w, req)
+		return
+	}
+
+	if r.authorize && req.Header.Get("Authorization") != "Bearer "+token {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+			`Bearer realm="%s/token",service="ocitest",scope="repository:%s:pull"`,
+			r.URL, "ignored",
+		))
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED")
+		return
+	}
+
+	p, ok := strings.CutPrefix(req.URL.Path, "/v2/")
+	if !ok {
+		writeError(w, http.StatusNotFound, "NAME_UNKNOWN")
+		return
+	}
+
+	if repository, id, ok := strings.Cut(p, "/blobs/uploads/"); ok {
+		r.serveUpload(w, req, repository, id)
+		return
+	}
+	if repository, reference, ok := cutLast(p, "/manifests/"); ok {
+		r.serveManifest(w, req, repository, reference)
+		return
+	}
+	if repository, digest, ok := cutLast(p, "/blobs/"); ok {
+		r.serveBlob(w, req, repository, digest)
+		return
+	}
+	writeError(w, http.StatusNotFound, "NAME_UNKNOWN")
+}
+
+func (r *Registry) serveToken(w http.ResponseWriter, req *http.Request) {
+	username, password, ok := req.BasicAuth()
+	if !ok || username != Username || password != Password {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"token":%q}`, token)
+}
+
+func (r *Registry) serveUpload(w http.ResponseWriter, req *http.Request, repository, id string) {
+	switch {
+	case req.Method == http.MethodPost && id == "":
+		w.Header().Set("Location", "/v2/"+repository+"/blobs/uploads/upload-id?state=abc")
+		w.WriteHeader(http.StatusAccepted)
+	case req.Method == http.MethodPut && id != "":
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BLOB_UPLOAD_INVALID")
+			return
+		}
+		digest := req.URL.Query().Get("digest")
+		if digest != digestOf(b) || req.URL.Query().Get("state") != "abc" {
+			writeError(w, http.StatusBadRequest, "DIGEST_INVALID")
+			return
+		}
+		r.mu.Lock()
+		r.blobs[repository+"@"+digest] = b
+		r.uploads++
+		r.mu.Unlock()
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "UNSUPPORTED")
+	}
+}
+
+func (r *Registry) serveBlob(w http.ResponseWriter, req *http.Request, repository, digest string) {
+	b, ok := r.Blob(repository, digest)
+	if !ok {
+		writeError(w, http.StatusNotFound, "BLOB_UNKNOWN")
+		return
+	}
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.Header().Set("Content-Length", fmt.Sprint(len(b)))
+	if req.Method == http.MethodGet {
+		_, _ = w.Write(b)
+	}
+}
+
+func (r *Registry) serveManifest(w http.ResponseWriter, req *http.Request, repository, reference string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch req.Method {
+	case http.MethodPut:
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "MANIFEST_INVALID")
+			return
+		}
+		digest := digestOf(b)
+		r.manifests[repository+"@"+digest] = b
+		if !strings.HasPrefix(reference, "sha256:") {
+			r.tags[repository+":"+reference] = digest
+		}
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodGet, http.MethodHead:
+		digest := reference
+		if !strings.HasPrefix(reference, "sha256:") {
+			digest = r.tags[repository+":"+reference]
+		}
+		b, ok := r.manifests[repository+"@"+digest]
+		if !ok {
+			writeError(w, http.StatusNotFound, "MANIFEST_UNKNOWN")
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+		w.Header().Set("Docker-Content-Digest", digest)
+		_, _ = w.Write(b)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "UNSUPPORTED")
+	}
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+func digestOf(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"errors":[{"code":%q,"message":%q}]}`, code, strings.ToLower(code))
+}
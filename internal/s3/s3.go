@@ -0,0 +1,440 @@
+// Package s3 is a minimal client for the S3 API of AWS and compatible object
+// stores such as MinIO. It only supports what geoipupdate needs, i.e.,
+// reading, writing, copying and deleting single objects, and signs requests
+// with AWS Signature Version 4.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
+)
+
+const (
+	// MetadataMD5 is the user metadata key of the MD5 of a database, which
+	// is not necessarily the ETag of its object, e.g., for multipart
+	// uploads.
+	MetadataMD5 = "md5"
+	// MetadataBuildDate is the user metadata key of the date a database was
+	// built on, as YYYY-MM-DD.
+	MetadataBuildDate = "build-date"
+
+	metadataPrefix  = "X-Amz-Meta-"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	defaultRegion   = "us-east-1"
+)
+
+// Config holds the settings of a Client.
+type Config struct {
+	// Endpoint is the URL of the object store, e.g., http://localhost:9000
+	// for a local MinIO. Objects are then addressed with the bucket in the
+	// path. By default, the AWS endpoint of Region is used, with the bucket
+	// in the host name.
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	HTTPClient      *http.Client
+}
+
+// Client makes requests to an S3 API.
+//
+// It is valid for concurrent use.
+type Client struct {
+	endpoint     *url.URL
+	pathStyle    bool
+	region       string
+	accessKeyID  string
+	secretKey    string
+	sessionToken string
+	httpClient   *http.Client
+	now          func() time.Time
+}
+
+// New creates a Client.
+func New(config Config) (*Client, error) {
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, errors.New("an access key ID and secret access key are required")
+	}
+
+	c := &Client{
+		region:       config.Region,
+		accessKeyID:  config.AccessKeyID,
+		secretKey:    config.SecretAccessKey,
+		sessionToken: config.SessionToken,
+		httpClient:   config.HTTPClient,
+		now:          time.Now,
+	}
+	if c.region == "" {
+		c.region = defaultRegion
+	}
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+
+	endpoint := config.Endpoint
+	c.pathStyle = endpoint != ""
+	if endpoint == "" {
+		endpoint = "https://s3." + c.region + ".amazonaws.com"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parsing endpoint: %w", err)
+	}
+	c.endpoint = u
+
+	return c, nil
+}
+
+// NewFromEnv creates a Client configured by the environment variables that
+// the AWS tools use: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY,
+// AWS_SESSION_TOKEN, AWS_REGION or AWS_DEFAULT_REGION, and
+// AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL.
+func NewFromEnv(httpClient *http.Client) (*Client, error) {
+	config := Config{
+		Endpoint:        firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"),
+		Region:          firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		HTTPClient:      httpClient,
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, errors.New("the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables are required")
+	}
+	return New(config)
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// ParseURL returns the bucket and key prefix of an s3://bucket/prefix URL.
+func ParseURL(u *url.URL) (bucket, prefix string, err error) {
+	if u.Scheme != "s3" || u.Host == "" {
+		return "", "", fmt.Errorf("%s is not an s3://bucket/prefix URL", u)
+	}
+	return u.Host, strings.Trim(u.Path, "/"), nil
+}
+
+// Object is an object, or only its attributes for Head.
+type Object struct {
+	// Body is the content of the object. It is nil for Head.
+	Body         io.ReadCloser
+	ETag         string
+	LastModified time.Time
+	// Metadata is the user metadata of the object, with lower case keys.
+	Metadata map[string]string
+	Size     int64
+}
+
+// Head returns the attributes of an object. A missing object is an
+// internal.HTTPError matching internal.ErrNotFound.
+func (c *Client) Head(ctx context.Context, bucket, key string) (Object, error) {
+	req, err := c.newRequest(ctx, http.MethodHead, bucket, key, nil)
+	if err != nil {
+		return Object{}, err
+	}
+	res, err := c.do(req)
+	if err != nil {
+		return Object{}, err
+	}
+	//nolint:errcheck // HEAD responses have no body.
+	_ = res.Body.Close()
+	return objectOf(res), nil
+}
+
+// Get returns an object. The caller must close its Body.
+func (c *Client) Get(ctx context.Context, bucket, key string) (Object, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, bucket, key, nil)
+	if err != nil {
+		return Object{}, err
+	}
+	res, err := c.do(req)
+	if err != nil {
+		return Object{}, err
+	}
+	obj := objectOf(res)
+	obj.Body = res.Body
+	return obj, nil
+}
+
+// Put stores the size bytes read from body at key, with the user metadata.
+// contentMD5 is the MD5 of the content, which the store verifies.
+func (c *Client) Put(
+	ctx context.Context,
+	bucket,
+	key string,
+	body io.Reader,
+	size int64,
+	contentMD5 []byte,
+	metadata map[string]string,
+) error {
+	req, err := c.newRequest(ctx, http.MethodPut, bucket, key, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(contentMD5))
+	for name, value := range metadata {
+		req.Header.Set(metadataPrefix+name, value)
+	}
+
+	res, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	return drain(res)
+}
+
+// Copy copies the object at src to dst in the same bucket, with its
+// metadata.
+func (c *Client) Copy(ctx context.Context, bucket, src, dst string) error {
+	req, err := c.newRequest(ctx, http.MethodPut, bucket, dst, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Amz-Copy-Source", escapePath("/"+bucket+"/"+src))
+	req.Header.Set("X-Amz-Metadata-Directive", "COPY")
+
+	res, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// A copy that fails after it started is still answered with status 200,
+	// with the error in the body.
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("reading copy result: %w", err)
+	}
+	if bytes.Contains(b, []byte("<Error>")) {
+		return internal.NewHTTPError(http.StatusInternalServerError, b)
+	}
+	return nil
+}
+
+// Delete deletes the object at key.
+func (c *Client) Delete(ctx context.Context, bucket, key string) error {
+	req, err := c.newRequest(ctx, http.MethodDelete, bucket, key, nil)
+	if err != nil {
+		return err
+	}
+	res, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	return drain(res)
+}
+
+// drain reads the rest of the body of res and closes it.
+func drain(res *http.Response) error {
+	defer res.Body.Close()
+	if _, err := io.Copy(io.Discard, res.Body); err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+	return nil
+}
+
+func objectOf(res *http.Response) Object {
+	obj := Object{
+		ETag:     strings.Trim(res.Header.Get("ETag"), `"`),
+		Metadata: map[string]string{},
+		Size:     res.ContentLength,
+	}
+	if t, err := http.ParseTime(res.Header.Get("Last-Modified")); err == nil {
+		obj.LastModified = t
+	}
+	for name, values := range res.Header {
+		if key, ok := strings.CutPrefix(name, metadataPrefix); ok && len(values) > 0 {
+			obj.Metadata[strings.ToLower(key)] = values[0]
+		}
+	}
+	return obj
+}
+
+// newRequest creates a request for an object.
+func (c *Client) newRequest(
+	ctx context.Context,
+	method,
+	bucket,
+	key string,
+	body io.Reader,
+) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.objectURL(bucket, key), body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	return req, nil
+}
+
+// do signs and sends req and returns the response if its status is 2xx.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	c.sign(req, unsignedPayload)
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("performing %s request for %s: %w", req.Method, req.URL.Path, err)
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		defer res.Body.Close()
+		//nolint:errcheck // the status code is the error.
+		b, _ := io.ReadAll(io.LimitReader(res.Body, 1<<16))
+		return nil, internal.NewHTTPError(res.StatusCode, b)
+	}
+	return res, nil
+}
+
+// objectURL returns the URL of an object.
+func (c *Client) objectURL(bucket, key string) string {
+	u := *c.endpoint
+	p := "/" + key
+	if c.pathStyle {
+		p = strings.TrimSuffix(u.Path, "/") + "/" + bucket + p
+	} else {
+		u.Host = bucket + "." + u.Host
+	}
+	u.Path = p
+	u.RawPath = escapePath(p)
+	return u.String()
+}
+
+// sign adds the AWS Signature Version 4 of req to it, signing all of its
+// headers.
+func (c *Client) sign(req *http.Request, payloadHash string) {
+	now := c.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.sessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	values := map[string]string{"host": host}
+	for name, v := range req.Header {
+		trimmed := make([]string, len(v))
+		for i, value := range v {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		values[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + values[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + c.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.secretKey), date)
+	key = hmacSHA256(key, c.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.accessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var params []string
+	for _, key := range keys {
+		values := slices.Clone(query[key])
+		slices.Sort(values)
+		for _, value := range values {
+			params = append(params, escape(key, true)+"="+escape(value, true))
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// escapePath URI-encodes a path as S3 expects, leaving slashes as they are.
+func escapePath(p string) string {
+	return escape(p, false)
+}
+
+// escape URI-encodes every byte of s but the unreserved characters and,
+// unless encodeSlash is set, slashes.
+func escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hashHex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
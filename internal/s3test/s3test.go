@@ -0,0 +1,173 @@
+// Package s3test provides an in-memory S3 API server for tests.
+package s3test
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	// AccessKeyID is the access key ID the server accepts.
+	AccessKeyID = "test-access-key"
+	// SecretAccessKey is the secret access key of AccessKeyID. Signatures
+	// are not checked.
+	SecretAccessKey = "test-secret-key"
+)
+
+// Object is an object stored by the Server.
+type Object struct {
+	Body         []byte
+	Metadata     map[string]string
+	LastModified time.Time
+}
+
+// Server serves the objects of its buckets with path-style addressing.
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	objects map[string]Object
+}
+
+// NewServer starts a Server that is closed when the test finishes.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{objects: map[string]Object{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Put stores an object at bucket/key.
+func (s *Server) Put(bucket, key string, body []byte, metadata map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[bucket+"/"+key] = Object{
+		Body:         body,
+		Metadata:     metadata,
+		LastModified: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// Object returns the object at bucket/key.
+func (s *Server) Object(bucket, key string) (Object, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[bucket+"/"+key]
+	return obj, ok
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Authorization"), "Credential="+AccessKeyID+"/") {
+		http.Error(w, "<Error><Code>AccessDenied</Code></Error>", http.StatusForbidden)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		obj, ok := s.Object(bucketKey(name))
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		writeHeaders(w, obj)
+		if r.Method == http.MethodGet {
+			//nolint:errcheck // the test notices.
+			_, _ = w.Write(obj.Body)
+		}
+	case http.MethodPut:
+		if src := r.Header.Get("X-Amz-Copy-Source"); src != "" {
+			s.copy(w, src, name)
+			return
+		}
+		s.put(w, r, name)
+	case http.MethodDelete:
+		s.mu.Lock()
+		delete(s.objects, name)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "<Error><Code>NotImplemented</Code></Error>", http.StatusNotImplemented)
+	}
+}
+
+func (s *Server) put(w http.ResponseWriter, r *http.Request, name string) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "<Error><Code>IncompleteBody</Code></Error>", http.StatusBadRequest)
+		return
+	}
+	if contentMD5 := r.Header.Get("Content-MD5"); contentMD5 != "" {
+		sum := md5.Sum(body)
+		if contentMD5 != base64.StdEncoding.EncodeToString(sum[:]) {
+			http.Error(w, "<Error><Code>BadDigest</Code></Error>", http.StatusBadRequest)
+			return
+		}
+	}
+
+	metadata := map[string]string{}
+	for header, values := range r.Header {
+		if key, ok := strings.CutPrefix(header, "X-Amz-Meta-"); ok {
+			metadata[strings.ToLower(key)] = values[0]
+		}
+	}
+	s.mu.Lock()
+	s.objects[name] = Object{
+		Body:         body,
+		Metadata:     metadata,
+		LastModified: time.Now().UTC().Truncate(time.Second),
+	}
+	s.mu.Unlock()
+	w.Header().Set("ETag", `"`+etag(body)+`"`)
+}
+
+func (s *Server) copy(w http.ResponseWriter, src, dst string) {
+	src, err := url.PathUnescape(strings.TrimPrefix(src, "/"))
+	if err != nil {
+		http.Error(w, "<Error><Code>InvalidArgument</Code></Error>", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[src]
+	if !ok {
+		http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+		return
+	}
+	obj.LastModified = time.Now().UTC().Truncate(time.Second)
+	s.objects[dst] = obj
+	//nolint:errcheck // the test notices.
+	_, _ = w.Write([]byte("<CopyObjectResult><ETag>\"" + etag(obj.Body) + "\"</ETag></CopyObjectResult>"))
+}
+
+func bucketKey(name string) (string, string) {
+	bucket, key, _ := strings.Cut(name, "/")
+	return bucket, key
+}
+
+func writeHeaders(w http.ResponseWriter, obj Object) {
+	w.Header().Set("ETag", `"`+etag(obj.Body)+`"`)
+	w.Header().Set("Last-Modified", obj.LastModified.Format(http.TimeFormat))
+	w.Header().Set("Content-Length", strconv.Itoa(len(obj.Body)))
+	for key, value := range obj.Metadata {
+		w.Header().Set("X-Amz-Meta-"+key, value)
+	}
+}
+
+func etag(b []byte) string {
+	sum := md5.Sum(b)
+	return hex.EncodeToString(sum[:])
+}
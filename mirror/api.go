@@ -0,0 +1,137 @@
+package mirror
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// These are the paths of the update API endpoints.
+const (
+	metadataPath   = "/geoip/updates/metadata"
+	downloadPrefix = "/geoip/databases/"
+	downloadSuffix = "/download"
+)
+
+// SignatureSuffix is the suffix that the download endpoint of a Server or
+// Proxy serves the detached signature of a database with. Clients request
+// it with [client.WithSignatureSuffix].
+const SignatureSuffix = "mmdb.minisig"
+
+// archiveSuffix is the suffix of the tar.gz archive of a database, which the
+// download endpoint also serves if there is no suffix.
+const archiveSuffix = "tar.gz"
+
+// credentials are the account ID and license key that downstream clients
+// authenticate with.
+type credentials struct {
+	accountID  int
+	licenseKey string
+}
+
+// authorized checks that the request is a GET request with the credentials
+// and, if it is not, responds like the update server does.
+func (c credentials) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "only GET requests are supported")
+		return false
+	}
+
+	user, password, ok := r.BasicAuth()
+	if !ok || user == "" {
+		writeError(w, http.StatusUnauthorized, "ACCOUNT_ID_REQUIRED", "You have not supplied an account ID")
+		return false
+	}
+
+	accountID, err := strconv.Atoi(user)
+	if err != nil || accountID != c.accountID ||
+		subtle.ConstantTimeCompare([]byte(password), []byte(c.licenseKey)) != 1 {
+		writeError(w, http.StatusUnauthorized, "AUTHORIZATION_INVALID", "Your account ID or license key is invalid")
+		return false
+	}
+	return true
+}
+
+// downloadEdition returns the edition of a request to the download
+// endpoint, or false if the request is for another endpoint.
+func downloadEdition(r *http.Request) (string, bool) {
+	rest, ok := strings.CutPrefix(r.URL.Path, downloadPrefix)
+	if !ok {
+		return "", false
+	}
+	editionID, ok := strings.CutSuffix(rest, downloadSuffix)
+	if !ok || editionID == "" || strings.Contains(editionID, "/") {
+		return "", false
+	}
+	return editionID, true
+}
+
+// downloadDate returns the date of a download request as YYYY-MM-DD, or an
+// empty string if the latest database is requested. If the date is not
+// valid, it responds with an error and returns false.
+func downloadDate(w http.ResponseWriter, r *http.Request) (string, bool) {
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		return "", true
+	}
+	parsed, err := time.Parse("20060102", date)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "DATE_INVALID", fmt.Sprintf("The date %q is invalid", date))
+		return "", false
+	}
+	return parsed.Format(time.DateOnly), true
+}
+
+// downloadArchive returns whether a download request is for the archive of
+// a database rather than its signature. If the suffix is not supported, it
+// responds with an error and returns false.
+func downloadArchive(w http.ResponseWriter, r *http.Request) (archive, ok bool) {
+	switch suffix := r.URL.Query().Get("suffix"); suffix {
+	case "", archiveSuffix:
+		return true, true
+	case SignatureSuffix:
+		return false, true
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("The suffix %q is not supported", suffix))
+		return false, false
+	}
+}
+
+// metadata is the metadata of an edition as returned by the metadata
+// endpoint.
+type metadata struct {
+	Date      string `json:"date"`
+	EditionID string `json:"edition_id"`
+	MD5       string `json:"md5"`
+}
+
+// writeMetadata responds with the metadata of the databases.
+func writeMetadata(w http.ResponseWriter, databases []metadata) {
+	w.Header().Set("Content-Type", "application/json")
+	//nolint:errcheck,errchkjson // the client went away.
+	_ = json.NewEncoder(w).Encode(struct {
+		Databases []metadata `json:"databases"`
+	}{databases})
+}
+
+// writeEditionNotFound responds that the edition is not available.
+func writeEditionNotFound(w http.ResponseWriter, editionID string) {
+	writeError(w, http.StatusNotFound, "EDITION_NOT_FOUND", fmt.Sprintf("The edition %s is not available", editionID))
+}
+
+// writeError responds with a JSON error document like those of the update
+// server.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	//nolint:errcheck,errchkjson // the client went away.
+	_ = json.NewEncoder(w).Encode(struct {
+		Code  string `json:"code"`
+		Error string `json:"error"`
+	}{code, message})
+}
@@ -0,0 +1,418 @@
+// Package mirror serves the databases in a database directory over the same
+// HTTP API as the MaxMind update server, so that other geoipupdate instances
+// and [client.Client] can update from it by pointing their Host at it.
+//
+// [Proxy] serves the same API as a pull-through cache of another update
+// server instead.
+//
+// Downstream clients authenticate with their own account ID and license key,
+// which are unrelated to the credentials used to download the databases from
+// MaxMind.
+package mirror
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+
+	"github.com/maxmind/geoipupdate/v8/internal/geoipupdate/database"
+)
+
+// Server is an http.Handler that serves the metadata and download endpoints
+// of the update API from a database directory.
+//
+// Metadata requests are answered with the current database of each edition,
+// <edition>.mmdb. Download requests for a date are answered with the release
+// of that date stored by geoipupdate backfill, or otherwise with the current
+// database, or one kept in the history because of KeepVersions, if it was
+// built on that date.
+//
+// Requests with the suffix mmdb.minisig are answered with the detached
+// signature stored next to the database that would be served, e.g.,
+// <edition>.mmdb.minisig.
+//
+// It is valid for concurrent use.
+type Server struct {
+	credentials
+
+	dir        string
+	editionIDs []string
+	group      []string
+	logger     *log.Logger
+	files      *database.LocalFileWriter
+
+	mu    sync.Mutex
+	infos map[string]fileInfo
+}
+
+// Option is an option for configuring Server.
+type Option func(*Server)
+
+// WithLogger sets the logger that requests are logged to. By default,
+// nothing is logged.
+func WithLogger(l *log.Logger) Option {
+	return func(s *Server) {
+		s.logger = l
+	}
+}
+
+// WithAtomicGroup sets the editions that geoipupdate publishes as an atomic
+// group, whose current databases are in the current directory of dir.
+func WithAtomicGroup(editionIDs ...string) Option {
+	return func(s *Server) {
+		s.group = editionIDs
+	}
+}
+
+// New creates a Server for the given editions in dir. Requests must
+// authenticate with accountID and licenseKey.
+func New(
+	dir string,
+	editionIDs []string,
+	accountID int,
+	licenseKey string,
+	options ...Option,
+) (*Server, error) {
+	if accountID == 0 || licenseKey == "" {
+		return nil, errors.New("an account ID and license key are required")
+	}
+
+	files, err := database.NewLocalFileWriter(dir, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		credentials: credentials{accountID: accountID, licenseKey: licenseKey},
+		dir:         dir,
+		editionIDs:  editionIDs,
+		files:       files,
+		infos:       map[string]fileInfo{},
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s, nil
+}
+
+// ServeHTTP serves a request to the update API.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) {
+		return
+	}
+
+	if r.URL.Path == metadataPath {
+		s.serveMetadata(w, r)
+		return
+	}
+	if editionID, ok := downloadEdition(r); ok {
+		s.serveDownload(w, r, editionID)
+		return
+	}
+	writeError(w, http.StatusNotFound, "NOT_FOUND", "not found")
+}
+
+func (s *Server) serveMetadata(w http.ResponseWriter, r *http.Request) {
+	editionIDs := r.URL.Query()["edition_id"]
+	if len(editionIDs) == 0 {
+		writeError(w, http.StatusBadRequest, "EDITION_ID_REQUIRED", "You have not supplied an edition ID")
+		return
+	}
+
+	var databases []metadata
+	for _, editionID := range editionIDs {
+		info, ok := s.current(w, editionID)
+		if !ok {
+			return
+		}
+		databases = append(databases, metadata{
+			Date:      info.buildDate,
+			EditionID: editionID,
+			MD5:       info.md5,
+		})
+	}
+	writeMetadata(w, databases)
+
+	s.logf("Served metadata of %s to %s", strings.Join(editionIDs, ", "), r.RemoteAddr)
+}
+
+func (s *Server) serveDownload(w http.ResponseWriter, r *http.Request, editionID string) {
+	var path string
+	var info fileInfo
+	date, ok := downloadDate(w, r)
+	if !ok {
+		return
+	}
+	archive, ok := downloadArchive(w, r)
+	if !ok {
+		return
+	}
+	if date != "" {
+		path, info, ok = s.dated(w, editionID, date)
+		if !ok {
+			return
+		}
+	} else {
+		path = s.currentPath(editionID)
+		info, ok = s.current(w, editionID)
+		if !ok {
+			return
+		}
+	}
+
+	if !archive {
+		s.serveSignature(w, r, editionID, path)
+		return
+	}
+
+	//nolint:gosec // the path is in the database directory.
+	f, err := os.Open(path)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "opening the database failed")
+		return
+	}
+	defer f.Close()
+
+	// The file may have been replaced since it was described. The archive
+	// is then still consistent, if not of the described build.
+	st, err := f.Stat()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "reading the database failed")
+		return
+	}
+
+	// The archive is built on the fly, so its size is not known in advance
+	// and range requests are not supported. It is named after the release
+	// date, as the update server names it.
+	if date == "" {
+		date = info.buildDate
+	}
+	name := editionID + "_" + strings.ReplaceAll(date, "-", "")
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.tar.gz", name))
+	w.Header().Set("Last-Modified", st.ModTime().UTC().Format(http.TimeFormat))
+
+	if err := writeArchive(w, f, name+"/"+editionID+".mmdb", st.Size(), st.ModTime()); err != nil {
+		// The status was already sent, so the client only notices the
+		// truncated archive.
+		s.logf("Serving %s to %s: %s", path, r.RemoteAddr, err)
+		return
+	}
+
+	s.logf("Served %s to %s", path, r.RemoteAddr)
+}
+
+// serveSignature serves the detached signature stored next to the database
+// at path.
+func (s *Server) serveSignature(w http.ResponseWriter, r *http.Request, editionID, path string) {
+	sigPath := strings.TrimSuffix(path, ".mmdb") + "." + SignatureSuffix
+	//nolint:gosec // the path is in the database directory.
+	sig, err := os.ReadFile(sigPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			writeError(w, http.StatusNotFound, "SIGNATURE_NOT_FOUND",
+				fmt.Sprintf("No signature of the database of %s is available", editionID))
+			return
+		}
+		s.logf("Reading %s: %s", sigPath, err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "reading the signature failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	//nolint:errcheck // the client went away.
+	_, _ = w.Write(sig)
+
+	s.logf("Served %s to %s", sigPath, r.RemoteAddr)
+}
+
+// currentPath returns the path of the current database of an edition.
+func (s *Server) currentPath(editionID string) string {
+	if slices.Contains(s.group, editionID) {
+		return database.GroupPath(s.dir, editionID)
+	}
+	return s.files.Path(editionID)
+}
+
+// current returns the current database of an edition or, if there is none,
+// responds with an error.
+func (s *Server) current(w http.ResponseWriter, editionID string) (fileInfo, bool) {
+	if !slices.Contains(s.editionIDs, editionID) {
+		writeEditionNotFound(w, editionID)
+		return fileInfo{}, false
+	}
+
+	info, err := s.stat(s.currentPath(editionID))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			writeEditionNotFound(w, editionID)
+			return fileInfo{}, false
+		}
+		s.logf("Reading the database of %s: %s", editionID, err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "reading the database failed")
+		return fileInfo{}, false
+	}
+	return info, true
+}
+
+// dated returns the path of the release of an edition on date or, if there
+// is none, responds with an error. A database stored as
+// <edition>_<date>.mmdb is the release of that date whatever date it was
+// built on. The current database and those kept in the history are only
+// known by their build date, so they are served for that date.
+func (s *Server) dated(w http.ResponseWriter, editionID, date string) (string, fileInfo, bool) {
+	if !slices.Contains(s.editionIDs, editionID) {
+		writeEditionNotFound(w, editionID)
+		return "", fileInfo{}, false
+	}
+
+	path := s.files.DatedPath(editionID, date)
+	info, err := s.stat(path)
+	if err == nil {
+		return path, info, true
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		s.logf("Reading %s: %s", path, err)
+	}
+
+	candidates := []string{s.currentPath(editionID)}
+	versions, err := s.files.Versions(editionID)
+	if err != nil {
+		s.logf("Reading the history of %s: %s", editionID, err)
+	}
+	for _, version := range versions {
+		if version.BuildDate == date {
+			candidates = append(candidates, version.Path)
+		}
+	}
+
+	for _, path := range candidates {
+		info, err := s.stat(path)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				s.logf("Reading %s: %s", path, err)
+			}
+			continue
+		}
+		if info.buildDate == date {
+			return path, info, true
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "DATABASE_NOT_FOUND",
+		fmt.Sprintf("No database of %s released on %s is available", editionID, date))
+	return "", fileInfo{}, false
+}
+
+// fileInfo describes a database file.
+type fileInfo struct {
+	md5       string
+	buildDate string
+	modTime   time.Time
+	size      int64
+	stat      os.FileInfo
+}
+
+// stat returns the description of the database at path. Descriptions are
+// cached until the file changes, which geoipupdate only ever does by
+// renaming a new file over it.
+func (s *Server) stat(path string) (fileInfo, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return fileInfo{}, err
+	}
+
+	s.mu.Lock()
+	cached, ok := s.infos[path]
+	s.mu.Unlock()
+	if ok && os.SameFile(cached.stat, st) &&
+		cached.modTime.Equal(st.ModTime()) &&
+		cached.size == st.Size() {
+		return cached, nil
+	}
+
+	info, err := describe(path, st)
+	if err != nil {
+		return fileInfo{}, err
+	}
+
+	s.mu.Lock()
+	s.infos[path] = info
+	s.mu.Unlock()
+	return info, nil
+}
+
+// describe computes the MD5 and build date of the database at path.
+func describe(path string, st os.FileInfo) (fileInfo, error) {
+	//nolint:gosec // the path is in the database directory.
+	f, err := os.Open(path)
+	if err != nil {
+		return fileInfo{}, err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fileInfo{}, fmt.Errorf("hashing %s: %w", path, err)
+	}
+
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return fileInfo{}, fmt.Errorf("reading metadata of %s: %w", path, err)
+	}
+	epoch := reader.Metadata.BuildEpoch
+	//nolint:errcheck // The database was only read.
+	_ = reader.Close()
+
+	return fileInfo{
+		md5: hex.EncodeToString(h.Sum(nil)),
+		//nolint:gosec // build epochs are well within range.
+		buildDate: time.Unix(int64(epoch), 0).UTC().Format(time.DateOnly),
+		modTime:   st.ModTime(),
+		size:      st.Size(),
+		stat:      st,
+	}, nil
+}
+
+// writeArchive writes a tar.gz archive with the database read from f under
+// name, as the update server does.
+func writeArchive(w io.Writer, f io.Reader, name string, size int64, modTime time.Time) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    size,
+		ModTime: modTime,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := io.CopyN(tw, f, size); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func (s *Server) logf(format string, v ...any) {
+	if s.logger != nil {
+		s.logger.Printf(format, v...)
+	}
+}
@@ -0,0 +1,152 @@
+package mirror
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/client"
+	"github.com/maxmind/geoipupdate/v8/internal/mmdbtest"
+)
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+
+	// The current database was built on 2026-09-18, a backfilled one on
+	// 2026-09-15 and a kept one on 2026-09-11.
+	current := mmdbtest.Build("GeoIP2-City", 1789430400+3*24*60*60)
+	backfilled := mmdbtest.Build("GeoIP2-City", 1789430400)
+	kept := mmdbtest.Build("GeoIP2-City", 1789430400-4*24*60*60)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "GeoIP2-City.mmdb"), current, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "GeoIP2-City_2026-09-15.mmdb"), backfilled, 0o600))
+	// The release of 2026-09-13 was built the day before.
+	released := mmdbtest.Build("GeoIP2-City", 1789430400-3*24*60*60)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "GeoIP2-City_2026-09-13.mmdb"), released, 0o600))
+	history := filepath.Join(dir, ".history", "GeoIP2-City")
+	require.NoError(t, os.MkdirAll(history, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(history, "2026-09-11-"+md5Hex(kept)+".mmdb"), kept, 0o600))
+
+	_, err := New(dir, []string{"GeoIP2-City"}, 0, "")
+	require.EqualError(t, err, "an account ID and license key are required")
+
+	s, err := New(dir, []string{"GeoIP2-City", "GeoIP2-ISP"}, 42, "downstream")
+	require.NoError(t, err)
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+
+	c, err := client.New(42, "downstream", client.WithEndpoint(server.URL))
+	require.NoError(t, err)
+
+	res, err := c.Download(t.Context(), "GeoIP2-City", "")
+	require.NoError(t, err)
+	require.True(t, res.UpdateAvailable)
+	require.Equal(t, "2026-09-18", res.Date)
+	require.Equal(t, md5Hex(current), res.MD5)
+	require.Equal(t, current, readAll(t, res.Reader))
+
+	res, err = c.Download(t.Context(), "GeoIP2-City", md5Hex(current))
+	require.NoError(t, err)
+	require.False(t, res.UpdateAvailable)
+
+	for date, want := range map[string][]byte{
+		"2026-09-18": current,
+		"2026-09-15": backfilled,
+		"2026-09-13": released,
+		"2026-09-11": kept,
+	} {
+		res, err = c.DownloadDate(t.Context(), "GeoIP2-City", date)
+		require.NoError(t, err, date)
+		require.Equal(t, want, readAll(t, res.Reader), date)
+	}
+
+	var httpErr client.HTTPError
+
+	_, err = c.DownloadDate(t.Context(), "GeoIP2-City", "2026-09-12")
+	require.ErrorAs(t, err, &httpErr)
+	require.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+	require.Equal(t, "DATABASE_NOT_FOUND", httpErr.Code)
+
+	// Signatures are served from next to the databases, if there are any.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "GeoIP2-City.mmdb.minisig"), []byte("signature"), 0o600))
+	signer, err := client.New(42, "downstream",
+		client.WithEndpoint(server.URL), client.WithSignatureSuffix("mmdb.minisig"))
+	require.NoError(t, err)
+	sig, err := signer.DownloadSignature(t.Context(), "GeoIP2-City", "2026-09-18")
+	require.NoError(t, err)
+	require.Equal(t, "signature", string(sig))
+
+	_, err = signer.DownloadSignature(t.Context(), "GeoIP2-City", "2026-09-15")
+	require.ErrorAs(t, err, &httpErr)
+	require.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+	require.Equal(t, "SIGNATURE_NOT_FOUND", httpErr.Code)
+
+	// Other suffixes are not served.
+	other, err := client.New(42, "downstream",
+		client.WithEndpoint(server.URL), client.WithSignatureSuffix("zip"))
+	require.NoError(t, err)
+	_, err = other.DownloadSignature(t.Context(), "GeoIP2-City", "2026-09-18")
+	require.ErrorAs(t, err, &httpErr)
+	require.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+	require.Equal(t, "NOT_FOUND", httpErr.Code)
+
+	// Editions that are not served, or that have no database yet, are not
+	// found.
+	for _, editionID := range []string{"GeoIP2-Country", "GeoIP2-ISP"} {
+		_, err = c.Download(t.Context(), editionID, "")
+		require.ErrorAs(t, err, &httpErr)
+		require.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+		require.Equal(t, "EDITION_NOT_FOUND", httpErr.Code)
+	}
+
+	// The upstream credentials do not work, only the downstream ones.
+	wrong, err := client.New(42, "upstream", client.WithEndpoint(server.URL))
+	require.NoError(t, err)
+	_, err = wrong.Download(t.Context(), "GeoIP2-City", "")
+	require.ErrorIs(t, err, client.ErrInvalidLicenseKey)
+}
+
+func TestServerAtomicGroup(t *testing.T) {
+	dir := t.TempDir()
+
+	// GeoIP2-City is published in the atomic group, GeoIP2-ISP is not.
+	city := mmdbtest.Build("GeoIP2-City", 1789430400)
+	isp := mmdbtest.Build("GeoIP2-ISP", 1789430400)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "current"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "current", "GeoIP2-City.mmdb"), city, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "GeoIP2-ISP.mmdb"), isp, 0o600))
+
+	s, err := New(dir, []string{"GeoIP2-City", "GeoIP2-ISP"}, 42, "downstream",
+		WithAtomicGroup("GeoIP2-City"))
+	require.NoError(t, err)
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+
+	c, err := client.New(42, "downstream", client.WithEndpoint(server.URL))
+	require.NoError(t, err)
+
+	for editionID, want := range map[string][]byte{"GeoIP2-City": city, "GeoIP2-ISP": isp} {
+		res, err := c.Download(t.Context(), editionID, "")
+		require.NoError(t, err, editionID)
+		require.Equal(t, want, readAll(t, res.Reader), editionID)
+	}
+}
+
+func md5Hex(b []byte) string {
+	sum := md5.Sum(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func readAll(t *testing.T, r io.ReadCloser) []byte {
+	t.Helper()
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return b
+}
@@ -0,0 +1,721 @@
+package mirror
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/maxmind/geoipupdate/v8/client"
+	"github.com/maxmind/geoipupdate/v8/internal"
+)
+
+const (
+	// statsPath is the path of the cache statistics endpoint of a Proxy.
+	statsPath = "/geoip/proxy/stats"
+	// archiveExtension is the extension of the cached archives.
+	archiveExtension = ".tar.gz"
+	// signatureExtension is the extension of the cached signatures.
+	signatureExtension = "." + SignatureSuffix
+	// currentFile is the file in the cache directory of an edition that
+	// describes its latest archive.
+	currentFile = "current.json"
+)
+
+// Upstream is where a Proxy downloads databases from. [client.Client]
+// implements it.
+type Upstream interface {
+	Download(ctx context.Context, editionID, md5 string) (client.DownloadResponse, error)
+	DownloadDate(ctx context.Context, editionID, date string) (client.DownloadResponse, error)
+}
+
+// SignatureUpstream is an Upstream that also serves the detached signatures
+// of the databases, which a Proxy then serves too. [client.Client]
+// implements it, and must request signatures with [SignatureSuffix].
+type SignatureUpstream interface {
+	Upstream
+	DownloadSignature(ctx context.Context, editionID, date string) ([]byte, error)
+}
+
+// Stats are the cache statistics of a Proxy.
+type Stats struct {
+	// Requests is the number of metadata and download requests.
+	Requests int64 `json:"requests"`
+	// Hits is the number of requests answered from the cache, including
+	// after checking that the latest database upstream did not change.
+	Hits int64 `json:"hits"`
+	// Misses is the number of databases downloaded upstream.
+	Misses int64 `json:"misses"`
+	// Deduplicated is the number of requests that waited for the download
+	// of another request instead of downloading upstream themselves.
+	Deduplicated int64 `json:"deduplicated"`
+	// UpstreamErrors is the number of failed requests upstream. When the
+	// cache has a database, it is served instead.
+	UpstreamErrors int64 `json:"upstream_errors"`
+}
+
+// Proxy is an http.Handler that serves the metadata and download endpoints
+// of the update API from a cache of the upstream update server. Databases
+// are downloaded upstream on the first request for them and then cached on
+// disk as <edition>/<edition>_<YYYYMMDD>.tar.gz. If upstream is a
+// SignatureUpstream, the signatures of the databases are cached the same way
+// as <edition>/<edition>_<YYYYMMDD>.mmdb.minisig.
+//
+// Metadata and download requests for the latest database are answered from
+// the cache as long as the MD5 of the latest database upstream does not
+// change. That is checked at most once per check interval. Concurrent
+// requests for the same database share a single upstream request.
+//
+// Cache statistics are served as JSON at /geoip/proxy/stats.
+//
+// It is valid for concurrent use.
+type Proxy struct {
+	credentials
+
+	dir           string
+	editionIDs    []string
+	upstream      Upstream
+	logger        *log.Logger
+	checkInterval time.Duration
+	now           func() time.Time
+
+	flight singleflight.Group
+
+	// mu guards current and kept, and orders opening the archive of the
+	// latest database against removing superseded ones.
+	mu      sync.Mutex
+	current map[string]cacheEntry
+	// kept are the paths of the archives that were requested by date while
+	// they were not the latest, which are not removed when a newer database
+	// is cached.
+	kept map[string]bool
+
+	requests       atomic.Int64
+	hits           atomic.Int64
+	misses         atomic.Int64
+	deduplicated   atomic.Int64
+	upstreamErrors atomic.Int64
+}
+
+// ProxyOption is an option for configuring Proxy.
+type ProxyOption func(*Proxy)
+
+// WithProxyLogger sets the logger that requests are logged to. By default,
+// nothing is logged.
+func WithProxyLogger(l *log.Logger) ProxyOption {
+	return func(p *Proxy) {
+		p.logger = l
+	}
+}
+
+// WithCheckInterval sets how often the latest database of an edition is
+// checked upstream. The default is one minute.
+func WithCheckInterval(d time.Duration) ProxyOption {
+	return func(p *Proxy) {
+		p.checkInterval = d
+	}
+}
+
+// NewProxy creates a Proxy for the given editions that caches them in dir.
+// Requests must authenticate with accountID and licenseKey, while upstream
+// uses its own credentials.
+func NewProxy(
+	dir string,
+	editionIDs []string,
+	upstream Upstream,
+	accountID int,
+	licenseKey string,
+	options ...ProxyOption,
+) (*Proxy, error) {
+	if accountID == 0 || licenseKey == "" {
+		return nil, errors.New("an account ID and license key are required")
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+
+	p := &Proxy{
+		credentials:   credentials{accountID: accountID, licenseKey: licenseKey},
+		dir:           dir,
+		editionIDs:    editionIDs,
+		upstream:      upstream,
+		checkInterval: time.Minute,
+		now:           time.Now,
+		current:       map[string]cacheEntry{},
+		kept:          map[string]bool{},
+	}
+	for _, opt := range options {
+		opt(p)
+	}
+	return p, nil
+}
+
+// Stats returns the cache statistics.
+func (p *Proxy) Stats() Stats {
+	return Stats{
+		Requests:       p.requests.Load(),
+		Hits:           p.hits.Load(),
+		Misses:         p.misses.Load(),
+		Deduplicated:   p.deduplicated.Load(),
+		UpstreamErrors: p.upstreamErrors.Load(),
+	}
+}
+
+// ServeHTTP serves a request to the update API.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !p.authorized(w, r) {
+		return
+	}
+
+	if r.URL.Path == statsPath {
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck,errchkjson // the client went away.
+		_ = json.NewEncoder(w).Encode(p.Stats())
+		return
+	}
+
+	if r.URL.Path == metadataPath {
+		p.serveMetadata(w, r)
+		return
+	}
+	if editionID, ok := downloadEdition(r); ok {
+		p.serveDownload(w, r, editionID)
+		return
+	}
+	writeError(w, http.StatusNotFound, "NOT_FOUND", "not found")
+}
+
+func (p *Proxy) serveMetadata(w http.ResponseWriter, r *http.Request) {
+	editionIDs := r.URL.Query()["edition_id"]
+	if len(editionIDs) == 0 {
+		writeError(w, http.StatusBadRequest, "EDITION_ID_REQUIRED", "You have not supplied an edition ID")
+		return
+	}
+
+	var databases []metadata
+	for _, editionID := range editionIDs {
+		entry, ok := p.latest(w, r, editionID)
+		if !ok {
+			return
+		}
+		databases = append(databases, metadata{
+			Date:      entry.Date,
+			EditionID: editionID,
+			MD5:       entry.MD5,
+		})
+	}
+	writeMetadata(w, databases)
+
+	p.logf("Served metadata of %s to %s", strings.Join(editionIDs, ", "), r.RemoteAddr)
+}
+
+func (p *Proxy) serveDownload(w http.ResponseWriter, r *http.Request, editionID string) {
+	date, ok := downloadDate(w, r)
+	if !ok {
+		return
+	}
+	archive, ok := downloadArchive(w, r)
+	if !ok {
+		return
+	}
+	if !archive {
+		p.serveSignature(w, r, editionID, date)
+		return
+	}
+
+	var f *os.File
+	var err error
+	if date == "" {
+		entry, ok := p.latest(w, r, editionID)
+		if !ok {
+			return
+		}
+		f, err = p.openLatest(editionID, entry.Date)
+	} else {
+		if !p.dated(w, r, editionID, date) {
+			return
+		}
+		//nolint:gosec // the path is in the cache directory.
+		f, err = os.Open(p.archivePath(editionID, date))
+		if errors.Is(err, os.ErrNotExist) {
+			// It was the latest database, and was removed as a newer one was
+			// cached in the meantime. It is downloaded again.
+			if !p.dated(w, r, editionID, date) {
+				return
+			}
+			//nolint:gosec // the path is in the cache directory.
+			f, err = os.Open(p.archivePath(editionID, date))
+		}
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "opening the archive failed")
+		return
+	}
+	defer f.Close()
+	path := f.Name()
+
+	st, err := f.Stat()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "reading the archive failed")
+		return
+	}
+
+	// The modification time of the archive is the Last-Modified time sent
+	// upstream. ServeContent also handles the range requests of resumed
+	// downloads.
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", "attachment; filename="+filepath.Base(path))
+	http.ServeContent(w, r, "", st.ModTime(), f)
+
+	p.logf("Served %s to %s", path, r.RemoteAddr)
+}
+
+// serveSignature serves the signature of the database of an edition released
+// on date or, if date is empty, of the latest one.
+func (p *Proxy) serveSignature(w http.ResponseWriter, r *http.Request, editionID, date string) {
+	if date == "" {
+		entry, ok := p.latest(w, r, editionID)
+		if !ok {
+			return
+		}
+		date = entry.Date
+	} else {
+		p.requests.Add(1)
+		if !slices.Contains(p.editionIDs, editionID) {
+			writeEditionNotFound(w, editionID)
+			return
+		}
+	}
+
+	var led bool
+	v, err, _ := p.flight.Do("dated/"+editionID+"/"+date+"/"+SignatureSuffix, func() (any, error) {
+		led = true
+		return p.fetchSignature(context.WithoutCancel(r.Context()), editionID, date)
+	})
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+	res := v.(signatureResult) //nolint:forcetypeassert // fetchSignature returns one.
+	p.count(res.hit, led)
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	//nolint:errcheck // the client went away.
+	_, _ = w.Write(res.signature)
+
+	p.logf("Served the signature of %s released on %s to %s", editionID, date, r.RemoteAddr)
+}
+
+// cacheEntry describes the cached archive of the latest database of an
+// edition.
+type cacheEntry struct {
+	// MD5 is the hash of the database in the archive.
+	MD5 string `json:"md5"`
+	// Date is the date the database was built on, as YYYY-MM-DD.
+	Date string `json:"date"`
+
+	// checkedAt is when the entry was last checked upstream.
+	checkedAt time.Time
+}
+
+// latest returns the cache entry of the latest database of an edition,
+// checking upstream first if it is due. If there is none, it responds with
+// an error.
+func (p *Proxy) latest(w http.ResponseWriter, r *http.Request, editionID string) (cacheEntry, bool) {
+	p.requests.Add(1)
+	if !slices.Contains(p.editionIDs, editionID) {
+		writeEditionNotFound(w, editionID)
+		return cacheEntry{}, false
+	}
+
+	var led bool
+	v, err, _ := p.flight.Do("latest/"+editionID, func() (any, error) {
+		led = true
+		// Other requests may be waiting for this download, so it must not
+		// be cancelled with the request that started it.
+		return p.refresh(context.WithoutCancel(r.Context()), editionID)
+	})
+	if err != nil {
+		writeUpstreamError(w, err)
+		return cacheEntry{}, false
+	}
+	res := v.(refreshResult) //nolint:forcetypeassert // refresh returns one.
+	p.count(res.hit, led)
+	return res.entry, true
+}
+
+// dated makes sure that the archive of the database of an edition built on
+// date is cached, downloading it upstream if it is not. If that fails, it
+// responds with an error.
+func (p *Proxy) dated(w http.ResponseWriter, r *http.Request, editionID, date string) bool {
+	p.requests.Add(1)
+	if !slices.Contains(p.editionIDs, editionID) {
+		writeEditionNotFound(w, editionID)
+		return false
+	}
+
+	// Downloads of the latest database also request it by date. Other
+	// dates are kept from now on, so that they are still there once cached.
+	p.mu.Lock()
+	if entry, ok := p.current[editionID]; !ok || entry.Date != date {
+		p.kept[p.archivePath(editionID, date)] = true
+	}
+	p.mu.Unlock()
+
+	var led bool
+	v, err, _ := p.flight.Do("dated/"+editionID+"/"+date+"/"+archiveSuffix, func() (any, error) {
+		led = true
+		return p.fetchDated(context.WithoutCancel(r.Context()), editionID, date)
+	})
+	if err != nil {
+		writeUpstreamError(w, err)
+		return false
+	}
+	p.count(v.(bool), led) //nolint:forcetypeassert // fetchDated returns one.
+	return true
+}
+
+// count records a request that was answered from the cache if hit is true.
+// led is whether the request made the upstream request itself.
+func (p *Proxy) count(hit, led bool) {
+	switch {
+	case hit:
+		p.hits.Add(1)
+	case !led:
+		p.deduplicated.Add(1)
+	}
+}
+
+// refreshResult is the result of refresh.
+type refreshResult struct {
+	entry cacheEntry
+	hit   bool
+}
+
+// refresh returns the cache entry of the latest database of an edition. If
+// it was not checked upstream within the check interval, it is, and a new
+// database is downloaded if there is one. If upstream fails, the cached
+// entry is still used.
+func (p *Proxy) refresh(ctx context.Context, editionID string) (refreshResult, error) {
+	entry, cached := p.cachedEntry(editionID)
+	if cached && p.now().Sub(entry.checkedAt) < p.checkInterval {
+		return refreshResult{entry: entry, hit: true}, nil
+	}
+
+	md5 := ""
+	if cached {
+		md5 = entry.MD5
+	}
+	res, err := p.upstream.Download(ctx, editionID, md5)
+	if err != nil {
+		p.upstreamErrors.Add(1)
+		if cached {
+			p.logf("Checking %s upstream, serving the cached database: %s", editionID, err)
+			return refreshResult{entry: entry, hit: true}, nil
+		}
+		return refreshResult{}, err
+	}
+	defer res.Reader.Close()
+
+	if !res.UpdateAvailable {
+		entry.checkedAt = p.now()
+		p.setEntry(editionID, entry)
+		return refreshResult{entry: entry, hit: true}, nil
+	}
+
+	p.misses.Add(1)
+	sum, err := p.store(editionID, res)
+	if err != nil {
+		return refreshResult{}, err
+	}
+
+	newEntry := cacheEntry{MD5: sum, Date: res.Date, checkedAt: p.now()}
+	if err := p.saveEntry(editionID, newEntry); err != nil {
+		return refreshResult{}, err
+	}
+	p.setEntry(editionID, newEntry)
+	p.logf("Cached %s built on %s", editionID, res.Date)
+
+	if cached && entry.Date != newEntry.Date {
+		p.removeSuperseded(editionID, entry.Date)
+	}
+
+	return refreshResult{entry: newEntry}, nil
+}
+
+// openLatest opens the archive of the latest database of an edition, which
+// was built on date. If a newer database was cached since and the archive
+// was removed, that of the newer one is opened.
+func (p *Proxy) openLatest(editionID, date string) (*os.File, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	//nolint:gosec // the path is in the cache directory.
+	f, err := os.Open(p.archivePath(editionID, date))
+	if errors.Is(err, os.ErrNotExist) {
+		if entry, ok := p.current[editionID]; ok && entry.Date != date {
+			//nolint:gosec // the path is in the cache directory.
+			return os.Open(p.archivePath(editionID, entry.Date))
+		}
+	}
+	return f, err
+}
+
+// removeSuperseded removes the archive of the database of an edition built
+// on date once a newer one is the latest, unless it was requested by date
+// before. Requests that are still reading it are not affected.
+func (p *Proxy) removeSuperseded(editionID, date string) {
+	path := p.archivePath(editionID, date)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.kept[path] {
+		return
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		p.logf("Removing the previous archive of %s: %s", editionID, err)
+	}
+	if err := os.Remove(p.signaturePath(editionID, date)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		p.logf("Removing the previous signature of %s: %s", editionID, err)
+	}
+}
+
+// fetchDated downloads the database of an edition built on date upstream
+// unless its archive is already cached. It returns whether it was.
+func (p *Proxy) fetchDated(ctx context.Context, editionID, date string) (bool, error) {
+	if _, err := os.Stat(p.archivePath(editionID, date)); err == nil {
+		return true, nil
+	}
+
+	res, err := p.upstream.DownloadDate(ctx, editionID, date)
+	if err != nil {
+		p.upstreamErrors.Add(1)
+		return false, err
+	}
+	defer res.Reader.Close()
+
+	p.misses.Add(1)
+	if _, err := p.store(editionID, res); err != nil {
+		return false, err
+	}
+	p.logf("Cached %s built on %s", editionID, date)
+	return false, nil
+}
+
+// signatureResult is the result of fetchSignature.
+type signatureResult struct {
+	signature []byte
+	hit       bool
+}
+
+// fetchSignature returns the signature of the database of an edition
+// released on date, downloading it upstream unless it is already cached.
+func (p *Proxy) fetchSignature(ctx context.Context, editionID, date string) (signatureResult, error) {
+	path := p.signaturePath(editionID, date)
+	//nolint:gosec // the path is in the cache directory.
+	if sig, err := os.ReadFile(path); err == nil {
+		return signatureResult{signature: sig, hit: true}, nil
+	}
+
+	upstream, ok := p.upstream.(SignatureUpstream)
+	if !ok {
+		return signatureResult{}, fmt.Errorf(
+			"no signature of the database of %s is available upstream: %w", editionID, client.ErrNotFound)
+	}
+	sig, err := upstream.DownloadSignature(ctx, editionID, date)
+	if err != nil {
+		p.upstreamErrors.Add(1)
+		return signatureResult{}, err
+	}
+
+	p.misses.Add(1)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return signatureResult{}, fmt.Errorf("creating cache directory of %s: %w", editionID, err)
+	}
+	//nolint:gosec // the signatures are not secret.
+	if err := os.WriteFile(path+".temporary", sig, 0o644); err != nil {
+		return signatureResult{}, fmt.Errorf("writing signature of %s: %w", editionID, err)
+	}
+	if err := os.Rename(path+".temporary", path); err != nil {
+		return signatureResult{}, fmt.Errorf("writing signature of %s: %w", editionID, err)
+	}
+	p.logf("Cached the signature of %s released on %s", editionID, date)
+	return signatureResult{signature: sig}, nil
+}
+
+// store caches the archive of the database downloaded upstream and returns
+// its MD5, which must match the one sent upstream if there is one.
+func (p *Proxy) store(editionID string, res client.DownloadResponse) (_ string, err error) {
+	dir := filepath.Join(p.dir, editionID)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("creating cache directory of %s: %w", editionID, err)
+	}
+
+	// The size of the database must be known to write the archive, so it
+	// is written to a file first.
+	db, err := os.CreateTemp(dir, ".download-*")
+	if err != nil {
+		return "", fmt.Errorf("creating temporary file: %w", err)
+	}
+	defer func() {
+		//nolint:errcheck // Best effort.
+		_ = db.Close()
+		//nolint:errcheck // Best effort.
+		_ = os.Remove(db.Name())
+	}()
+
+	h := md5.New()
+	size, err := io.Copy(io.MultiWriter(db, h), res.Reader)
+	if err != nil {
+		return "", fmt.Errorf("downloading %s: %w", editionID, err)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	if res.MD5 != "" && !strings.EqualFold(sum, res.MD5) {
+		return "", fmt.Errorf(
+			"%w: the MD5 of the database of %s is %s rather than %s",
+			internal.ErrHashMismatch,
+			editionID,
+			sum,
+			res.MD5,
+		)
+	}
+	if _, err := db.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("rewinding temporary file: %w", err)
+	}
+
+	path := p.archivePath(editionID, res.Date)
+	archive, err := os.CreateTemp(dir, ".archive-*")
+	if err != nil {
+		return "", fmt.Errorf("creating temporary file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			//nolint:errcheck // Best effort.
+			_ = archive.Close()
+			//nolint:errcheck // Best effort.
+			_ = os.Remove(archive.Name())
+		}
+	}()
+
+	modTime := res.LastModified
+	if modTime.IsZero() {
+		modTime = p.now()
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), archiveExtension) + "/" + editionID + ".mmdb"
+	if err := writeArchive(archive, db, name, size, modTime); err != nil {
+		return "", fmt.Errorf("writing archive of %s: %w", editionID, err)
+	}
+	if err := archive.Sync(); err != nil {
+		return "", fmt.Errorf("syncing archive of %s: %w", editionID, err)
+	}
+	if err := archive.Close(); err != nil {
+		return "", fmt.Errorf("closing archive of %s: %w", editionID, err)
+	}
+	//nolint:gosec // the archives are not secret.
+	if err := os.Chmod(archive.Name(), 0o644); err != nil {
+		return "", fmt.Errorf("setting permissions of archive of %s: %w", editionID, err)
+	}
+	if err := os.Chtimes(archive.Name(), modTime, modTime); err != nil {
+		return "", fmt.Errorf("setting modification time of archive of %s: %w", editionID, err)
+	}
+	if err := os.Rename(archive.Name(), path); err != nil {
+		return "", fmt.Errorf("moving archive of %s into place: %w", editionID, err)
+	}
+	return sum, nil
+}
+
+// cachedEntry returns the cache entry of the latest database of an edition,
+// loading it from the cache directory the first time.
+func (p *Proxy) cachedEntry(editionID string) (cacheEntry, bool) {
+	p.mu.Lock()
+	entry, ok := p.current[editionID]
+	p.mu.Unlock()
+	if ok {
+		return entry, true
+	}
+
+	// Entries loaded from disk are checked upstream on their first use.
+	b, err := os.ReadFile(filepath.Join(p.dir, editionID, currentFile))
+	if err != nil {
+		return cacheEntry{}, false
+	}
+	if err := json.Unmarshal(b, &entry); err != nil {
+		p.logf("Reading the cache entry of %s: %s", editionID, err)
+		return cacheEntry{}, false
+	}
+	if _, err := os.Stat(p.archivePath(editionID, entry.Date)); err != nil {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// setEntry sets the cache entry of the latest database of an edition.
+func (p *Proxy) setEntry(editionID string, entry cacheEntry) {
+	p.mu.Lock()
+	p.current[editionID] = entry
+	p.mu.Unlock()
+}
+
+// saveEntry writes the cache entry of the latest database of an edition to
+// the cache directory, so that it is still used after a restart.
+func (p *Proxy) saveEntry(editionID string, entry cacheEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encoding cache entry of %s: %w", editionID, err)
+	}
+	path := filepath.Join(p.dir, editionID, currentFile)
+	if err := os.WriteFile(path+".temporary", b, 0o600); err != nil {
+		return fmt.Errorf("writing cache entry of %s: %w", editionID, err)
+	}
+	if err := os.Rename(path+".temporary", path); err != nil {
+		return fmt.Errorf("writing cache entry of %s: %w", editionID, err)
+	}
+	return nil
+}
+
+// archivePath returns the path of the cached archive of the database of an
+// edition built on date, as YYYY-MM-DD.
+func (p *Proxy) archivePath(editionID, date string) string {
+	name := editionID + "_" + strings.ReplaceAll(date, "-", "") + archiveExtension
+	return filepath.Join(p.dir, editionID, name)
+}
+
+// signaturePath returns the path of the cached signature of the database of
+// an edition released on date, as YYYY-MM-DD.
+func (p *Proxy) signaturePath(editionID, date string) string {
+	name := editionID + "_" + strings.ReplaceAll(date, "-", "") + signatureExtension
+	return filepath.Join(p.dir, editionID, name)
+}
+
+func (p *Proxy) logf(format string, v ...any) {
+	if p.logger != nil {
+		p.logger.Printf(format, v...)
+	}
+}
+
+// writeUpstreamError responds with the error of an upstream request. Only
+// a missing database is passed on as is. Other errors, e.g., with the
+// upstream credentials, are not the downstream client's.
+func writeUpstreamError(w http.ResponseWriter, err error) {
+	if errors.Is(err, client.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "DATABASE_NOT_FOUND", err.Error())
+		return
+	}
+	writeError(w, http.StatusBadGateway, "UPSTREAM_ERROR", err.Error())
+}
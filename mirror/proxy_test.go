@@ -0,0 +1,369 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/client"
+	"github.com/maxmind/geoipupdate/v8/internal"
+	"github.com/maxmind/geoipupdate/v8/internal/mmdbtest"
+)
+
+func TestProxy(t *testing.T) {
+	dir := t.TempDir()
+
+	first := mmdbtest.Build("GeoIP2-City", 1789430400)
+	upstream := &fakeUpstream{
+		latest:     first,
+		latestDate: "2026-09-15",
+		dated: map[string][]byte{
+			"2026-09-11": mmdbtest.Build("GeoIP2-City", 1789430400-4*24*60*60),
+		},
+	}
+
+	_, err := NewProxy(dir, []string{"GeoIP2-City"}, upstream, 0, "")
+	require.EqualError(t, err, "an account ID and license key are required")
+
+	now := time.Date(2026, 9, 15, 12, 0, 0, 0, time.UTC)
+	p, err := NewProxy(dir, []string{"GeoIP2-City"}, upstream, 42, "downstream")
+	require.NoError(t, err)
+	p.now = func() time.Time { return now }
+	server := httptest.NewServer(p)
+	t.Cleanup(server.Close)
+
+	c, err := client.New(42, "downstream", client.WithEndpoint(server.URL))
+	require.NoError(t, err)
+
+	// Concurrent clients share a single upstream download.
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			res, err := c.Download(context.Background(), "GeoIP2-City", "")
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer res.Reader.Close()
+			db, err := io.ReadAll(res.Reader)
+			assert.NoError(t, err)
+			assert.Equal(t, first, db)
+			assert.Equal(t, md5Hex(first), res.MD5)
+			assert.Equal(t, "2026-09-15", res.Date)
+		})
+	}
+	wg.Wait()
+	require.Equal(t, 1, upstream.calls("latest"))
+	stats := p.Stats()
+	require.Equal(t, int64(20), stats.Requests)
+	require.Equal(t, int64(1), stats.Misses)
+	require.Equal(t, int64(19), stats.Hits+stats.Deduplicated)
+
+	// Once the check interval passed, upstream is checked again, but the
+	// database is only downloaded again once it changed.
+	now = now.Add(time.Hour)
+	res, err := c.Download(t.Context(), "GeoIP2-City", md5Hex(first))
+	require.NoError(t, err)
+	require.False(t, res.UpdateAvailable)
+	require.Equal(t, 2, upstream.calls("latest"))
+	require.Equal(t, int64(1), p.Stats().Misses)
+
+	second := mmdbtest.Build("GeoIP2-City", 1789430400+3*24*60*60)
+	upstream.set(second, "2026-09-18")
+	now = now.Add(time.Hour)
+	res, err = c.Download(t.Context(), "GeoIP2-City", md5Hex(first))
+	require.NoError(t, err)
+	require.Equal(t, second, readAll(t, res.Reader))
+	require.Equal(t, int64(2), p.Stats().Misses)
+
+	// If upstream fails, the cached database is still served.
+	upstream.fail(errors.New("connection reset"))
+	now = now.Add(time.Hour)
+	res, err = c.Download(t.Context(), "GeoIP2-City", "")
+	require.NoError(t, err)
+	require.Equal(t, second, readAll(t, res.Reader))
+	require.Equal(t, int64(1), p.Stats().UpstreamErrors)
+	upstream.fail(nil)
+
+	// Databases pinned to a date are cached too, and missing ones are not
+	// found.
+	for range 2 {
+		res, err = c.DownloadDate(t.Context(), "GeoIP2-City", "2026-09-11")
+		require.NoError(t, err)
+		require.Equal(t, upstream.dated["2026-09-11"], readAll(t, res.Reader))
+	}
+	require.Equal(t, 1, upstream.calls("dated"))
+
+	var httpErr client.HTTPError
+	_, err = c.DownloadDate(t.Context(), "GeoIP2-City", "2026-09-12")
+	require.ErrorAs(t, err, &httpErr)
+	require.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+
+	_, err = c.Download(t.Context(), "GeoIP2-Country", "")
+	require.ErrorAs(t, err, &httpErr)
+	require.Equal(t, "EDITION_NOT_FOUND", httpErr.Code)
+
+	// The statistics are served too.
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL+statsPath, http.NoBody)
+	require.NoError(t, err)
+	req.SetBasicAuth("42", "downstream")
+	statsRes, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer statsRes.Body.Close()
+	var served Stats
+	require.NoError(t, json.NewDecoder(statsRes.Body).Decode(&served))
+	require.Equal(t, p.Stats(), served)
+
+	// After a restart, the cached database is checked upstream rather than
+	// downloaded again.
+	restarted, err := NewProxy(dir, []string{"GeoIP2-City"}, upstream, 42, "downstream")
+	require.NoError(t, err)
+	restartedServer := httptest.NewServer(restarted)
+	t.Cleanup(restartedServer.Close)
+	c, err = client.New(42, "downstream", client.WithEndpoint(restartedServer.URL))
+	require.NoError(t, err)
+	res, err = c.Download(t.Context(), "GeoIP2-City", "")
+	require.NoError(t, err)
+	require.Equal(t, second, readAll(t, res.Reader))
+	require.Equal(t, int64(0), restarted.Stats().Misses)
+}
+
+func TestProxyKeepsServedArchives(t *testing.T) {
+	upstream := &fakeUpstream{
+		latest:     mmdbtest.Build("GeoIP2-City", 1789430400),
+		latestDate: "2026-09-15",
+		dated:      map[string][]byte{},
+	}
+	upstream.dated["2026-09-15"] = upstream.latest
+
+	now := time.Date(2026, 9, 15, 12, 0, 0, 0, time.UTC)
+	p, err := NewProxy(t.TempDir(), []string{"GeoIP2-City"}, upstream, 42, "downstream")
+	require.NoError(t, err)
+	p.now = func() time.Time { return now }
+	server := httptest.NewServer(p)
+	t.Cleanup(server.Close)
+
+	c, err := client.New(42, "downstream", client.WithEndpoint(server.URL))
+	require.NoError(t, err)
+
+	res, err := c.Download(t.Context(), "GeoIP2-City", "")
+	require.NoError(t, err)
+	readAll(t, res.Reader)
+
+	// A request that resolved the latest database before a newer one was
+	// cached is served the newer one.
+	second := mmdbtest.Build("GeoIP2-City", 1789430400+3*24*60*60)
+	upstream.set(second, "2026-09-18")
+	now = now.Add(time.Hour)
+	res, err = c.Download(t.Context(), "GeoIP2-City", "")
+	require.NoError(t, err)
+	require.Equal(t, second, readAll(t, res.Reader))
+	f, err := p.openLatest("GeoIP2-City", "2026-09-15")
+	require.NoError(t, err)
+	require.Equal(t, p.archivePath("GeoIP2-City", "2026-09-18"), f.Name())
+	require.NoError(t, f.Close())
+
+	// Superseded archives are removed, unless they were requested by date.
+	require.NoFileExists(t, p.archivePath("GeoIP2-City", "2026-09-15"))
+	res, err = c.DownloadDate(t.Context(), "GeoIP2-City", "2026-09-15")
+	require.NoError(t, err)
+	readAll(t, res.Reader)
+	upstream.set(mmdbtest.Build("GeoIP2-City", 1789430400+6*24*60*60), "2026-09-21")
+	now = now.Add(time.Hour)
+	res, err = c.Download(t.Context(), "GeoIP2-City", "")
+	require.NoError(t, err)
+	readAll(t, res.Reader)
+
+	require.FileExists(t, p.archivePath("GeoIP2-City", "2026-09-15"))
+	require.NoFileExists(t, p.archivePath("GeoIP2-City", "2026-09-18"))
+}
+
+func TestProxySignatures(t *testing.T) {
+	upstream := &fakeUpstream{
+		latest:     mmdbtest.Build("GeoIP2-City", 1789430400),
+		latestDate: "2026-09-15",
+		dated:      map[string][]byte{},
+		signatures: map[string][]byte{
+			"2026-09-11": []byte("signature of 2026-09-11"),
+			"2026-09-15": []byte("signature of 2026-09-15"),
+		},
+	}
+
+	dir := t.TempDir()
+	p, err := NewProxy(dir, []string{"GeoIP2-City"}, upstream, 42, "downstream")
+	require.NoError(t, err)
+	server := httptest.NewServer(p)
+	t.Cleanup(server.Close)
+
+	c, err := client.New(
+		42,
+		"downstream",
+		client.WithEndpoint(server.URL),
+		client.WithSignatureSuffix(SignatureSuffix),
+	)
+	require.NoError(t, err)
+
+	// Signatures are cached apart from the archives of the same dates.
+	res, err := c.Download(t.Context(), "GeoIP2-City", "")
+	require.NoError(t, err)
+	require.Equal(t, upstream.latest, readAll(t, res.Reader))
+	for range 2 {
+		sig, err := c.DownloadSignature(t.Context(), "GeoIP2-City", "2026-09-15")
+		require.NoError(t, err)
+		require.Equal(t, upstream.signatures["2026-09-15"], sig)
+	}
+	require.Equal(t, 1, upstream.calls("signature"))
+	require.FileExists(t, p.signaturePath("GeoIP2-City", "2026-09-15"))
+
+	sig, err := c.DownloadSignature(t.Context(), "GeoIP2-City", "2026-09-11")
+	require.NoError(t, err)
+	require.Equal(t, upstream.signatures["2026-09-11"], sig)
+	require.Equal(t, 0, upstream.calls("dated"))
+
+	var httpErr client.HTTPError
+	_, err = c.DownloadSignature(t.Context(), "GeoIP2-City", "2026-09-12")
+	require.ErrorAs(t, err, &httpErr)
+	require.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+
+	// Other suffixes are not served.
+	other, err := client.New(
+		42,
+		"downstream",
+		client.WithEndpoint(server.URL),
+		client.WithSignatureSuffix("mmdb.sig"),
+	)
+	require.NoError(t, err)
+	_, err = other.DownloadSignature(t.Context(), "GeoIP2-City", "2026-09-15")
+	require.ErrorAs(t, err, &httpErr)
+	require.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+	require.Equal(t, "NOT_FOUND", httpErr.Code)
+
+	// Nor are signatures if upstream does not serve them.
+	unsigned, err := NewProxy(
+		t.TempDir(),
+		[]string{"GeoIP2-City"},
+		struct{ Upstream }{upstream},
+		42,
+		"downstream",
+	)
+	require.NoError(t, err)
+	unsignedServer := httptest.NewServer(unsigned)
+	t.Cleanup(unsignedServer.Close)
+	c, err = client.New(
+		42,
+		"downstream",
+		client.WithEndpoint(unsignedServer.URL),
+		client.WithSignatureSuffix(SignatureSuffix),
+	)
+	require.NoError(t, err)
+	_, err = c.DownloadSignature(t.Context(), "GeoIP2-City", "2026-09-15")
+	require.ErrorAs(t, err, &httpErr)
+	require.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+}
+
+// fakeUpstream serves latest as the latest database, the databases in dated
+// by date and the signatures in signatures by date.
+type fakeUpstream struct {
+	mu         sync.Mutex
+	latest     []byte
+	latestDate string
+	dated      map[string][]byte
+	signatures map[string][]byte
+	err        error
+	counts     map[string]int
+}
+
+func (u *fakeUpstream) Download(
+	_ context.Context,
+	_,
+	md5 string,
+) (client.DownloadResponse, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.count("latest")
+
+	if u.err != nil {
+		return client.DownloadResponse{}, u.err
+	}
+	if md5 == md5Hex(u.latest) {
+		return client.DownloadResponse{Reader: io.NopCloser(bytes.NewReader(nil))}, nil
+	}
+	return client.DownloadResponse{
+		Date:            u.latestDate,
+		LastModified:    time.Date(2026, 9, 15, 12, 0, 0, 0, time.UTC),
+		MD5:             md5Hex(u.latest),
+		Reader:          io.NopCloser(bytes.NewReader(u.latest)),
+		UpdateAvailable: true,
+	}, nil
+}
+
+func (u *fakeUpstream) DownloadDate(
+	_ context.Context,
+	_,
+	date string,
+) (client.DownloadResponse, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.count("dated")
+
+	db, ok := u.dated[date]
+	if !ok {
+		return client.DownloadResponse{}, internal.HTTPError{StatusCode: http.StatusNotFound}
+	}
+	return client.DownloadResponse{
+		Date:            date,
+		Reader:          io.NopCloser(bytes.NewReader(db)),
+		UpdateAvailable: true,
+	}, nil
+}
+
+func (u *fakeUpstream) DownloadSignature(
+	_ context.Context,
+	_,
+	date string,
+) ([]byte, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.count("signature")
+
+	sig, ok := u.signatures[date]
+	if !ok {
+		return nil, internal.HTTPError{StatusCode: http.StatusNotFound}
+	}
+	return sig, nil
+}
+
+func (u *fakeUpstream) count(name string) {
+	if u.counts == nil {
+		u.counts = map[string]int{}
+	}
+	u.counts[name]++
+}
+
+func (u *fakeUpstream) calls(name string) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.counts[name]
+}
+
+func (u *fakeUpstream) set(db []byte, date string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.latest = db
+	u.latestDate = date
+}
+
+func (u *fakeUpstream) fail(err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.err = err
+}
@@ -0,0 +1,333 @@
+// Package provider serves the databases in a database directory to the
+// program that reads them, swapping in new databases as geoipupdate writes
+// them.
+//
+// The directory is watched with inotify on Linux. Elsewhere, or when inotify
+// is unavailable, it is polled. A database is only closed once it has been
+// replaced and every Reader for it has been closed, so lookups in progress
+// are never affected by an update.
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+const extension = ".mmdb"
+
+var (
+	// ErrUnknownEdition is returned when acquiring an edition that the
+	// Provider was not created with.
+	ErrUnknownEdition = errors.New("unknown edition")
+
+	// ErrNotLoaded is returned when acquiring an edition whose database does
+	// not exist yet.
+	ErrNotLoaded = errors.New("database not loaded")
+)
+
+// Provider hands out Readers for the databases in a directory.
+//
+// It is valid for concurrent use.
+type Provider struct {
+	dir          string
+	editions     map[string]*edition
+	logger       *log.Logger
+	pollInterval time.Duration
+	polling      bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Option is an option for configuring Provider.
+type Option func(*Provider)
+
+// WithLogger sets the logger that errors reloading databases are logged to.
+// By default, log.Default() is used.
+func WithLogger(l *log.Logger) Option {
+	return func(p *Provider) {
+		p.logger = l
+	}
+}
+
+// WithPollInterval sets how often the directory is checked for new databases
+// when it is polled. The default is 10 seconds.
+func WithPollInterval(d time.Duration) Option {
+	return func(p *Provider) {
+		p.pollInterval = d
+	}
+}
+
+// WithPolling makes the Provider poll the directory even where inotify is
+// available, e.g., for network file systems that do not support it.
+func WithPolling() Option {
+	return func(p *Provider) {
+		p.polling = true
+	}
+}
+
+// New creates a Provider for the given editions in dir and loads the ones
+// whose databases exist. Editions without a database are loaded once it is
+// written.
+//
+// Close must be called to stop watching dir.
+func New(dir string, editionIDs []string, options ...Option) (*Provider, error) {
+	p := &Provider{
+		dir:          dir,
+		editions:     map[string]*edition{},
+		logger:       log.Default(),
+		pollInterval: 10 * time.Second,
+		done:         make(chan struct{}),
+	}
+
+	for _, opt := range options {
+		opt(p)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	// The directory is watched before the databases are loaded so that no
+	// change is missed.
+	var changes <-chan string
+	if !p.polling {
+		var err error
+		changes, err = watchDir(ctx, dir)
+		if err != nil && !errors.Is(err, errors.ErrUnsupported) {
+			p.logger.Printf("Watching %s failed, polling it instead: %s", dir, err)
+		}
+	}
+
+	for _, editionID := range editionIDs {
+		e := &edition{path: filepath.Join(dir, editionID+extension)}
+		p.editions[editionID] = e
+		if err := e.reload(); err != nil && !errors.Is(err, os.ErrNotExist) {
+			cancel()
+			//nolint:errcheck // we are already returning an error.
+			_ = p.closeEditions()
+			return nil, fmt.Errorf("loading %s: %w", editionID, err)
+		}
+	}
+
+	go p.watch(ctx, changes)
+
+	return p, nil
+}
+
+// Acquire returns a Reader for the current database of the edition. The
+// Reader must be closed once the lookups with it are done.
+func (p *Provider) Acquire(editionID string) (*Reader, error) {
+	e, ok := p.editions[editionID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEdition, editionID)
+	}
+
+	for {
+		db := e.current.Load()
+		if db == nil {
+			return nil, fmt.Errorf("%w: %s", ErrNotLoaded, editionID)
+		}
+		// This fails if db was replaced and closed since it was loaded, in
+		// which case the replacement is now current.
+		if db.acquire() {
+			return &Reader{Reader: db.reader, db: db}, nil
+		}
+	}
+}
+
+// Reload reloads the edition's database if the file has changed. Changes
+// are picked up automatically, but this can be used to pick one up right
+// away, e.g., when receiving an updater.DatabaseReplaced event.
+func (p *Provider) Reload(editionID string) error {
+	e, ok := p.editions[editionID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownEdition, editionID)
+	}
+	if err := e.reload(); err != nil {
+		return fmt.Errorf("reloading %s: %w", editionID, err)
+	}
+	return nil
+}
+
+// Close stops watching the directory. Readers that have been acquired remain
+// usable until they are closed.
+func (p *Provider) Close() error {
+	p.cancel()
+	<-p.done
+	return p.closeEditions()
+}
+
+func (p *Provider) closeEditions() error {
+	var errs []error
+	for _, e := range p.editions {
+		if db := e.current.Swap(nil); db != nil {
+			errs = append(errs, db.release())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// watch reloads databases as they change until ctx is canceled. If changes
+// is nil or closed, the directory is polled.
+func (p *Provider) watch(ctx context.Context, changes <-chan string) {
+	defer close(p.done)
+
+	if changes != nil {
+		p.watchChanges(ctx, changes)
+		if ctx.Err() != nil {
+			return
+		}
+		p.logger.Printf("Stopped watching %s, polling it instead", p.dir)
+	}
+
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.reloadAll()
+		}
+	}
+}
+
+// watchChanges reloads the editions whose files are sent on changes, until
+// it is closed. An empty name means that changes may have been missed.
+func (p *Provider) watchChanges(ctx context.Context, changes <-chan string) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case name, ok := <-changes:
+			if !ok {
+				return
+			}
+			if name == "" {
+				p.reloadAll()
+				continue
+			}
+			editionID, ok := strings.CutSuffix(name, extension)
+			if !ok {
+				continue
+			}
+			if _, ok := p.editions[editionID]; !ok {
+				continue
+			}
+			if err := p.Reload(editionID); err != nil {
+				p.logger.Print(err)
+			}
+		}
+	}
+}
+
+func (p *Provider) reloadAll() {
+	for editionID := range p.editions {
+		err := p.Reload(editionID)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			p.logger.Print(err)
+		}
+	}
+}
+
+// edition holds the current database of an edition.
+type edition struct {
+	path string
+
+	// mu serializes reloads.
+	mu      sync.Mutex
+	current atomic.Pointer[database]
+}
+
+// reload opens the database if the file is not the one that is loaded.
+func (e *edition) reload() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	info, err := os.Stat(e.path)
+	if err != nil {
+		return err
+	}
+
+	current := e.current.Load()
+	if current != nil &&
+		os.SameFile(current.info, info) &&
+		current.info.ModTime().Equal(info.ModTime()) &&
+		current.info.Size() == info.Size() {
+		return nil
+	}
+
+	reader, err := maxminddb.Open(e.path)
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+
+	db := &database{reader: reader, info: info}
+	// This reference is held until the database is replaced.
+	db.refs.Store(1)
+
+	if old := e.current.Swap(db); old != nil {
+		return old.release()
+	}
+	return nil
+}
+
+// database is a reference-counted database.
+type database struct {
+	reader *maxminddb.Reader
+	info   os.FileInfo
+	refs   atomic.Int64
+}
+
+// acquire adds a reference. It returns false if the database was closed.
+func (d *database) acquire() bool {
+	for {
+		n := d.refs.Load()
+		if n <= 0 {
+			return false
+		}
+		if d.refs.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// release removes a reference, closing the database with the last one.
+func (d *database) release() error {
+	if d.refs.Add(-1) != 0 {
+		return nil
+	}
+	if err := d.reader.Close(); err != nil {
+		return fmt.Errorf("closing database: %w", err)
+	}
+	return nil
+}
+
+// Reader is a reference to a database. It must not be used after it is
+// closed.
+type Reader struct {
+	*maxminddb.Reader
+
+	db     *database
+	closed atomic.Bool
+}
+
+// Close releases the reference to the database. The database itself is
+// closed once it has been replaced and all of its Readers are closed.
+// Closing a Reader more than once has no effect.
+func (r *Reader) Close() error {
+	if !r.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	return r.db.release()
+}
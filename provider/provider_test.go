@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/internal/mmdbtest"
+)
+
+func TestProvider(t *testing.T) {
+	tests := []struct {
+		description string
+		options     []Option
+	}{
+		{
+			description: "default",
+		},
+		{
+			description: "polling",
+			options:     []Option{WithPolling(), WithPollInterval(10 * time.Millisecond)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "GeoIP2-City.mmdb")
+			mmdbtest.Write(t, path, "GeoIP2-City", 1)
+
+			p, err := New(dir, []string{"GeoIP2-City", "GeoIP2-Country"}, test.options...)
+			require.NoError(t, err)
+			defer func() {
+				require.NoError(t, p.Close())
+			}()
+
+			old, err := p.Acquire("GeoIP2-City")
+			require.NoError(t, err)
+			require.Equal(t, uint(1), old.Metadata.BuildEpoch)
+
+			// Replace the database the way LocalFileWriter does.
+			replace(t, path, "GeoIP2-City", 2)
+
+			require.Eventually(t, func() bool {
+				r, err := p.Acquire("GeoIP2-City")
+				if err != nil {
+					return false
+				}
+				defer r.Close()
+				return r.Metadata.BuildEpoch == 2
+			}, 5*time.Second, 5*time.Millisecond)
+
+			// The old database stays open until its Reader is closed.
+			require.Equal(t, int64(1), old.db.refs.Load())
+			require.Equal(t, uint(1), old.Metadata.BuildEpoch)
+			require.NoError(t, old.Close())
+			require.Equal(t, int64(0), old.db.refs.Load())
+			require.NoError(t, old.Close())
+
+			// Editions are loaded once their database is written.
+			_, err = p.Acquire("GeoIP2-Country")
+			require.ErrorIs(t, err, ErrNotLoaded)
+
+			replace(t, filepath.Join(dir, "GeoIP2-Country.mmdb"), "GeoIP2-Country", 3)
+
+			require.Eventually(t, func() bool {
+				r, err := p.Acquire("GeoIP2-Country")
+				if err != nil {
+					return false
+				}
+				defer r.Close()
+				return r.Metadata.BuildEpoch == 3
+			}, 5*time.Second, 5*time.Millisecond)
+		})
+	}
+}
+
+func TestProviderReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "GeoIP2-City.mmdb")
+	mmdbtest.Write(t, path, "GeoIP2-City", 1)
+
+	// The directory is never polled during the test.
+	p, err := New(dir, []string{"GeoIP2-City"}, WithPolling(), WithPollInterval(time.Hour))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, p.Close())
+	}()
+
+	first, err := p.Acquire("GeoIP2-City")
+	require.NoError(t, err)
+	defer first.Close()
+
+	// Reloading an unchanged file keeps the same database.
+	require.NoError(t, p.Reload("GeoIP2-City"))
+	same, err := p.Acquire("GeoIP2-City")
+	require.NoError(t, err)
+	require.Same(t, first.db, same.db)
+	require.NoError(t, same.Close())
+
+	replace(t, path, "GeoIP2-City", 2)
+	require.NoError(t, p.Reload("GeoIP2-City"))
+
+	r, err := p.Acquire("GeoIP2-City")
+	require.NoError(t, err)
+	defer r.Close()
+	require.Equal(t, uint(2), r.Metadata.BuildEpoch)
+
+	require.ErrorIs(t, p.Reload("GeoIP2-ISP"), ErrUnknownEdition)
+	_, err = p.Acquire("GeoIP2-ISP")
+	require.ErrorIs(t, err, ErrUnknownEdition)
+}
+
+func TestNewInvalidDatabase(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "GeoIP2-City.mmdb"), []byte("not a database"), 0o600)
+	require.NoError(t, err)
+
+	_, err = New(dir, []string{"GeoIP2-City"})
+	require.ErrorContains(t, err, "loading GeoIP2-City")
+}
+
+// replace atomically replaces the database at path.
+func replace(t *testing.T, path, databaseType string, buildEpoch uint64) {
+	t.Helper()
+	tmp := path + ".temporary"
+	mmdbtest.Write(t, tmp, databaseType, buildEpoch)
+	require.NoError(t, os.Rename(tmp, path))
+}
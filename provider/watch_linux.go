@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// watchDir sends the names of the files in dir that are renamed into place
+// or written to. An empty name is sent when events were lost. The channel is
+// closed when ctx is canceled or dir can no longer be watched, e.g., because
+// it was removed.
+func watchDir(ctx context.Context, dir string) (<-chan string, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("initializing inotify: %w", err)
+	}
+
+	_, err = unix.InotifyAddWatch(
+		fd,
+		dir,
+		unix.IN_MOVED_TO|unix.IN_CLOSE_WRITE|unix.IN_DELETE_SELF|unix.IN_MOVE_SELF,
+	)
+	if err != nil {
+		//nolint:errcheck // we are already returning an error.
+		_ = unix.Close(fd)
+		return nil, fmt.Errorf("watching %s: %w", dir, err)
+	}
+
+	// As the file descriptor is non-blocking, reads go through the runtime
+	// poller and closing the file interrupts them.
+	f := os.NewFile(uintptr(fd), "inotify")
+
+	go func() {
+		<-ctx.Done()
+		//nolint:errcheck // nothing to do about errors here.
+		_ = f.Close()
+	}()
+
+	changes := make(chan string)
+	go func() {
+		defer close(changes)
+
+		buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+		for {
+			n, err := f.Read(buf)
+			if err != nil {
+				return
+			}
+
+			for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+				mask := binary.NativeEndian.Uint32(buf[offset+4:])
+				nameLen := int(binary.NativeEndian.Uint32(buf[offset+12:]))
+				start := offset + unix.SizeofInotifyEvent
+				name := strings.TrimRight(string(buf[start:start+nameLen]), "\x00")
+				offset = start + nameLen
+
+				switch {
+				case mask&(unix.IN_DELETE_SELF|unix.IN_MOVE_SELF|unix.IN_IGNORED) != 0:
+					return
+				case mask&unix.IN_Q_OVERFLOW != 0:
+					name = ""
+				}
+
+				select {
+				case changes <- name:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return changes, nil
+}
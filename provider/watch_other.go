@@ -0,0 +1,13 @@
+//go:build !linux
+
+package provider
+
+import (
+	"context"
+	"errors"
+)
+
+// watchDir is only implemented on Linux. Elsewhere, the directory is polled.
+func watchDir(context.Context, string) (<-chan string, error) {
+	return nil, errors.ErrUnsupported
+}
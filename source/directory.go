@@ -0,0 +1,142 @@
+package source
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+
+	"github.com/maxmind/geoipupdate/v8/client"
+)
+
+const extension = ".mmdb"
+
+// directory is a Source that reads the databases from a directory.
+type directory struct {
+	dir string
+}
+
+func (d directory) Download(
+	_ context.Context,
+	editionID,
+	md5 string,
+) (client.DownloadResponse, error) {
+	return d.open(editionID, editionID+extension, md5)
+}
+
+// DownloadDate returns the database stored as <edition>_<date>.mmdb, e.g.,
+// by geoipupdate backfill, which is the release of that date whatever date
+// it was built on. Otherwise, it returns the current database of the
+// edition if it was built on date, as that is the only date known for it.
+func (d directory) DownloadDate(
+	_ context.Context,
+	editionID,
+	date string,
+) (client.DownloadResponse, error) {
+	res, err := d.open(editionID, editionID+"_"+date+extension, "")
+	if err == nil {
+		res.Date = date
+		res.MD5 = ""
+		return res, nil
+	}
+	if !errors.Is(err, client.ErrNotFound) {
+		return client.DownloadResponse{}, err
+	}
+
+	res, err = d.open(editionID, editionID+extension, "")
+	if err != nil && !errors.Is(err, client.ErrNotFound) {
+		return client.DownloadResponse{}, err
+	}
+	if err == nil {
+		if res.Date == date {
+			res.MD5 = ""
+			return res, nil
+		}
+		//nolint:errcheck // the database was only read.
+		_ = res.Reader.Close()
+	}
+	return client.DownloadResponse{}, fmt.Errorf(
+		"no database of %s released on %s in %s: %w", editionID, date, d.dir, client.ErrNotFound)
+}
+
+// open returns the database in the file name, unless its MD5 is md5.
+func (d directory) open(editionID, name, md5 string) (_ client.DownloadResponse, err error) {
+	path := filepath.Join(d.dir, name)
+	//nolint:gosec // the path is in the source directory.
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return client.DownloadResponse{}, fmt.Errorf(
+				"no database of %s in %s: %w", editionID, d.dir, client.ErrNotFound)
+		}
+		return client.DownloadResponse{}, fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer func() {
+		if err != nil {
+			//nolint:errcheck // the error is already returned.
+			_ = f.Close()
+		}
+	}()
+
+	st, err := f.Stat()
+	if err != nil {
+		return client.DownloadResponse{}, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	sum, err := hashFile(f)
+	if err != nil {
+		return client.DownloadResponse{}, fmt.Errorf("hashing %s: %w", path, err)
+	}
+	if sum == md5 {
+		//nolint:errcheck // the database was only read.
+		_ = f.Close()
+		return client.DownloadResponse{Reader: io.NopCloser(strings.NewReader(""))}, nil
+	}
+
+	date, err := buildDate(path)
+	if err != nil {
+		return client.DownloadResponse{}, err
+	}
+
+	return client.DownloadResponse{
+		Date:            date,
+		LastModified:    st.ModTime(),
+		MD5:             sum,
+		Reader:          f,
+		UpdateAvailable: true,
+	}, nil
+}
+
+// hashFile returns the MD5 of f and rewinds it.
+func hashFile(f *os.File) (string, error) {
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// buildDate returns the date the database at path was built on.
+func buildDate(path string) (string, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("reading metadata of %s: %w", path, err)
+	}
+	epoch := reader.Metadata.BuildEpoch
+	//nolint:errcheck // The database was only read.
+	_ = reader.Close()
+
+	//nolint:gosec // build epochs are well within range.
+	return time.Unix(int64(epoch), 0).UTC().Format(time.DateOnly), nil
+}
@@ -0,0 +1,50 @@
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/client"
+	"github.com/maxmind/geoipupdate/v8/internal/mmdbtest"
+)
+
+func TestDirectory(t *testing.T) {
+	dir := t.TempDir()
+	currentMD5 := mmdbtest.Write(t, filepath.Join(dir, "GeoIP2-City.mmdb"), "GeoIP2-City", 1789430400)
+	mmdbtest.Write(t, filepath.Join(dir, "GeoIP2-City_2026-09-11.mmdb"), "GeoIP2-City", 1789430400-4*24*60*60)
+	// The release of 2026-09-13 was built the day before.
+	mmdbtest.Write(t, filepath.Join(dir, "GeoIP2-City_2026-09-13.mmdb"), "GeoIP2-City", 1789430400-3*24*60*60)
+
+	s, err := New("file://"+dir, 0, "")
+	require.NoError(t, err)
+
+	res, err := s.Download(t.Context(), "GeoIP2-City", "")
+	require.NoError(t, err)
+	require.True(t, res.UpdateAvailable)
+	require.Equal(t, currentMD5, res.MD5)
+	require.Equal(t, "2026-09-15", res.Date)
+	current, err := os.ReadFile(filepath.Join(dir, "GeoIP2-City.mmdb"))
+	require.NoError(t, err)
+	require.Equal(t, current, readAll(t, res))
+
+	res, err = s.Download(t.Context(), "GeoIP2-City", currentMD5)
+	require.NoError(t, err)
+	require.False(t, res.UpdateAvailable)
+	require.Empty(t, readAll(t, res))
+
+	for _, date := range []string{"2026-09-15", "2026-09-13", "2026-09-11"} {
+		res, err = s.DownloadDate(t.Context(), "GeoIP2-City", date)
+		require.NoError(t, err)
+		require.Equal(t, date, res.Date)
+		require.NotEmpty(t, readAll(t, res))
+	}
+
+	_, err = s.DownloadDate(t.Context(), "GeoIP2-City", "2026-09-12")
+	require.ErrorIs(t, err, client.ErrNotFound)
+
+	_, err = s.Download(t.Context(), "GeoIP2-ISP", "")
+	require.ErrorIs(t, err, client.ErrNotFound)
+}
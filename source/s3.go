@@ -0,0 +1,96 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/maxmind/geoipupdate/v8/client"
+	"github.com/maxmind/geoipupdate/v8/internal/s3"
+)
+
+// s3Source is a Source that reads the databases from objects in an S3
+// bucket, laid out as in a directory.
+type s3Source struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func (s *s3Source) Download(
+	ctx context.Context,
+	editionID,
+	md5 string,
+) (client.DownloadResponse, error) {
+	key := s.key(editionID + extension)
+
+	// Checking the metadata first avoids downloading databases that did
+	// not change.
+	head, err := s.client.Head(ctx, s.bucket, key)
+	if err != nil {
+		return client.DownloadResponse{}, s.wrap(editionID, err)
+	}
+	if sum := head.Metadata[s3.MetadataMD5]; sum != "" && sum == md5 {
+		return client.DownloadResponse{Reader: io.NopCloser(strings.NewReader(""))}, nil
+	}
+
+	obj, err := s.client.Get(ctx, s.bucket, key)
+	if err != nil {
+		return client.DownloadResponse{}, s.wrap(editionID, err)
+	}
+	return response(obj, obj.Metadata[s3.MetadataMD5]), nil
+}
+
+// DownloadDate returns the database stored as <edition>_<date>.mmdb, which
+// is the release of that date whatever date it was built on. Otherwise, it
+// returns the current database of the edition if its build-date metadata is
+// date.
+func (s *s3Source) DownloadDate(
+	ctx context.Context,
+	editionID,
+	date string,
+) (client.DownloadResponse, error) {
+	obj, err := s.client.Get(ctx, s.bucket, s.key(editionID+"_"+date+extension))
+	if err != nil && !errors.Is(err, client.ErrNotFound) {
+		return client.DownloadResponse{}, s.wrap(editionID, err)
+	}
+	if err != nil {
+		key := s.key(editionID + extension)
+		head, err := s.client.Head(ctx, s.bucket, key)
+		if err != nil {
+			return client.DownloadResponse{}, s.wrap(editionID, err)
+		}
+		if head.Metadata[s3.MetadataBuildDate] != date {
+			return client.DownloadResponse{}, s.wrap(editionID, fmt.Errorf(
+				"no database released on %s: %w", date, client.ErrNotFound))
+		}
+		obj, err = s.client.Get(ctx, s.bucket, key)
+		if err != nil {
+			return client.DownloadResponse{}, s.wrap(editionID, err)
+		}
+	}
+	res := response(obj, "")
+	res.Date = date
+	return res, nil
+}
+
+func (s *s3Source) key(name string) string {
+	return path.Join(s.prefix, name)
+}
+
+func (s *s3Source) wrap(editionID string, err error) error {
+	return fmt.Errorf("getting database of %s from s3://%s/%s: %w", editionID, s.bucket, s.prefix, err)
+}
+
+func response(obj s3.Object, md5 string) client.DownloadResponse {
+	return client.DownloadResponse{
+		Date:            obj.Metadata[s3.MetadataBuildDate],
+		LastModified:    obj.LastModified,
+		MD5:             md5,
+		Reader:          obj.Body,
+		UpdateAvailable: true,
+	}
+}
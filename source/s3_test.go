@@ -0,0 +1,74 @@
+package source
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/client"
+	"github.com/maxmind/geoipupdate/v8/internal/mmdbtest"
+	"github.com/maxmind/geoipupdate/v8/internal/s3"
+	"github.com/maxmind/geoipupdate/v8/internal/s3test"
+)
+
+func TestS3(t *testing.T) {
+	server := s3test.NewServer(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", s3test.AccessKeyID)
+	t.Setenv("AWS_SECRET_ACCESS_KEY", s3test.SecretAccessKey)
+	t.Setenv("AWS_ENDPOINT_URL_S3", server.URL)
+
+	current := mmdbtest.Build("GeoIP2-City", 1789430400)
+	dated := mmdbtest.Build("GeoIP2-City", 1789430400-4*24*60*60)
+	server.Put("geoip", "prod/GeoIP2-City.mmdb", current, map[string]string{
+		s3.MetadataMD5:       "0123456789abcdef0123456789abcdef",
+		s3.MetadataBuildDate: "2026-09-15",
+	})
+	server.Put("geoip", "prod/GeoIP2-City_2026-09-11.mmdb", dated, nil)
+
+	s, err := New("s3://geoip/prod", 0, "")
+	require.NoError(t, err)
+
+	res, err := s.Download(t.Context(), "GeoIP2-City", "")
+	require.NoError(t, err)
+	require.True(t, res.UpdateAvailable)
+	require.Equal(t, "0123456789abcdef0123456789abcdef", res.MD5)
+	require.Equal(t, "2026-09-15", res.Date)
+	require.Equal(t, current, readAll(t, res))
+
+	res, err = s.Download(t.Context(), "GeoIP2-City", "0123456789abcdef0123456789abcdef")
+	require.NoError(t, err)
+	require.False(t, res.UpdateAvailable)
+
+	res, err = s.DownloadDate(t.Context(), "GeoIP2-City", "2026-09-15")
+	require.NoError(t, err)
+	require.Equal(t, current, readAll(t, res))
+
+	res, err = s.DownloadDate(t.Context(), "GeoIP2-City", "2026-09-11")
+	require.NoError(t, err)
+	require.Equal(t, "2026-09-11", res.Date)
+	require.Equal(t, dated, readAll(t, res))
+
+	_, err = s.DownloadDate(t.Context(), "GeoIP2-City", "2026-09-12")
+	require.ErrorIs(t, err, client.ErrNotFound)
+
+	_, err = s.Download(t.Context(), "GeoIP2-ISP", "")
+	require.ErrorIs(t, err, client.ErrNotFound)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "someone-else")
+	s, err = New("s3://geoip/prod", 0, "")
+	require.NoError(t, err)
+	_, err = s.Download(t.Context(), "GeoIP2-City", "")
+	var httpErr client.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	require.Equal(t, http.StatusForbidden, httpErr.StatusCode)
+}
+
+func readAll(t *testing.T, res client.DownloadResponse) []byte {
+	t.Helper()
+	defer res.Reader.Close()
+	b, err := io.ReadAll(res.Reader)
+	require.NoError(t, err)
+	return b
+}
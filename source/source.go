@@ -0,0 +1,138 @@
+// Package source provides the sources that databases are downloaded from.
+//
+// The MaxMind update server is the default source, but databases can also be
+// read from a directory, e.g., on an NFS share, or from an S3-compatible
+// object store. New picks the source from the scheme of a URL, so that the
+// source can be chosen with the Host setting. Whatever the source, the
+// databases are checked and written the same way.
+package source
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/maxmind/geoipupdate/v8/client"
+	"github.com/maxmind/geoipupdate/v8/internal/s3"
+)
+
+// Source downloads databases. Missing databases are errors that match
+// [client.ErrNotFound].
+type Source interface {
+	// Download returns the latest database of an edition, unless its MD5
+	// is md5.
+	Download(ctx context.Context, editionID, md5 string) (client.DownloadResponse, error)
+	// DownloadDate returns the release of an edition on date, as
+	// YYYY-MM-DD, which may have been built on an earlier date.
+	DownloadDate(ctx context.Context, editionID, date string) (client.DownloadResponse, error)
+}
+
+// Option is an option for configuring the Source created by New.
+type Option func(*options)
+
+type options struct {
+	httpClient      *http.Client
+	logger          *log.Logger
+	resumeDir       string
+	signatureSuffix string
+}
+
+// WithHTTPClient sets the HTTP client used for the update server and object
+// stores. By default, http.DefaultClient is used.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = c
+	}
+}
+
+// WithLogger sets the logger of the update server client. See
+// [client.WithLogger].
+func WithLogger(l *log.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithResumeDirectory sets the directory that interrupted downloads from
+// the update server are kept in, so that they can be resumed. See
+// [client.WithResumeDirectory].
+func WithResumeDirectory(dir string) Option {
+	return func(o *options) {
+		o.resumeDir = dir
+	}
+}
+
+// WithSignatureSuffix sets the suffix that signatures are downloaded from
+// the update server with. See [client.WithSignatureSuffix].
+func WithSignatureSuffix(suffix string) Option {
+	return func(o *options) {
+		o.signatureSuffix = suffix
+	}
+}
+
+// New creates the Source for rawURL, based on its scheme:
+//
+//   - https:// or http:// is an update server, e.g.,
+//     https://updates.maxmind.com, or a mirror, which accountID and
+//     licenseKey authenticate with. This is the default if rawURL is empty.
+//   - file:///path is a directory of <edition>.mmdb files, and of
+//     <edition>_<YYYY-MM-DD>.mmdb files for the releases of past dates.
+//   - s3://bucket/prefix is the same layout as objects under prefix in an
+//     S3 bucket. The MD5 of a database is read from the md5 metadata of its
+//     object. The credentials, region and endpoint are read from the
+//     environment variables that the AWS tools use, e.g.,
+//     AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_REGION and
+//     AWS_ENDPOINT_URL_S3.
+func New(
+	rawURL string,
+	accountID int,
+	licenseKey string,
+	opts ...Option,
+) (Source, error) {
+	o := &options{httpClient: http.DefaultClient}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing source URL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "", "http", "https":
+		clientOpts := []client.Option{client.WithHTTPClient(o.httpClient)}
+		if rawURL != "" {
+			clientOpts = append(clientOpts, client.WithEndpoint(rawURL))
+		}
+		if o.resumeDir != "" {
+			clientOpts = append(clientOpts, client.WithResumeDirectory(o.resumeDir))
+		}
+		if o.logger != nil {
+			clientOpts = append(clientOpts, client.WithLogger(o.logger))
+		}
+		if o.signatureSuffix != "" {
+			clientOpts = append(clientOpts, client.WithSignatureSuffix(o.signatureSuffix))
+		}
+		return client.New(accountID, licenseKey, clientOpts...)
+	case "file":
+		if u.Path == "" {
+			return nil, fmt.Errorf("%s has no directory", rawURL)
+		}
+		return directory{dir: u.Path}, nil
+	case "s3":
+		bucket, prefix, err := s3.ParseURL(u)
+		if err != nil {
+			return nil, err
+		}
+		c, err := s3.NewFromEnv(o.httpClient)
+		if err != nil {
+			return nil, err
+		}
+		return &s3Source{client: c, bucket: bucket, prefix: prefix}, nil
+	default:
+		return nil, fmt.Errorf("unsupported source scheme %q", u.Scheme)
+	}
+}
@@ -0,0 +1,44 @@
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/client"
+	"github.com/maxmind/geoipupdate/v8/internal/s3test"
+)
+
+func TestNew(t *testing.T) {
+	s, err := New("", 42, "license")
+	require.NoError(t, err)
+	require.IsType(t, client.Client{}, s)
+
+	s, err = New("https://mirror.example.com", 42, "license")
+	require.NoError(t, err)
+	require.IsType(t, client.Client{}, s)
+
+	_, err = New("https://mirror.example.com", 0, "")
+	require.EqualError(t, err, "invalid account ID: 0")
+
+	s, err = New("file:///srv/geoip", 0, "")
+	require.NoError(t, err)
+	require.Equal(t, directory{dir: "/srv/geoip"}, s)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	_, err = New("s3://geoip/prod", 0, "")
+	require.EqualError(t, err, "the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables are required")
+
+	t.Setenv("AWS_ACCESS_KEY_ID", s3test.AccessKeyID)
+	t.Setenv("AWS_SECRET_ACCESS_KEY", s3test.SecretAccessKey)
+	s, err = New("s3://geoip/prod/", 0, "")
+	require.NoError(t, err)
+	require.Equal(t, "geoip", s.(*s3Source).bucket)
+	require.Equal(t, "prod", s.(*s3Source).prefix)
+
+	_, err = New("s3:///prod", 0, "")
+	require.EqualError(t, err, "s3:///prod is not an s3://bucket/prefix URL")
+
+	_, err = New("ftp://example.com", 0, "")
+	require.EqualError(t, err, `unsupported source scheme "ftp"`)
+}
@@ -0,0 +1,214 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
+	"github.com/maxmind/geoipupdate/v8/internal/geoipupdate/database"
+)
+
+// BackfillResult describes a release of an edition stored by Backfill.
+type BackfillResult struct {
+	EditionID string
+	// Date is the date the database was released on, as YYYY-MM-DD. It may
+	// have been built on an earlier date.
+	Date string
+	// Path is where the database is stored.
+	Path string
+	// Downloaded is whether the database was downloaded. It is false if it
+	// was already stored.
+	Downloaded bool
+	// Attempts is the number of download attempts made.
+	Attempts int
+}
+
+// Backfill downloads the releases of an edition on the dates from through to
+// and stores them in Config.DatabaseDirectory as
+// <edition>_<YYYY-MM-DD>.mmdb, with the build of each recorded in
+// <edition>_<YYYY-MM-DD>.mmdb.json, as a database may be released on a later
+// date than it was built on. The current database of the edition is left
+// alone. As there is no list of releases, every date is tried, and those
+// without a release are skipped. Releases that are already stored are not
+// downloaded again.
+//
+// Up to Config.Parallelism dates are downloaded at the same time and each is
+// retried for Config.RetryFor. The databases are checked like those of Run,
+// except that their MD5 is not known in advance. Backfill stops at the first
+// failure.
+//
+// The results are those of the dates with a release, in order, including
+// when there is an error. Backfill requires Config.DatabaseDirectory and a
+// DatedClient.
+func (u *Updater) Backfill(
+	ctx context.Context,
+	editionID string,
+	from,
+	to time.Time,
+) ([]BackfillResult, error) {
+	c, ok := u.client.(DatedClient)
+	if !ok {
+		return nil, errors.New("the client cannot download editions by date")
+	}
+	if u.config.DatabaseDirectory == "" || database.IsRemoteURL(u.config.DatabaseDirectory) {
+		return nil, errors.New("backfilling requires a local database directory")
+	}
+
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	if to.Before(from) {
+		return nil, fmt.Errorf(
+			"the end date %s is before the start date %s",
+			to.Format(time.DateOnly),
+			from.Format(time.DateOnly),
+		)
+	}
+
+	w, err := database.NewLocalFileWriter(
+		u.config.DatabaseDirectory,
+		u.config.PreserveFileTimes,
+		u.logger != nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating writer: %w", err)
+	}
+
+	var dates []string
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		dates = append(dates, d.Format(time.DateOnly))
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(u.config.Parallelism)
+
+	// Each goroutine only sets its own element.
+	results := make([]*BackfillResult, len(dates))
+	for i, date := range dates {
+		g.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				return fmt.Errorf("stop backfilling on the first error: %w", err)
+			}
+
+			result := &BackfillResult{
+				EditionID: editionID,
+				Date:      date,
+				Path:      w.DatedPath(editionID, date),
+			}
+			found, err := u.backfillDate(ctx, c, w, result)
+			if found {
+				results[i] = result
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", date, err)
+			}
+			return nil
+		})
+	}
+	err = g.Wait()
+
+	var stored []BackfillResult
+	for _, result := range results {
+		if result != nil {
+			stored = append(stored, *result)
+		}
+	}
+
+	if err != nil {
+		return stored, fmt.Errorf("backfilling %s: %w", editionID, err)
+	}
+	return stored, nil
+}
+
+// backfillDate stores the release of result.Date with retries. It returns
+// whether there is such a release.
+func (u *Updater) backfillDate(
+	ctx context.Context,
+	c DatedClient,
+	w *database.LocalFileWriter,
+	result *BackfillResult,
+) (bool, error) {
+	editionID := result.EditionID
+	date := result.Date
+
+	if _, err := os.Stat(result.Path); err == nil {
+		return true, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false, fmt.Errorf("checking for %s: %w", result.Path, err)
+	}
+
+	opts := []backoff.RetryOption{
+		backoff.WithBackOff(backoff.NewExponentialBackOff()),
+		backoff.WithNotify(func(err error, d time.Duration) {
+			u.logf("Couldn't download %s released on %s, retrying in %v: %v", editionID, date, d, err)
+		}),
+	}
+
+	if u.config.RetryFor == 0 {
+		opts = append(opts, backoff.WithMaxTries(1))
+	} else {
+		opts = append(opts, backoff.WithMaxElapsedTime(u.config.RetryFor))
+	}
+
+	found, err := backoff.Retry(
+		ctx,
+		func() (bool, error) {
+			result.Attempts++
+
+			res, err := c.DownloadDate(ctx, editionID, date)
+			if err != nil {
+				if errors.Is(err, internal.ErrNotFound) {
+					// There was no release on that date.
+					return false, nil
+				}
+				if !internal.IsRetryableError(err) {
+					return false, backoff.Permanent(err)
+				}
+
+				return false, err
+			}
+			defer res.Reader.Close()
+
+			reader := res.Reader
+			if u.signatureKey != nil {
+				verifier, err := u.signatureVerifier(ctx, editionID, editionID+"_"+date, date)
+				if err != nil {
+					if u.config.SignatureDirectory != "" || !internal.IsRetryableError(err) {
+						return false, backoff.Permanent(err)
+					}
+
+					return false, err
+				}
+				reader = &verifyingReader{ReadCloser: reader, verifier: verifier}
+			}
+
+			if err := w.WriteDated(editionID, date, reader, res.LastModified); err != nil {
+				err = WriteError{Err: err}
+				if !internal.IsRetryableError(err) {
+					return false, backoff.Permanent(err)
+				}
+
+				return false, err
+			}
+
+			result.Downloaded = true
+			u.logf("Stored %s released on %s at %s", editionID, date, result.Path)
+			return true, nil
+		},
+		opts...,
+	)
+	if err != nil {
+		// Retry returns errors that are still retryable once it gives up.
+		if ctx.Err() == nil && internal.IsRetryableError(err) {
+			err = RetryError{Attempts: result.Attempts, Err: err}
+		}
+		return false, err
+	}
+
+	return found, nil
+}
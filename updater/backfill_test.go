@@ -0,0 +1,211 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/client"
+	"github.com/maxmind/geoipupdate/v8/internal"
+	"github.com/maxmind/geoipupdate/v8/internal/mmdbtest"
+)
+
+func TestBackfill(t *testing.T) {
+	dir := t.TempDir()
+
+	// Releases on 2026-09-15 and 2026-09-18, of which the first is already
+	// stored.
+	c := &releasesClient{
+		releases: map[string][]byte{
+			"2026-09-15": mmdbtest.Build("GeoIP2-City", 1789430400),
+			"2026-09-18": mmdbtest.Build("GeoIP2-City", 1789430400+3*24*60*60),
+		},
+	}
+	stored := filepath.Join(dir, "GeoIP2-City_2026-09-15.mmdb")
+	require.NoError(t, os.WriteFile(stored, c.releases["2026-09-15"], 0o600))
+
+	u, err := New(
+		Config{DatabaseDirectory: dir, Parallelism: 3},
+		WithClient(c),
+	)
+	require.NoError(t, err)
+
+	from := time.Date(2026, 9, 14, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 9, 19, 0, 0, 0, 0, time.UTC)
+	results, err := u.Backfill(t.Context(), "GeoIP2-City", from, to)
+	require.NoError(t, err)
+	require.Equal(t, []BackfillResult{
+		{
+			EditionID: "GeoIP2-City",
+			Date:      "2026-09-15",
+			Path:      stored,
+		},
+		{
+			EditionID:  "GeoIP2-City",
+			Date:       "2026-09-18",
+			Path:       filepath.Join(dir, "GeoIP2-City_2026-09-18.mmdb"),
+			Downloaded: true,
+			Attempts:   1,
+		},
+	}, results)
+
+	got, err := os.ReadFile(results[1].Path)
+	require.NoError(t, err)
+	require.Equal(t, c.releases["2026-09-18"], got)
+
+	// Every date but the stored one was tried.
+	dates := c.requested()
+	slices.Sort(dates)
+	require.Equal(t, []string{
+		"2026-09-14",
+		"2026-09-16",
+		"2026-09-17",
+		"2026-09-18",
+		"2026-09-19",
+	}, dates)
+
+	// The current database is left alone.
+	_, err = os.Stat(filepath.Join(dir, "GeoIP2-City.mmdb"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = u.Backfill(t.Context(), "GeoIP2-City", to, from)
+	require.EqualError(t, err, "the end date 2026-09-14 is before the start date 2026-09-19")
+}
+
+func TestBackfillBuiltEarlier(t *testing.T) {
+	dir := t.TempDir()
+
+	// The release of 2026-09-15 was built the day before.
+	c := &releasesClient{
+		releases: map[string][]byte{
+			"2026-09-15": mmdbtest.Build("GeoIP2-City", 1789430400-24*60*60),
+		},
+	}
+
+	u, err := New(Config{DatabaseDirectory: dir}, WithClient(c))
+	require.NoError(t, err)
+
+	day := time.Date(2026, 9, 15, 0, 0, 0, 0, time.UTC)
+	results, err := u.Backfill(t.Context(), "GeoIP2-City", day, day)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	// It is stored under its release date, with its build next to it.
+	stored := filepath.Join(dir, "GeoIP2-City_2026-09-15.mmdb")
+	require.Equal(t, stored, results[0].Path)
+	got, err := os.ReadFile(stored)
+	require.NoError(t, err)
+	require.Equal(t, c.releases["2026-09-15"], got)
+
+	build, err := os.ReadFile(stored + ".json")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"build_epoch":1789344000,"build_date":"2026-09-14"}`, string(build))
+}
+
+func TestBackfillErrors(t *testing.T) {
+	date := time.Date(2026, 9, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		config   Config
+		client   Client
+		err      string
+		errIs    error
+		retryErr bool
+	}{
+		{
+			name:   "client without dates",
+			config: Config{DatabaseDirectory: t.TempDir()},
+			client: mockClient{},
+			err:    "the client cannot download editions by date",
+		},
+		{
+			name:   "invalid database",
+			config: Config{DatabaseDirectory: t.TempDir()},
+			client: &releasesClient{
+				releases: map[string][]byte{"2026-09-15": []byte("not a database")},
+			},
+			errIs: ErrInvalidDatabase,
+		},
+		{
+			name: "server error",
+			config: Config{
+				DatabaseDirectory: t.TempDir(),
+				RetryFor:          time.Millisecond,
+			},
+			client: &releasesClient{
+				err: internal.HTTPError{StatusCode: http.StatusInternalServerError},
+			},
+			retryErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			u, err := New(test.config, WithClient(test.client))
+			require.NoError(t, err)
+
+			_, err = u.Backfill(t.Context(), "GeoIP2-City", date, date)
+			if test.retryErr {
+				var retryErr RetryError
+				require.ErrorAs(t, err, &retryErr)
+				return
+			}
+			if test.errIs != nil {
+				require.ErrorIs(t, err, test.errIs)
+				return
+			}
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
+
+// releasesClient serves the databases in releases by date and responds
+// with a 404 for the other dates, or always fails with err if it is set.
+type releasesClient struct {
+	mockClient
+
+	releases map[string][]byte
+	err      error
+
+	mu    sync.Mutex
+	dates []string
+}
+
+func (c *releasesClient) DownloadDate(
+	_ context.Context,
+	_,
+	date string,
+) (client.DownloadResponse, error) {
+	c.mu.Lock()
+	c.dates = append(c.dates, date)
+	c.mu.Unlock()
+
+	if c.err != nil {
+		return client.DownloadResponse{}, c.err
+	}
+	db, ok := c.releases[date]
+	if !ok {
+		return client.DownloadResponse{}, internal.HTTPError{StatusCode: http.StatusNotFound}
+	}
+	return client.DownloadResponse{
+		Date:            date,
+		Reader:          io.NopCloser(bytes.NewReader(db)),
+		UpdateAvailable: true,
+	}, nil
+}
+
+func (c *releasesClient) requested() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.dates)
+}
@@ -0,0 +1,58 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/maxmind/geoipupdate/v8/internal"
+)
+
+// ErrLockHeld is wrapped by the error returned when Config.LockFile is held
+// by another process.
+var ErrLockHeld = internal.ErrLockHeld
+
+// ErrInvalidDatabase is wrapped by the error returned when the default Writer
+// finds that a downloaded database is not a well-formed MMDB of its edition.
+// The database is not installed.
+var ErrInvalidDatabase = internal.ErrInvalidDatabase
+
+// ErrDowngradeRefused is wrapped by the error the default Writer returns
+// when a downloaded database was built before the current one. Writers set
+// with WithWriter may wrap it too. The edition's Result then has the status
+// StatusDowngradeRefused instead of failing.
+var ErrDowngradeRefused = internal.ErrDowngradeRefused
+
+// errAtomicGroupFailed is the error of the editions of the atomic group that
+// were downloaded but not published because another one failed.
+var errAtomicGroupFailed = errors.New("not published because another edition of the atomic group failed")
+
+// WriteError is returned when a downloaded database could not be written.
+type WriteError struct {
+	Err error
+}
+
+func (e WriteError) Error() string {
+	return e.Err.Error()
+}
+
+func (e WriteError) Unwrap() error {
+	return e.Err
+}
+
+// RetryError is returned when an edition could still not be downloaded after
+// retrying for Config.RetryFor, e.g., because the network was unavailable.
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (e RetryError) Error() string {
+	if e.Attempts == 1 {
+		return fmt.Sprintf("giving up after 1 attempt: %s", e.Err)
+	}
+	return fmt.Sprintf("giving up after %d attempts: %s", e.Attempts, e.Err)
+}
+
+func (e RetryError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,217 @@
+package updater
+
+import (
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// progressInterval is how many bytes are read between DownloadProgress
+// events.
+const progressInterval = 1 << 20
+
+// Event is an event published while updating an edition. It is one of
+// CheckStarted, UpdateAvailable, DownloadProgress, DatabaseReplaced,
+// UpToDate, DowngradeRefused and EditionFailed.
+//
+// Every edition starts with a CheckStarted event and ends with exactly one
+// DatabaseReplaced, UpToDate, DowngradeRefused or EditionFailed event. When downloads are
+// retried, UpdateAvailable and DownloadProgress events may be published again
+// for the new attempt.
+type Event interface {
+	// Edition returns the edition ID that the event is about.
+	Edition() string
+
+	event()
+}
+
+// CheckStarted is published before checking for an update to an edition.
+type CheckStarted struct {
+	EditionID string
+	// OldHash is the MD5 of the current database.
+	OldHash string
+}
+
+// UpdateAvailable is published when a new database is about to be
+// downloaded.
+type UpdateAvailable struct {
+	EditionID string
+	OldHash   string
+	NewHash   string
+	// ModifiedAt is when the new database was built.
+	ModifiedAt time.Time
+}
+
+// DownloadProgress is published periodically while a database is being
+// downloaded.
+type DownloadProgress struct {
+	EditionID string
+	// BytesTransferred is the number of bytes of the database downloaded so
+	// far in this attempt.
+	BytesTransferred int64
+}
+
+// DatabaseReplaced is published once a new database has been written.
+type DatabaseReplaced struct {
+	Result
+	// BytesTransferred is the size of the new database.
+	BytesTransferred int64
+	// Path is where the database was written, if the Writer has a
+	// Path(editionID string) string method.
+	Path string
+}
+
+// UpToDate is published when the database was already current.
+type UpToDate struct {
+	Result
+	// Path is where the database is, if the Writer has a
+	// Path(editionID string) string method.
+	Path string
+}
+
+// DowngradeRefused is published when the downloaded database was built
+// before the current one and was not installed. Its Result's Status is
+// StatusDowngradeRefused.
+type DowngradeRefused struct {
+	Result
+	// Path is where the current database is, if the Writer has a
+	// Path(editionID string) string method.
+	Path string
+}
+
+// EditionFailed is published when an edition could not be updated. Its
+// Result's Status is StatusFailed.
+type EditionFailed struct {
+	Result
+	// BytesTransferred is the number of bytes of the database downloaded in
+	// the last attempt.
+	BytesTransferred int64
+	Err              error
+}
+
+// Edition returns the edition ID.
+func (e CheckStarted) Edition() string { return e.EditionID }
+
+// Edition returns the edition ID.
+func (e UpdateAvailable) Edition() string { return e.EditionID }
+
+// Edition returns the edition ID.
+func (e DownloadProgress) Edition() string { return e.EditionID }
+
+// Edition returns the edition ID.
+func (e DatabaseReplaced) Edition() string { return e.EditionID }
+
+// Edition returns the edition ID.
+func (e UpToDate) Edition() string { return e.EditionID }
+
+// Edition returns the edition ID.
+func (e DowngradeRefused) Edition() string { return e.EditionID }
+
+// Edition returns the edition ID.
+func (e EditionFailed) Edition() string { return e.EditionID }
+
+func (CheckStarted) event()     {}
+func (UpdateAvailable) event()  {}
+func (DownloadProgress) event() {}
+func (DatabaseReplaced) event() {}
+func (UpToDate) event()         {}
+func (DowngradeRefused) event() {}
+func (EditionFailed) event()    {}
+
+// WithEventHandler adds a function that is called with every event. When
+// Parallelism is greater than 1, it may be called concurrently for different
+// editions. It is called synchronously, so it should return quickly.
+func WithEventHandler(handler func(Event)) Option {
+	return func(u *Updater) {
+		u.handlers = append(u.handlers, handler)
+	}
+}
+
+// WithEvents sends every event to ch. Sends block, so ch must be received
+// from until Run returns.
+func WithEvents(ch chan<- Event) Option {
+	return WithEventHandler(func(e Event) {
+		ch <- e
+	})
+}
+
+// publish calls the event handlers.
+func (u *Updater) publish(e Event) {
+	for _, handler := range u.handlers {
+		handler(e)
+	}
+}
+
+// logEvents returns an event handler that logs to logger.
+func logEvents(logger *log.Logger) func(Event) {
+	return func(e Event) {
+		switch e := e.(type) {
+		case UpdateAvailable:
+			logger.Printf("Updates available for %s", e.EditionID)
+		case UpToDate:
+			logger.Printf("No new updates available for %s", e.EditionID)
+			logger.Printf("Database %s up to date", e.EditionID)
+		case DowngradeRefused:
+			logger.Printf("Not installing %s: %s", e.EditionID, e.Error)
+		}
+	}
+}
+
+// pathOf returns the path w stores the edition at, if it has one.
+func pathOf(w Writer, editionID string) string {
+	if p, ok := w.(interface{ Path(string) string }); ok {
+		return p.Path(editionID)
+	}
+	return ""
+}
+
+// destinationResultsOf returns the outcome for each destination of the
+// last write of the edition, if w has a DestinationResults(editionID string)
+// []DestinationResult method.
+func destinationResultsOf(w Writer, editionID string) []DestinationResult {
+	if d, ok := w.(interface {
+		DestinationResults(string) []DestinationResult
+	}); ok {
+		return d.DestinationResults(editionID)
+	}
+	return nil
+}
+
+// progressReader counts the bytes read and publishes DownloadProgress
+// events.
+type progressReader struct {
+	io.ReadCloser
+
+	editionID string
+	publish   func(Event)
+
+	mu        sync.Mutex
+	n         int64
+	published int64
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+
+	r.mu.Lock()
+	r.n += int64(n)
+	total := r.n
+	due := total-r.published >= progressInterval || (err == io.EOF && total > r.published)
+	if due {
+		r.published = total
+	}
+	r.mu.Unlock()
+
+	if due {
+		r.publish(DownloadProgress{EditionID: r.editionID, BytesTransferred: total})
+	}
+	return n, err
+}
+
+// bytesRead returns the number of bytes read so far.
+func (r *progressReader) bytesRead() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.n
+}
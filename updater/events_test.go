@@ -0,0 +1,137 @@
+package updater
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/client"
+	"github.com/maxmind/geoipupdate/v8/internal/mmdbtest"
+)
+
+func TestEvents(t *testing.T) {
+	// Large enough for two progress events.
+	content := string(mmdbtest.Database{
+		DatabaseType: "GeoIP2-City",
+		RecordSize:   24,
+		Nodes:        [][2]uint32{{1, 1}},
+		Data:         make([]byte, progressInterval),
+	}.Bytes())
+	sum := md5.Sum([]byte(content))
+	newHash := hex.EncodeToString(sum[:])
+	modifiedAt := time.Date(2026, 9, 15, 12, 0, 0, 0, time.UTC)
+	unauthorized := client.HTTPError{StatusCode: http.StatusUnauthorized}
+
+	c := mockClient{
+		responses: map[string]client.DownloadResponse{
+			"GeoIP2-City": {
+				LastModified:    modifiedAt,
+				MD5:             newHash,
+				Reader:          io.NopCloser(strings.NewReader(content)),
+				UpdateAvailable: true,
+			},
+			"GeoIP2-Country": {Reader: io.NopCloser(strings.NewReader(""))},
+		},
+		errs: map[string]error{"GeoIP2-ISP": unauthorized},
+	}
+
+	dir := t.TempDir()
+
+	var mu sync.Mutex
+	events := map[string][]Event{}
+	u, err := New(
+		Config{
+			EditionIDs:        []string{"GeoIP2-City", "GeoIP2-Country", "GeoIP2-ISP"},
+			DatabaseDirectory: dir,
+			ContinueOnError:   true,
+			Parallelism:       3,
+		},
+		WithClient(c),
+		WithEventHandler(func(e Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			events[e.Edition()] = append(events[e.Edition()], e)
+		}),
+	)
+	require.NoError(t, err)
+
+	_, err = u.Run(t.Context())
+	require.ErrorIs(t, err, unauthorized)
+
+	cityPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+	city := events["GeoIP2-City"]
+	require.Len(t, city, 5)
+	require.Equal(t, CheckStarted{EditionID: "GeoIP2-City", OldHash: ZeroMD5}, city[0])
+	require.Equal(t, UpdateAvailable{
+		EditionID:  "GeoIP2-City",
+		OldHash:    ZeroMD5,
+		NewHash:    newHash,
+		ModifiedAt: modifiedAt,
+	}, city[1])
+	require.Equal(t, DownloadProgress{
+		EditionID:        "GeoIP2-City",
+		BytesTransferred: progressInterval,
+	}, city[2])
+	require.Equal(t, DownloadProgress{
+		EditionID:        "GeoIP2-City",
+		BytesTransferred: int64(len(content)),
+	}, city[3])
+	replaced, ok := city[4].(DatabaseReplaced)
+	require.True(t, ok)
+	require.Equal(t, StatusUpdated, replaced.Status)
+	require.Equal(t, newHash, replaced.NewHash)
+	require.Equal(t, int64(len(content)), replaced.BytesTransferred)
+	require.Equal(t, cityPath, replaced.Path)
+
+	country := events["GeoIP2-Country"]
+	require.Len(t, country, 2)
+	require.IsType(t, CheckStarted{}, country[0])
+	upToDate, ok := country[1].(UpToDate)
+	require.True(t, ok)
+	require.Equal(t, StatusUpToDate, upToDate.Status)
+	require.Equal(t, filepath.Join(dir, "GeoIP2-Country.mmdb"), upToDate.Path)
+
+	isp := events["GeoIP2-ISP"]
+	require.Len(t, isp, 2)
+	require.IsType(t, CheckStarted{}, isp[0])
+	failed, ok := isp[1].(EditionFailed)
+	require.True(t, ok)
+	require.Equal(t, StatusFailed, failed.Status)
+	require.Equal(t, 1, failed.Attempts)
+	require.ErrorIs(t, failed.Err, unauthorized)
+}
+
+func TestWithEvents(t *testing.T) {
+	ch := make(chan Event, 10)
+	u, err := New(
+		Config{EditionIDs: []string{"GeoIP2-City"}},
+		WithClient(mockClient{
+			responses: map[string]client.DownloadResponse{
+				"GeoIP2-City": {Reader: io.NopCloser(strings.NewReader(""))},
+			},
+		}),
+		WithWriter(&mockWriter{}),
+		WithEvents(ch),
+	)
+	require.NoError(t, err)
+
+	_, err = u.Run(t.Context())
+	require.NoError(t, err)
+	close(ch)
+
+	var got []Event
+	for e := range ch {
+		got = append(got, e)
+	}
+	require.Len(t, got, 2)
+	require.IsType(t, CheckStarted{}, got[0])
+	require.IsType(t, UpToDate{}, got[1])
+}
@@ -0,0 +1,171 @@
+package updater
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/maxmind/geoipupdate/v8/internal/geoipupdate/database"
+)
+
+// pinnedFile is the file in Config.DatabaseDirectory that records the
+// releases installed for pinned editions and the editions that are held.
+const pinnedFile = ".geoipupdate-pinned.json"
+
+// pinnedRelease is the database installed for the date an edition is pinned
+// to or, if Held is set, the database an edition is held at.
+type pinnedRelease struct {
+	Date string `json:"date,omitempty"`
+	MD5  string `json:"md5"`
+	Held bool   `json:"held,omitempty"`
+}
+
+// pinnedReleases records the releases installed for pinned editions. A
+// database may be released on a different date than it was built on, so
+// its build date does not tell whether the release of the pinned date is
+// installed. The record is kept in memory and, if path is set, on disk.
+type pinnedReleases struct {
+	path string
+
+	mu       sync.Mutex
+	releases map[string]pinnedRelease
+}
+
+// loadPinnedReleases reads the record at path, if there is one. path may be
+// empty, in which case the record is only kept in memory.
+func loadPinnedReleases(path string) (*pinnedReleases, error) {
+	p := &pinnedReleases{
+		path:     path,
+		releases: map[string]pinnedRelease{},
+	}
+	if path == "" {
+		return p, nil
+	}
+
+	//nolint:gosec // the path is built from the database directory.
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if err := json.Unmarshal(b, &p.releases); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// installed returns whether the database with md5 was installed as the
+// release of an edition on date.
+func (p *pinnedReleases) installed(editionID, date, md5 string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.releases[editionID] == pinnedRelease{Date: date, MD5: md5}
+}
+
+// record records that the database with md5 was installed as the release of
+// an edition on date.
+func (p *pinnedReleases) record(editionID, date, md5 string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.releases[editionID] = pinnedRelease{Date: date, MD5: md5}
+	return p.save()
+}
+
+// held returns whether an edition is held.
+func (p *pinnedReleases) held(editionID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.releases[editionID].Held
+}
+
+// hold records that an edition is held at the database with md5.
+func (p *pinnedReleases) hold(editionID, md5 string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.releases[editionID] = pinnedRelease{MD5: md5, Held: true}
+	return p.save()
+}
+
+// unhold clears the hold of an edition. It returns whether it was held.
+func (p *pinnedReleases) unhold(editionID string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.releases[editionID].Held {
+		return false, nil
+	}
+	delete(p.releases, editionID)
+	return true, p.save()
+}
+
+// save writes the record to disk, if it is kept there. p.mu must be held.
+func (p *pinnedReleases) save() error {
+	if p.path == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(p.releases)
+	if err != nil {
+		return fmt.Errorf("encoding pinned releases: %w", err)
+	}
+	tmp := p.path + ".temporary"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return fmt.Errorf("writing %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, p.path); err != nil {
+		return fmt.Errorf("renaming %s: %w", tmp, err)
+	}
+	return nil
+}
+
+// Hold records that the database of an edition in dir, a local database
+// directory, is held at the database with md5, e.g., after it was rolled
+// back. Run then leaves the edition as it is, whatever its configuration,
+// until Unhold is called. Hold must be called while holding the lock file.
+func Hold(dir, editionID, md5 string) error {
+	p, err := loadHolds(dir)
+	if err != nil {
+		return err
+	}
+	return p.hold(editionID, md5)
+}
+
+// Unhold clears the hold of an edition in dir recorded by Hold, so that Run
+// updates it again. It returns whether the edition was held. Unhold must be
+// called while holding the lock file.
+func Unhold(dir, editionID string) (bool, error) {
+	p, err := loadHolds(dir)
+	if err != nil {
+		return false, err
+	}
+	return p.unhold(editionID)
+}
+
+// loadHolds loads the record of dir for Hold and Unhold.
+func loadHolds(dir string) (*pinnedReleases, error) {
+	path := pinnedPath(dir)
+	if path == "" {
+		return nil, errors.New("holding editions requires a local database directory")
+	}
+	p, err := loadPinnedReleases(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading held editions: %w", err)
+	}
+	return p, nil
+}
+
+// pinnedPath returns where the record of the pinned releases is kept, which
+// is only on disk if the database directory is a local directory.
+func pinnedPath(dir string) string {
+	if dir == "" || database.IsRemoteURL(dir) {
+		return ""
+	}
+	return filepath.Join(dir, pinnedFile)
+}
@@ -0,0 +1,38 @@
+package updater
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/maxmind/geoipupdate/v8/internal/minisign"
+)
+
+// signatureExtension is the extension of the signature files in
+// Config.SignatureDirectory.
+const signatureExtension = ".mmdb.minisig"
+
+// ErrInvalidSignature is wrapped by the error returned when the signature of
+// a database does not verify. The database is not installed.
+var ErrInvalidSignature = minisign.ErrInvalidSignature
+
+// verifyingReader verifies the signature of the database read through it.
+// Rather than io.EOF, it returns an error if the signature does not match,
+// so that the Writer discards the database instead of installing it.
+type verifyingReader struct {
+	io.ReadCloser
+
+	verifier *minisign.Verifier
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	//nolint:errcheck // Verifier.Write never fails.
+	_, _ = r.verifier.Write(p[:n])
+
+	if err == io.EOF {
+		if verifyErr := r.verifier.Verify(); verifyErr != nil {
+			return n, fmt.Errorf("verifying signature: %w", verifyErr)
+		}
+	}
+	return n, err
+}
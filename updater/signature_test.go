@@ -0,0 +1,195 @@
+package updater
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/internal/minisigntest"
+	"github.com/maxmind/geoipupdate/v8/internal/mmdbtest"
+)
+
+func TestSignatures(t *testing.T) {
+	dbContent := string(mmdbtest.Build("GeoIP2-City", 2))
+	oldContent := string(mmdbtest.Build("GeoIP2-City", 1))
+	keyID := [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, otherPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	validSig := minisigntest.Sign(private, keyID, []byte(dbContent), "GeoIP2-City 2026-09-15")
+
+	tests := []struct {
+		description string
+		// serverSig is the signature the server sends. It sends a 404 if it
+		// is nil.
+		serverSig []byte
+		// dirSig is the signature in the signature directory. No directory
+		// is configured if it is nil.
+		dirSig []byte
+		err    string
+	}{
+		{
+			description: "signature from the server",
+			serverSig:   validSig,
+		},
+		{
+			description: "signature from the signature directory",
+			dirSig:      validSig,
+		},
+		{
+			description: "signature by another key",
+			serverSig:   minisigntest.Sign(otherPrivate, keyID, []byte(dbContent), "GeoIP2-City 2026-09-15"),
+			err:         "invalid signature: the signature does not match the data",
+		},
+		{
+			description: "signature of another database",
+			dirSig:      minisigntest.Sign(private, keyID, []byte(oldContent), "GeoIP2-City 2026-09-08"),
+			err:         "invalid signature: the signature does not match the data",
+		},
+		{
+			description: "no signature on the server",
+			err:         "received HTTP status code: 404",
+		},
+		{
+			description: "no signature in the signature directory",
+			dirSig:      []byte{},
+			err:         "no such file or directory",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			server := signatureServer(t, dbContent, test.serverSig)
+			defer server.Close()
+
+			dir := t.TempDir()
+			dbPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+			require.NoError(t, os.WriteFile(dbPath, []byte(oldContent), 0o600))
+
+			config := Config{
+				AccountID:          1,
+				LicenseKey:         "key",
+				URL:                server.URL,
+				EditionIDs:         []string{"GeoIP2-City"},
+				DatabaseDirectory:  dir,
+				RetryFor:           time.Minute,
+				ContinueOnError:    true,
+				SignaturePublicKey: minisigntest.EncodePublicKey(public, keyID),
+			}
+			if test.dirSig == nil {
+				config.SignatureSuffix = "mmdb.minisig"
+			} else {
+				config.SignatureDirectory = t.TempDir()
+				if len(test.dirSig) > 0 {
+					sigPath := filepath.Join(config.SignatureDirectory, "GeoIP2-City.mmdb.minisig")
+					require.NoError(t, os.WriteFile(sigPath, test.dirSig, 0o600))
+				}
+			}
+
+			u, err := New(config)
+			require.NoError(t, err)
+
+			results, err := u.Run(t.Context())
+			require.Len(t, results, 1)
+			// Signature errors are not retried.
+			require.Equal(t, 1, results[0].Attempts)
+
+			got, readErr := os.ReadFile(dbPath)
+			require.NoError(t, readErr)
+
+			if test.err == "" {
+				require.NoError(t, err)
+				require.Equal(t, StatusUpdated, results[0].Status)
+				require.Equal(t, dbContent, string(got))
+				return
+			}
+
+			require.ErrorContains(t, err, test.err)
+			if strings.HasPrefix(test.err, "invalid signature") {
+				require.ErrorIs(t, err, ErrInvalidSignature)
+			}
+			require.Equal(t, StatusFailed, results[0].Status)
+			require.Equal(t, oldContent, string(got), "the old database is untouched")
+
+			_, err = os.Stat(dbPath + ".temporary")
+			require.ErrorIs(t, err, os.ErrNotExist)
+		})
+	}
+}
+
+func TestNewSignatureClient(t *testing.T) {
+	config := Config{
+		DatabaseDirectory:  t.TempDir(),
+		SignaturePublicKey: "RWQBAgMEBQYHCAABAgMEBQYHCAkKCwwNDg8QERITFBUWFxgZGhscHR4f",
+	}
+
+	_, err := New(config, WithClient(mockClient{}))
+	require.EqualError(t, err, "the client cannot download signatures, a signature directory is required")
+
+	config.SignatureDirectory = t.TempDir()
+	_, err = New(config, WithClient(mockClient{}))
+	require.NoError(t, err)
+
+	config.SignaturePublicKey = "RWQBAgMEBQYHCAAB"
+	_, err = New(config, WithClient(mockClient{}))
+	require.EqualError(t, err, "parsing signature public key: not a minisign public key")
+}
+
+// signatureServer serves a GeoIP2-City database with content dbContent and
+// the signature sig.
+func signatureServer(t *testing.T, dbContent string, sig []byte) *httptest.Server {
+	t.Helper()
+
+	sum := md5.Sum([]byte(dbContent))
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/geoip/updates/metadata" {
+			_, err := w.Write([]byte(`{"databases":[{"edition_id":"GeoIP2-City",` +
+				`"md5":"` + hex.EncodeToString(sum[:]) + `","date":"2026-09-15"}]}`))
+			assert.NoError(t, err)
+			return
+		}
+
+		assert.Equal(t, "20260915", r.URL.Query().Get("date"))
+
+		if r.URL.Query().Get("suffix") == "mmdb.minisig" {
+			if sig == nil {
+				http.NotFound(w, r)
+				return
+			}
+			_, err := w.Write(sig)
+			assert.NoError(t, err)
+			return
+		}
+
+		w.Header().Set("Last-Modified", "Tue, 15 Sep 2026 12:00:00 GMT")
+		gw := gzip.NewWriter(w)
+		tw := tar.NewWriter(gw)
+		err := tw.WriteHeader(&tar.Header{
+			Name: "GeoIP2-City.mmdb",
+			Mode: 0o600,
+			Size: int64(len(dbContent)),
+		})
+		assert.NoError(t, err)
+		_, err = tw.Write([]byte(dbContent))
+		assert.NoError(t, err)
+		assert.NoError(t, tw.Close())
+		assert.NoError(t, gw.Close())
+	}))
+}
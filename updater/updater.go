@@ -0,0 +1,836 @@
+// Package updater downloads and updates GeoIP and GeoLite MMDB databases. It
+// is the library that the geoipupdate program is built on and can be used to
+// run updates from within other programs.
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/maxmind/geoipupdate/v8/client"
+	"github.com/maxmind/geoipupdate/v8/internal"
+	"github.com/maxmind/geoipupdate/v8/internal/geoipupdate/database"
+	"github.com/maxmind/geoipupdate/v8/internal/minisign"
+	"github.com/maxmind/geoipupdate/v8/source"
+)
+
+// Client downloads editions. [client.Client] implements it.
+type Client interface {
+	Download(ctx context.Context, editionID, md5 string) (client.DownloadResponse, error)
+}
+
+// SignatureClient is a Client that can also download the detached
+// signatures of databases. [client.Client] implements it.
+type SignatureClient interface {
+	Client
+	DownloadSignature(ctx context.Context, editionID, date string) ([]byte, error)
+}
+
+// DatedClient is a Client that can also download the database of an edition
+// built on a given date. [client.Client] implements it.
+type DatedClient interface {
+	Client
+	DownloadDate(ctx context.Context, editionID, date string) (client.DownloadResponse, error)
+}
+
+// Writer stores databases. The default Writer stores them in
+// Config.DatabaseDirectory.
+type Writer interface {
+	// Write stores the database read from reader. It must verify that the
+	// database matches newMD5 before replacing the current database, and
+	// must close reader. newMD5 is empty for editions pinned to a date, as
+	// it is not known in advance.
+	Write(editionID string, reader io.ReadCloser, newMD5 string, lastModified time.Time) error
+	// GetHash returns the MD5 of the current database, or ZeroMD5 if there
+	// is none.
+	GetHash(editionID string) (string, error)
+}
+
+// ZeroMD5 is the hash of a database that does not exist yet.
+const ZeroMD5 = database.ZeroMD5
+
+// Result describes the outcome of updating an edition.
+type Result = database.ReadResult
+
+// DestinationResult is the outcome of writing a database to one of
+// Config.Destinations, or Config.DatabaseDirectory. Its Status is one of the
+// Status constants.
+type DestinationResult = database.DestinationResult
+
+// These are the values of Result.Status.
+const (
+	// StatusUpdated means that a new database was installed.
+	StatusUpdated = database.StatusUpdated
+	// StatusUpToDate means that the database was already current.
+	StatusUpToDate = database.StatusUpToDate
+	// StatusFailed means that the edition could not be updated. The error is
+	// in Result.Error.
+	StatusFailed = database.StatusFailed
+	// StatusDowngradeRefused means that the downloaded database was built
+	// before the current one and was not installed. The reason is in
+	// Result.Error.
+	StatusDowngradeRefused = database.StatusDowngradeRefused
+)
+
+// Config holds the settings of an Updater.
+type Config struct {
+	// AccountID is the account ID. It is not needed when a Client is set
+	// with WithClient.
+	AccountID int
+	// LicenseKey is the license key of the account. It is not needed when a
+	// Client is set with WithClient.
+	LicenseKey string
+	// URL is the base URL of the update server. By default,
+	// https://updates.maxmind.com is used. It may also be a file:// or s3://
+	// URL of another source, see [source.New].
+	URL string
+	// EditionIDs are the editions to update.
+	EditionIDs []string
+	// EditionDates pins editions to the release of a date, as YYYY-MM-DD,
+	// rather than the latest one. A pinned edition is only downloaded if its
+	// current database is not recorded as the release of that date, and may
+	// be replaced by an older build whatever AllowDowngrade is. A release
+	// may have been built on an earlier date, so the build date of the
+	// current database is not relied on. Which releases are installed is
+	// recorded in Config.DatabaseDirectory if it is a local directory.
+	// Otherwise, it is only recorded for the life of the Updater, so the
+	// release is downloaded again by each new Updater. Pinning requires the
+	// Client to be a DatedClient.
+	EditionDates map[string]string
+	// DatabaseDirectory is where the default Writer stores the databases. It
+	// is not needed when a Writer is set with WithWriter. Interrupted
+	// downloads are also kept there, so that they can be resumed, unless a
+	// Writer or Client is set. It may instead be the URL of object storage,
+	// s3://bucket/prefix, gs://bucket/prefix or azblob://container/prefix,
+	// whose credentials are read from the environment as by the AWS, Google
+	// Cloud and Azure tools, or of a container registry, oci://registry/prefix,
+	// whose credentials are read from the Docker configuration. With
+	// exec:///path/to/program, a program stores the databases, see
+	// doc/GeoIP.conf.md for the protocol it speaks.
+	DatabaseDirectory string
+	// Destinations are further directories or URLs, as for
+	// DatabaseDirectory, that the default Writer also writes every database
+	// to. Each database is downloaded once, and installed as a hard link
+	// where a local destination is on the same file system as one that was
+	// already written. The outcome for each destination is in
+	// Result.Destinations. DatabaseDirectory is the primary destination,
+	// where downloads are resumed and atomic groups are published.
+	Destinations []string
+	// PreserveFileTimes sets whether the default Writer sets the modification
+	// time of the databases to when they were built.
+	PreserveFileTimes bool
+	// LockFile is the path of a lock file that ensures only one update runs
+	// at a time across processes. No lock is taken if it is empty.
+	LockFile string
+	// Parallelism is the number of editions updated at the same time. It
+	// defaults to 1.
+	Parallelism int
+	// RetryFor is how long failed downloads are retried for. Downloads are
+	// not retried if it is zero.
+	RetryFor time.Duration
+	// ContinueOnError sets whether the remaining editions are still updated
+	// after one fails.
+	ContinueOnError bool
+	// SignaturePublicKey is a minisign public key. If it is set, every
+	// database must have a valid detached signature by this key before it
+	// replaces the current database.
+	SignaturePublicKey string
+	// SignatureDirectory is the directory the signatures are read from, as
+	// <edition>.mmdb.minisig, or <edition>_<YYYY-MM-DD>.mmdb.minisig for
+	// Backfill. By default, they are downloaded next to the databases,
+	// which requires the Client to be a SignatureClient.
+	SignatureDirectory string
+	// SignatureSuffix is the suffix the default Client downloads signatures
+	// with, e.g., mmdb.minisig. The MaxMind update server does not serve
+	// signatures, so it is only for servers that do. Without it, the default
+	// Client cannot download signatures. See [client.WithSignatureSuffix].
+	SignatureSuffix string
+	// AllowDowngrade sets whether the default Writer replaces a database with
+	// one that was built before it. Such databases are refused by default.
+	AllowDowngrade bool
+	// KeepVersions is the number of replaced databases the default Writer
+	// keeps for each edition, under .history in Config.DatabaseDirectory.
+	KeepVersions int
+	// AtomicGroup are editions that are published together, so that they
+	// are always from the same update. They are downloaded into a staging
+	// directory and, only once all of them succeeded, published together in
+	// a new release directory that the current symlink in
+	// Config.DatabaseDirectory is switched to. Their databases are thus at
+	// current/<edition>.mmdb there, whatever Writer is set. KeepVersions
+	// does not apply to them.
+	AtomicGroup []string
+}
+
+// Updater updates databases.
+//
+// After creation, it is valid for concurrent use.
+type Updater struct {
+	config    Config
+	client    Client
+	writer    Writer
+	logger    *log.Logger
+	now       func() time.Time
+	transport http.RoundTripper
+	// httpClient is the HTTP client of the default Client and Writer.
+	httpClient *http.Client
+	handlers   []func(Event)
+
+	signatureKey *minisign.PublicKey
+	pinned       *pinnedReleases
+}
+
+// Option is an option for configuring Updater.
+type Option func(*Updater)
+
+// WithClient sets the Client used to download editions. By default, the
+// source for the URL in the Config is created with [source.New], which is a
+// [client.Client] for the update server.
+func WithClient(c Client) Option {
+	return func(u *Updater) {
+		u.client = c
+	}
+}
+
+// WithWriter sets the Writer used to store databases. By default, databases
+// are stored in Config.DatabaseDirectory.
+func WithWriter(w Writer) Option {
+	return func(u *Updater) {
+		u.writer = w
+	}
+}
+
+// WithLogger sets the logger that progress is logged to. By default, nothing
+// is logged. The logger is an event handler like those added with
+// WithEventHandler, and is called before them.
+func WithLogger(l *log.Logger) Option {
+	return func(u *Updater) {
+		u.logger = l
+	}
+}
+
+// WithClock sets the function used to get the current time for the times
+// and durations in the results. By default, time.Now is used.
+func WithClock(now func() time.Time) Option {
+	return func(u *Updater) {
+		u.now = now
+	}
+}
+
+// WithHTTPTransport sets the transport used by the default Client, including
+// for object stores. By
+// default, http.DefaultTransport is used. It has no effect when a Client is
+// set with WithClient, or an HTTP client with WithHTTPClient.
+func WithHTTPTransport(rt http.RoundTripper) Option {
+	return func(u *Updater) {
+		u.transport = rt
+	}
+}
+
+// WithHTTPClient sets the HTTP client used by the default Client and by the
+// default Writer for object stores and container registries, e.g., to bound
+// requests with a timeout. By default, a client with the transport set with
+// WithHTTPTransport is used.
+func WithHTTPClient(c *http.Client) Option {
+	return func(u *Updater) {
+		u.httpClient = c
+	}
+}
+
+// New creates an Updater.
+func New(config Config, options ...Option) (*Updater, error) {
+	u := &Updater{
+		config:    config,
+		now:       time.Now,
+		transport: http.DefaultTransport,
+	}
+
+	for _, opt := range options {
+		opt(u)
+	}
+	if u.httpClient == nil {
+		u.httpClient = &http.Client{Transport: u.transport}
+	}
+
+	if u.config.Parallelism <= 0 {
+		u.config.Parallelism = 1
+	}
+
+	for _, dir := range append([]string{u.config.DatabaseDirectory}, u.config.Destinations...) {
+		if err := database.CheckDirectory(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	if u.logger != nil {
+		u.handlers = append([]func(Event){logEvents(u.logger)}, u.handlers...)
+	}
+
+	defaultClient := u.client == nil
+	if defaultClient {
+		sourceOpts := []source.Option{
+			source.WithHTTPClient(u.httpClient),
+			source.WithSignatureSuffix(u.config.SignatureSuffix),
+		}
+		if u.logger != nil {
+			sourceOpts = append(sourceOpts, source.WithLogger(u.logger))
+		}
+		if u.config.DatabaseDirectory != "" && u.writer == nil &&
+			!database.IsRemoteURL(u.config.DatabaseDirectory) {
+			sourceOpts = append(sourceOpts, source.WithResumeDirectory(u.config.DatabaseDirectory))
+		}
+
+		s, err := source.New(u.config.URL, u.config.AccountID, u.config.LicenseKey, sourceOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("creating client: %w", err)
+		}
+		u.client = s
+	}
+
+	if u.config.SignaturePublicKey != "" {
+		key, err := minisign.ParsePublicKey(u.config.SignaturePublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("parsing signature public key: %w", err)
+		}
+		u.signatureKey = key
+
+		if _, ok := u.client.(SignatureClient); !ok && u.config.SignatureDirectory == "" {
+			return nil, errors.New("the client cannot download signatures, a signature directory is required")
+		}
+		if defaultClient && u.config.SignatureDirectory == "" && u.config.SignatureSuffix == "" {
+			return nil, errors.New("a signature directory or suffix is required to check signatures")
+		}
+	}
+
+	if _, ok := u.client.(DatedClient); !ok && len(u.config.EditionDates) > 0 {
+		return nil, errors.New("the client cannot download editions pinned to a date")
+	}
+
+	pinned, err := loadPinnedReleases(pinnedPath(u.config.DatabaseDirectory))
+	if err != nil {
+		return nil, fmt.Errorf("loading pinned releases: %w", err)
+	}
+	u.pinned = pinned
+
+	if (len(u.config.AtomicGroup) > 0 || u.config.KeepVersions > 0) &&
+		database.IsRemoteURL(u.config.DatabaseDirectory) {
+		return nil, errors.New("atomic groups and kept versions require a local database directory")
+	}
+
+	if len(u.config.AtomicGroup) > 0 && len(u.config.Destinations) > 0 {
+		return nil, errors.New("atomic groups cannot be used with destinations")
+	}
+	if u.config.KeepVersions > 0 && slices.ContainsFunc(u.config.Destinations, database.IsRemoteURL) {
+		return nil, errors.New("kept versions require local destinations")
+	}
+
+	if len(u.config.AtomicGroup) > 0 && u.config.DatabaseDirectory == "" {
+		return nil, errors.New("an atomic group requires a database directory")
+	}
+
+	if u.writer == nil {
+		if u.config.DatabaseDirectory == "" {
+			return nil, errors.New("a database directory or writer is required")
+		}
+
+		w, err := u.newWriter()
+		if err != nil {
+			return nil, fmt.Errorf("creating writer: %w", err)
+		}
+		u.writer = w
+	}
+
+	return u, nil
+}
+
+// newWriter creates the default Writer for Config.DatabaseDirectory and
+// Config.Destinations.
+func (u *Updater) newWriter() (Writer, error) {
+	if len(u.config.Destinations) == 0 {
+		return u.newDirectoryWriter(u.config.DatabaseDirectory)
+	}
+
+	dirs := append([]string{u.config.DatabaseDirectory}, u.config.Destinations...)
+	destinations := make([]database.Destination, 0, len(dirs))
+	for _, dir := range dirs {
+		w, err := u.newDirectoryWriter(dir)
+		if err != nil {
+			return nil, fmt.Errorf("creating writer for %s: %w", dir, err)
+		}
+		destinations = append(destinations, database.Destination{Name: dir, Writer: w})
+	}
+	return database.NewMultiWriter(destinations, u.logger != nil), nil
+}
+
+// newDirectoryWriter creates the Writer for dir, which is either a local
+// directory, a program, a container registry or object storage.
+func (u *Updater) newDirectoryWriter(dir string) (Writer, error) {
+	if database.IsExecURL(dir) {
+		return database.NewExecWriter(
+			dir,
+			u.logger != nil,
+			database.WithExecAllowDowngrade(u.config.AllowDowngrade, u.pinnedEditions()...),
+		)
+	}
+	if database.IsOCIURL(dir) {
+		return database.NewOCIWriter(
+			dir,
+			u.httpClient,
+			u.logger != nil,
+			database.WithOCIAllowDowngrade(u.config.AllowDowngrade, u.pinnedEditions()...),
+		)
+	}
+	if database.IsObjectURL(dir) {
+		return database.NewObjectWriter(
+			dir,
+			u.httpClient,
+			u.logger != nil,
+			database.WithObjectAllowDowngrade(u.config.AllowDowngrade, u.pinnedEditions()...),
+		)
+	}
+	return database.NewLocalFileWriter(
+		dir,
+		u.config.PreserveFileTimes,
+		u.logger != nil,
+		database.WithAllowDowngrade(u.config.AllowDowngrade),
+		database.WithAllowDowngradeFor(u.pinnedEditions()...),
+		database.WithKeepVersions(u.config.KeepVersions),
+	)
+}
+
+// Run updates every edition in Config.EditionIDs.
+//
+// The results are in the order of the editions. Without ContinueOnError, Run
+// stops at the first failure and only returns the results of the editions
+// that were processed before it. With ContinueOnError, every edition has a
+// result, and the error joins the errors of all the editions that failed.
+func (u *Updater) Run(ctx context.Context) ([]Result, error) {
+	return u.RunEditions(ctx, u.config.EditionIDs)
+}
+
+// RunEditions is like Run but updates the given editions.
+func (u *Updater) RunEditions(ctx context.Context, editionIDs []string) ([]Result, error) {
+	if u.config.LockFile != "" {
+		fileLock, err := internal.NewFileLock(u.config.LockFile, u.logger != nil)
+		if err != nil {
+			return nil, fmt.Errorf("initializing file lock: %w", err)
+		}
+		if err := fileLock.Acquire(); err != nil {
+			return nil, fmt.Errorf("acquiring file lock: %w", err)
+		}
+		defer func() {
+			if err := fileLock.Release(); err != nil {
+				u.logf("Couldn't release the file lock: %s", err)
+			}
+		}()
+	}
+
+	var group *database.GroupWriter
+	if slices.ContainsFunc(editionIDs, u.inAtomicGroup) {
+		gw, err := database.NewGroupWriter(
+			u.config.DatabaseDirectory,
+			u.config.AtomicGroup,
+			u.config.PreserveFileTimes,
+			u.logger != nil,
+			database.WithAllowDowngrade(u.config.AllowDowngrade),
+			database.WithAllowDowngradeFor(u.pinnedEditions()...),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("staging atomic group: %w", err)
+		}
+		group = gw
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(u.config.Parallelism)
+
+	outcomes := make([]*outcome, len(editionIDs))
+	var mu sync.Mutex
+	for i, editionID := range editionIDs {
+		g.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				return fmt.Errorf("stop updating on the first error: %w", err)
+			}
+
+			var w Writer = u.writer
+			if group != nil && u.inAtomicGroup(editionID) {
+				w = group
+			}
+
+			o := u.updateEdition(ctx, editionID, w)
+			mu.Lock()
+			outcomes[i] = o
+			mu.Unlock()
+
+			// The editions of the atomic group are only done once it is
+			// published.
+			if w != group {
+				u.publishOutcome(o, pathOf(w, editionID))
+			}
+
+			if o.err != nil && !u.config.ContinueOnError {
+				return o.err
+			}
+			return nil
+		})
+	}
+
+	// Wait blocks until all the editions are downloaded or exits early after
+	// the first encountered error. With ContinueOnError, the goroutines never
+	// return an error and every edition is attempted.
+	err := g.Wait()
+
+	if group != nil {
+		if groupErr := u.finishAtomicGroup(group, editionIDs, outcomes, err); err == nil {
+			err = groupErr
+		}
+	}
+
+	var processed []Result
+	var editionErrs []error
+	for i, o := range outcomes {
+		if o == nil || (o.err != nil && !u.config.ContinueOnError) {
+			continue
+		}
+		processed = append(processed, *o.result)
+		if o.err != nil {
+			editionErrs = append(editionErrs, fmt.Errorf("%s: %w", editionIDs[i], o.err))
+		}
+	}
+
+	if err != nil {
+		return processed, fmt.Errorf("downloading editions: %w", err)
+	}
+
+	if err := errors.Join(editionErrs...); err != nil {
+		return processed, fmt.Errorf("downloading editions: %w", err)
+	}
+
+	return processed, nil
+}
+
+// outcome is how updating an edition ended.
+type outcome struct {
+	// result is never nil and records the attempts made even when there is
+	// an error.
+	result      *Result
+	transferred int64
+	err         error
+}
+
+// updateEdition updates the edition using w.
+func (u *Updater) updateEdition(
+	ctx context.Context,
+	editionID string,
+	w Writer,
+) *outcome {
+	start := u.now()
+	edition := &Result{EditionID: editionID}
+
+	var progress *progressReader
+	err := u.download(ctx, w, edition, &progress)
+
+	edition.Duration = u.now().Sub(start)
+	edition.CheckedAt = u.now().In(time.UTC)
+
+	var transferred int64
+	if progress != nil {
+		transferred = progress.bytesRead()
+	}
+
+	if err != nil {
+		edition.Status = StatusFailed
+		edition.Error = err.Error()
+	}
+
+	return &outcome{result: edition, transferred: transferred, err: err}
+}
+
+// publishOutcome publishes the final event of an edition, whose database is
+// at path.
+func (u *Updater) publishOutcome(o *outcome, path string) {
+	edition := o.result
+	switch {
+	case o.err != nil:
+		u.publish(EditionFailed{
+			Result:           *edition,
+			BytesTransferred: o.transferred,
+			Err:              o.err,
+		})
+	case edition.Status == StatusDowngradeRefused:
+		u.publish(DowngradeRefused{Result: *edition, Path: path})
+	case edition.Status == StatusUpdated:
+		u.publish(DatabaseReplaced{
+			Result:           *edition,
+			BytesTransferred: o.transferred,
+			Path:             path,
+		})
+	default:
+		u.publish(UpToDate{Result: *edition, Path: path})
+	}
+}
+
+// pinnedEditions returns the editions in Config.EditionDates.
+func (u *Updater) pinnedEditions() []string {
+	return slices.Sorted(maps.Keys(u.config.EditionDates))
+}
+
+// inAtomicGroup returns whether the edition is in Config.AtomicGroup.
+func (u *Updater) inAtomicGroup(editionID string) bool {
+	return slices.Contains(u.config.AtomicGroup, editionID)
+}
+
+// finishAtomicGroup publishes the staged editions of the atomic group if
+// none of them failed, or discards them otherwise, and then publishes the
+// final events of the editions. waitErr is the error that stopped the
+// update early, if any. The returned error is that of publishing the group.
+func (u *Updater) finishAtomicGroup(
+	group *database.GroupWriter,
+	editionIDs []string,
+	outcomes []*outcome,
+	waitErr error,
+) error {
+	failed := waitErr != nil
+	var grouped []*outcome
+	for i, editionID := range editionIDs {
+		if !u.inAtomicGroup(editionID) {
+			continue
+		}
+		if outcomes[i] == nil {
+			failed = true
+			continue
+		}
+		if outcomes[i].err != nil {
+			failed = true
+		}
+		grouped = append(grouped, outcomes[i])
+	}
+
+	var notPublished, publishErr error
+	if failed {
+		if err := group.Abort(); err != nil {
+			u.logf("Couldn't discard the atomic group: %s", err)
+		}
+		notPublished = errAtomicGroupFailed
+	} else if err := group.Commit(); err != nil {
+		publishErr = WriteError{Err: fmt.Errorf("publishing atomic group: %w", err)}
+		notPublished = publishErr
+	}
+
+	for _, o := range grouped {
+		edition := o.result
+		if notPublished != nil && o.err == nil && edition.Status == StatusUpdated {
+			o.err = notPublished
+			edition.Status = StatusFailed
+			edition.Error = notPublished.Error()
+			edition.NewHash = ""
+			edition.ModifiedAt = time.Time{}
+		}
+		u.publishOutcome(o, group.Path(edition.EditionID))
+	}
+
+	return publishErr
+}
+
+// download downloads the edition with retries, recording the outcome in
+// edition. progress is set to the reader of the last download attempt.
+func (u *Updater) download(
+	ctx context.Context,
+	w Writer,
+	edition *Result,
+	progress **progressReader,
+) error {
+	editionID := edition.EditionID
+
+	editionHash, err := database.GetHashContext(ctx, w, editionID)
+	if err != nil {
+		return err
+	}
+	edition.OldHash = editionHash
+
+	u.publish(CheckStarted{EditionID: editionID, OldHash: editionHash})
+
+	if u.pinned.held(editionID) {
+		u.logf("Not updating %s as it is held", editionID)
+		edition.NewHash = editionHash
+		edition.Status = StatusUpToDate
+		return nil
+	}
+
+	date, pinned := u.config.EditionDates[editionID]
+	if pinned && u.pinned.installed(editionID, date, editionHash) {
+		edition.NewHash = editionHash
+		edition.Status = StatusUpToDate
+		return nil
+	}
+
+	b := backoff.NewExponentialBackOff()
+
+	opts := []backoff.RetryOption{
+		backoff.WithBackOff(b),
+		backoff.WithNotify(func(err error, d time.Duration) {
+			u.logf("Couldn't download %s, retrying in %v: %v", editionID, d, err)
+		}),
+	}
+
+	if u.config.RetryFor == 0 {
+		opts = append(opts, backoff.WithMaxTries(1))
+	} else {
+		opts = append(opts, backoff.WithMaxElapsedTime(u.config.RetryFor))
+	}
+
+	_, err = backoff.Retry(
+		ctx,
+		func() (bool, error) {
+			edition.Attempts++
+
+			var res client.DownloadResponse
+			var err error
+			if pinned {
+				res, err = u.client.(DatedClient).DownloadDate(ctx, editionID, date)
+			} else {
+				res, err = u.client.Download(ctx, editionID, editionHash)
+			}
+			if err != nil {
+				if !internal.IsRetryableError(err) {
+					return false, backoff.Permanent(err)
+				}
+
+				return false, err
+			}
+			defer res.Reader.Close()
+
+			if !res.UpdateAvailable {
+				edition.NewHash = editionHash
+				edition.Status = StatusUpToDate
+				return false, nil
+			}
+
+			u.publish(UpdateAvailable{
+				EditionID:  editionID,
+				OldHash:    editionHash,
+				NewHash:    res.MD5,
+				ModifiedAt: res.LastModified,
+			})
+
+			reader := res.Reader
+			if u.signatureKey != nil {
+				verifier, err := u.signatureVerifier(ctx, editionID, editionID, res.Date)
+				if err != nil {
+					if u.config.SignatureDirectory != "" || !internal.IsRetryableError(err) {
+						return false, backoff.Permanent(err)
+					}
+
+					return false, err
+				}
+				reader = &verifyingReader{ReadCloser: reader, verifier: verifier}
+			}
+
+			*progress = &progressReader{
+				ReadCloser: reader,
+				editionID:  editionID,
+				publish:    u.publish,
+			}
+
+			err = database.WriteContext(
+				ctx,
+				w,
+				editionID,
+				*progress,
+				res.MD5,
+				res.LastModified,
+			)
+			edition.Destinations = destinationResultsOf(w, editionID)
+			if errors.Is(err, ErrDowngradeRefused) {
+				edition.NewHash = editionHash
+				edition.Status = StatusDowngradeRefused
+				edition.Error = err.Error()
+				return false, nil
+			}
+			if err != nil {
+				err = WriteError{Err: err}
+				if !internal.IsRetryableError(err) {
+					return false, backoff.Permanent(err)
+				}
+
+				return false, err
+			}
+
+			edition.NewHash = res.MD5
+			if edition.NewHash == "" {
+				// The MD5 of pinned databases is only known once written.
+				edition.NewHash, err = database.GetHashContext(ctx, w, editionID)
+				if err != nil {
+					return false, backoff.Permanent(fmt.Errorf("getting hash of the new database: %w", err))
+				}
+			}
+			if pinned {
+				if err := u.pinned.record(editionID, date, edition.NewHash); err != nil {
+					u.logf("Couldn't record the release of %s: %v", editionID, err)
+				}
+			}
+			edition.ModifiedAt = res.LastModified
+			edition.Status = StatusUpdated
+			return false, nil
+		},
+		opts...,
+	)
+	if err != nil {
+		// Retry returns errors that are still retryable once it gives up.
+		if ctx.Err() == nil && internal.IsRetryableError(err) {
+			err = RetryError{Attempts: edition.Attempts, Err: err}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// signatureVerifier returns a Verifier for the signature of the edition's
+// database built on date. name is the name of the database file without
+// extension, which the signature in Config.SignatureDirectory is named
+// after.
+func (u *Updater) signatureVerifier(
+	ctx context.Context,
+	editionID,
+	name,
+	date string,
+) (*minisign.Verifier, error) {
+	var sig []byte
+	var err error
+	if u.config.SignatureDirectory != "" {
+		path := filepath.Join(u.config.SignatureDirectory, name+signatureExtension)
+		//nolint:gosec // the path is built from the signature directory.
+		sig, err = os.ReadFile(path)
+	} else {
+		//nolint:forcetypeassert // New checks that it is one.
+		sig, err = u.client.(SignatureClient).DownloadSignature(ctx, editionID, date)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting signature of %s: %w", editionID, err)
+	}
+
+	parsed, err := minisign.ParseSignature(sig)
+	if err != nil {
+		return nil, fmt.Errorf("parsing signature of %s: %w", editionID, err)
+	}
+	return u.signatureKey.NewVerifier(parsed), nil
+}
+
+func (u *Updater) logf(format string, v ...any) {
+	if u.logger != nil {
+		u.logger.Printf(format, v...)
+	}
+}
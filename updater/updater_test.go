@@ -0,0 +1,719 @@
+package updater
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxmind/geoipupdate/v8/client"
+	"github.com/maxmind/geoipupdate/v8/internal/mmdbtest"
+)
+
+func TestRun(t *testing.T) {
+	modifiedAt := time.Date(2026, 9, 15, 12, 0, 0, 0, time.UTC)
+
+	c := mockClient{
+		responses: map[string]client.DownloadResponse{
+			"GeoIP2-City": {
+				LastModified:    modifiedAt,
+				MD5:             "B",
+				Reader:          io.NopCloser(strings.NewReader("new")),
+				UpdateAvailable: true,
+			},
+			"GeoIP2-Country": {
+				Reader: io.NopCloser(strings.NewReader("")),
+			},
+		},
+	}
+	w := &mockWriter{
+		md5s: map[string]string{
+			"GeoIP2-City":    "A",
+			"GeoIP2-Country": "C",
+		},
+	}
+
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	u, err := New(
+		Config{
+			EditionIDs:  []string{"GeoIP2-City", "GeoIP2-Country"},
+			LockFile:    filepath.Join(t.TempDir(), ".geoipupdate.lock"),
+			Parallelism: 1,
+		},
+		WithClient(c),
+		WithWriter(w),
+		WithClock(clock),
+	)
+	require.NoError(t, err)
+
+	results, err := u.Run(t.Context())
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	city := results[0]
+	require.Equal(t, "GeoIP2-City", city.EditionID)
+	require.Equal(t, StatusUpdated, city.Status)
+	require.Equal(t, "A", city.OldHash)
+	require.Equal(t, "B", city.NewHash)
+	require.Equal(t, modifiedAt, city.ModifiedAt)
+	require.Equal(t, 1, city.Attempts)
+	require.Equal(t, time.Second, city.Duration)
+	require.False(t, city.CheckedAt.IsZero())
+
+	country := results[1]
+	require.Equal(t, "GeoIP2-Country", country.EditionID)
+	require.Equal(t, StatusUpToDate, country.Status)
+	require.Equal(t, "C", country.NewHash)
+
+	require.Equal(t, "new", w.written["GeoIP2-City"])
+	require.NotContains(t, w.written, "GeoIP2-Country")
+}
+
+func TestRunErrors(t *testing.T) {
+	unauthorized := client.HTTPError{StatusCode: http.StatusUnauthorized}
+
+	tests := []struct {
+		description     string
+		continueOnError bool
+		wantEditions    []string
+	}{
+		{
+			description:  "stops at the first failure",
+			wantEditions: []string{"GeoIP2-City"},
+		},
+		{
+			description:     "continue on error",
+			continueOnError: true,
+			wantEditions:    []string{"GeoIP2-City", "GeoIP2-Country", "GeoIP2-ISP"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			c := mockClient{
+				responses: map[string]client.DownloadResponse{
+					"GeoIP2-City": {Reader: io.NopCloser(strings.NewReader(""))},
+					"GeoIP2-ISP":  {Reader: io.NopCloser(strings.NewReader(""))},
+				},
+				errs: map[string]error{"GeoIP2-Country": unauthorized},
+			}
+
+			u, err := New(
+				Config{
+					EditionIDs:      []string{"GeoIP2-City", "GeoIP2-Country", "GeoIP2-ISP"},
+					ContinueOnError: test.continueOnError,
+				},
+				WithClient(c),
+				WithWriter(&mockWriter{}),
+			)
+			require.NoError(t, err)
+
+			results, err := u.Run(t.Context())
+			require.ErrorIs(t, err, unauthorized)
+
+			var editions []string
+			for _, r := range results {
+				editions = append(editions, r.EditionID)
+			}
+			require.Equal(t, test.wantEditions, editions)
+
+			if test.continueOnError {
+				require.Equal(t, StatusFailed, results[1].Status)
+				require.Equal(t, unauthorized.Error(), results[1].Error)
+				require.Equal(t, StatusUpToDate, results[2].Status)
+			}
+		})
+	}
+}
+
+func TestRunLockHeld(t *testing.T) {
+	bc := blockingClient{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+
+	u, err := New(
+		Config{
+			EditionIDs: []string{"GeoIP2-City"},
+			LockFile:   filepath.Join(t.TempDir(), ".geoipupdate.lock"),
+		},
+		WithClient(bc),
+		WithWriter(&mockWriter{}),
+	)
+	require.NoError(t, err)
+
+	// The second Run uses its own lock, like another process would.
+	other, err := New(u.config, WithClient(bc), WithWriter(&mockWriter{}))
+	require.NoError(t, err)
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := u.Run(t.Context())
+		errCh <- err
+	}()
+	<-bc.started
+
+	_, err = other.Run(t.Context())
+	require.ErrorIs(t, err, ErrLockHeld)
+
+	close(bc.release)
+	require.NoError(t, <-errCh)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{AccountID: 1, LicenseKey: "key"})
+	require.EqualError(t, err, "a database directory or writer is required")
+
+	_, err = New(Config{DatabaseDirectory: t.TempDir()})
+	require.ErrorContains(t, err, "creating client")
+
+	_, err = New(Config{DatabaseDirectory: "file:///usr/share/GeoIP"})
+	require.EqualError(t, err, `the scheme "file" of file:///usr/share/GeoIP is not supported`)
+}
+
+func TestWithHTTPTransport(t *testing.T) {
+	dbContent := string(mmdbtest.Build("GeoIP2-City", 1))
+	sum := md5.Sum([]byte(dbContent))
+	md5 := hex.EncodeToString(sum[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/geoip/updates/metadata" {
+			_, err := w.Write([]byte(`{"databases":[{"edition_id":"GeoIP2-City",` +
+				`"md5":"` + md5 + `","date":"2026-09-15"}]}`))
+			assert.NoError(t, err)
+			return
+		}
+
+		w.Header().Set("Last-Modified", "Tue, 15 Sep 2026 12:00:00 GMT")
+		gw := gzip.NewWriter(w)
+		tw := tar.NewWriter(gw)
+		err := tw.WriteHeader(&tar.Header{
+			Name: "GeoIP2-City.mmdb",
+			Mode: 0o600,
+			Size: int64(len(dbContent)),
+		})
+		assert.NoError(t, err)
+		_, err = tw.Write([]byte(dbContent))
+		assert.NoError(t, err)
+		assert.NoError(t, tw.Close())
+		assert.NoError(t, gw.Close())
+	}))
+	defer server.Close()
+
+	rt := &countingTransport{}
+	dir := t.TempDir()
+
+	u, err := New(
+		Config{
+			AccountID:         1,
+			LicenseKey:        "key",
+			URL:               server.URL,
+			EditionIDs:        []string{"GeoIP2-City"},
+			DatabaseDirectory: dir,
+		},
+		WithHTTPTransport(rt),
+	)
+	require.NoError(t, err)
+
+	results, err := u.Run(t.Context())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, StatusUpdated, results[0].Status)
+	require.Equal(t, 2, rt.requests)
+
+	got, err := os.ReadFile(filepath.Join(dir, "GeoIP2-City.mmdb"))
+	require.NoError(t, err)
+	require.Equal(t, dbContent, string(got))
+}
+
+func TestRunFromDirectory(t *testing.T) {
+	src := t.TempDir()
+	md5 := mmdbtest.Write(t, filepath.Join(src, "GeoIP2-City.mmdb"), "GeoIP2-City", 1789430400)
+
+	u, err := New(Config{
+		URL:               "file://" + src,
+		EditionIDs:        []string{"GeoIP2-City"},
+		DatabaseDirectory: t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	for _, status := range []string{StatusUpdated, StatusUpToDate} {
+		results, err := u.Run(t.Context())
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		require.Equal(t, status, results[0].Status)
+		require.Equal(t, md5, results[0].NewHash)
+	}
+}
+
+func TestDestinations(t *testing.T) {
+	src := t.TempDir()
+	hash := mmdbtest.Write(t, filepath.Join(src, "GeoIP2-City.mmdb"), "GeoIP2-City", 1789430400)
+	dir := t.TempDir()
+	appDir := t.TempDir()
+
+	u, err := New(Config{
+		URL:               "file://" + src,
+		EditionIDs:        []string{"GeoIP2-City"},
+		DatabaseDirectory: dir,
+		Destinations:      []string{appDir},
+	})
+	require.NoError(t, err)
+
+	results, err := u.Run(t.Context())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, StatusUpdated, results[0].Status)
+	require.Equal(t, []DestinationResult{
+		{Destination: dir, Status: StatusUpdated},
+		{Destination: appDir, Status: StatusUpdated, Linked: true},
+	}, results[0].Destinations)
+
+	for _, d := range []string{dir, appDir} {
+		got, err := os.ReadFile(filepath.Join(d, "GeoIP2-City.mmdb"))
+		require.NoError(t, err)
+		sum := md5.Sum(got)
+		require.Equal(t, hash, hex.EncodeToString(sum[:]))
+	}
+
+	// Nothing is written once every destination is up to date.
+	results, err = u.Run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, StatusUpToDate, results[0].Status)
+	require.Empty(t, results[0].Destinations)
+
+	_, err = New(Config{
+		EditionIDs:        []string{"GeoIP2-City"},
+		DatabaseDirectory: dir,
+		Destinations:      []string{appDir},
+		AtomicGroup:       []string{"GeoIP2-City"},
+	}, WithClient(mockClient{}))
+	require.EqualError(t, err, "atomic groups cannot be used with destinations")
+}
+
+func TestDowngrade(t *testing.T) {
+	current := mmdbtest.Build("GeoIP2-City", 2000)
+	older := mmdbtest.Build("GeoIP2-City", 1000)
+	sum := md5.Sum(older)
+	olderHash := hex.EncodeToString(sum[:])
+
+	for _, allowDowngrade := range []bool{false, true} {
+		dir := t.TempDir()
+		dbPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+		require.NoError(t, os.WriteFile(dbPath, current, 0o600))
+
+		c := mockClient{
+			responses: map[string]client.DownloadResponse{
+				"GeoIP2-City": {
+					MD5:             olderHash,
+					Reader:          io.NopCloser(bytes.NewReader(older)),
+					UpdateAvailable: true,
+				},
+			},
+		}
+
+		var events []Event
+		u, err := New(
+			Config{
+				EditionIDs:        []string{"GeoIP2-City"},
+				DatabaseDirectory: dir,
+				RetryFor:          time.Minute,
+				AllowDowngrade:    allowDowngrade,
+			},
+			WithClient(c),
+			WithEventHandler(func(e Event) { events = append(events, e) }),
+		)
+		require.NoError(t, err)
+
+		results, err := u.Run(t.Context())
+		require.NoError(t, err, "a refused downgrade is not an error")
+		require.Len(t, results, 1)
+
+		got, err := os.ReadFile(dbPath)
+		require.NoError(t, err)
+
+		if allowDowngrade {
+			require.Equal(t, StatusUpdated, results[0].Status)
+			require.Equal(t, older, got)
+			continue
+		}
+
+		require.Equal(t, StatusDowngradeRefused, results[0].Status)
+		require.Equal(t, 1, results[0].Attempts)
+		require.Equal(t, results[0].OldHash, results[0].NewHash)
+		require.Contains(t, results[0].Error, "downgrade refused")
+		require.Equal(t, current, got)
+		require.Equal(t, DowngradeRefused{Result: results[0], Path: dbPath}, events[len(events)-1])
+	}
+}
+
+func TestAtomicGroup(t *testing.T) {
+	city := mmdbtest.Build("GeoIP2-City", 1000)
+	isp := mmdbtest.Build("GeoIP2-ISP", 1000)
+	response := func(db []byte) client.DownloadResponse {
+		sum := md5.Sum(db)
+		return client.DownloadResponse{
+			MD5:             hex.EncodeToString(sum[:]),
+			Reader:          io.NopCloser(bytes.NewReader(db)),
+			UpdateAvailable: true,
+		}
+	}
+
+	for _, ispFails := range []bool{false, true} {
+		c := mockClient{
+			responses: map[string]client.DownloadResponse{
+				"GeoIP2-City": response(city),
+				"GeoIP2-ISP":  response(isp),
+			},
+		}
+		if ispFails {
+			c.errs = map[string]error{"GeoIP2-ISP": errors.New("connection reset")}
+		}
+
+		dir := t.TempDir()
+		var events []Event
+		u, err := New(
+			Config{
+				EditionIDs:        []string{"GeoIP2-City", "GeoIP2-ISP"},
+				DatabaseDirectory: dir,
+				ContinueOnError:   true,
+				AtomicGroup:       []string{"GeoIP2-City", "GeoIP2-ISP"},
+			},
+			WithClient(c),
+			WithEventHandler(func(e Event) { events = append(events, e) }),
+		)
+		require.NoError(t, err)
+
+		results, err := u.Run(t.Context())
+		require.Len(t, results, 2)
+		cityPath := filepath.Join(dir, "current", "GeoIP2-City.mmdb")
+
+		if !ispFails {
+			require.NoError(t, err)
+			require.Equal(t, StatusUpdated, results[0].Status)
+			require.Equal(t, StatusUpdated, results[1].Status)
+
+			got, err := os.ReadFile(cityPath)
+			require.NoError(t, err)
+			require.Equal(t, city, got)
+			require.Contains(t, events, DatabaseReplaced{
+				Result:           results[0],
+				BytesTransferred: int64(len(city)),
+				Path:             cityPath,
+			})
+			continue
+		}
+
+		require.ErrorContains(t, err, "connection reset")
+		require.Equal(t, StatusFailed, results[0].Status)
+		require.Equal(t, errAtomicGroupFailed.Error(), results[0].Error)
+		require.Equal(t, StatusFailed, results[1].Status)
+
+		// Neither edition was published.
+		_, err = os.Lstat(filepath.Join(dir, "current"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+		require.Contains(t, events, EditionFailed{
+			Result:           results[0],
+			BytesTransferred: int64(len(city)),
+			Err:              errAtomicGroupFailed,
+		})
+	}
+}
+
+func TestAtomicGroupParallel(t *testing.T) {
+	editionIDs := []string{"GeoIP2-City", "GeoIP2-ISP", "GeoIP2-Domain", "GeoIP2-Connection-Type"}
+	responses := map[string]client.DownloadResponse{}
+	for _, editionID := range editionIDs {
+		db := mmdbtest.Build(editionID, 1000)
+		sum := md5.Sum(db)
+		responses[editionID] = client.DownloadResponse{
+			MD5:             hex.EncodeToString(sum[:]),
+			Reader:          io.NopCloser(bytes.NewReader(db)),
+			UpdateAvailable: true,
+		}
+	}
+
+	dir := t.TempDir()
+	u, err := New(
+		Config{
+			EditionIDs:        editionIDs,
+			DatabaseDirectory: dir,
+			Parallelism:       2,
+			AtomicGroup:       editionIDs,
+		},
+		WithClient(mockClient{responses: responses}),
+	)
+	require.NoError(t, err)
+
+	results, err := u.Run(t.Context())
+	require.NoError(t, err)
+	require.Len(t, results, len(editionIDs))
+	for i, editionID := range editionIDs {
+		require.Equal(t, StatusUpdated, results[i].Status)
+		_, err := os.Stat(filepath.Join(dir, "current", editionID+".mmdb"))
+		require.NoError(t, err)
+	}
+}
+
+func TestPinnedEdition(t *testing.T) {
+	// This was built on 2026-09-15, the pinned date.
+	pinned := mmdbtest.Build("GeoIP2-City", 1789430400)
+	sum := md5.Sum(pinned)
+	pinnedHash := hex.EncodeToString(sum[:])
+
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+	require.NoError(t, os.WriteFile(dbPath, mmdbtest.Build("GeoIP2-City", 1789430400+7*24*60*60), 0o600))
+
+	c := &datedClient{db: pinned}
+	config := Config{
+		EditionIDs:        []string{"GeoIP2-City"},
+		EditionDates:      map[string]string{"GeoIP2-City": "2026-09-15"},
+		DatabaseDirectory: dir,
+	}
+
+	_, err := New(config, WithClient(mockClient{}))
+	require.EqualError(t, err, "the client cannot download editions pinned to a date")
+
+	u, err := New(config, WithClient(c))
+	require.NoError(t, err)
+
+	// The pinned database replaces the newer one.
+	results, err := u.Run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, StatusUpdated, results[0].Status)
+	require.Equal(t, pinnedHash, results[0].NewHash)
+	require.Equal(t, []string{"2026-09-15"}, c.dates)
+
+	got, err := os.ReadFile(dbPath)
+	require.NoError(t, err)
+	require.Equal(t, pinned, got)
+
+	// It is not downloaded again.
+	results, err = u.Run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, StatusUpToDate, results[0].Status)
+	require.Equal(t, pinnedHash, results[0].NewHash)
+	require.Len(t, c.dates, 1)
+}
+
+func TestPinnedEditionReleasedLater(t *testing.T) {
+	// This was built on 2026-09-14, the day before it was released.
+	pinned := mmdbtest.Build("GeoIP2-City", 1789430400-24*60*60)
+	config := Config{
+		EditionIDs:        []string{"GeoIP2-City"},
+		EditionDates:      map[string]string{"GeoIP2-City": "2026-09-15"},
+		DatabaseDirectory: t.TempDir(),
+	}
+	c := &datedClient{db: pinned}
+
+	u, err := New(config, WithClient(c))
+	require.NoError(t, err)
+	results, err := u.Run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, StatusUpdated, results[0].Status)
+
+	// The installed release is recorded, so that later runs do not download
+	// it again.
+	u, err = New(config, WithClient(c))
+	require.NoError(t, err)
+	results, err = u.Run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, StatusUpToDate, results[0].Status)
+	require.Len(t, c.dates, 1)
+
+	// Pinning another date downloads its release.
+	config.EditionDates = map[string]string{"GeoIP2-City": "2026-09-16"}
+	u, err = New(config, WithClient(c))
+	require.NoError(t, err)
+	results, err = u.Run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, StatusUpdated, results[0].Status)
+	require.Equal(t, []string{"2026-09-15", "2026-09-16"}, c.dates)
+}
+
+func TestHeldEdition(t *testing.T) {
+	held := mmdbtest.Build("GeoIP2-City", 1000)
+	sum := md5.Sum(held)
+	heldHash := hex.EncodeToString(sum[:])
+
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "GeoIP2-City.mmdb")
+	require.NoError(t, os.WriteFile(dbPath, held, 0o600))
+	require.NoError(t, Hold(dir, "GeoIP2-City", heldHash))
+
+	latest := mmdbtest.Build("GeoIP2-City", 2000)
+	sum = md5.Sum(latest)
+	newClient := func() Client {
+		return mockClient{responses: map[string]client.DownloadResponse{
+			"GeoIP2-City": {
+				MD5:             hex.EncodeToString(sum[:]),
+				Reader:          io.NopCloser(bytes.NewReader(latest)),
+				UpdateAvailable: true,
+			},
+		}}
+	}
+	config := Config{
+		EditionIDs:        []string{"GeoIP2-City"},
+		DatabaseDirectory: dir,
+	}
+
+	// A held edition is left alone.
+	u, err := New(config, WithClient(newClient()))
+	require.NoError(t, err)
+	results, err := u.Run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, StatusUpToDate, results[0].Status)
+	require.Equal(t, heldHash, results[0].NewHash)
+	got, err := os.ReadFile(dbPath)
+	require.NoError(t, err)
+	require.Equal(t, held, got)
+
+	// Once the hold is cleared, it is updated again.
+	unheld, err := Unhold(dir, "GeoIP2-City")
+	require.NoError(t, err)
+	require.True(t, unheld)
+	unheld, err = Unhold(dir, "GeoIP2-City")
+	require.NoError(t, err)
+	require.False(t, unheld)
+
+	u, err = New(config, WithClient(newClient()))
+	require.NoError(t, err)
+	results, err = u.Run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, StatusUpdated, results[0].Status)
+	got, err = os.ReadFile(dbPath)
+	require.NoError(t, err)
+	require.Equal(t, latest, got)
+
+	require.EqualError(
+		t,
+		Hold("s3://bucket/prefix", "GeoIP2-City", heldHash),
+		"holding editions requires a local database directory",
+	)
+}
+
+// datedClient serves db for every date.
+type datedClient struct {
+	mockClient
+
+	db    []byte
+	dates []string
+}
+
+func (c *datedClient) DownloadDate(
+	_ context.Context,
+	_,
+	date string,
+) (client.DownloadResponse, error) {
+	c.dates = append(c.dates, date)
+	return client.DownloadResponse{
+		Date:            date,
+		Reader:          io.NopCloser(bytes.NewReader(c.db)),
+		UpdateAvailable: true,
+	}, nil
+}
+
+type mockClient struct {
+	responses map[string]client.DownloadResponse
+	errs      map[string]error
+}
+
+func (m mockClient) Download(
+	_ context.Context,
+	editionID,
+	_ string,
+) (client.DownloadResponse, error) {
+	if err, ok := m.errs[editionID]; ok {
+		return client.DownloadResponse{}, err
+	}
+	res, ok := m.responses[editionID]
+	if !ok {
+		return client.DownloadResponse{}, errors.New("unknown edition")
+	}
+	return res, nil
+}
+
+type blockingClient struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c blockingClient) Download(
+	_ context.Context,
+	_,
+	_ string,
+) (client.DownloadResponse, error) {
+	close(c.started)
+	<-c.release
+	return client.DownloadResponse{Reader: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+type mockWriter struct {
+	mu      sync.Mutex
+	md5s    map[string]string
+	written map[string]string
+}
+
+func (w *mockWriter) Write(
+	editionID string,
+	reader io.ReadCloser,
+	_ string,
+	_ time.Time,
+) error {
+	defer reader.Close()
+
+	buf := &bytes.Buffer{}
+	if _, err := io.Copy(buf, reader); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.written == nil {
+		w.written = map[string]string{}
+	}
+	w.written[editionID] = buf.String()
+	return nil
+}
+
+func (w *mockWriter) GetHash(editionID string) (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if h, ok := w.md5s[editionID]; ok {
+		return h, nil
+	}
+	return ZeroMD5, nil
+}
+
+type countingTransport struct {
+	mu       sync.Mutex
+	requests int
+}
+
+func (c *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	c.requests++
+	c.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(r)
+}